@@ -0,0 +1,141 @@
+package cli
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	cmd2 "github.com/antoine-granier/urlshortener/cmd"
+	"github.com/antoine-granier/urlshortener/internal/models"
+	"github.com/antoine-granier/urlshortener/internal/repository"
+	"github.com/antoine-granier/urlshortener/internal/services"
+	"github.com/spf13/cobra"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// Flags de la commande 'alert'
+var (
+	alertCodeFlag      string
+	alertCampaignFlag  string
+	alertOwnerFlag     string
+	alertKindFlag      string
+	alertThresholdFlag int
+	alertWindowFlag    int
+	alertListFlag      bool
+	alertDeleteFlag    uint
+)
+
+// AlertCmd représente la commande 'alert'
+var AlertCmd = &cobra.Command{
+	Use:   "alert",
+	Short: "Crée, liste ou supprime des règles d'alerte sur le trafic d'un lien ou d'une campagne.",
+	Long: `Cette commande gère les règles d'alerte évaluées par le serveur (run-server) :
+  click_spike  plus de --threshold clics en --window minutes (5 par défaut)
+  no_clicks    aucun clic depuis --window minutes (1440 par défaut)
+  new_country  clics venant d'un pays jamais vu
+  anomaly      trafic des --window dernières minutes (60 par défaut) supérieur à --threshold fois
+               le trafic habituel (3 par défaut)
+Les alertes sont notifiées dans les logs du serveur et aux webhooks abonnés à alert.triggered.
+
+Exemple:
+  url-shortener alert --code="xyz123" --kind=click_spike --threshold=500
+  url-shortener alert --campaign="soldes" --owner="marketing" --kind=anomaly
+  url-shortener alert --list [--code="xyz123" | --campaign="soldes" --owner="marketing" | --owner="marketing"]
+  url-shortener alert --delete=4`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Charger la configuration globale
+		cfg := cmd2.Cfg
+		if cfg == nil {
+			log.Fatal("Configuration non initialisée")
+		}
+
+		// Initialiser la connexion à la base de données SQLite
+		db, err := gorm.Open(sqlite.Open(cfg.Database.Name), &gorm.Config{})
+		if err != nil {
+			log.Fatalf("Erreur de connexion à la BDD : %v", err)
+		}
+		sqlDB, err := db.DB()
+		if err != nil {
+			log.Fatalf("Échec de l'obtention de la DB SQL : %v", err)
+		}
+		defer sqlDB.Close()
+
+		// Initialiser les repositories et services nécessaires
+		linkSvc := services.NewLinkService(repository.NewLinkRepository(db), repository.NewClickRepository(db))
+		alertSvc := services.NewAlertService(linkSvc, repository.NewAlertRepository(db), nil, cfg.Alerts.AnomalyBaselineDays)
+
+		switch {
+		case alertListFlag:
+			rules, err := alertSvc.ListRules(alertCodeFlag, alertCampaignFlag, alertOwnerFlag)
+			if err != nil {
+				log.Fatalf("Erreur lors de la récupération des règles d'alerte : %v", err)
+			}
+			if len(rules) == 0 {
+				fmt.Println("Aucune règle d'alerte.")
+				return
+			}
+			for _, rule := range rules {
+				target := rule.Target
+				if rule.CampaignID != nil {
+					target = "campagne " + target
+				}
+				fmt.Printf("#%-4d %-12s %-25s %s", rule.ID, rule.Kind, target, describeAlertRule(&rule))
+				if rule.LastTriggeredAt != nil {
+					fmt.Printf("  (dernière alerte : %s)", rule.LastTriggeredAt.Local().Format(time.RFC3339))
+				}
+				fmt.Println()
+			}
+
+		case alertDeleteFlag != 0:
+			if err := alertSvc.DeleteRule(alertDeleteFlag); err != nil {
+				log.Fatalf("Erreur lors de la suppression : %v", err)
+			}
+			fmt.Printf("Règle d'alerte #%d supprimée.\n", alertDeleteFlag)
+
+		default:
+			rule, err := alertSvc.CreateRule(services.AlertRuleInput{
+				ShortCode:     alertCodeFlag,
+				Campaign:      alertCampaignFlag,
+				Owner:         alertOwnerFlag,
+				Kind:          alertKindFlag,
+				Threshold:     alertThresholdFlag,
+				WindowMinutes: alertWindowFlag,
+			})
+			if err != nil {
+				log.Fatalf("Erreur lors de la création de la règle d'alerte : %v", err)
+			}
+			fmt.Printf("Règle d'alerte #%d créée : %s sur %s (%s).\n", rule.ID, rule.Kind, rule.Target, describeAlertRule(rule))
+		}
+	},
+}
+
+// describeAlertRule résume la condition d'une règle d'alerte.
+func describeAlertRule(rule *models.AlertRule) string {
+	window := time.Duration(rule.WindowMinutes) * time.Minute
+	switch rule.Kind {
+	case models.AlertClickSpike:
+		return fmt.Sprintf("plus de %d clics en %v", rule.Threshold, window)
+	case models.AlertNoClicks:
+		return fmt.Sprintf("aucun clic depuis %v", window)
+	case models.AlertAnomaly:
+		return fmt.Sprintf("trafic sur %v supérieur à %d fois la normale", window, rule.Threshold)
+	default:
+		return "clics depuis un nouveau pays"
+	}
+}
+
+func init() {
+	AlertCmd.Flags().StringVarP(&alertCodeFlag, "code", "c", "", "Code court du lien surveillé")
+	AlertCmd.Flags().StringVar(&alertCampaignFlag, "campaign", "", "Campagne surveillée (avec --owner)")
+	AlertCmd.Flags().StringVar(&alertOwnerFlag, "owner", "", "Equipe propriétaire de la campagne")
+	AlertCmd.Flags().StringVar(&alertKindFlag, "kind", "", "Type de règle ("+strings.Join(services.AlertKinds, ", ")+")")
+	AlertCmd.Flags().IntVar(&alertThresholdFlag, "threshold", 0, "Seuil de clics (click_spike) ou multiple du trafic habituel (anomaly)")
+	AlertCmd.Flags().IntVar(&alertWindowFlag, "window", 0, "Fenêtre d'évaluation en minutes (24h maximum)")
+	AlertCmd.Flags().BoolVar(&alertListFlag, "list", false, "Liste les règles d'alerte")
+	AlertCmd.Flags().UintVar(&alertDeleteFlag, "delete", 0, "Supprime la règle d'alerte d'ID donné")
+
+	// Ajouter la commande à RootCmd
+	cmd2.RootCmd.AddCommand(AlertCmd)
+}
@@ -1,12 +1,17 @@
 package cli
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"log"
 	"net/url" // Pour valider le format de l'URL
 	"os"
+	"strings"
+	"time"
 
 	cmd2 "github.com/antoine-granier/urlshortener/cmd"
+	"github.com/antoine-granier/urlshortener/internal/models"
 	"github.com/antoine-granier/urlshortener/internal/repository"
 	"github.com/antoine-granier/urlshortener/internal/services"
 	"github.com/spf13/cobra"
@@ -17,14 +22,96 @@ import (
 // Faire une variable longURLFlag qui stockera la valeur du flag --url
 var longURLFlag string
 
+// Propriétaire du lien et réutilisation d'un lien existant (flags --owner et --reuse)
+var (
+	ownerFlag string
+	reuseFlag bool
+)
+
+// Comportement de redirection du lien (flags --redirect-type, --forward-query et --wildcard)
+var (
+	redirectTypeFlag int
+	forwardQueryFlag bool
+	wildcardFlag     bool
+)
+
+// Variantes A/B au format nom:poids:url (flag --variant, répétable)
+var variantFlags []string
+
+// Règles géographiques au format PAYS=url ou continent:CODE=url (flag --geo-rule, répétable)
+var geoRuleFlags []string
+
+// Fichier JSON de règles de routage conditionnelles (flag --rules-file)
+var rulesFileFlag string
+
+// Mise en service différée (flags --activates-at et --coming-soon)
+var (
+	activatesAtFlag string
+	comingSoonFlag  bool
+)
+
+// Protection du lien (flags --password, --one-time et --signed-only)
+var (
+	passwordFlag   string
+	oneTimeFlag    bool
+	signedOnlyFlag bool
+)
+
+// Page "vous quittez…" avant la redirection (flag --interstitial)
+var interstitialFlag bool
+
+// Personnalisation de la carte de partage (flags --card-title, --card-description et --card-image)
+var (
+	cardTitleFlag       string
+	cardDescriptionFlag string
+	cardImageFlag       string
+)
+
+// Organisation du lien (flags --tag, répétable, et --campaign)
+var (
+	tagFlags     []string
+	campaignFlag string
+)
+
+// Paramètres UTM ajoutés à l'URL (flags --utm-source, --utm-medium, ... et --utm-preset)
+var (
+	utmFlags      models.UTM
+	utmPresetFlag string
+)
+
+// Règles de plateforme au format plateforme=url et liens profonds au format plateforme=uri (flags répétables)
+var (
+	deviceRuleFlags []string
+	deepLinkFlags   []string
+)
+
 // CreateCmd représente la commande 'create'
 var CreateCmd = &cobra.Command{
 	Use:   "create",
 	Short: "Crée une URL courte à partir d'une URL longue.",
 	Long: `Cette commande raccourcit une URL longue fournie et affiche le code court généré.
 
+Avec --reuse, si le propriétaire possède déjà un lien vers la même URL (après canonicalisation)
+et avec les mêmes réglages (type de redirection, variantes, règles, tags, campagne...),
+ce lien est réutilisé au lieu d'en créer un nouveau.
+
 Exemple:
-  url-shortener create --url="https://www.google.com/search?q=go+lang"`,
+  url-shortener create --url="https://www.google.com/search?q=go+lang"
+  url-shortener create --url="https://example.com/page" --owner="marketing" --reuse
+  url-shortener create --url="https://docs.example.com/" --wildcard --redirect-type=301
+  url-shortener create --url="https://example.com/" --variant="A:50:https://example.com/a" --variant="B:50:https://example.com/b"
+  url-shortener create --url="https://example.com/app" --device-rule="ios=https://apps.apple.com/app/id123" --deep-link="ios=myapp://home"
+  url-shortener create --url="https://shop.example.com/" --geo-rule="FR=https://shop.example.fr/" --geo-rule="continent:EU=https://shop.example.eu/"
+  url-shortener create --url="https://example.com/" --rules-file=rules.json
+  url-shortener create --url="https://example.com/produit" --activates-at="2027-03-01T09:00:00+01:00" --coming-soon
+  url-shortener create --url="https://example.com/doc-confidentiel" --password="s3cret" --one-time
+  url-shortener create --url="https://example.com/soldes" --card-title="Soldes d'été" --card-image="https://cdn.example.com/soldes.png"
+  url-shortener create --url="https://example.com/ete" --owner="marketing" --campaign="soldes-ete" --tag=newsletter --tag=emailing
+  url-shortener create --url="https://example.com/ete" --owner="marketing" --utm-preset="newsletter" --utm-content="bandeau"
+
+Le fichier de règles contient une liste JSON évaluée dans l'ordre, par exemple :
+  [{"name": "noel", "when": {"before": "2027-01-02", "timezone": "Europe/Paris"}, "url": "https://example.com/noel"},
+   {"name": "fr", "when": {"languages": ["fr"]}, "url": "https://example.com/fr"}]`,
 	Run: func(cmd *cobra.Command, args []string) {
 		// Valider que le flag --url a été fourni
 		if longURLFlag == "" {
@@ -37,6 +124,70 @@ Exemple:
 			log.Fatalf("URL invalide : %v", err)
 		}
 
+		// Lire les variantes A/B éventuelles
+		var variants []services.VariantInput
+		for _, raw := range variantFlags {
+			v, err := services.ParseVariant(raw)
+			if err != nil {
+				log.Fatalf("Variante invalide : %v", err)
+			}
+			variants = append(variants, v)
+		}
+
+		// Lire les règles de plateforme et leur lien profond éventuel
+		var deviceRules []services.DeviceRuleInput
+		for _, raw := range deviceRuleFlags {
+			rule, err := services.ParseDeviceRule(raw)
+			if err != nil {
+				log.Fatalf("Règle de plateforme invalide : %v", err)
+			}
+			deviceRules = append(deviceRules, rule)
+		}
+		for _, raw := range deepLinkFlags {
+			platform, uri, ok := strings.Cut(raw, "=")
+			found := false
+			for i := range deviceRules {
+				if ok && strings.EqualFold(deviceRules[i].Platform, platform) {
+					deviceRules[i].DeepLink, found = uri, true
+				}
+			}
+			if !found {
+				log.Fatalf("Lien profond invalide : %q (format plateforme=uri, avec une règle --device-rule pour cette plateforme)", raw)
+			}
+		}
+
+		// Lire les règles géographiques
+		var geoRules []services.GeoRuleInput
+		for _, raw := range geoRuleFlags {
+			rule, err := services.ParseGeoRule(raw)
+			if err != nil {
+				log.Fatalf("Règle géographique invalide : %v", err)
+			}
+			geoRules = append(geoRules, rule)
+		}
+
+		// Lire les règles de routage conditionnelles
+		var routingRules []models.RoutingRule
+		if rulesFileFlag != "" {
+			raw, err := os.ReadFile(rulesFileFlag)
+			if err != nil {
+				log.Fatalf("Impossible de lire le fichier de règles : %v", err)
+			}
+			if err := json.Unmarshal(raw, &routingRules); err != nil {
+				log.Fatalf("Fichier de règles invalide : %v", err)
+			}
+		}
+
+		// Lire la date de mise en service éventuelle
+		var activatesAt *time.Time
+		if activatesAtFlag != "" {
+			t, err := time.Parse(time.RFC3339, activatesAtFlag)
+			if err != nil {
+				log.Fatalf("Date de mise en service invalide (format RFC 3339 attendu) : %v", err)
+			}
+			activatesAt = &t
+		}
+
 		// Charger la configuration globale
 		cfg := cmd2.Cfg
 		if cfg == nil {
@@ -56,19 +207,101 @@ Exemple:
 
 		// Initialiser les repositories et services nécessaires
 		linkRepo := repository.NewLinkRepository(db)
-		linkSvc := services.NewLinkService(linkRepo)
+		clickRepo := repository.NewClickRepository(db)
+		linkSvc := services.NewLinkService(linkRepo, clickRepo)
+
+		// Configurer la stratégie de génération des codes courts
+		codeGen, err := services.NewCodeGenerator(services.CodeGeneratorConfig{
+			Strategy:  cfg.ShortCode.Strategy,
+			Length:    cfg.ShortCode.Length,
+			Obfuscate: cfg.ShortCode.Obfuscate,
+			Secret:    cfg.ShortCode.Secret,
+		}, repository.NewSequenceRepository(db))
+		if err != nil {
+			log.Fatalf("Erreur de configuration des codes courts : %v", err)
+		}
+		linkSvc.SetCodeGenerator(codeGen)
+		linkSvc.SetURLCanonicalizer(&services.URLCanonicalizer{
+			StripTracking:  cfg.Links.StripTrackingParams,
+			TrackingParams: cfg.Links.TrackingParams,
+		})
+
+		// Enregistrer la notification link.created des webhooks, envoyée ensuite par le serveur
+		events := services.NewEventBus()
+		services.NewWebhookService(repository.NewWebhookRepository(db), events, 0, 0, 0)
+		linkSvc.SetEventBus(events)
 
 		// Créer le lien court
-		link, err := linkSvc.CreateLink(longURLFlag)
+		link, reused, err := linkSvc.CreateLinkWithOptions(context.Background(), services.CreateLinkOptions{
+			LongURL:       longURLFlag,
+			Owner:         ownerFlag,
+			ReuseExisting: reuseFlag,
+
+			RedirectType:    redirectTypeFlag,
+			ForwardQuery:    forwardQueryFlag,
+			PathPassthrough: wildcardFlag,
+
+			Variants:    variants,
+			DeviceRules: deviceRules,
+			GeoRules:    geoRules,
+
+			RoutingRules: routingRules,
+
+			ActivatesAt: activatesAt,
+			ComingSoon:  comingSoonFlag,
+
+			Password: passwordFlag,
+			OneTime:  oneTimeFlag,
+
+			SignedOnly: signedOnlyFlag,
+
+			Interstitial: interstitialFlag,
+
+			CardTitle:       cardTitleFlag,
+			CardDescription: cardDescriptionFlag,
+			CardImageURL:    cardImageFlag,
+
+			Tags:     tagFlags,
+			Campaign: campaignFlag,
+
+			UTM:       utmFlags,
+			UTMPreset: utmPresetFlag,
+		})
 		if err != nil {
 			log.Fatalf("Erreur lors de la création du lien : %v", err)
 		}
 
 		// Afficher le résultat
 		fullShortURL := fmt.Sprintf("%s/%s", cfg.Server.BaseURL, link.ShortCode)
-		fmt.Println("URL courte créée avec succès:")
+		if reused {
+			fmt.Println("Lien existant réutilisé:")
+		} else {
+			fmt.Println("URL courte créée avec succès:")
+		}
 		fmt.Printf("Code: %s\n", link.ShortCode)
 		fmt.Printf("URL complète: %s\n", fullShortURL)
+		fmt.Printf("Aperçu: %s+\n", fullShortURL)
+		if !link.UTM.IsZero() {
+			fmt.Printf("Destination: %s\n", link.LongURL)
+		}
+		if link.ActivatesAt != nil {
+			fmt.Printf("Mise en service: %s\n", link.ActivatesAt.Local().Format(time.RFC3339))
+		}
+		if link.PasswordHash != "" {
+			fmt.Println("Protégé par mot de passe")
+		}
+		if link.OneTime {
+			fmt.Println("Usage unique: le lien sera invalidé après la première redirection")
+		}
+		if link.SignedOnly {
+			fmt.Println("Accessible uniquement via des liens signés (commande 'sign')")
+		}
+		if link.Campaign != nil {
+			fmt.Printf("Campagne: %s\n", link.Campaign.Name)
+		}
+		if len(link.Tags) > 0 {
+			fmt.Printf("Tags: %s\n", tagNames(link.Tags))
+		}
 	},
 }
 
@@ -76,6 +309,33 @@ func init() {
 	// Définir et marquer le flag --url comme requis
 	CreateCmd.Flags().StringVarP(&longURLFlag, "url", "u", "", "URL à raccourcir")
 	CreateCmd.MarkFlagRequired("url")
+	CreateCmd.Flags().StringVar(&ownerFlag, "owner", "", "Propriétaire du lien (équipe ou système)")
+	CreateCmd.Flags().BoolVar(&reuseFlag, "reuse", false, "Réutilise le lien existant du propriétaire pour une URL et des réglages identiques")
+	CreateCmd.Flags().IntVar(&redirectTypeFlag, "redirect-type", 302, "Code HTTP de redirection: 301, 302, 307 ou 308")
+	CreateCmd.Flags().BoolVar(&forwardQueryFlag, "forward-query", false, "Transmet la query string de la requête à la destination")
+	CreateCmd.Flags().BoolVar(&wildcardFlag, "wildcard", false, "Lien joker: /code/suite redirige vers <destination>/suite")
+	CreateCmd.Flags().StringArrayVar(&variantFlags, "variant", nil, "Variante A/B au format nom:poids:url (répétable)")
+	CreateCmd.Flags().StringArrayVar(&deviceRuleFlags, "device-rule", nil, "Destination par plateforme au format plateforme=url (ios, android, mobile, tablet, desktop, windows, macos, linux ; répétable)")
+	CreateCmd.Flags().StringArrayVar(&geoRuleFlags, "geo-rule", nil, "Destination par pays (FR=url) ou continent (continent:EU=url), le pays étant prioritaire (répétable)")
+	CreateCmd.Flags().StringVar(&rulesFileFlag, "rules-file", "", "Fichier JSON de règles de routage (langue, horaires, provenance, paramètres, dates), évaluées en premier")
+	CreateCmd.Flags().StringVar(&activatesAtFlag, "activates-at", "", "Date de mise en service (RFC 3339) ; le lien répond 404 avant cette date")
+	CreateCmd.Flags().BoolVar(&comingSoonFlag, "coming-soon", false, "Affiche une page \"bientôt disponible\" au lieu d'une 404 avant la mise en service")
+	CreateCmd.Flags().StringVar(&passwordFlag, "password", "", "Mot de passe demandé aux visiteurs avant la redirection")
+	CreateCmd.Flags().BoolVar(&oneTimeFlag, "one-time", false, "Lien à usage unique, invalidé après la première redirection réussie")
+	CreateCmd.Flags().BoolVar(&signedOnlyFlag, "signed-only", false, "Le code court seul ne redirige pas : accès uniquement via des liens signés (commande 'sign')")
+	CreateCmd.Flags().BoolVar(&interstitialFlag, "interstitial", false, "Affiche une page \"vous quittez…\" avec compte à rebours avant la redirection")
+	CreateCmd.Flags().StringVar(&cardTitleFlag, "card-title", "", "Titre de la carte de partage (réseaux sociaux), à la place de celui de la destination")
+	CreateCmd.Flags().StringVar(&cardDescriptionFlag, "card-description", "", "Description de la carte de partage")
+	CreateCmd.Flags().StringVar(&cardImageFlag, "card-image", "", "Image de la carte de partage (URL http(s))")
+	CreateCmd.Flags().StringSliceVar(&tagFlags, "tag", nil, "Tag du lien (répétable ou séparé par des virgules)")
+	CreateCmd.Flags().StringVar(&campaignFlag, "campaign", "", "Campagne du lien, créée au besoin pour le propriétaire")
+	CreateCmd.Flags().StringVar(&utmFlags.Source, "utm-source", "", "Paramètre utm_source ajouté à l'URL (ex: newsletter)")
+	CreateCmd.Flags().StringVar(&utmFlags.Medium, "utm-medium", "", "Paramètre utm_medium ajouté à l'URL (ex: email)")
+	CreateCmd.Flags().StringVar(&utmFlags.Campaign, "utm-campaign", "", "Paramètre utm_campaign ajouté à l'URL")
+	CreateCmd.Flags().StringVar(&utmFlags.Term, "utm-term", "", "Paramètre utm_term ajouté à l'URL")
+	CreateCmd.Flags().StringVar(&utmFlags.Content, "utm-content", "", "Paramètre utm_content ajouté à l'URL")
+	CreateCmd.Flags().StringVar(&utmPresetFlag, "utm-preset", "", "Jeu de paramètres UTM du propriétaire (commande 'utm-preset'), complété par les flags --utm-*")
+	CreateCmd.Flags().StringArrayVar(&deepLinkFlags, "deep-link", nil, "Lien profond d'application au format plateforme=uri, tenté avant la destination de la règle (répétable)")
 
 	// Ajouter la commande à RootCmd
 	cmd2.RootCmd.AddCommand(CreateCmd)
@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+
+	cmd2 "github.com/antoine-granier/urlshortener/cmd"
+	"github.com/antoine-granier/urlshortener/internal/repository"
+	"github.com/antoine-granier/urlshortener/internal/services"
+	"github.com/spf13/cobra"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// Flags de la commande 'delete'
+var (
+	deleteCodeFlag string
+	deleteYesFlag  bool
+)
+
+// DeleteCmd représente la commande 'delete'
+var DeleteCmd = &cobra.Command{
+	Use:   "delete",
+	Short: "Supprime un lien court, ses clics et ses statistiques.",
+	Long: `Cette commande supprime définitivement un lien et les données qui lui sont rattachées
+(clics, statistiques, règles, conversions, règles d'alerte). Le code court redevient disponible.
+La suppression est inscrite au journal d'audit et notifiée aux webhooks (link.deleted),
+la notification étant envoyée par le serveur.
+
+Exemple:
+  url-shortener delete --code="xYz123" --yes`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if !deleteYesFlag {
+			fmt.Fprintln(os.Stderr, "Erreur : la suppression est définitive, confirmez-la avec --yes")
+			os.Exit(1)
+		}
+
+		// Charger la configuration globale
+		cfg := cmd2.Cfg
+		if cfg == nil {
+			log.Fatal("Configuration non initialisée")
+		}
+
+		// Initialiser la connexion à la base de données SQLite
+		db, err := gorm.Open(sqlite.Open(cfg.Database.Name), &gorm.Config{})
+		if err != nil {
+			log.Fatalf("Erreur de connexion à la BDD : %v", err)
+		}
+		sqlDB, err := db.DB()
+		if err != nil {
+			log.Fatalf("Échec de l'obtention de la DB SQL : %v", err)
+		}
+		defer sqlDB.Close()
+
+		// Initialiser les repositories et services nécessaires
+		linkSvc := services.NewLinkService(repository.NewLinkRepository(db), repository.NewClickRepository(db))
+		auditSvc := services.NewAuditService(repository.NewAuditRepository(db))
+
+		// Enregistrer la notification link.deleted des webhooks, envoyée ensuite par le serveur
+		events := services.NewEventBus()
+		services.NewWebhookService(repository.NewWebhookRepository(db), events, 0, 0, 0)
+		linkSvc.SetEventBus(events)
+
+		link, err := linkSvc.DeleteLink(context.Background(), deleteCodeFlag)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				fmt.Fprintf(os.Stderr, "Erreur : lien '%s' introuvable\n", deleteCodeFlag)
+				os.Exit(1)
+			}
+			log.Fatalf("Erreur lors de la suppression du lien : %v", err)
+		}
+		auditSvc.Record(context.Background(), services.ActorCLI, "link.deleted", link.ID,
+			fmt.Sprintf("lien %s supprimé (destination %s)", link.ShortCode, link.LongURL))
+
+		fmt.Printf("Lien %s supprimé (destination : %s).\n", link.ShortCode, link.LongURL)
+	},
+}
+
+func init() {
+	DeleteCmd.Flags().StringVarP(&deleteCodeFlag, "code", "c", "", "Code court du lien à supprimer")
+	DeleteCmd.MarkFlagRequired("code")
+	DeleteCmd.Flags().BoolVar(&deleteYesFlag, "yes", false, "Confirme la suppression définitive")
+
+	// Ajouter la commande à RootCmd
+	cmd2.RootCmd.AddCommand(DeleteCmd)
+}
@@ -0,0 +1,103 @@
+package cli
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/antoine-granier/urlshortener/internal/services"
+)
+
+// followClicks affiche les clics d'un lien (code) ou d'une équipe (owner) au fil de l'eau, en suivant
+// le flux Server-Sent Events du serveur jusqu'à son interruption.
+func followClicks(baseURL, code, owner string, includeBots bool) {
+	endpoint := strings.TrimRight(baseURL, "/") + "/api/v1/live"
+	query := url.Values{}
+	if code != "" {
+		endpoint = strings.TrimRight(baseURL, "/") + "/api/v1/links/" + url.PathEscape(code) + "/live"
+	} else {
+		query.Set("owner", owner)
+	}
+	if includeBots {
+		query.Set("include_bots", "true")
+	}
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		log.Fatalf("Requête invalide : %v", err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		log.Fatalf("Impossible de joindre le serveur (%s) : %v", baseURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		var body struct {
+			Error string `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&body)
+		log.Fatalf("Le serveur a répondu %s : %s", resp.Status, body.Error)
+	}
+
+	if code != "" {
+		fmt.Printf("Suivi des clics de %s en temps réel (Ctrl+C pour arrêter)...\n", code)
+	} else {
+		fmt.Printf("Suivi des clics de l'équipe %q en temps réel (Ctrl+C pour arrêter)...\n", owner)
+	}
+
+	// Format SSE : lignes "event:" et "data:", un événement se termine par une ligne vide
+	var event, data string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data = strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		case line == "":
+			printLiveEvent(event, data)
+			event, data = "", ""
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		log.Fatalf("Flux interrompu : %v", err)
+	}
+	fmt.Println("Flux terminé par le serveur.")
+}
+
+// printLiveEvent affiche un événement du flux de suivi en temps réel.
+func printLiveEvent(event, data string) {
+	switch event {
+	case "click":
+		var click services.LiveClick
+		if err := json.Unmarshal([]byte(data), &click); err != nil {
+			log.Printf("Événement illisible : %v", err)
+			return
+		}
+		details := []string{}
+		for _, field := range []struct{ name, value string }{
+			{"source", click.Source}, {"variant", click.Variant}, {"rule", click.Rule},
+			{"country", click.Country}, {"region", click.Region}, {"bot", click.BotCategory},
+		} {
+			if field.value != "" {
+				details = append(details, field.name+"="+field.value)
+			}
+		}
+		if click.Anonymous {
+			details = append(details, "(anonyme)")
+		}
+		fmt.Printf("%s  %-10s %s\n", click.Timestamp.Local().Format(time.TimeOnly), click.ShortCode, strings.Join(details, " "))
+	case "dropped":
+		fmt.Println("Le serveur a interrompu le flux : affichage trop lent.")
+	}
+}
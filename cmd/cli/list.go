@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	cmd2 "github.com/antoine-granier/urlshortener/cmd"
+	"github.com/antoine-granier/urlshortener/internal/models"
+	"github.com/antoine-granier/urlshortener/internal/repository"
+	"github.com/antoine-granier/urlshortener/internal/services"
+	"github.com/spf13/cobra"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// Critères et pagination de la liste (flags --owner, --tag, --campaign, --limit et --offset)
+var (
+	listOwnerFlag    string
+	listTagFlag      string
+	listCampaignFlag string
+	listLimitFlag    int
+	listOffsetFlag   int
+)
+
+// ListCmd représente la commande 'list'
+var ListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Liste les liens, filtrés par propriétaire, tag ou campagne.",
+	Long: `Cette commande affiche une page de liens, du plus récent au plus ancien,
+avec leur nombre de clics, leurs tags et leur campagne.
+
+Exemple:
+  url-shortener list --tag=newsletter
+  url-shortener list --owner="marketing" --campaign="soldes-ete" --limit=100 --offset=100`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Charger la configuration globale
+		cfg := cmd2.Cfg
+		if cfg == nil {
+			log.Fatal("Configuration non initialisée")
+		}
+
+		// Initialiser la connexion à la base de données SQLite
+		db, err := gorm.Open(sqlite.Open(cfg.Database.Name), &gorm.Config{})
+		if err != nil {
+			log.Fatalf("Erreur de connexion à la BDD : %v", err)
+		}
+		sqlDB, err := db.DB()
+		if err != nil {
+			log.Fatalf("Échec de l'obtention de la DB SQL : %v", err)
+		}
+		defer sqlDB.Close()
+
+		linkSvc := services.NewLinkService(repository.NewLinkRepository(db), repository.NewClickRepository(db))
+
+		page, err := linkSvc.ListLinks(services.LinkListOptions{
+			Owner:    listOwnerFlag,
+			Tag:      listTagFlag,
+			Campaign: listCampaignFlag,
+			Limit:    listLimitFlag,
+			Offset:   listOffsetFlag,
+		})
+		if err != nil {
+			log.Fatalf("Erreur lors de la recherche des liens : %v", err)
+		}
+
+		if len(page.Links) == 0 {
+			fmt.Println("Aucun lien trouvé.")
+			return
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "CODE\tCLICS\tCAMPAGNE\tTAGS\tURL LONGUE")
+		for _, link := range page.Links {
+			campaign := "-"
+			if link.Campaign != nil {
+				campaign = link.Campaign.Name
+			}
+			tags := tagNames(link.Tags)
+			if tags == "" {
+				tags = "-"
+			}
+			fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\n", link.ShortCode, page.Clicks[link.ID], campaign, tags, link.LongURL)
+		}
+		w.Flush()
+		fmt.Printf("Liens %d à %d sur %d\n", page.Offset+1, page.Offset+len(page.Links), page.Total)
+	},
+}
+
+func init() {
+	ListCmd.Flags().StringVar(&listOwnerFlag, "owner", "", "Propriétaire des liens")
+	ListCmd.Flags().StringVar(&listTagFlag, "tag", "", "Ne liste que les liens portant ce tag")
+	ListCmd.Flags().StringVar(&listCampaignFlag, "campaign", "", "Ne liste que les liens de cette campagne")
+	ListCmd.Flags().IntVar(&listLimitFlag, "limit", 50, "Nombre maximum de liens affichés (500 au plus)")
+	ListCmd.Flags().IntVar(&listOffsetFlag, "offset", 0, "Nombre de liens à sauter (pagination)")
+
+	// Ajouter la commande à RootCmd
+	cmd2.RootCmd.AddCommand(ListCmd)
+}
+
+// tagNames retourne les noms des tags séparés par des virgules.
+func tagNames(tags []models.Tag) string {
+	names := make([]string, len(tags))
+	for i, tag := range tags {
+		names[i] = tag.Name
+	}
+	return strings.Join(names, ",")
+}
@@ -6,6 +6,7 @@ import (
 
 	cmd2 "github.com/antoine-granier/urlshortener/cmd"
 	"github.com/antoine-granier/urlshortener/internal/models"
+	"github.com/antoine-granier/urlshortener/internal/repository"
 	"github.com/spf13/cobra"
 	"gorm.io/driver/sqlite" // Driver SQLite pour GORM
 	"gorm.io/gorm"
@@ -37,9 +38,24 @@ basées sur les modèles Go.`,
 		defer sqlDB.Close()
 
 		// Exécuter les migrations automatiques de GORM
-		if err := db.AutoMigrate(&models.Link{}, &models.Click{}); err != nil {
+		if err := db.AutoMigrate(
+			&models.Link{}, &models.Click{}, &models.Sequence{}, &models.LinkVariant{},
+			&models.ScheduledChange{}, &models.AuditEntry{}, &models.LinkMetadata{},
+			&models.Tag{}, &models.Campaign{}, &models.UTMPreset{}, &models.VisitorSketch{},
+			&models.HourlyClickRollup{}, &models.DailyClickRollup{},
+			&models.Webhook{}, &models.WebhookDelivery{}, &models.ClickThreshold{}, &models.AlertRule{},
+			&models.Conversion{},
+		); err != nil {
 			log.Fatalf("Erreur lors des migrations : %v", err)
 		}
+		// Les anciennes règles de plateforme et géographiques deviennent des règles de routage
+		migrated, err := repository.MigrateTargetingRules(db)
+		if err != nil {
+			log.Fatalf("Erreur lors de la reprise des règles de plateforme et géographiques : %v", err)
+		}
+		if migrated > 0 {
+			fmt.Printf("Règles de plateforme et géographiques de %d lien(s) reprises dans les règles de routage.\n", migrated)
+		}
 
 		fmt.Println("Migrations de la base de données exécutées avec succès.")
 	},
@@ -0,0 +1,86 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	cmd2 "github.com/antoine-granier/urlshortener/cmd"
+	"github.com/antoine-granier/urlshortener/internal/repository"
+	"github.com/antoine-granier/urlshortener/internal/services"
+	"github.com/spf13/cobra"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// Flags de la commande 'purge-clicks'
+var (
+	purgeIPFlag     string
+	purgeBeforeFlag string
+)
+
+// PurgeClicksCmd représente la commande 'purge-clicks'
+var PurgeClicksCmd = &cobra.Command{
+	Use:   "purge-clicks",
+	Short: "Efface les données personnelles (IP, User-Agent) des clics d'un visiteur ou antérieurs à une date.",
+	Long: `Cette commande efface l'adresse IP et le User-Agent des clics visés (droit à l'effacement).
+Les clics restent comptés dans les statistiques et l'effacement est inscrit au journal d'audit.
+Avec --ip et --before, seuls les clics correspondant aux deux critères sont visés.
+
+En mode privacy.ip_mode "hash", les IP hachées ne peuvent pas être retrouvées depuis la CLI :
+utiliser --before, ou l'API du serveur (POST /api/v1/clicks/purge) pour les clics du jour.
+
+Exemple:
+  url-shortener purge-clicks --ip="203.0.113.7"
+  url-shortener purge-clicks --before="2026-01-01"`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if purgeIPFlag == "" && purgeBeforeFlag == "" {
+			fmt.Fprintln(os.Stderr, "Erreur : un des flags --ip ou --before est requis")
+			os.Exit(1)
+		}
+		purge, err := services.ParseClickPurge(purgeIPFlag, purgeBeforeFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Erreur : %v\n", err)
+			os.Exit(1)
+		}
+
+		// Charger la configuration globale
+		cfg := cmd2.Cfg
+		if cfg == nil {
+			log.Fatal("Configuration non initialisée")
+		}
+
+		// Initialiser la connexion à la base de données SQLite
+		db, err := gorm.Open(sqlite.Open(cfg.Database.Name), &gorm.Config{})
+		if err != nil {
+			log.Fatalf("Erreur de connexion à la BDD : %v", err)
+		}
+		sqlDB, err := db.DB()
+		if err != nil {
+			log.Fatalf("Échec de l'obtention de la DB SQL : %v", err)
+		}
+		defer sqlDB.Close()
+
+		// Initialiser les repositories et services nécessaires
+		auditSvc := services.NewAuditService(repository.NewAuditRepository(db))
+		privacySvc, err := services.NewPrivacyService(repository.NewClickRepository(db), auditSvc, cfg.Privacy.IPMode, cfg.Privacy.HonorDNT)
+		if err != nil {
+			log.Fatalf("Erreur de configuration de la confidentialité : %v", err)
+		}
+
+		erased, err := privacySvc.PurgeClicks(context.Background(), services.ActorCLI, purge)
+		if err != nil {
+			log.Fatalf("Erreur lors de l'effacement : %v", err)
+		}
+		fmt.Printf("Données personnelles effacées pour %d clic(s).\n", erased)
+	},
+}
+
+func init() {
+	PurgeClicksCmd.Flags().StringVar(&purgeIPFlag, "ip", "", "Adresse IP du visiteur dont les clics sont anonymisés")
+	PurgeClicksCmd.Flags().StringVar(&purgeBeforeFlag, "before", "", "Anonymise les clics antérieurs à ce jour (AAAA-MM-JJ, UTC)")
+
+	// Ajouter la commande à RootCmd
+	cmd2.RootCmd.AddCommand(PurgeClicksCmd)
+}
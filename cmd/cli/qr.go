@@ -0,0 +1,128 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	cmd2 "github.com/antoine-granier/urlshortener/cmd"
+	"github.com/antoine-granier/urlshortener/internal/repository"
+	"github.com/antoine-granier/urlshortener/internal/services"
+	"github.com/spf13/cobra"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// Flags de la commande 'qr'
+var (
+	qrCodeFlag   string
+	qrOutFlag    string
+	qrFormatFlag string
+	qrSizeFlag   int
+	qrEccFlag    string
+	qrFgFlag     string
+	qrBgFlag     string
+	qrLogoFlag   string
+)
+
+// QrCmd représente la commande 'qr'
+var QrCmd = &cobra.Command{
+	Use:   "qr",
+	Short: "Génère le QR code d'une URL courte.",
+	Long: `Cette commande génère un QR code (PNG ou SVG) de l'URL courte complète d'un lien,
+construite à partir de 'server.base_url'. Les scans sont comptabilisés avec la source "qr".
+
+Exemple:
+  url-shortener qr --code="xyz123" --out=xyz123.png --size=512 --fg="#1a237e" --logo=logo.png`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if qrCodeFlag == "" || qrOutFlag == "" {
+			fmt.Fprintln(os.Stderr, "Erreur : les flags --code et --out sont requis")
+			os.Exit(1)
+		}
+
+		// Charger la configuration globale
+		cfg := cmd2.Cfg
+		if cfg == nil {
+			log.Fatal("Configuration non initialisée")
+		}
+
+		// Le format est déduit de l'extension du fichier s'il n'est pas précisé
+		format := qrFormatFlag
+		if format == "" {
+			format = strings.TrimPrefix(strings.ToLower(filepath.Ext(qrOutFlag)), ".")
+		}
+
+		var logo image.Image
+		if qrLogoFlag != "" {
+			var err error
+			if logo, err = services.LoadQRLogo(qrLogoFlag); err != nil {
+				log.Fatalf("Erreur lors du chargement du logo : %v", err)
+			}
+		}
+
+		size := qrSizeFlag
+		if !cmd.Flags().Changed("size") {
+			size = cfg.QRCode.DefaultSize
+		}
+		opts, err := services.NewQROptions(size, format, qrEccFlag, qrFgFlag, qrBgFlag, logo)
+		if err != nil {
+			log.Fatalf("Options de QR code invalides : %v", err)
+		}
+
+		// Initialiser la connexion à la base de données SQLite
+		db, err := gorm.Open(sqlite.Open(cfg.Database.Name), &gorm.Config{})
+		if err != nil {
+			log.Fatalf("Erreur de connexion à la BDD : %v", err)
+		}
+		sqlDB, err := db.DB()
+		if err != nil {
+			log.Fatalf("FATAL: Échec de l'obtention de la DB SQL : %v", err)
+		}
+		defer sqlDB.Close()
+
+		linkRepo := repository.NewLinkRepository(db)
+		clickRepo := repository.NewClickRepository(db)
+		linkService := services.NewLinkService(linkRepo, clickRepo)
+
+		link, err := linkService.GetLinkByShortCode(qrCodeFlag)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				fmt.Fprintf(os.Stderr, "Aucun lien trouvé pour le code '%s'\n", qrCodeFlag)
+				os.Exit(1)
+			}
+			log.Fatalf("Erreur lors de la récupération du lien : %v", err)
+		}
+
+		out, err := os.Create(qrOutFlag)
+		if err != nil {
+			log.Fatalf("Erreur lors de la création du fichier : %v", err)
+		}
+		defer out.Close()
+
+		content := services.QRScanURL(cfg.Server.BaseURL, link.ShortCode)
+		if err := services.WriteQRCode(out, content, opts); err != nil {
+			log.Fatalf("Erreur lors de la génération du QR code : %v", err)
+		}
+
+		fmt.Printf("QR code de %s écrit dans %s\n", content, qrOutFlag)
+	},
+}
+
+func init() {
+	QrCmd.Flags().StringVarP(&qrCodeFlag, "code", "c", "", "Code court du lien")
+	QrCmd.Flags().StringVarP(&qrOutFlag, "out", "o", "", "Fichier de sortie (.png ou .svg)")
+	QrCmd.Flags().StringVar(&qrFormatFlag, "format", "", "Format de sortie: png ou svg (déduit de l'extension par défaut)")
+	QrCmd.Flags().IntVar(&qrSizeFlag, "size", 256, "Taille de l'image en pixels (qrcode.default_size par défaut)")
+	QrCmd.Flags().StringVar(&qrEccFlag, "ecc", "", "Niveau de correction d'erreur: L, M, Q ou H (H par défaut avec un logo, M sinon)")
+	QrCmd.Flags().StringVar(&qrFgFlag, "fg", "", "Couleur des modules (ex: #000000)")
+	QrCmd.Flags().StringVar(&qrBgFlag, "bg", "", "Couleur du fond (ex: #ffffff)")
+	QrCmd.Flags().StringVar(&qrLogoFlag, "logo", "", "Image PNG/JPEG à superposer au centre du code")
+	QrCmd.MarkFlagRequired("code")
+	QrCmd.MarkFlagRequired("out")
+
+	cmd2.RootCmd.AddCommand(QrCmd)
+}
@@ -0,0 +1,118 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	cmd2 "github.com/antoine-granier/urlshortener/cmd"
+	"github.com/antoine-granier/urlshortener/internal/repository"
+	"github.com/antoine-granier/urlshortener/internal/services"
+	"github.com/spf13/cobra"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// Flags de la commande 'schedule'
+var (
+	scheduleCodeFlag   string
+	scheduleURLFlag    string
+	scheduleAtFlag     string
+	scheduleListFlag   bool
+	scheduleCancelFlag uint
+)
+
+// ScheduleCmd représente la commande 'schedule'
+var ScheduleCmd = &cobra.Command{
+	Use:   "schedule",
+	Short: "Programme, liste ou annule des changements de destination d'un lien.",
+	Long: `Cette commande programme le remplacement de la destination d'un lien à une date donnée.
+Le changement est appliqué par le planificateur du serveur (run-server) et inscrit au journal d'audit.
+
+Exemple:
+  url-shortener schedule --code="xyz123" --url="https://example.com/lancement" --at="2027-03-01T09:00:00+01:00"
+  url-shortener schedule --code="xyz123" --list
+  url-shortener schedule --code="xyz123" --cancel=4`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if scheduleCodeFlag == "" {
+			fmt.Fprintln(os.Stderr, "Erreur : le flag --code est requis")
+			os.Exit(1)
+		}
+
+		// Charger la configuration globale
+		cfg := cmd2.Cfg
+		if cfg == nil {
+			log.Fatal("Configuration non initialisée")
+		}
+
+		// Initialiser la connexion à la base de données SQLite
+		db, err := gorm.Open(sqlite.Open(cfg.Database.Name), &gorm.Config{})
+		if err != nil {
+			log.Fatalf("Erreur de connexion à la BDD : %v", err)
+		}
+		sqlDB, err := db.DB()
+		if err != nil {
+			log.Fatalf("Échec de l'obtention de la DB SQL : %v", err)
+		}
+		defer sqlDB.Close()
+
+		// Initialiser les repositories et services nécessaires
+		linkSvc := services.NewLinkService(repository.NewLinkRepository(db), repository.NewClickRepository(db))
+		linkSvc.SetURLCanonicalizer(&services.URLCanonicalizer{
+			StripTracking:  cfg.Links.StripTrackingParams,
+			TrackingParams: cfg.Links.TrackingParams,
+		})
+		auditSvc := services.NewAuditService(repository.NewAuditRepository(db))
+		scheduleSvc := services.NewScheduleService(linkSvc, repository.NewScheduledChangeRepository(db), auditSvc)
+
+		switch {
+		case scheduleListFlag:
+			changes, err := scheduleSvc.ListScheduledChanges(scheduleCodeFlag)
+			if err != nil {
+				log.Fatalf("Erreur lors de la récupération des changements programmés : %v", err)
+			}
+			if len(changes) == 0 {
+				fmt.Println("Aucun changement programmé.")
+				return
+			}
+			for _, change := range changes {
+				fmt.Printf("#%-4d %-10s %s  %s\n", change.ID, change.Status, change.ApplyAt.Local().Format(time.RFC3339), change.LongURL)
+			}
+
+		case scheduleCancelFlag != 0:
+			if err := scheduleSvc.CancelScheduledChange(context.Background(), scheduleCodeFlag, scheduleCancelFlag, services.ActorCLI); err != nil {
+				log.Fatalf("Erreur lors de l'annulation : %v", err)
+			}
+			fmt.Printf("Changement #%d annulé.\n", scheduleCancelFlag)
+
+		default:
+			if scheduleURLFlag == "" || scheduleAtFlag == "" {
+				fmt.Fprintln(os.Stderr, "Erreur : les flags --url et --at sont requis pour programmer un changement")
+				os.Exit(1)
+			}
+			applyAt, err := time.Parse(time.RFC3339, scheduleAtFlag)
+			if err != nil {
+				log.Fatalf("Date invalide (format RFC 3339 attendu, ex: 2027-03-01T09:00:00+01:00) : %v", err)
+			}
+			change, err := scheduleSvc.ScheduleChange(context.Background(), scheduleCodeFlag, scheduleURLFlag, applyAt, services.ActorCLI)
+			if err != nil {
+				log.Fatalf("Erreur lors de la programmation : %v", err)
+			}
+			fmt.Printf("Changement #%d programmé pour le %s.\n", change.ID, change.ApplyAt.Local().Format(time.RFC3339))
+		}
+	},
+}
+
+func init() {
+	ScheduleCmd.Flags().StringVarP(&scheduleCodeFlag, "code", "c", "", "Code court du lien")
+	ScheduleCmd.Flags().StringVar(&scheduleURLFlag, "url", "", "Nouvelle destination")
+	ScheduleCmd.Flags().StringVar(&scheduleAtFlag, "at", "", "Date d'application (RFC 3339)")
+	ScheduleCmd.Flags().BoolVar(&scheduleListFlag, "list", false, "Liste les changements programmés du lien")
+	ScheduleCmd.Flags().UintVar(&scheduleCancelFlag, "cancel", 0, "Annule le changement programmé d'ID donné")
+	ScheduleCmd.MarkFlagRequired("code")
+
+	// Ajouter la commande à RootCmd
+	cmd2.RootCmd.AddCommand(ScheduleCmd)
+}
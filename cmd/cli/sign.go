@@ -0,0 +1,98 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	cmd2 "github.com/antoine-granier/urlshortener/cmd"
+	"github.com/antoine-granier/urlshortener/internal/repository"
+	"github.com/antoine-granier/urlshortener/internal/services"
+	"github.com/spf13/cobra"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// Flags de la commande 'sign'
+var (
+	signCodeFlag      string
+	signTTLFlag       time.Duration
+	signExpiresAtFlag string
+)
+
+// SignCmd représente la commande 'sign'
+var SignCmd = &cobra.Command{
+	Use:   "sign",
+	Short: "Émet un lien signé à durée limitée vers un lien existant.",
+	Long: `Cette commande émet une URL courte signée (clé active de 'signing.keys') qui embarque l'identifiant
+du lien et sa date d'expiration. Elle se vérifie sans base de données : rien n'est enregistré,
+ce qui permet d'en générer en masse (ex: un lien par destinataire d'un envoi d'emails).
+
+Exemple:
+  url-shortener sign --code="xyz123" --ttl=72h
+  url-shortener sign --code="xyz123" --expires-at="2027-03-01T09:00:00+01:00"`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if (signTTLFlag > 0) == (signExpiresAtFlag != "") {
+			fmt.Fprintln(os.Stderr, "Erreur : un seul des flags --ttl ou --expires-at est requis")
+			os.Exit(1)
+		}
+		expiresAt := time.Now().Add(signTTLFlag)
+		if signExpiresAtFlag != "" {
+			var err error
+			if expiresAt, err = time.Parse(time.RFC3339, signExpiresAtFlag); err != nil {
+				log.Fatalf("Date d'expiration invalide (format RFC 3339 attendu) : %v", err)
+			}
+		}
+
+		// Charger la configuration globale
+		cfg := cmd2.Cfg
+		if cfg == nil {
+			log.Fatal("Configuration non initialisée")
+		}
+		keys := make([]services.SigningKey, 0, len(cfg.Signing.Keys))
+		for _, k := range cfg.Signing.Keys {
+			keys = append(keys, services.SigningKey{ID: k.ID, Secret: k.Secret})
+		}
+		signer, err := services.NewLinkSigner(keys, cfg.Signing.ActiveKey, time.Duration(cfg.Signing.MaxTTLHours)*time.Hour)
+		if err != nil {
+			log.Fatalf("Erreur de configuration des liens signés : %v", err)
+		}
+
+		// Initialiser la connexion à la base de données SQLite
+		db, err := gorm.Open(sqlite.Open(cfg.Database.Name), &gorm.Config{})
+		if err != nil {
+			log.Fatalf("Erreur de connexion à la BDD : %v", err)
+		}
+		sqlDB, err := db.DB()
+		if err != nil {
+			log.Fatalf("Échec de l'obtention de la DB SQL : %v", err)
+		}
+		defer sqlDB.Close()
+
+		linkSvc := services.NewLinkService(repository.NewLinkRepository(db), repository.NewClickRepository(db))
+		linkSvc.SetLinkSigner(signer)
+
+		code, err := linkSvc.SignLink(signCodeFlag, expiresAt)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				fmt.Fprintf(os.Stderr, "Aucun lien trouvé pour le code '%s'\n", signCodeFlag)
+				os.Exit(1)
+			}
+			log.Fatalf("Erreur lors de la signature du lien : %v", err)
+		}
+
+		fmt.Printf("URL signée: %s/%s\n", cfg.Server.BaseURL, code)
+		fmt.Printf("Expire le: %s\n", time.Unix(expiresAt.Unix(), 0).Format(time.RFC3339))
+	},
+}
+
+func init() {
+	SignCmd.Flags().StringVarP(&signCodeFlag, "code", "c", "", "Code court du lien")
+	SignCmd.Flags().DurationVar(&signTTLFlag, "ttl", 0, "Durée de validité (ex: 72h)")
+	SignCmd.Flags().StringVar(&signExpiresAtFlag, "expires-at", "", "Date d'expiration (RFC 3339)")
+	SignCmd.MarkFlagRequired("code")
+
+	cmd2.RootCmd.AddCommand(SignCmd)
+}
@@ -1,9 +1,11 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"sort"
 
 	cmd2 "github.com/antoine-granier/urlshortener/cmd"
 	"github.com/antoine-granier/urlshortener/internal/repository"
@@ -17,20 +19,60 @@ import (
 // variable shortCodeFlag qui stockera la valeur du flag --code
 var shortCodeFlag string
 
+// Statistiques agrégées d'une campagne (flags --campaign et --owner), à la place de --code,
+// ou clics regroupés par paramètre UTM (flag --utm)
+var (
+	statsCampaignFlag string
+	statsOwnerFlag    string
+	statsUTMFlag      bool
+)
+
+// Période des clics et visiteurs uniques d'un lien (flags --from et --to, AAAA-MM-JJ)
+var (
+	statsFromFlag string
+	statsToFlag   string
+)
+
+// Inclut les clics de robots, exclus par défaut des statistiques (flag --include-bots)
+var statsIncludeBotsFlag bool
+
+// Suit les clics en temps réel, via le serveur (flag --follow, avec --code ou --owner)
+var statsFollowFlag bool
+
 // StatsCmd représente la commande 'stats'
 var StatsCmd = &cobra.Command{
 	Use:   "stats",
-	Short: "Affiche les statistiques (nombre de clics) pour un lien court.",
+	Short: "Affiche les statistiques (nombre de clics) pour un lien court ou une campagne.",
 	Long: `Cette commande permet de récupérer et d'afficher le nombre total de clics
-pour une URL courte spécifique en utilisant son code.
+pour une URL courte spécifique en utilisant son code, ou la somme des clics
+de tous les liens d'une campagne.
 
 Exemple:
-  url-shortener stats --code="xyz123"`,
+  url-shortener stats --code="xyz123"
+  url-shortener stats --code="xyz123" --from="2026-10-01" --to="2026-10-31"
+  url-shortener stats --code="xyz123" --include-bots
+  url-shortener stats --campaign="soldes-ete" --owner="marketing"
+  url-shortener stats --utm --owner="marketing"
+  url-shortener stats --code="xyz123" --follow
+  url-shortener stats --owner="marketing" --follow`,
 	Run: func(cmd *cobra.Command, args []string) {
-		// Valider que le flag --code a été fourni.
-		// os.Exit(1) si erreur
-		if shortCodeFlag == "" {
-			fmt.Fprintln(os.Stderr, "Erreur : le flag --code est requis")
+		if statsFollowFlag {
+			if shortCodeFlag == "" && !cmd.Flags().Changed("owner") {
+				fmt.Fprintln(os.Stderr, "Erreur : --follow requiert --code ou --owner")
+				os.Exit(1)
+			}
+			if cmd2.Cfg == nil {
+				log.Fatal("Configuration non initialisée")
+			}
+			followClicks(cmd2.Cfg.Server.BaseURL, shortCodeFlag, statsOwnerFlag, statsIncludeBotsFlag)
+			return
+		}
+
+		// Valider qu'un (et un seul) des flags --code, --campaign et --utm a été fourni
+		// (--utm peut être restreint à une campagne). os.Exit(1) si erreur
+		if shortCodeFlag != "" && (statsCampaignFlag != "" || statsUTMFlag) ||
+			shortCodeFlag == "" && statsCampaignFlag == "" && !statsUTMFlag {
+			fmt.Fprintln(os.Stderr, "Erreur : un des flags --code, --campaign ou --utm est requis")
 			os.Exit(1)
 		}
 
@@ -54,7 +96,28 @@ Exemple:
 
 		// Initialiser les repositories et services nécessaires
 		linkRepo := repository.NewLinkRepository(db)
-		linkService := services.NewLinkService(linkRepo)
+		clickRepo := repository.NewClickRepository(db)
+		linkService := services.NewLinkService(linkRepo, clickRepo)
+		conversionService, err := services.NewConversionService(repository.NewConversionRepository(db), services.ConversionOptions{
+			Mode:            cfg.Conversions.ClickIDMode,
+			AttributionDays: cfg.Conversions.AttributionDays,
+		})
+		if err != nil {
+			log.Fatalf("Erreur de configuration du suivi des conversions : %v", err)
+		}
+		linkService.SetConversionService(conversionService)
+		if statsIncludeBotsFlag {
+			linkService = linkService.WithBots()
+		}
+
+		if statsUTMFlag {
+			printUTMStats(linkService, statsOwnerFlag, statsCampaignFlag)
+			return
+		}
+		if statsCampaignFlag != "" {
+			printCampaignStats(linkService, statsOwnerFlag, statsCampaignFlag)
+			return
+		}
 
 		// Appeler GetLinkStats pour récupérer le lien et ses statistiques.
 		link, err := linkService.GetLinkByShortCode(shortCodeFlag)
@@ -72,18 +135,199 @@ Exemple:
 			log.Fatalf("Erreur lors de la récupération des stats : %v", err)
 		}
 
+		// Clics et visiteurs uniques sur la période demandée (toute la vie du lien par défaut)
+		period, err := services.ParseDayRange(statsFromFlag, statsToFlag)
+		if err != nil {
+			log.Fatalf("Erreur : %v", err)
+		}
+		if !period.IsZero() {
+			if totalClicks, err = linkService.CountClicks(link.ID, period); err != nil {
+				log.Fatalf("Erreur lors de la récupération des stats : %v", err)
+			}
+		}
+		uniqueVisitors, err := linkService.CountUniqueVisitors(link.ID, period)
+		if err != nil {
+			log.Fatalf("Erreur lors de la récupération des stats : %v", err)
+		}
+
+		// Ventilation des clics par provenance (ex: scans de QR code) et par variante A/B
+		bySource, err := linkService.GetClickBreakdown(link.ID, "source")
+		if err != nil {
+			log.Fatalf("Erreur lors de la récupération des stats : %v", err)
+		}
+		byVariant, err := linkService.GetClickBreakdown(link.ID, "variant")
+		if err != nil {
+			log.Fatalf("Erreur lors de la récupération des stats : %v", err)
+		}
+		byRule, err := linkService.GetClickBreakdown(link.ID, "rule")
+		if err != nil {
+			log.Fatalf("Erreur lors de la récupération des stats : %v", err)
+		}
+		// Ventilation géographique (si une base GeoIP est configurée sur le serveur)
+		byCountry, err := linkService.GetClickBreakdown(link.ID, "country")
+		if err != nil {
+			log.Fatalf("Erreur lors de la récupération des stats : %v", err)
+		}
+		byRegion, err := linkService.GetClickBreakdown(link.ID, "region")
+		if err != nil {
+			log.Fatalf("Erreur lors de la récupération des stats : %v", err)
+		}
+		byBot, err := linkService.GetBotBreakdown(link.ID)
+		if err != nil {
+			log.Fatalf("Erreur lors de la récupération des stats : %v", err)
+		}
+		conversions, err := linkService.GetConversionStats(link.ID, period, totalClicks)
+		if err != nil {
+			log.Fatalf("Erreur lors de la récupération des stats : %v", err)
+		}
+
 		// Afficher le résultat
 		fmt.Printf("Statistiques pour le code court: %s\n", link.ShortCode)
 		fmt.Printf("URL longue: %s\n", link.LongURL)
+		if !period.IsZero() {
+			fmt.Printf("Période: %s → %s\n", dayOrDash(statsFromFlag), dayOrDash(statsToFlag))
+		}
 		fmt.Printf("Total de clics: %d\n", totalClicks)
+		fmt.Printf("Visiteurs uniques (estimation): %d\n", uniqueVisitors)
+		printConversions(conversions)
+		printBreakdown("Clics par source", "(direct)", bySource)
+		if len(link.Variants) > 0 {
+			printBreakdown("Clics par variante", "(aucune)", byVariant)
+		}
+		if len(link.RoutingRules) > 0 {
+			printBreakdown("Clics par règle", "(aucune)", byRule)
+		}
+		if !onlyEmptyKey(byCountry) {
+			printBreakdown("Clics par pays", "(inconnu)", byCountry)
+			printBreakdown("Clics par région", "(inconnue)", byRegion)
+		}
+		if len(byBot) > 0 {
+			title := "Clics de robots par catégorie (exclus du total)"
+			if statsIncludeBotsFlag {
+				title = "Clics de robots par catégorie (inclus dans le total)"
+			}
+			printBreakdown(title, "", byBot)
+		}
 	},
 }
 
 func init() {
-	// Définir et marquer le flag --code comme requis
+	// Définir les flags --code, ou --campaign (et --owner) pour les statistiques d'une campagne
 	StatsCmd.Flags().StringVarP(&shortCodeFlag, "code", "c", "", "Code court à interroger")
-	StatsCmd.MarkFlagRequired("code")
+	StatsCmd.Flags().StringVar(&statsCampaignFlag, "campaign", "", "Campagne dont les clics sont agrégés")
+	StatsCmd.Flags().StringVar(&statsOwnerFlag, "owner", "", "Propriétaire de la campagne (ou des liens avec --utm)")
+	StatsCmd.Flags().StringVar(&statsFromFlag, "from", "", "Premier jour (AAAA-MM-JJ, UTC) pris en compte pour les clics, visiteurs uniques et conversions")
+	StatsCmd.Flags().StringVar(&statsToFlag, "to", "", "Dernier jour (AAAA-MM-JJ, UTC) pris en compte pour les clics, visiteurs uniques et conversions")
+	StatsCmd.Flags().BoolVar(&statsUTMFlag, "utm", false, "Regroupe les clics des liens du propriétaire par paramètre UTM")
+	StatsCmd.Flags().BoolVar(&statsIncludeBotsFlag, "include-bots", false, "Inclut les clics de robots (aperçus de liens, scanners...) dans les statistiques")
+	StatsCmd.Flags().BoolVar(&statsFollowFlag, "follow", false, "Affiche les clics en temps réel (serveur en cours d'exécution requis)")
 
 	// Ajouter la commande à RootCmd
 	cmd2.RootCmd.AddCommand(StatsCmd)
 }
+
+// printCampaignStats affiche les clics agrégés d'une campagne, puis ceux de chacun de ses liens.
+func printCampaignStats(linkService *services.LinkService, owner, name string) {
+	stats, err := linkService.GetCampaignStats(owner, name)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			fmt.Fprintf(os.Stderr, "Aucune campagne '%s' trouvée\n", name)
+			os.Exit(1)
+		}
+		log.Fatalf("Erreur lors de la récupération des stats : %v", err)
+	}
+
+	fmt.Printf("Statistiques pour la campagne: %s\n", stats.Campaign.Name)
+	if stats.Campaign.Owner != "" {
+		fmt.Printf("Propriétaire: %s\n", stats.Campaign.Owner)
+	}
+	fmt.Printf("Liens: %d\n", len(stats.Links))
+	fmt.Printf("Total de clics: %d\n", stats.Clicks)
+	printConversions(stats.Conversions)
+	printBreakdown("Clics par source", "(direct)", stats.BySource)
+	if !onlyEmptyKey(stats.ByCountry) {
+		printBreakdown("Clics par pays", "(inconnu)", stats.ByCountry)
+	}
+	if len(stats.Links) > 0 {
+		fmt.Println("Clics par lien:")
+		for _, l := range stats.Links {
+			fmt.Printf("  %-20s %d\t%d conv.\t%s\n", l.ShortCode, l.Clicks, l.Conversions, l.LongURL)
+		}
+	}
+}
+
+// printUTMStats affiche les clics des liens d'un propriétaire regroupés par paramètre UTM.
+func printUTMStats(linkService *services.LinkService, owner, campaign string) {
+	stats, err := linkService.GetUTMStats(owner, campaign)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			fmt.Fprintf(os.Stderr, "Aucune campagne '%s' trouvée\n", campaign)
+			os.Exit(1)
+		}
+		log.Fatalf("Erreur lors de la récupération des stats : %v", err)
+	}
+
+	fmt.Println("Clics par paramètre UTM")
+	for _, field := range services.UTMFields {
+		if !onlyEmptyKey(stats[field]) {
+			printBreakdown("utm_"+field, "(aucun)", stats[field])
+		}
+	}
+}
+
+// printConversions affiche le nombre de conversions, le taux de conversion et les montants par devise.
+func printConversions(stats *services.ConversionStats) {
+	fmt.Printf("Conversions: %d (taux de conversion: %.2f%%)\n", stats.Conversions, stats.Rate*100)
+	currencies := make([]string, 0, len(stats.Value))
+	for currency := range stats.Value {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+	for _, currency := range currencies {
+		label := currency
+		if label == "" {
+			label = "(sans devise)"
+		}
+		fmt.Printf("  %-20s %.2f\n", label, stats.Value[currency])
+	}
+}
+
+// dayOrDash retourne le jour donné, ou "…" pour une borne de période non renseignée.
+func dayOrDash(day string) string {
+	if day == "" {
+		return "…"
+	}
+	return day
+}
+
+// printBreakdown affiche une ventilation des clics, triée par valeur.
+// Les clics sans valeur sont affichés sous le libellé emptyLabel.
+func printBreakdown(title, emptyLabel string, counts map[string]int) {
+	if len(counts) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	fmt.Printf("%s:\n", title)
+	for _, k := range keys {
+		label := k
+		if label == "" {
+			label = emptyLabel
+		}
+		fmt.Printf("  %-20s %d\n", label, counts[k])
+	}
+}
+
+// onlyEmptyKey indique si une ventilation ne contient que des clics sans valeur (ou aucun clic).
+func onlyEmptyKey(counts map[string]int) bool {
+	for k := range counts {
+		if k != "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	cmd2 "github.com/antoine-granier/urlshortener/cmd"
+	"github.com/antoine-granier/urlshortener/internal/models"
+	"github.com/antoine-granier/urlshortener/internal/repository"
+	"github.com/antoine-granier/urlshortener/internal/services"
+	"github.com/spf13/cobra"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// Flags de la commande 'utm-preset'
+var (
+	utmPresetOwnerFlag  string
+	utmPresetNameFlag   string
+	utmPresetListFlag   bool
+	utmPresetDeleteFlag bool
+	utmPresetValues     models.UTM
+)
+
+// UTMPresetCmd représente la commande 'utm-preset'
+var UTMPresetCmd = &cobra.Command{
+	Use:   "utm-preset",
+	Short: "Enregistre, liste ou supprime les jeux de paramètres UTM d'une équipe.",
+	Long: `Cette commande gère les jeux de paramètres UTM réutilisables d'un propriétaire,
+appliqués à la création des liens avec 'create --utm-preset'. Les valeurs sont mises en minuscules.
+
+Exemple:
+  url-shortener utm-preset --owner="marketing" --name="newsletter" --source="newsletter" --medium="email"
+  url-shortener utm-preset --owner="marketing" --list
+  url-shortener utm-preset --owner="marketing" --name="newsletter" --delete`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if !utmPresetListFlag && utmPresetNameFlag == "" {
+			fmt.Fprintln(os.Stderr, "Erreur : le flag --name (ou --list) est requis")
+			os.Exit(1)
+		}
+
+		// Charger la configuration globale
+		cfg := cmd2.Cfg
+		if cfg == nil {
+			log.Fatal("Configuration non initialisée")
+		}
+
+		// Initialiser la connexion à la base de données SQLite
+		db, err := gorm.Open(sqlite.Open(cfg.Database.Name), &gorm.Config{})
+		if err != nil {
+			log.Fatalf("Erreur de connexion à la BDD : %v", err)
+		}
+		sqlDB, err := db.DB()
+		if err != nil {
+			log.Fatalf("Échec de l'obtention de la DB SQL : %v", err)
+		}
+		defer sqlDB.Close()
+
+		linkSvc := services.NewLinkService(repository.NewLinkRepository(db), repository.NewClickRepository(db))
+
+		switch {
+		case utmPresetListFlag:
+			presets, err := linkSvc.ListUTMPresets(utmPresetOwnerFlag)
+			if err != nil {
+				log.Fatalf("Erreur lors de la récupération des jeux de paramètres : %v", err)
+			}
+			if len(presets) == 0 {
+				fmt.Println("Aucun jeu de paramètres UTM.")
+				return
+			}
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "NOM\tSOURCE\tMEDIUM\tCAMPAIGN\tTERM\tCONTENT")
+			for _, p := range presets {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", p.Name, p.UTM.Source, p.UTM.Medium, p.UTM.Campaign, p.UTM.Term, p.UTM.Content)
+			}
+			w.Flush()
+
+		case utmPresetDeleteFlag:
+			if err := linkSvc.DeleteUTMPreset(utmPresetOwnerFlag, utmPresetNameFlag); err != nil {
+				log.Fatalf("Erreur lors de la suppression : %v", err)
+			}
+			fmt.Printf("Jeu de paramètres '%s' supprimé.\n", utmPresetNameFlag)
+
+		default:
+			preset, err := linkSvc.SaveUTMPreset(utmPresetOwnerFlag, utmPresetNameFlag, utmPresetValues)
+			if err != nil {
+				log.Fatalf("Erreur lors de l'enregistrement : %v", err)
+			}
+			fmt.Printf("Jeu de paramètres '%s' enregistré.\n", preset.Name)
+		}
+	},
+}
+
+func init() {
+	UTMPresetCmd.Flags().StringVar(&utmPresetOwnerFlag, "owner", "", "Équipe propriétaire du jeu de paramètres")
+	UTMPresetCmd.Flags().StringVar(&utmPresetNameFlag, "name", "", "Nom du jeu de paramètres")
+	UTMPresetCmd.Flags().BoolVar(&utmPresetListFlag, "list", false, "Liste les jeux de paramètres du propriétaire")
+	UTMPresetCmd.Flags().BoolVar(&utmPresetDeleteFlag, "delete", false, "Supprime le jeu de paramètres --name")
+	UTMPresetCmd.Flags().StringVar(&utmPresetValues.Source, "source", "", "utm_source")
+	UTMPresetCmd.Flags().StringVar(&utmPresetValues.Medium, "medium", "", "utm_medium")
+	UTMPresetCmd.Flags().StringVar(&utmPresetValues.Campaign, "campaign", "", "utm_campaign")
+	UTMPresetCmd.Flags().StringVar(&utmPresetValues.Term, "term", "", "utm_term")
+	UTMPresetCmd.Flags().StringVar(&utmPresetValues.Content, "content", "", "utm_content")
+
+	// Ajouter la commande à RootCmd
+	cmd2.RootCmd.AddCommand(UTMPresetCmd)
+}
@@ -0,0 +1,160 @@
+package cli
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	cmd2 "github.com/antoine-granier/urlshortener/cmd"
+	"github.com/antoine-granier/urlshortener/internal/repository"
+	"github.com/antoine-granier/urlshortener/internal/services"
+	"github.com/spf13/cobra"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// Flags de la commande 'webhook'
+var (
+	webhookURLFlag        string
+	webhookOwnerFlag      string
+	webhookEventsFlag     []string
+	webhookSecretFlag     string
+	webhookListFlag       bool
+	webhookDeleteFlag     uint
+	webhookDeliveriesFlag uint
+	webhookStatusFlag     string
+	webhookReplayFlag     uint
+	webhookDeliveryFlag   uint
+)
+
+// WebhookCmd représente la commande 'webhook'
+var WebhookCmd = &cobra.Command{
+	Use:   "webhook",
+	Short: "Enregistre, liste ou supprime des webhooks et rejoue leurs livraisons.",
+	Long: `Cette commande gère les webhooks notifiés des événements des liens (` + strings.Join(services.EventTypes, ", ") + `).
+Les livraisons sont envoyées par le serveur (run-server), signées avec le secret du webhook
+(en-tête X-Webhook-Signature), et renvoyées avec un délai croissant en cas d'échec.
+
+Exemple:
+  url-shortener webhook --url="https://crm.example.com/hooks" --owner="marketing" --events=link.created,click.threshold_reached
+  url-shortener webhook --list [--owner="marketing"]
+  url-shortener webhook --deliveries=3 --status=dead
+  url-shortener webhook --replay=3 [--delivery=42]
+  url-shortener webhook --delete=3`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Charger la configuration globale
+		cfg := cmd2.Cfg
+		if cfg == nil {
+			log.Fatal("Configuration non initialisée")
+		}
+
+		// Initialiser la connexion à la base de données SQLite
+		db, err := gorm.Open(sqlite.Open(cfg.Database.Name), &gorm.Config{})
+		if err != nil {
+			log.Fatalf("Erreur de connexion à la BDD : %v", err)
+		}
+		sqlDB, err := db.DB()
+		if err != nil {
+			log.Fatalf("Échec de l'obtention de la DB SQL : %v", err)
+		}
+		defer sqlDB.Close()
+
+		webhookSvc := services.NewWebhookService(repository.NewWebhookRepository(db), services.NewEventBus(),
+			cfg.Webhooks.MaxAttempts, 0, 0)
+
+		switch {
+		case webhookListFlag:
+			var owner *string
+			if cmd.Flags().Changed("owner") {
+				owner = &webhookOwnerFlag
+			}
+			webhooks, err := webhookSvc.ListWebhooks(owner)
+			if err != nil {
+				log.Fatalf("Erreur lors de la récupération des webhooks : %v", err)
+			}
+			if len(webhooks) == 0 {
+				fmt.Println("Aucun webhook.")
+				return
+			}
+			for _, webhook := range webhooks {
+				owner := webhook.Owner
+				if owner == "" {
+					owner = "(tous)"
+				}
+				fmt.Printf("#%-4d %-15s %-40s %s\n", webhook.ID, owner, webhook.Events, webhook.URL)
+			}
+
+		case webhookDeleteFlag != 0:
+			if err := webhookSvc.DeleteWebhook(webhookDeleteFlag); err != nil {
+				log.Fatalf("Erreur lors de la suppression : %v", err)
+			}
+			fmt.Printf("Webhook #%d supprimé.\n", webhookDeleteFlag)
+
+		case webhookDeliveriesFlag != 0:
+			deliveries, err := webhookSvc.ListDeliveries(webhookDeliveriesFlag, webhookStatusFlag)
+			if err != nil {
+				log.Fatalf("Erreur lors de la récupération des livraisons : %v", err)
+			}
+			if len(deliveries) == 0 {
+				fmt.Println("Aucune livraison.")
+				return
+			}
+			for _, d := range deliveries {
+				fmt.Printf("#%-6d %-10s %-24s %s  tentatives=%d", d.ID, d.Status, d.EventType,
+					d.CreatedAt.Local().Format(time.RFC3339), d.Attempts)
+				if d.LastError != "" {
+					fmt.Printf("  dernière erreur : %s", d.LastError)
+				}
+				fmt.Println()
+			}
+
+		case webhookReplayFlag != 0:
+			if webhookDeliveryFlag != 0 {
+				found, err := webhookSvc.ReplayDelivery(webhookReplayFlag, webhookDeliveryFlag)
+				if err != nil {
+					log.Fatalf("Erreur lors du rejeu : %v", err)
+				}
+				if !found {
+					log.Fatalf("Livraison #%d introuvable pour le webhook #%d", webhookDeliveryFlag, webhookReplayFlag)
+				}
+				fmt.Printf("Livraison #%d remise en file.\n", webhookDeliveryFlag)
+				return
+			}
+			n, err := webhookSvc.ReplayDeadLetters(webhookReplayFlag)
+			if err != nil {
+				log.Fatalf("Erreur lors du rejeu : %v", err)
+			}
+			fmt.Printf("%d livraison(s) abandonnée(s) remise(s) en file.\n", n)
+
+		default:
+			if webhookURLFlag == "" {
+				fmt.Fprintln(os.Stderr, "Erreur : le flag --url est requis pour enregistrer un webhook")
+				os.Exit(1)
+			}
+			webhook, err := webhookSvc.CreateWebhook(webhookOwnerFlag, webhookURLFlag, webhookEventsFlag, webhookSecretFlag)
+			if err != nil {
+				log.Fatalf("Erreur lors de l'enregistrement du webhook : %v", err)
+			}
+			fmt.Printf("Webhook #%d enregistré (événements : %s).\n", webhook.ID, webhook.Events)
+			fmt.Printf("Secret de signature (affiché une seule fois) : %s\n", webhook.Secret)
+		}
+	},
+}
+
+func init() {
+	WebhookCmd.Flags().StringVar(&webhookURLFlag, "url", "", "URL notifiée des événements")
+	WebhookCmd.Flags().StringVar(&webhookOwnerFlag, "owner", "", "Equipe dont les liens sont suivis (vide : tous les liens)")
+	WebhookCmd.Flags().StringSliceVar(&webhookEventsFlag, "events", nil, "Evénements souscrits, séparés par des virgules (tous par défaut ; click.recorded doit être listé explicitement)")
+	WebhookCmd.Flags().StringVar(&webhookSecretFlag, "secret", "", "Secret de signature (généré par défaut)")
+	WebhookCmd.Flags().BoolVar(&webhookListFlag, "list", false, "Liste les webhooks")
+	WebhookCmd.Flags().UintVar(&webhookDeleteFlag, "delete", 0, "Supprime le webhook d'ID donné")
+	WebhookCmd.Flags().UintVar(&webhookDeliveriesFlag, "deliveries", 0, "Affiche les dernières livraisons du webhook d'ID donné")
+	WebhookCmd.Flags().StringVar(&webhookStatusFlag, "status", "", "Filtre les livraisons par statut (pending, succeeded, dead)")
+	WebhookCmd.Flags().UintVar(&webhookReplayFlag, "replay", 0, "Rejoue les livraisons abandonnées du webhook d'ID donné")
+	WebhookCmd.Flags().UintVar(&webhookDeliveryFlag, "delivery", 0, "Avec --replay, rejoue uniquement cette livraison")
+
+	// Ajouter la commande à RootCmd
+	cmd2.RootCmd.AddCommand(WebhookCmd)
+}
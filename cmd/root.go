@@ -4,7 +4,7 @@ import (
 	"fmt"
 	"github.com/antoine-granier/urlshortener/internal/config"
 	"github.com/spf13/cobra"
-	"log"
+	"log/slog"
 	"os"
 )
 
@@ -72,7 +72,7 @@ func initConfig() {
 		// gère déjà l'absence de fichier avec des valeurs par défaut.
 		// Si LoadConfig() termine le programme en cas d'erreur fatale,
 		// cette vérification est surtout pour les avertissements.
-		log.Printf("Attention: Problème lors du chargement de la configuration: %v. Utilisation des valeurs par défaut.", err)
+		slog.Warn("Failed to load configuration, using defaults", "error", err)
 	}
 	// La configuration est maintenant disponible via la variable globale 'cmd.cfg'.
 }
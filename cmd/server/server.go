@@ -4,7 +4,6 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
 	"net/http"
 	"os"
 	"os/signal"
@@ -12,11 +11,16 @@ import (
 	"time"
 
 	"github.com/antoine-granier/urlshortener/internal/api"
+	"github.com/antoine-granier/urlshortener/internal/botdetect"
+	"github.com/antoine-granier/urlshortener/internal/geoip"
+	"github.com/antoine-granier/urlshortener/internal/logging"
 	"github.com/antoine-granier/urlshortener/internal/repository"
 
 	cmd2 "github.com/antoine-granier/urlshortener/cmd"
+	"github.com/antoine-granier/urlshortener/internal/metrics"
 	"github.com/antoine-granier/urlshortener/internal/models"
 	"github.com/antoine-granier/urlshortener/internal/monitor"
+	"github.com/antoine-granier/urlshortener/internal/scheduler"
 	"github.com/antoine-granier/urlshortener/internal/services"
 	"github.com/antoine-granier/urlshortener/internal/workers"
 	"github.com/gin-gonic/gin"
@@ -37,50 +41,236 @@ puis lance le serveur HTTP.`,
 		// Charger la configuration globale
 		cfg := cmd2.Cfg
 		if cfg == nil {
-			log.Fatalf("Configuration non initialisée : cmd.Cfg est nil")
+			fatal("Configuration not initialized", errors.New("cmd.Cfg is nil"))
+		}
+
+		// Configurer les logs avant tout le reste : les composants démarrés ensuite en héritent
+		if err := logging.Setup(logging.Options{
+			Format:     cfg.Logging.Format,
+			Level:      cfg.Logging.Level,
+			Components: cfg.Logging.Components,
+			Sampling: logging.SamplingOptions{
+				Initial:    cfg.Logging.Sampling.Initial,
+				Thereafter: cfg.Logging.Sampling.Thereafter,
+				Interval:   time.Duration(cfg.Logging.Sampling.IntervalSeconds) * time.Second,
+			},
+		}); err != nil {
+			fatal("Invalid logging configuration", err)
 		}
 
 		// Initialiser la connexion à la base de données SQLite avec GORM
 		db, err := gorm.Open(sqlite.Open(cfg.Database.Name), &gorm.Config{})
 		if err != nil {
-			log.Fatalf("Erreur de connexion à la BDD : %v", err)
+			fatal("Failed to connect to database", err)
 		}
 
 		// Migrations automatiques
-		if err := db.AutoMigrate(&models.Link{}, &models.Click{}); err != nil {
-			log.Fatalf("Erreur lors des migrations : %v", err)
+		if err := db.AutoMigrate(
+			&models.Link{}, &models.Click{}, &models.Sequence{}, &models.LinkVariant{},
+			&models.ScheduledChange{}, &models.AuditEntry{}, &models.LinkMetadata{},
+			&models.Tag{}, &models.Campaign{}, &models.UTMPreset{}, &models.VisitorSketch{},
+			&models.HourlyClickRollup{}, &models.DailyClickRollup{},
+			&models.Webhook{}, &models.WebhookDelivery{}, &models.ClickThreshold{}, &models.AlertRule{},
+			&models.Conversion{},
+		); err != nil {
+			fatal("Failed to run migrations", err)
+		}
+		if migrated, err := repository.MigrateTargetingRules(db); err != nil {
+			fatal("Failed to migrate device and geo rules", err)
+		} else if migrated > 0 {
+			logger.Info("Device and geo rules migrated to routing rules", "links", migrated)
 		}
 
 		// Initialiser les repositories
 		linkRepo := repository.NewLinkRepository(db)
 		clickRepo := repository.NewClickRepository(db)
-		log.Println("Repositories initialisés.")
+		changeRepo := repository.NewScheduledChangeRepository(db)
+		auditRepo := repository.NewAuditRepository(db)
+		metadataRepo := repository.NewMetadataRepository(db)
+		webhookRepo := repository.NewWebhookRepository(db)
+		logger.Debug("Repositories initialized")
 
 		// Initialiser les services métiers
-		linkSvc := services.NewLinkService(linkRepo)
-		log.Println("Services métiers initialisés.")
+		linkSvc := services.NewLinkService(linkRepo, clickRepo)
+
+		// Configurer la stratégie de génération des codes courts
+		codeGen, err := services.NewCodeGenerator(services.CodeGeneratorConfig{
+			Strategy:  cfg.ShortCode.Strategy,
+			Length:    cfg.ShortCode.Length,
+			Obfuscate: cfg.ShortCode.Obfuscate,
+			Secret:    cfg.ShortCode.Secret,
+		}, repository.NewSequenceRepository(db))
+		if err != nil {
+			fatal("Invalid short code configuration", err)
+		}
+		linkSvc.SetCodeGenerator(codeGen)
+		linkSvc.SetURLCanonicalizer(&services.URLCanonicalizer{
+			StripTracking:  cfg.Links.StripTrackingParams,
+			TrackingParams: cfg.Links.TrackingParams,
+		})
+
+		linkSvc.SetPasswordAttemptLimit(cfg.Security.PasswordMaxAttempts,
+			time.Duration(cfg.Security.PasswordLockoutMinutes)*time.Minute)
+
+		// Activer les liens signés si des clés sont configurées
+		if len(cfg.Signing.Keys) > 0 {
+			keys := make([]services.SigningKey, 0, len(cfg.Signing.Keys))
+			for _, k := range cfg.Signing.Keys {
+				keys = append(keys, services.SigningKey{ID: k.ID, Secret: k.Secret})
+			}
+			signer, err := services.NewLinkSigner(keys, cfg.Signing.ActiveKey, time.Duration(cfg.Signing.MaxTTLHours)*time.Hour)
+			if err != nil {
+				fatal("Invalid link signing configuration", err)
+			}
+			linkSvc.SetLinkSigner(signer)
+			logger.Info("Signed links enabled", "keys", len(keys))
+		}
+
+		// Charger la base GeoIP locale (optionnelle) pour le ciblage géographique
+		var geoLocator *geoip.Locator
+		if cfg.GeoIP.DatabasePath != "" {
+			geoLocator, err = geoip.NewLocator(cfg.GeoIP.DatabasePath)
+			if err != nil {
+				logger.Warn("GeoIP database unavailable, geographic targeting disabled", logging.Err(err))
+			} else {
+				linkSvc.SetGeoLocator(geoLocator)
+				if cfg.GeoIP.ReloadIntervalMinutes > 0 {
+					go geoLocator.Watch(time.Duration(cfg.GeoIP.ReloadIntervalMinutes) * time.Minute)
+				}
+				logger.Info("GeoIP database loaded", "path", cfg.GeoIP.DatabasePath)
+			}
+		}
+		auditSvc := services.NewAuditService(auditRepo)
+
+		// Classer les visiteurs robots (signatures intégrées, complétées par un fichier optionnel)
+		botSignatures := botdetect.DefaultSignatures()
+		if cfg.Analytics.BotSignaturesPath != "" {
+			extra, err := botdetect.LoadSignatures(cfg.Analytics.BotSignaturesPath)
+			if err != nil {
+				fatal("Failed to load bot signatures", err)
+			}
+			botSignatures = append(extra, botSignatures...)
+			logger.Info("Bot signatures loaded", "count", len(extra), "path", cfg.Analytics.BotSignaturesPath)
+		}
+		linkSvc.SetBotClassifier(botdetect.NewClassifier(botSignatures, cfg.Analytics.BotBurstLimit,
+			time.Duration(cfg.Analytics.BotBurstWindowSeconds)*time.Second))
+
+		// Récupérer en arrière-plan les informations des pages de destination (titre, Open Graph, icône)
+		if cfg.Metadata.Enabled {
+			metadataSvc := services.NewMetadataService(metadataRepo)
+			metadataSvc.Start(cfg.Metadata.WorkerCount)
+			linkSvc.SetMetadataService(metadataSvc)
+			logger.Info("Page metadata fetching enabled", "workers", max(cfg.Metadata.WorkerCount, 1))
+		}
+		scheduleSvc := services.NewScheduleService(linkSvc, changeRepo, auditSvc)
+
+		// Diffuser les événements des liens et des clics aux webhooks
+		events := services.NewEventBus()
+		linkSvc.SetEventBus(events)
+		webhookSvc := services.NewWebhookService(webhookRepo, events, cfg.Webhooks.MaxAttempts,
+			time.Duration(cfg.Webhooks.RetryBaseSeconds)*time.Second, time.Duration(cfg.Webhooks.TimeoutSeconds)*time.Second)
+		webhookSvc.SetDeliveryRetention(cfg.Webhooks.DeliveryRetentionDays)
+		services.WatchClickThresholds(events, clickRepo, webhookRepo, cfg.Webhooks.ClickThresholds)
+		go webhookSvc.Start(time.Duration(max(cfg.Webhooks.PollIntervalSeconds, 1)) * time.Second)
+
+		// Evaluer les règles d'alerte sur le trafic des liens et des campagnes
+		alertSvc := services.NewAlertService(linkSvc, repository.NewAlertRepository(db), events, cfg.Alerts.AnomalyBaselineDays)
+		go alertSvc.Start(time.Duration(max(cfg.Alerts.IntervalSeconds, 1)) * time.Second)
+
+		// Attribuer un identifiant aux clics des visiteurs et rattacher les conversions signalées
+		conversionSvc, err := services.NewConversionService(repository.NewConversionRepository(db), services.ConversionOptions{
+			Mode:            cfg.Conversions.ClickIDMode,
+			QueryParam:      cfg.Conversions.QueryParam,
+			CookieName:      cfg.Conversions.CookieName,
+			AttributionDays: cfg.Conversions.AttributionDays,
+			HonorDNT:        cfg.Privacy.HonorDNT,
+		})
+		if err != nil {
+			fatal("Invalid conversion tracking configuration", err)
+		}
+		linkSvc.SetConversionService(conversionSvc)
+		if retention := cfg.Analytics.RawClickRetentionDays; retention > 0 && retention < cfg.Conversions.AttributionDays {
+			logger.Warn("Raw clicks are purged before the attribution window ends, later conversions cannot be attributed",
+				"raw_click_retention_days", retention, "attribution_days", cfg.Conversions.AttributionDays)
+		}
+		logger.Debug("Services initialized")
 
 		// Initialiser le channel ClickEventsChannel et lancer les workers
 		bufferSize := cfg.Analytics.BufferSize
 		numWorkers := 5
 		clickChan := make(chan models.ClickEvent, bufferSize)
-		workers.StartClickWorkers(numWorkers, clickChan, clickRepo)
+		visitors, err := services.NewVisitorCounter(clickRepo, cfg.Analytics.VisitorSalt)
+		if err != nil {
+			fatal("Failed to initialize unique visitor counting", err)
+		}
+		visitors.Start(time.Duration(max(cfg.Analytics.VisitorFlushSeconds, 1)) * time.Second)
+		privacySvc, err := services.NewPrivacyService(clickRepo, auditSvc, cfg.Privacy.IPMode, cfg.Privacy.HonorDNT)
+		if err != nil {
+			fatal("Invalid privacy configuration", err)
+		}
+		clickStream := services.NewClickStream(cfg.Analytics.LiveBufferSize)
+		workers.StartClickWorkers(numWorkers, cfg.Analytics.BatchSize, clickChan, clickRepo, visitors, privacySvc, clickStream, events)
+
+		// Agréger les clics dans les tables de statistiques et purger les clics bruts expirés
+		compactor := services.NewClickCompactor(clickRepo, cfg.Analytics.RawClickRetentionDays)
+		compactor.Start(time.Duration(max(cfg.Analytics.RollupIntervalSeconds, 1)) * time.Second)
 
-		log.Printf(
-			"Channel d'événements de clic initialisé avec un buffer de %d. %d worker(s) de clics démarré(s).",
-			bufferSize, numWorkers,
-		)
+		logger.Info("Click workers started", "buffer_size", bufferSize, "workers", numWorkers)
 
 		// Initialiser et lancer le moniteur d'URLs
 		interval := time.Duration(cfg.Monitor.IntervalMinutes) * time.Minute
 		urlMonitor := monitor.NewUrlMonitor(linkRepo, interval)
+		urlMonitor.OnHealthChange(func(link models.Link, accessible bool) {
+			events.Emit(services.EventLinkHealthChanged, link.Owner,
+				services.LinkHealthEventData{LinkEventData: services.NewLinkEventData(&link), Accessible: accessible})
+		})
 		go urlMonitor.Start()
-		log.Printf("Moniteur d'URLs démarré avec un intervalle de %v.", interval)
+		logger.Info("URL monitor started", "interval", interval)
 
-		// Configurer le routeur Gin et les handlers API
-		router := gin.Default()
-		api.SetupRoutes(router, linkSvc, clickChan)
-		log.Println("Routes API configurées.")
+		// Les aperçus de liens (/code+) affichent l'état relevé par le moniteur
+		previewSvc := services.NewPreviewService(linkSvc, urlMonitor)
+
+		// Lancer le planificateur des changements de destination programmés
+		pollInterval := time.Duration(cfg.Scheduler.PollIntervalSeconds) * time.Second
+		go scheduler.NewScheduler(scheduleSvc, pollInterval).Start()
+		logger.Info("Scheduler started", "poll_interval", pollInterval)
+
+		// Configurer le routeur Gin et les handlers API. Le journal des requêtes de Gin est remplacé
+		// par celui de l'application, qui porte l'identifiant de requête.
+		router := gin.New()
+		router.Use(gin.Recovery(), api.RequestIDMiddleware(), api.AccessLogMiddleware(privacySvc))
+		api.SetupRoutes(router, linkSvc, scheduleSvc, auditSvc, previewSvc, privacySvc, clickStream, webhookSvc, alertSvc, conversionSvc, clickChan)
+		logger.Debug("API routes configured")
+
+		// Exposer les métriques Prometheus sur le port principal, ou sur une adresse dédiée
+		// pour les garder hors de portée des visiteurs.
+		var metricsSrv *http.Server
+		if cfg.Metrics.Enabled {
+			if sqlDB, err := db.DB(); err == nil {
+				metrics.RegisterDBStats(sqlDB)
+			}
+			metrics.Default.NewGaugeFunc("urlshortener_click_events_queue_depth",
+				"Evénements de clic en attente dans le channel des workers.", func() float64 { return float64(len(clickChan)) })
+			metrics.Default.NewGaugeFunc("urlshortener_click_events_queue_capacity",
+				"Capacité du channel des événements de clic.", func() float64 { return float64(cap(clickChan)) })
+			metrics.Default.NewCounterFunc("urlshortener_log_messages_dropped_total",
+				"Messages de log écartés par l'échantillonnage.", func() float64 { return float64(logging.Dropped()) })
+
+			if cfg.Metrics.ListenAddress == "" {
+				router.GET("/metrics", gin.WrapH(metrics.Default))
+				logger.Info("Metrics exposed", "path", "/metrics")
+			} else {
+				mux := http.NewServeMux()
+				mux.Handle("/metrics", metrics.Default)
+				metricsSrv = &http.Server{Addr: cfg.Metrics.ListenAddress, Handler: mux}
+				go func() {
+					logger.Info("Metrics exposed", "address", cfg.Metrics.ListenAddress, "path", "/metrics")
+					if err := metricsSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+						fatal("Metrics server failed", err)
+					}
+				}()
+			}
+		}
 
 		// Créer le serveur HTTP Gin
 		serverAddr := fmt.Sprintf(":%d", cfg.Server.Port)
@@ -88,33 +278,61 @@ puis lance le serveur HTTP.`,
 			Addr:    serverAddr,
 			Handler: router,
 		}
+		// Les flux de suivi en temps réel restent ouverts : ils sont interrompus à l'arrêt du serveur.
+		srv.RegisterOnShutdown(clickStream.Close)
 
 		// Démarrer le serveur Gin dans une goroutine anonyme pour ne pas bloquer.
 		go func() {
-			log.Printf("Démarrage du serveur sur %s…", serverAddr)
+			logger.Info("Starting HTTP server", "address", serverAddr)
 			if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-				log.Fatalf("Erreur du serveur HTTP : %v", err)
+				fatal("HTTP server failed", err)
 			}
 		}()
 
 		// Gère l'arrêt propre du serveur (graceful shutdown)
 		quit := make(chan os.Signal, 1)
-		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-		// Bloquer jusqu'à ce qu'un signal d'arrêt soit reçu
-		<-quit
-		log.Println("Signal d'arrêt reçu. Arrêt du serveur...")
-
-		log.Println("Arrêt en cours... Donnez un peu de temps aux workers pour finir.")
+		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+		// Bloquer jusqu'à ce qu'un signal d'arrêt soit reçu ; SIGHUP recharge la base GeoIP
+		for sig := range quit {
+			if sig != syscall.SIGHUP {
+				break
+			}
+			if geoLocator == nil {
+				continue
+			}
+			if err := geoLocator.Reload(); err != nil {
+				logger.Error("Failed to reload GeoIP database", logging.Err(err))
+			} else {
+				logger.Info("GeoIP database reloaded")
+			}
+		}
+		logger.Info("Shutdown signal received, stopping server")
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
 		if err := srv.Shutdown(ctx); err != nil {
-			log.Fatalf("Erreur lors du shutdown : %v", err)
+			fatal("Server shutdown failed", err)
+		}
+		if metricsSrv != nil {
+			_ = metricsSrv.Shutdown(ctx)
+		}
+		// Enregistrer les visiteurs uniques encore en mémoire
+		if err := visitors.Flush(); err != nil {
+			logger.Error("Failed to save unique visitors", logging.Err(err))
 		}
 
-		log.Println("Serveur arrêté proprement.")
+		logger.Info("Server stopped")
 	},
 }
 
+// logger journalise le démarrage et l'arrêt du serveur.
+var logger = logging.For("server")
+
+// fatal journalise une erreur empêchant le serveur de fonctionner et arrête le processus.
+func fatal(msg string, err error) {
+	logger.Error(msg, logging.Err(err))
+	os.Exit(1)
+}
+
 func init() {
 	cmd2.RootCmd.AddCommand(RunServerCmd)
 }
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/antoine-granier/urlshortener/internal/logging"
+	"github.com/antoine-granier/urlshortener/internal/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// CreateAlertRuleRequest représente le corps JSON de la création d'une règle d'alerte.
+// La cible est un lien (short_code) ou une campagne (campaign, de l'équipe owner).
+type CreateAlertRuleRequest struct {
+	ShortCode     string `json:"short_code"`
+	Campaign      string `json:"campaign"`
+	Owner         string `json:"owner"`
+	Kind          string `json:"kind" binding:"required"` // click_spike, no_clicks, new_country ou anomaly
+	Threshold     int    `json:"threshold"`               // Clics (click_spike) ou multiple du trafic habituel (anomaly)
+	WindowMinutes int    `json:"window_minutes"`
+}
+
+// CreateAlertRuleHandler enregistre une règle d'alerte sur le trafic d'un lien ou d'une campagne.
+func CreateAlertRuleHandler(alertService *services.AlertService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req CreateAlertRuleRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		rule, err := alertService.CreateRule(services.AlertRuleInput{
+			ShortCode:     req.ShortCode,
+			Campaign:      req.Campaign,
+			Owner:         req.Owner,
+			Kind:          req.Kind,
+			Threshold:     req.Threshold,
+			WindowMinutes: req.WindowMinutes,
+		})
+		if err != nil {
+			switch {
+			case errors.Is(err, gorm.ErrRecordNotFound):
+				c.JSON(http.StatusNotFound, gin.H{"error": "Link or campaign not found"})
+			case errors.Is(err, services.ErrInvalidAlertRule):
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			default:
+				logger.ErrorContext(c.Request.Context(), "Error creating alert rule", logging.Err(err))
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			}
+			return
+		}
+
+		c.JSON(http.StatusCreated, rule)
+	}
+}
+
+// ListAlertRulesHandler retourne les règles d'alerte d'un lien (?short_code=), d'une campagne
+// (?campaign=&owner=) ou d'une équipe (?owner=).
+func ListAlertRulesHandler(alertService *services.AlertService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rules, err := alertService.ListRules(c.Query("short_code"), c.Query("campaign"), c.Query("owner"))
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Link or campaign not found"})
+				return
+			}
+			logger.ErrorContext(c.Request.Context(), "Error listing alert rules", logging.Err(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"alerts": rules})
+	}
+}
+
+// DeleteAlertRuleHandler supprime une règle d'alerte.
+func DeleteAlertRuleHandler(alertService *services.AlertService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 0)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid alert rule id"})
+			return
+		}
+		if err := alertService.DeleteRule(uint(id)); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Alert rule not found"})
+				return
+			}
+			logger.ErrorContext(c.Request.Context(), "Error deleting alert rule", "id", id, logging.Err(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
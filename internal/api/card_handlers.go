@@ -0,0 +1,69 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/antoine-granier/urlshortener/internal/logging"
+	"github.com/antoine-granier/urlshortener/internal/models"
+	"github.com/antoine-granier/urlshortener/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+)
+
+// renderCard sert la carte de partage (balises Open Graph / Twitter) d'un lien aux robots des réseaux sociaux.
+func renderCard(c *gin.Context, link *models.Link, shortCode string) {
+	shortURL := fmt.Sprintf("%s/%s", viper.GetString("server.base_url"), shortCode)
+	renderPage(c, http.StatusOK, "card.html", gin.H{
+		"Card": services.BuildLinkCard(link, shortURL),
+	})
+}
+
+// GetLinkMetadataHandler retourne les informations récupérées sur la destination d'un lien
+// et la carte de partage qui en résulte (après application des personnalisations).
+func GetLinkMetadataHandler(linkService *services.LinkService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		shortCode := c.Param("shortCode")
+
+		link, err := linkService.GetLinkByShortCode(shortCode)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Link not found"})
+				return
+			}
+			logger.ErrorContext(c.Request.Context(), "Error retrieving link", "short_code", shortCode, logging.Err(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		shortURL := fmt.Sprintf("%s/%s", viper.GetString("server.base_url"), link.ShortCode)
+		c.JSON(http.StatusOK, gin.H{
+			"metadata": link.Metadata, // null tant que la récupération n'a pas eu lieu
+			"card":     services.BuildLinkCard(link, shortURL),
+		})
+	}
+}
+
+// RefreshLinkMetadataHandler demande une nouvelle récupération des informations de la destination d'un lien.
+func RefreshLinkMetadataHandler(linkService *services.LinkService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		shortCode := c.Param("shortCode")
+
+		if err := linkService.RefreshMetadata(c.Request.Context(), shortCode); err != nil {
+			switch {
+			case errors.Is(err, gorm.ErrRecordNotFound):
+				c.JSON(http.StatusNotFound, gin.H{"error": "Link not found"})
+			case errors.Is(err, services.ErrMetadataDisabled), errors.Is(err, services.ErrMetadataQueueFull):
+				c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+			default:
+				logger.ErrorContext(c.Request.Context(), "Error refreshing metadata", "short_code", shortCode, logging.Err(err))
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			}
+			return
+		}
+
+		c.JSON(http.StatusAccepted, gin.H{"status": "queued"})
+	}
+}
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/antoine-granier/urlshortener/internal/logging"
+	"github.com/antoine-granier/urlshortener/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// transparentGIF est une image GIF transparente de 1x1 pixel.
+var transparentGIF = []byte{
+	0x47, 0x49, 0x46, 0x38, 0x39, 0x61, 0x01, 0x00, 0x01, 0x00, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0xff, 0xff, 0xff, 0x21, 0xf9, 0x04, 0x01, 0x00, 0x00, 0x00, 0x00, 0x2c, 0x00, 0x00, 0x00, 0x00,
+	0x01, 0x00, 0x01, 0x00, 0x00, 0x02, 0x02, 0x44, 0x01, 0x00, 0x3b,
+}
+
+// RecordConversionRequest représente le corps JSON du signalement d'une conversion.
+type RecordConversionRequest struct {
+	ClickID  string  `json:"click_id" binding:"required"` // Identifiant de clic reçu par le site de destination
+	Value    float64 `json:"value"`                       // Montant de la conversion (optionnel)
+	Currency string  `json:"currency"`                    // Devise ISO 4217 du montant (optionnel)
+	OrderID  string  `json:"order_id"`                    // Identifiant de commande (optionnel)
+}
+
+// RecordConversionHandler enregistre une conversion signalée de serveur à serveur (postback).
+// Une conversion déjà enregistrée pour le même clic et la même commande est retournée avec 200.
+func RecordConversionHandler(conversionService *services.ConversionService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req RecordConversionRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		conversion, created, err := conversionService.Record(services.ConversionInput{
+			ClickID:  req.ClickID,
+			Value:    req.Value,
+			Currency: req.Currency,
+			OrderID:  req.OrderID,
+		}, services.ConversionSourcePostback)
+		if err != nil {
+			switch {
+			case errors.Is(err, services.ErrInvalidConversion):
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			case errors.Is(err, services.ErrUnknownClickID):
+				c.JSON(http.StatusNotFound, gin.H{"error": "Click not found"})
+			case errors.Is(err, services.ErrAttributionExpired):
+				c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			default:
+				logger.ErrorContext(c.Request.Context(), "Error recording conversion", "click_id", req.ClickID, logging.Err(err))
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			}
+			return
+		}
+
+		status := http.StatusCreated
+		if !created {
+			status = http.StatusOK
+		}
+		c.JSON(status, gin.H{"conversion": conversion, "duplicate": !created})
+	}
+}
+
+// ConversionPixelHandler enregistre la conversion signalée par un pixel placé sur la page de confirmation
+// du site de destination : /t/pixel.gif?cid=...&value=...&currency=...&order_id=...
+// Sans ?cid=, l'identifiant de clic est lu dans le cookie posé lors de la redirection.
+// L'image est toujours retournée : une erreur ne doit pas s'afficher sur le site de destination.
+func ConversionPixelHandler(conversionService *services.ConversionService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		quietAccessLog(c)
+		input := services.ConversionInput{
+			ClickID:  c.Query("cid"),
+			Currency: c.Query("currency"),
+			OrderID:  c.Query("order_id"),
+		}
+		if input.ClickID == "" {
+			input.ClickID, _ = c.Cookie(conversionService.CookieName())
+		}
+		valueOK := true
+		if raw := c.Query("value"); raw != "" {
+			var err error
+			input.Value, err = strconv.ParseFloat(raw, 64)
+			valueOK = err == nil
+		}
+
+		if input.ClickID != "" && valueOK {
+			_, _, err := conversionService.Record(input, services.ConversionSourcePixel)
+			if err != nil && !errors.Is(err, services.ErrInvalidConversion) &&
+				!errors.Is(err, services.ErrUnknownClickID) && !errors.Is(err, services.ErrAttributionExpired) {
+				logger.ErrorContext(c.Request.Context(), "Error recording pixel conversion", "click_id", input.ClickID, logging.Err(err))
+			}
+		}
+
+		c.Header("Cache-Control", "no-store")
+		c.Data(http.StatusOK, "image/gif", transparentGIF)
+	}
+}
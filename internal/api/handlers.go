@@ -1,13 +1,21 @@
 package api
 
 import (
+	"errors"
 	"fmt"
-	"log"
+	"html/template"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/antoine-granier/urlshortener/internal/botdetect"
+	"github.com/antoine-granier/urlshortener/internal/logging"
+	"github.com/antoine-granier/urlshortener/internal/metrics"
 	"github.com/antoine-granier/urlshortener/internal/models"
 	"github.com/antoine-granier/urlshortener/internal/services"
+	"github.com/antoine-granier/urlshortener/internal/useragent"
 	"github.com/gin-gonic/gin"
 	"github.com/spf13/viper"
 	"gorm.io/gorm" // Pour gérer gorm.ErrRecordNotFound
@@ -18,7 +26,10 @@ import (
 // aux workers asynchrones. Il est bufferisé pour ne pas bloquer les requêtes de redirection.
 
 // SetupRoutes configure toutes les routes de l'API Gin et injecte les dépendances nécessaires
-func SetupRoutes(router *gin.Engine, linkService *services.LinkService, ClickEventsChannel chan models.ClickEvent) {
+func SetupRoutes(router *gin.Engine, linkService *services.LinkService, scheduleService *services.ScheduleService,
+	auditService *services.AuditService, previewService *services.PreviewService, privacyService *services.PrivacyService,
+	clickStream *services.ClickStream, webhookService *services.WebhookService, alertService *services.AlertService, conversionService *services.ConversionService,
+	ClickEventsChannel chan models.ClickEvent) {
 	// Le channel est initialisé ici.
 	bufferSize := viper.GetInt("analitics.bufferSize") // Récupère la taille du buffer depuis la configuration
 	if ClickEventsChannel == nil {
@@ -29,8 +40,16 @@ func SetupRoutes(router *gin.Engine, linkService *services.LinkService, ClickEve
 
 	// TODO : Route de Health Check , /health
 	router.GET("/health", HealthCheckHandler)
-	// Route de Redirection (au niveau racine pour les short codes)
-	router.GET("/:shortCode", RedirectHandler(linkService, ClickEventsChannel))
+	// Route de Redirection (au niveau racine pour les short codes) ; /:shortCode+ affiche l'aperçu du lien
+	router.GET("/:shortCode", ShortCodeHandler(RedirectHandler(linkService, ClickEventsChannel), PreviewHandler(previewService)))
+	// Soumission du formulaire des liens protégés par mot de passe
+	router.POST("/:shortCode", RedirectHandler(linkService, ClickEventsChannel))
+	// QR code de l'URL courte complète
+	router.GET("/:shortCode/qr", QRCodeHandler(linkService))
+	// Pixel de conversion appelé par les sites de destination (?cid= ou cookie d'identifiant de clic)
+	router.GET("/t/pixel.gif", ConversionPixelHandler(conversionService))
+	// Liens "joker" : /:shortCode/suite/du/chemin
+	router.NoRoute(WildcardRedirectHandler(linkService, ClickEventsChannel))
 
 	api := router.Group("/api/v1")
 	{
@@ -39,6 +58,79 @@ func SetupRoutes(router *gin.Engine, linkService *services.LinkService, ClickEve
 		// POST /links
 		api.POST("/links", CreateShortLinkHandler(linkService))
 
+		// GET /links?tag=&campaign=&owner=&limit=&offset= (liste paginée et filtrée)
+		api.GET("/links", ListLinksHandler(linkService))
+
+		// GET /campaigns/:name/stats (clics agrégés de tous les liens de la campagne)
+		api.GET("/campaigns/:name/stats", GetCampaignStatsHandler(linkService))
+
+		// Jeux de paramètres UTM par équipe (?owner=) et clics regroupés par paramètre UTM
+		api.GET("/utm-presets", ListUTMPresetsHandler(linkService))
+		api.PUT("/utm-presets/:name", SaveUTMPresetHandler(linkService))
+		api.DELETE("/utm-presets/:name", DeleteUTMPresetHandler(linkService))
+		api.GET("/stats/utm", GetUTMStatsHandler(linkService))
+
+		// PATCH /links/:shortCode
+		api.PATCH("/links/:shortCode", UpdateLinkHandler(linkService))
+
+		// DELETE /links/:shortCode (supprime le lien, ses clics et ses statistiques)
+		api.DELETE("/links/:shortCode", DeleteLinkHandler(linkService, auditService))
+
+		// PUT /links/:shortCode/variants (remplace les variantes A/B et leurs poids)
+		api.PUT("/links/:shortCode/variants", SetVariantsHandler(linkService))
+
+		// PUT /links/:shortCode/device-rules (remplace les règles de ciblage par plateforme)
+		api.PUT("/links/:shortCode/device-rules", SetDeviceRulesHandler(linkService))
+
+		// PUT /links/:shortCode/geo-rules (remplace les règles de ciblage par pays ou continent)
+		api.PUT("/links/:shortCode/geo-rules", SetGeoRulesHandler(linkService))
+
+		// PUT /links/:shortCode/rules (remplace les règles de routage conditionnelles)
+		api.PUT("/links/:shortCode/rules", SetRoutingRulesHandler(linkService))
+
+		// Informations récupérées sur la destination et carte de partage
+		api.GET("/links/:shortCode/metadata", GetLinkMetadataHandler(linkService))
+		api.POST("/links/:shortCode/metadata/refresh", RefreshLinkMetadataHandler(linkService))
+
+		// POST /links/:shortCode/sign (lien signé à durée limitée, sans écriture en base)
+		api.POST("/links/:shortCode/sign", SignLinkHandler(linkService))
+
+		// POST /links/:shortCode/dry-run (destination choisie pour une requête synthétique)
+		api.POST("/links/:shortCode/dry-run", DryRunHandler(linkService))
+
+		// Changements de destination programmés
+		api.POST("/links/:shortCode/scheduled-changes", ScheduleChangeHandler(scheduleService))
+		api.GET("/links/:shortCode/scheduled-changes", ListScheduledChangesHandler(scheduleService))
+		api.DELETE("/links/:shortCode/scheduled-changes/:id", CancelScheduledChangeHandler(scheduleService))
+
+		// GET /links/:shortCode/audit (journal d'audit du lien)
+		api.GET("/links/:shortCode/audit", GetAuditLogHandler(linkService, auditService))
+
+		// Suivi des clics en temps réel (Server-Sent Events, ou WebSocket sur demande d'upgrade),
+		// pour un lien ou pour tous les liens d'une équipe (?owner=)
+		api.GET("/links/:shortCode/live", LinkLiveHandler(linkService, clickStream))
+		api.GET("/live", TeamLiveHandler(clickStream))
+
+		// POST /clicks/purge (effacement des IP et User-Agents des clics d'un visiteur et/ou antérieurs à une date)
+		api.POST("/clicks/purge", PurgeClicksHandler(privacyService))
+
+		// Webhooks sortants : enregistrement, journal des livraisons, rejeu des lettres mortes et test
+		api.POST("/webhooks", CreateWebhookHandler(webhookService))
+		api.GET("/webhooks", ListWebhooksHandler(webhookService))
+		api.DELETE("/webhooks/:id", DeleteWebhookHandler(webhookService))
+		api.GET("/webhooks/:id/deliveries", ListWebhookDeliveriesHandler(webhookService))
+		api.POST("/webhooks/:id/deliveries/:deliveryID/replay", ReplayWebhookDeliveryHandler(webhookService))
+		api.POST("/webhooks/:id/replay", ReplayDeadLettersHandler(webhookService))
+		api.POST("/webhooks/:id/ping", PingWebhookHandler(webhookService))
+
+		// Règles d'alerte sur le trafic d'un lien ou d'une campagne (pics, absence de clics, nouveaux pays, anomalies)
+		api.POST("/alerts", CreateAlertRuleHandler(alertService))
+		api.GET("/alerts", ListAlertRulesHandler(alertService))
+		api.DELETE("/alerts/:id", DeleteAlertRuleHandler(alertService))
+
+		// POST /conversions (postback serveur à serveur d'une conversion rattachée à un identifiant de clic)
+		api.POST("/conversions", RecordConversionHandler(conversionService))
+
 		// GET /links/:shortCode/stats
 		api.GET("/links/:shortCode/stats", GetLinkStatsHandler(linkService))
 
@@ -53,29 +145,117 @@ func HealthCheckHandler(c *gin.Context) {
 
 // CreateLinkRequest représente le corps de la requête JSON pour la création d'un lien.
 type CreateLinkRequest struct {
-	LongURL string `json:"long_url" binding:"required,url"` // 'binding:required' pour validation, 'url' pour format URL
+	LongURL       string `json:"long_url" binding:"required,url"` // 'binding:required' pour validation, 'url' pour format URL
+	Owner         string `json:"owner"`                           // Propriétaire du lien (équipe ou système appelant)
+	ReuseExisting bool   `json:"reuse_existing"`                  // Réutilise le lien existant du propriétaire pour une URL et des réglages identiques
+
+	RedirectType    int  `json:"redirect_type"`    // 301, 302 (par défaut), 307 ou 308
+	ForwardQuery    bool `json:"forward_query"`    // Transmet la query string entrante à la destination
+	PathPassthrough bool `json:"path_passthrough"` // Lien "joker" : /code/suite ajoute 'suite' à la destination
+
+	Variants    []services.VariantInput    `json:"variants"`     // Destinations A/B pondérées (optionnel)
+	DeviceRules []services.DeviceRuleInput `json:"device_rules"` // Destinations par plateforme, dans l'ordre d'évaluation (optionnel)
+	GeoRules    []services.GeoRuleInput    `json:"geo_rules"`    // Destinations par pays ou continent (optionnel)
+
+	RoutingRules []models.RoutingRule `json:"rules"` // Règles conditionnelles évaluées dans l'ordre, avant device_rules puis geo_rules (optionnel)
+
+	ActivatesAt *time.Time `json:"activates_at"` // Mise en service différée (RFC 3339, optionnel)
+	ComingSoon  bool       `json:"coming_soon"`  // Page "bientôt disponible" au lieu d'une 404 avant activates_at
+
+	Password string `json:"password"` // Mot de passe demandé aux visiteurs (optionnel)
+	OneTime  bool   `json:"one_time"` // Lien invalidé après la première redirection réussie
+
+	SignedOnly bool `json:"signed_only"` // Accessible uniquement via des liens signés (POST /links/:shortCode/sign)
+
+	Interstitial bool `json:"interstitial"` // Page "vous quittez…" avec compte à rebours avant la redirection
+
+	// Carte de partage (Open Graph / Twitter) ; remplace les informations récupérées sur la destination
+	CardTitle       string `json:"card_title"`
+	CardDescription string `json:"card_description"`
+	CardImageURL    string `json:"card_image_url"`
+
+	Tags     []string `json:"tags"`     // Étiquettes du lien (optionnel)
+	Campaign string   `json:"campaign"` // Campagne du propriétaire (optionnel)
+
+	// Paramètres utm_source, utm_medium, utm_campaign, utm_term et utm_content ajoutés à long_url,
+	// complétant éventuellement le jeu de paramètres utm_preset du propriétaire
+	models.UTM
+	UTMPreset string `json:"utm_preset"`
 }
 
 // CreateShortLinkHandler gère la création d'une URL courte.
 func CreateShortLinkHandler(linkService *services.LinkService) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// TODO : Tente de lier le JSON de la requête à la structure CreateLinkRequest.
-		req := CreateLinkRequest{
-			LongURL: c.Param("LongURL"),
+		// Lier le JSON de la requête à la structure CreateLinkRequest.
+		// Gin gère la validation 'binding'.
+		var req CreateLinkRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
 		}
-		// TODO: Appeler le LinkService (CreateLink pour créer le nouveau lien.
-		link, err := linkService.CreateLink(req.LongURL)
+
+		// Appeler le LinkService pour créer (ou réutiliser) le lien.
+		link, reused, err := linkService.CreateLinkWithOptions(c.Request.Context(), services.CreateLinkOptions{
+			LongURL:       req.LongURL,
+			Owner:         req.Owner,
+			ReuseExisting: req.ReuseExisting,
+
+			RedirectType:    req.RedirectType,
+			ForwardQuery:    req.ForwardQuery,
+			PathPassthrough: req.PathPassthrough,
+
+			Variants:    req.Variants,
+			DeviceRules: req.DeviceRules,
+			GeoRules:    req.GeoRules,
+
+			RoutingRules: req.RoutingRules,
+
+			ActivatesAt: req.ActivatesAt,
+			ComingSoon:  req.ComingSoon,
+
+			Password: req.Password,
+			OneTime:  req.OneTime,
+
+			SignedOnly: req.SignedOnly,
+
+			Interstitial: req.Interstitial,
+
+			CardTitle:       req.CardTitle,
+			CardDescription: req.CardDescription,
+			CardImageURL:    req.CardImageURL,
+
+			Tags:     req.Tags,
+			Campaign: req.Campaign,
+
+			UTM:       req.UTM,
+			UTMPreset: req.UTMPreset,
+		})
 		if err != nil {
-			if err == gorm.ErrRecordNotFound {
-				c.JSON(http.StatusNotFound, gin.H{"error": "Link not found"})
+			if errors.Is(err, services.ErrInvalidURL) || errors.Is(err, services.ErrInvalidRedirectType) ||
+				errors.Is(err, services.ErrInvalidVariants) || errors.Is(err, services.ErrInvalidDeviceRules) ||
+				errors.Is(err, services.ErrInvalidGeoRules) || errors.Is(err, services.ErrInvalidRoutingRules) ||
+				errors.Is(err, services.ErrInvalidLinkPassword) || errors.Is(err, services.ErrInvalidCard) ||
+				errors.Is(err, services.ErrInvalidTags) || errors.Is(err, services.ErrInvalidCampaign) ||
+				errors.Is(err, services.ErrInvalidUTM) || errors.Is(err, services.ErrUTMPresetNotFound) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 				return
 			}
+			logger.ErrorContext(c.Request.Context(), "Error creating link", "long_url", req.LongURL, logging.Err(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
 		}
-		// Gin gère la validation 'binding'.
 
-		// Retourne le code court et l'URL longue dans la réponse JSON.
-		// TODO Choisir le bon code HTTP
-		c.JSON(http.StatusOK, gin.H{"longUrl": link.LongURL, "shortCode": link.ShortCode})
+		// 201 pour un nouveau lien, 200 pour un lien existant réutilisé.
+		status := http.StatusCreated
+		if reused {
+			status = http.StatusOK
+		}
+		c.JSON(status, gin.H{
+			"longUrl":   link.LongURL,
+			"shortCode": link.ShortCode,
+			"fullUrl":   fmt.Sprintf("%s/%s", viper.GetString("server.base_url"), link.ShortCode),
+			"reused":    reused,
+		})
 	}
 }
 
@@ -83,48 +263,388 @@ func CreateShortLinkHandler(linkService *services.LinkService) gin.HandlerFunc {
 func RedirectHandler(linkService *services.LinkService, ClickEventsChannel chan models.ClickEvent) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Récupère le shortCode de l'URL avec c.Param
-		shortCode := c.Param("shortCode")
+		start := time.Now()
+		serveRedirect(c, linkService, ClickEventsChannel, c.Param("shortCode"), "")
+		metrics.ObserveRedirect(c.Writer.Status(), time.Since(start))
+	}
+}
 
-		// TODO 2: Récupérer l'URL longue associée au shortCode depuis le linkService (GetLinkByShortCode)
-		fmt.Printf("Redirecting short code: %s\n", shortCode)
-		link, err := linkService.GetLinkByShortCode(shortCode)
-		if err != nil {
-			// Si le lien n'est pas trouvé, retourner HTTP 404 Not Found.
-			// Utiliser errors.Is et l'erreur Gorm
-			// Utilisez errors.Is(err, gorm.ErrRecordNotFound) en production si l'erreur est wrappée
-			if err == gorm.ErrRecordNotFound {
-				c.JSON(http.StatusNotFound, gin.H{"error": "Link not found"})
+// WildcardRedirectHandler gère les chemins à plusieurs segments (/code/suite) des liens "joker".
+// Il est enregistré via router.NoRoute car Gin n'accepte pas de route joker à côté de /:shortCode/qr.
+func WildcardRedirectHandler(linkService *services.LinkService, ClickEventsChannel chan models.ClickEvent) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodPost:
+		default:
+			c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+			return
+		}
+		start := time.Now()
+		shortCode, suffix, _ := strings.Cut(strings.TrimPrefix(c.Request.URL.Path, "/"), "/")
+		serveRedirect(c, linkService, ClickEventsChannel, shortCode, suffix)
+		metrics.ObserveRedirect(c.Writer.Status(), time.Since(start))
+	}
+}
+
+// serveRedirect résout la destination d'un lien, publie l'événement de clic puis redirige
+// avec le code HTTP propre au lien.
+func serveRedirect(c *gin.Context, linkService *services.LinkService, ClickEventsChannel chan models.ClickEvent, shortCode, suffix string) {
+	// Récupérer l'URL longue associée au shortCode depuis le linkService (GetLinkByShortCode)
+	ctx := c.Request.Context()
+	quietAccessLog(c)
+	redirectLogger.DebugContext(ctx, "Redirecting short code", "short_code", shortCode)
+	// Un code signé désigne le lien par son identifiant : sa signature et son expiration
+	// sont vérifiées avant toute lecture en base.
+	signed := services.IsSignedCode(shortCode)
+	link, err := linkService.LookupLink(shortCode)
+	if err != nil {
+		writeLookupError(c, shortCode, err)
+		return
+	}
+
+	// Avant sa mise en service, le lien n'existe pas pour les visiteurs (aucun clic n'est enregistré).
+	if !services.LinkActive(link, time.Now()) {
+		if link.ComingSoon {
+			renderPage(c, http.StatusNotFound, "comingsoon.html", gin.H{
+				"ActivatesAt":       link.ActivatesAt.Format(time.RFC3339),
+				"ActivatesAtMillis": link.ActivatesAt.UnixMilli(),
+			})
+			return
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": "Link not found"})
+		return
+	}
+
+	// Lien à usage unique déjà utilisé : définitivement indisponible.
+	if link.OneTime && link.ConsumedAt != nil {
+		c.JSON(http.StatusGone, gin.H{"error": "Link already used"})
+		return
+	}
+
+	// Robots des réseaux sociaux : carte de partage au lieu de la redirection (aucun clic enregistré,
+	// un lien à usage unique n'est pas consommé).
+	if c.Request.Method != http.MethodPost && useragent.IsSocialCrawler(c.GetHeader("User-Agent")) {
+		renderCard(c, link, shortCode)
+		return
+	}
+
+	// Lien protégé : formulaire en GET, vérification du mot de passe en POST.
+	protected := link.PasswordHash != ""
+	if protected || link.OneTime || signed {
+		// La réponse dépend du mot de passe, de l'état du lien ou de l'expiration : jamais de cache.
+		c.Header("Cache-Control", "no-store")
+	}
+	if c.Request.Method == http.MethodPost {
+		if !protected {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Link not found"})
+			return
+		}
+		if !checkPassword(c, linkService, link) {
+			return
+		}
+	} else if protected {
+		renderPasswordForm(c, http.StatusOK, "")
+		return
+	}
+
+	// Choisir la destination : règle de plateforme, règle géographique, variante A/B attachée
+	// au visiteur ou URL longue, puis suffixe de chemin des liens "joker" et query string transmise.
+	userAgent := c.GetHeader("User-Agent")
+	cookieName := variantCookieName(link.ShortCode)
+	remembered, _ := c.Cookie(cookieName)
+	decision, err := linkService.ResolveRedirect(link, services.RedirectRequest{
+		IP:                c.ClientIP(),
+		UserAgent:         userAgent,
+		AcceptLanguage:    c.GetHeader("Accept-Language"),
+		Referrer:          c.GetHeader("Referer"),
+		RememberedVariant: remembered,
+		Suffix:            suffix,
+		Query:             c.Request.URL.Query(),
+	})
+	if err != nil {
+		if errors.Is(err, services.ErrPathNotAllowed) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Link not found"})
+			return
+		}
+		logger.ErrorContext(c.Request.Context(), "Error resolving destination", "short_code", shortCode, logging.Err(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if decision.Variant != "" {
+		c.SetCookie(cookieName, decision.Variant, variantCookieMaxAge, "/", "", false, true)
+		// Une réponse mise en cache contournerait la répartition entre variantes.
+		c.Header("Cache-Control", "no-store")
+	} else if len(link.RoutingRules) > 0 {
+		// La destination dépend du visiteur ou de l'heure : elle ne doit pas être partagée par un cache.
+		c.Header("Cache-Control", "no-store")
+	}
+
+	// Lien à usage unique : seule la requête qui l'invalide en base est redirigée, même en cas de
+	// requêtes simultanées. Les requêtes HEAD et les robots (aperçus de liens des messageries)
+	// ne le consomment pas et ne reçoivent pas la destination.
+	if link.OneTime {
+		if c.Request.Method == http.MethodHead || useragent.Parse(userAgent).Device == useragent.DeviceBot {
+			c.Status(http.StatusNoContent)
+			return
+		}
+		if err := linkService.ConsumeOneTimeLink(ctx, link); err != nil {
+			if errors.Is(err, services.ErrLinkConsumed) {
+				c.JSON(http.StatusGone, gin.H{"error": "Link already used"})
 				return
 			}
-			// Gérer d'autres erreurs potentielles de la base de données ou du service
-			log.Printf("Error retrieving link for %s: %v", shortCode, err)
+			logger.ErrorContext(c.Request.Context(), "Error consuming one-time link", "short_code", shortCode, logging.Err(err))
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
 			return
 		}
+	}
 
-		// TODO 3: Créer un ClickEvent avec les informations pertinentes.
-		clickEvent := models.ClickEvent{
-			LinkID:    link.ID,
-			Timestamp: time.Now(),
-			UserAgent: c.GetHeader("User-Agent"),
-			IPAddress: c.ClientIP(),
+	// Créer un ClickEvent avec les informations pertinentes.
+	bot := linkService.ClassifyVisitor(botdetect.Request{
+		Method:    c.Request.Method,
+		UserAgent: userAgent,
+		Header:    c.Request.Header,
+		IP:        c.ClientIP(),
+	})
+	// Identifiant de clic transmis au site de destination (cookie et/ou paramètre) pour y rattacher
+	// les conversions ; ni les robots ni les visiteurs refusant le suivi n'en reçoivent.
+	dnt := doNotTrackRequested(c.Request.Header)
+	tag, err := linkService.TagConversion(decision.Destination, bot.Bot, dnt)
+	if err != nil {
+		logger.ErrorContext(c.Request.Context(), "Error tagging click for conversion tracking", "short_code", shortCode, logging.Err(err))
+	}
+	decision.Destination = tag.Destination
+	if tag.Cookie != "" {
+		// Le pixel est chargé depuis le site de destination : en HTTPS, le cookie doit être envoyé
+		// dans ce contexte tiers (SameSite=None).
+		if c.Request.TLS != nil {
+			c.SetSameSite(http.SameSiteNoneMode)
 		}
+		c.SetCookie(tag.Cookie, tag.ClickID, tag.MaxAge, "/", "", c.Request.TLS != nil, true)
+	}
+	if tag.ClickID != "" {
+		// Chaque redirection porte un identifiant propre : elle ne doit pas être mise en cache.
+		c.Header("Cache-Control", "no-store")
+	}
 
-		// TODO 4: Envoyer le ClickEvent dans le ClickEventsChannel avec le Multiplexage.
-		// Utilise un `select` avec un `default` pour éviter de bloquer si le channel est plein.
-		// Pour le default, juste un message à afficher :
-		select {
-		case ClickEventsChannel <- clickEvent:
-			log.Printf("ClickEvent for %s successfully sent to the channel.", shortCode)
-		default:
-			log.Printf("Warning: ClickEventsChannel is full, dropping click event for %s.", shortCode)
+	clickEvent := models.ClickEvent{
+		LinkID:      link.ID,
+		ShortCode:   link.ShortCode,
+		Owner:       link.Owner,
+		Timestamp:   time.Now(),
+		UserAgent:   userAgent,
+		IPAddress:   c.ClientIP(),
+		Source:      clickSource(c.Query("source")),
+		Variant:     decision.Variant,
+		Rule:        decision.Rule,
+		Country:     decision.Country,
+		Region:      decision.Region,
+		Bot:         bot.Bot,
+		BotCategory: bot.Category,
+		BotReason:   bot.Reason,
+		DoNotTrack:  dnt,
+		ClickID:     tag.ClickID,
+
+		RequestID: logging.RequestID(ctx),
+	}
+
+	// Envoyer le ClickEvent dans le ClickEventsChannel avec le Multiplexage.
+	// Utilise un `select` avec un `default` pour éviter de bloquer si le channel est plein.
+	select {
+	case ClickEventsChannel <- clickEvent:
+		metrics.ClickEventsEnqueued.Inc()
+		redirectLogger.DebugContext(ctx, "Click event queued", "short_code", shortCode)
+	default:
+		metrics.ClickEventsDropped.Inc()
+		redirectLogger.WarnContext(ctx, "Click events channel full, dropping click event", "short_code", shortCode)
+	}
+
+	// Lien profond : la page tente d'ouvrir l'application puis se rabat sur la destination.
+	if decision.DeepLink != "" {
+		renderPage(c, http.StatusOK, "deeplink.html", gin.H{
+			"DeepLink":    template.URL(decision.DeepLink),
+			"Fallback":    template.URL(decision.Destination),
+			"DelayMillis": deepLinkFallbackDelay.Milliseconds(),
+		})
+		return
+	}
+
+	// Page intermédiaire : le visiteur voit la destination avant d'y être envoyé.
+	if link.Interstitial {
+		renderPage(c, http.StatusOK, "interstitial.html", gin.H{
+			"ShortURL":    fmt.Sprintf("%s/%s", viper.GetString("server.base_url"), shortCode),
+			"Destination": decision.Destination,
+			"Seconds":     max(viper.GetInt("preview.interstitial_seconds"), 1),
+		})
+		return
+	}
+
+	// Effectuer la redirection HTTP (302 par défaut, ou le type choisi pour le lien).
+	// Après le formulaire de mot de passe, 303 garantit que la destination est demandée en GET
+	// (un 307/308 renverrait le mot de passe à la destination).
+	status := services.RedirectStatus(link)
+	if c.Request.Method == http.MethodPost {
+		status = http.StatusSeeOther
+	}
+	c.Redirect(status, decision.Destination)
+}
+
+// writeLookupError écrit la réponse d'erreur de la recherche d'un lien par le code d'une URL visiteur.
+func writeLookupError(c *gin.Context, shortCode string, err error) {
+	switch {
+	// Si le lien n'est pas trouvé (ou la signature invalide), retourner HTTP 404 Not Found.
+	case errors.Is(err, gorm.ErrRecordNotFound), errors.Is(err, services.ErrBadSignature):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Link not found"})
+	case errors.Is(err, services.ErrSignedLinkExpired):
+		c.JSON(http.StatusGone, gin.H{"error": "Link expired"})
+	default:
+		// Gérer d'autres erreurs potentielles de la base de données ou du service
+		logger.ErrorContext(c.Request.Context(), "Error retrieving link", "short_code", shortCode, logging.Err(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+	}
+}
+
+// checkPassword vérifie le mot de passe soumis par le formulaire d'un lien protégé.
+// En cas d'échec, la réponse (formulaire avec message d'erreur) est déjà écrite et false est retourné.
+func checkPassword(c *gin.Context, linkService *services.LinkService, link *models.Link) bool {
+	err := linkService.CheckLinkPassword(link, c.PostForm("password"), c.ClientIP())
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, services.ErrWrongPassword):
+		renderPasswordForm(c, http.StatusUnauthorized, "Mot de passe incorrect.")
+	case errors.Is(err, services.ErrTooManyAttempts):
+		renderPasswordForm(c, http.StatusTooManyRequests, "Trop de tentatives, réessayez plus tard.")
+	default:
+		logger.ErrorContext(c.Request.Context(), "Error checking password", "short_code", link.ShortCode, logging.Err(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+	}
+	return false
+}
+
+// renderPasswordForm affiche le formulaire de mot de passe, qui se soumet à l'URL courante
+// (suffixe de chemin et query string compris).
+func renderPasswordForm(c *gin.Context, status int, message string) {
+	renderPage(c, status, "password.html", gin.H{
+		"Action": c.Request.URL.RequestURI(),
+		"Error":  message,
+	})
+}
+
+// deepLinkFallbackDelay est le délai laissé à l'application pour s'ouvrir avant la redirection de secours.
+const deepLinkFallbackDelay = 1500 * time.Millisecond
+
+// UpdateLinkRequest représente le corps JSON d'une modification partielle de lien.
+// Les champs absents ne sont pas modifiés.
+type UpdateLinkRequest struct {
+	RedirectType    *int       `json:"redirect_type"`
+	ForwardQuery    *bool      `json:"forward_query"`
+	PathPassthrough *bool      `json:"path_passthrough"`
+	ActivatesAt     *time.Time `json:"activates_at"`
+	ComingSoon      *bool      `json:"coming_soon"`
+	Password        *string    `json:"password"` // Une chaîne vide supprime la protection
+	SignedOnly      *bool      `json:"signed_only"`
+	Interstitial    *bool      `json:"interstitial"`
+	CardTitle       *string    `json:"card_title"` // Une chaîne vide rétablit l'information de la destination
+	CardDescription *string    `json:"card_description"`
+	CardImageURL    *string    `json:"card_image_url"`
+	Tags            *[]string  `json:"tags"`     // Remplace les tags ; une liste vide les retire
+	Campaign        *string    `json:"campaign"` // Une chaîne vide retire le lien de sa campagne
+}
+
+// UpdateLinkHandler gère la modification des paramètres de redirection d'un lien.
+func UpdateLinkHandler(linkService *services.LinkService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		shortCode := c.Param("shortCode")
+
+		var req UpdateLinkRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		link, err := linkService.UpdateLink(c.Request.Context(), shortCode, services.LinkUpdate{
+			RedirectType:    req.RedirectType,
+			ForwardQuery:    req.ForwardQuery,
+			PathPassthrough: req.PathPassthrough,
+			ActivatesAt:     req.ActivatesAt,
+			ComingSoon:      req.ComingSoon,
+			Password:        req.Password,
+			SignedOnly:      req.SignedOnly,
+			Interstitial:    req.Interstitial,
+			CardTitle:       req.CardTitle,
+			CardDescription: req.CardDescription,
+			CardImageURL:    req.CardImageURL,
+			Tags:            req.Tags,
+			Campaign:        req.Campaign,
+		})
+		if err != nil {
+			switch {
+			case errors.Is(err, gorm.ErrRecordNotFound):
+				c.JSON(http.StatusNotFound, gin.H{"error": "Link not found"})
+			case errors.Is(err, services.ErrInvalidRedirectType), errors.Is(err, services.ErrInvalidLinkPassword),
+				errors.Is(err, services.ErrInvalidCard), errors.Is(err, services.ErrInvalidTags),
+				errors.Is(err, services.ErrInvalidCampaign):
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			default:
+				logger.ErrorContext(c.Request.Context(), "Error updating link", "short_code", shortCode, logging.Err(err))
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			}
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"link": link})
+	}
+}
+
+// DeleteLinkHandler supprime un lien et ses données rattachées, et l'inscrit au journal d'audit.
+func DeleteLinkHandler(linkService *services.LinkService, auditService *services.AuditService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		shortCode := c.Param("shortCode")
+
+		link, err := linkService.DeleteLink(c.Request.Context(), shortCode)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Link not found"})
+				return
+			}
+			logger.ErrorContext(c.Request.Context(), "Error deleting link", "short_code", shortCode, logging.Err(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
 		}
+		auditService.Record(c.Request.Context(), services.ActorAPI, "link.deleted", link.ID,
+			fmt.Sprintf("lien %s supprimé (destination %s)", link.ShortCode, link.LongURL))
 
-		//log.Printf("Warning: ClickEventsChannel is full, dropping click event for %s.", shortCode)
+		c.Status(http.StatusNoContent)
+	}
+}
 
-		// TODO 5: Effectuer la redirection HTTP 302 (StatusFound) vers l'URL longue.
-		c.Redirect(http.StatusFound, link.LongURL)
+// statsView retourne le service à utiliser pour les statistiques d'une requête : les clics de robots
+// n'y sont inclus qu'avec ?include_bots=true. En cas de paramètre invalide, la réponse 400 est écrite
+// et ok vaut false.
+func statsView(c *gin.Context, linkService *services.LinkService) (view *services.LinkService, ok bool) {
+	includeBots, ok := includeBotsParam(c)
+	if !ok {
+		return nil, false
 	}
+	if includeBots {
+		return linkService.WithBots(), true
+	}
+	return linkService, true
+}
+
+// includeBotsParam lit le paramètre ?include_bots= (faux par défaut). En cas de valeur invalide,
+// la réponse 400 est écrite et ok vaut false.
+func includeBotsParam(c *gin.Context) (includeBots, ok bool) {
+	raw := c.Query("include_bots")
+	if raw == "" {
+		return false, true
+	}
+	includeBots, err := strconv.ParseBool(raw)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "include_bots must be a boolean"})
+		return false, false
+	}
+	return includeBots, true
 }
 
 // GetLinkStatsHandler gère la récupération des statistiques pour un lien spécifique.
@@ -133,11 +653,17 @@ func GetLinkStatsHandler(linkService *services.LinkService) gin.HandlerFunc {
 		// TODO Récupère le shortCode de l'URL avec c.Param
 		shortCode := c.Param("shortCode")
 
+		// Les clics de robots sont exclus, sauf avec ?include_bots=true
+		stats, ok := statsView(c, linkService)
+		if !ok {
+			return
+		}
+
 		// TODO 6: Appeler le LinkService pour obtenir le lien et le nombre total de clics.
-		link, count, err := linkService.GetLinkStats(shortCode)
+		link, count, err := stats.GetLinkStats(shortCode)
 		if err != nil {
 			// Gérer le cas où le lien n'est pas trouvé (Gorm ErrRecordNotFound)
-			if err == gorm.ErrRecordNotFound {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
 				c.JSON(http.StatusNotFound, gin.H{"error": "Link not found"})
 				return
 			}
@@ -148,10 +674,311 @@ func GetLinkStatsHandler(linkService *services.LinkService) gin.HandlerFunc {
 		// toujours avec l'erreur Gorm ErrRecordNotFound
 		// Gérer d'autres erreurs
 
+		// Visiteurs uniques (et clics) sur la période ?from=AAAA-MM-JJ&to=AAAA-MM-JJ, toute la vie du lien par défaut
+		period, err := services.ParseDayRange(c.Query("from"), c.Query("to"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if !period.IsZero() {
+			if count, err = stats.CountClicks(link.ID, period); err != nil {
+				logger.ErrorContext(c.Request.Context(), "Error counting clicks", "short_code", shortCode, logging.Err(err))
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+				return
+			}
+		}
+		uniqueVisitors, err := stats.CountUniqueVisitors(link.ID, period)
+		if err != nil {
+			logger.ErrorContext(c.Request.Context(), "Error counting unique visitors", "short_code", shortCode, logging.Err(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+			return
+		}
+
+		// Conversions signalées sur la même période, rapportées aux clics
+		conversions, err := stats.GetConversionStats(link.ID, period, count)
+		if err != nil {
+			logger.ErrorContext(c.Request.Context(), "Error counting conversions", "short_code", shortCode, logging.Err(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+			return
+		}
+
+		// Ventilation des clics par provenance (ex: scans de QR code) et par variante A/B
+		bySource, err := stats.GetClickBreakdown(link.ID, "source")
+		if err != nil {
+			logger.ErrorContext(c.Request.Context(), "Error retrieving click breakdown", "short_code", shortCode, logging.Err(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+			return
+		}
+		byVariant, err := stats.GetClickBreakdown(link.ID, "variant")
+		if err != nil {
+			logger.ErrorContext(c.Request.Context(), "Error retrieving click breakdown", "short_code", shortCode, logging.Err(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+			return
+		}
+		byRule, err := stats.GetClickBreakdown(link.ID, "rule")
+		if err != nil {
+			logger.ErrorContext(c.Request.Context(), "Error retrieving click breakdown", "short_code", shortCode, logging.Err(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+			return
+		}
+		byCountry, err := stats.GetClickBreakdown(link.ID, "country")
+		if err != nil {
+			logger.ErrorContext(c.Request.Context(), "Error retrieving click breakdown", "short_code", shortCode, logging.Err(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+			return
+		}
+		byRegion, err := stats.GetClickBreakdown(link.ID, "region")
+		if err != nil {
+			logger.ErrorContext(c.Request.Context(), "Error retrieving click breakdown", "short_code", shortCode, logging.Err(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+			return
+		}
+
+		// Clics de robots par catégorie (toujours comptés à part, quel que soit include_bots)
+		byBot, err := stats.GetBotBreakdown(link.ID)
+		if err != nil {
+			logger.ErrorContext(c.Request.Context(), "Error retrieving bot breakdown", "short_code", shortCode, logging.Err(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+			return
+		}
+
 		// Retourne les statistiques dans la réponse JSON.
 		c.JSON(http.StatusOK, gin.H{
-			"link":   link,
-			"clicks": count,
+			"link":            link,
+			"clicks":          count,
+			"unique_visitors": uniqueVisitors,
+			"by_source":       bySource,
+			"by_variant":      byVariant,
+			"by_rule":         byRule,
+			"by_country":      byCountry,
+			"by_region":       byRegion,
+			"by_bot":          byBot,
+
+			"conversions":      conversions.Conversions,
+			"conversion_rate":  conversions.Rate,
+			"conversion_value": conversions.Value,
 		})
 	}
 }
+
+// variantCookieMaxAge est la durée (en secondes) pendant laquelle un visiteur reste attaché à sa variante A/B.
+const variantCookieMaxAge = 30 * 24 * 3600
+
+// variantCookieName retourne le nom du cookie mémorisant la variante A/B servie pour un lien.
+func variantCookieName(shortCode string) string {
+	return "ab_" + shortCode
+}
+
+// SetVariantsRequest représente le corps JSON du remplacement des variantes A/B d'un lien.
+type SetVariantsRequest struct {
+	Variants []services.VariantInput `json:"variants"`
+}
+
+// SetVariantsHandler remplace les variantes A/B d'un lien (et donc leurs poids) à chaud.
+// Une liste vide supprime les variantes : le lien redirige à nouveau vers son URL longue.
+func SetVariantsHandler(linkService *services.LinkService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		shortCode := c.Param("shortCode")
+
+		var req SetVariantsRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		link, err := linkService.SetLinkVariants(c.Request.Context(), shortCode, req.Variants)
+		if err != nil {
+			switch {
+			case errors.Is(err, gorm.ErrRecordNotFound):
+				c.JSON(http.StatusNotFound, gin.H{"error": "Link not found"})
+			case errors.Is(err, services.ErrInvalidVariants):
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			default:
+				logger.ErrorContext(c.Request.Context(), "Error updating variants", "short_code", shortCode, logging.Err(err))
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			}
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"link": link})
+	}
+}
+
+// SetDeviceRulesRequest représente le corps JSON du remplacement des règles de plateforme d'un lien.
+type SetDeviceRulesRequest struct {
+	DeviceRules []services.DeviceRuleInput `json:"device_rules"`
+}
+
+// SetDeviceRulesHandler remplace les règles de ciblage par plateforme d'un lien
+// (ses règles de routage d'origine "device", voir services.LinkService.SetLinkDeviceRules).
+func SetDeviceRulesHandler(linkService *services.LinkService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		shortCode := c.Param("shortCode")
+
+		var req SetDeviceRulesRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		link, err := linkService.SetLinkDeviceRules(c.Request.Context(), shortCode, req.DeviceRules)
+		if err != nil {
+			switch {
+			case errors.Is(err, gorm.ErrRecordNotFound):
+				c.JSON(http.StatusNotFound, gin.H{"error": "Link not found"})
+			case errors.Is(err, services.ErrInvalidDeviceRules):
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			default:
+				logger.ErrorContext(c.Request.Context(), "Error updating device rules", "short_code", shortCode, logging.Err(err))
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			}
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"link": link})
+	}
+}
+
+// SetGeoRulesRequest représente le corps JSON du remplacement des règles géographiques d'un lien.
+type SetGeoRulesRequest struct {
+	GeoRules []services.GeoRuleInput `json:"geo_rules"`
+}
+
+// SetGeoRulesHandler remplace les règles de ciblage par pays ou continent d'un lien
+// (ses règles de routage d'origine "geo", voir services.LinkService.SetLinkGeoRules).
+func SetGeoRulesHandler(linkService *services.LinkService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		shortCode := c.Param("shortCode")
+
+		var req SetGeoRulesRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		link, err := linkService.SetLinkGeoRules(c.Request.Context(), shortCode, req.GeoRules)
+		if err != nil {
+			switch {
+			case errors.Is(err, gorm.ErrRecordNotFound):
+				c.JSON(http.StatusNotFound, gin.H{"error": "Link not found"})
+			case errors.Is(err, services.ErrInvalidGeoRules):
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			default:
+				logger.ErrorContext(c.Request.Context(), "Error updating geo rules", "short_code", shortCode, logging.Err(err))
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			}
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"link": link})
+	}
+}
+
+// SetRoutingRulesRequest représente le corps JSON du remplacement des règles de routage d'un lien.
+type SetRoutingRulesRequest struct {
+	Rules []models.RoutingRule `json:"rules"`
+}
+
+// SetRoutingRulesHandler remplace les règles de routage conditionnelles directes d'un lien, après validation.
+// Les règles de plateforme et géographiques du lien sont conservées.
+func SetRoutingRulesHandler(linkService *services.LinkService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		shortCode := c.Param("shortCode")
+
+		var req SetRoutingRulesRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		link, err := linkService.SetLinkRoutingRules(c.Request.Context(), shortCode, req.Rules)
+		if err != nil {
+			switch {
+			case errors.Is(err, gorm.ErrRecordNotFound):
+				c.JSON(http.StatusNotFound, gin.H{"error": "Link not found"})
+			case errors.Is(err, services.ErrInvalidRoutingRules):
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			default:
+				logger.ErrorContext(c.Request.Context(), "Error updating routing rules", "short_code", shortCode, logging.Err(err))
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			}
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"link": link})
+	}
+}
+
+// DryRunRequest décrit une requête de redirection synthétique.
+// Les champs absents prennent la valeur d'une requête sans en-têtes, à l'instant présent.
+type DryRunRequest struct {
+	IP             string     `json:"ip"`
+	UserAgent      string     `json:"user_agent"`
+	AcceptLanguage string     `json:"accept_language"`
+	Referrer       string     `json:"referrer"`
+	Time           *time.Time `json:"time"`    // Instant simulé (RFC 3339)
+	Path           string     `json:"path"`    // Suffixe de chemin d'un lien "joker"
+	Query          string     `json:"query"`   // Query string, ex: "lang=fr&promo=1"
+	Variant        string     `json:"variant"` // Variante A/B mémorisée (cookie)
+
+	Rules []models.RoutingRule `json:"rules"` // Règles à tester à la place de celles du lien (optionnel)
+}
+
+// DryRunHandler retourne la destination qui serait choisie pour une requête synthétique,
+// sans enregistrer de clic.
+func DryRunHandler(linkService *services.LinkService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		shortCode := c.Param("shortCode")
+
+		var req DryRunRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		query, err := url.ParseQuery(strings.TrimPrefix(req.Query, "?"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query string"})
+			return
+		}
+		redirect := services.RedirectRequest{
+			IP:                req.IP,
+			UserAgent:         req.UserAgent,
+			AcceptLanguage:    req.AcceptLanguage,
+			Referrer:          req.Referrer,
+			RememberedVariant: req.Variant,
+			Suffix:            req.Path,
+			Query:             query,
+		}
+		if req.Time != nil {
+			redirect.Time = *req.Time
+		}
+
+		decision, err := linkService.SimulateRedirect(shortCode, redirect, req.Rules)
+		if err != nil {
+			switch {
+			case errors.Is(err, gorm.ErrRecordNotFound):
+				c.JSON(http.StatusNotFound, gin.H{"error": "Link not found"})
+			case errors.Is(err, services.ErrInvalidRoutingRules), errors.Is(err, services.ErrPathNotAllowed):
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			default:
+				logger.ErrorContext(c.Request.Context(), "Error simulating redirect", "short_code", shortCode, logging.Err(err))
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			}
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"result": decision})
+	}
+}
+
+// maxClickSourceLength correspond à la taille de la colonne 'source' de la table 'clicks'.
+const maxClickSourceLength = 32
+
+// clickSource normalise la provenance d'un clic passée en paramètre de requête (?source=...).
+func clickSource(source string) string {
+	source = strings.ToLower(strings.TrimSpace(source))
+	if len(source) > maxClickSourceLength {
+		source = source[:maxClickSourceLength]
+	}
+	return source
+}
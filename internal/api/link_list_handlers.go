@@ -0,0 +1,106 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/antoine-granier/urlshortener/internal/logging"
+	"github.com/antoine-granier/urlshortener/internal/models"
+	"github.com/antoine-granier/urlshortener/internal/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// linkListItem est un lien d'une page de résultats, accompagné de son nombre de clics.
+type linkListItem struct {
+	models.Link
+	Clicks int `json:"clicks"`
+}
+
+// ListLinksHandler retourne une page de liens filtrés par propriétaire, tag et/ou campagne.
+// Paramètres : ?owner=, ?tag=, ?campaign=, ?limit= (50 par défaut, 500 maximum), ?offset= et ?include_bots=.
+func ListLinksHandler(linkService *services.LinkService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		opts := services.LinkListOptions{
+			Owner:    c.Query("owner"),
+			Tag:      c.Query("tag"),
+			Campaign: c.Query("campaign"),
+		}
+		var err error
+		if v := c.Query("limit"); v != "" {
+			if opts.Limit, err = strconv.Atoi(v); err != nil || opts.Limit < 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+				return
+			}
+		}
+		if v := c.Query("offset"); v != "" {
+			if opts.Offset, err = strconv.Atoi(v); err != nil || opts.Offset < 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "offset must be a positive integer"})
+				return
+			}
+		}
+
+		view, ok := statsView(c, linkService)
+		if !ok {
+			return
+		}
+
+		page, err := view.ListLinks(opts)
+		if err != nil {
+			if errors.Is(err, services.ErrInvalidTags) || errors.Is(err, services.ErrInvalidCampaign) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			logger.ErrorContext(c.Request.Context(), "Error listing links", logging.Err(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		items := make([]linkListItem, len(page.Links))
+		for i, link := range page.Links {
+			items[i] = linkListItem{Link: link, Clicks: page.Clicks[link.ID]}
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"links":  items,
+			"total":  page.Total,
+			"limit":  page.Limit,
+			"offset": page.Offset,
+		})
+	}
+}
+
+// GetCampaignStatsHandler retourne les statistiques agrégées d'une campagne (?owner= pour celle d'une équipe).
+func GetCampaignStatsHandler(linkService *services.LinkService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		name := c.Param("name")
+		view, ok := statsView(c, linkService)
+		if !ok {
+			return
+		}
+
+		stats, err := view.GetCampaignStats(c.Query("owner"), name)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Campaign not found"})
+				return
+			}
+			logger.ErrorContext(c.Request.Context(), "Error retrieving campaign stats", "campaign", name, logging.Err(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"campaign":   stats.Campaign,
+			"clicks":     stats.Clicks,
+			"link_count": len(stats.Links),
+			"links":      stats.Links,
+			"by_source":  stats.BySource,
+			"by_country": stats.ByCountry,
+
+			"conversions":      stats.Conversions.Conversions,
+			"conversion_rate":  stats.Conversions.Rate,
+			"conversion_value": stats.Conversions.Value,
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/antoine-granier/urlshortener/internal/logging"
+	"github.com/antoine-granier/urlshortener/internal/pubsub"
+	"github.com/antoine-granier/urlshortener/internal/services"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+	"gorm.io/gorm"
+)
+
+// liveHeartbeatInterval est l'intervalle des commentaires SSE envoyés pour garder la connexion ouverte
+// (proxies, équilibreurs de charge) quand aucun clic n'arrive.
+const liveHeartbeatInterval = 15 * time.Second
+
+// liveDroppedMessage est envoyé à un abonné déconnecté parce qu'il ne lisait pas assez vite.
+const liveDroppedMessage = "Client too slow, live stream closed"
+
+// liveMessage est un message du flux WebSocket : un clic ("click") ou la fin du flux ("dropped").
+type liveMessage struct {
+	Type  string              `json:"type"`
+	Click *services.LiveClick `json:"click,omitempty"`
+	Error string              `json:"error,omitempty"`
+}
+
+// LinkLiveHandler diffuse en temps réel les clics d'un lien, en Server-Sent Events
+// ou en WebSocket (requête d'upgrade). Les clics de robots ne sont transmis qu'avec ?include_bots=true.
+func LinkLiveHandler(linkService *services.LinkService, clickStream *services.ClickStream) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		shortCode := c.Param("shortCode")
+		includeBots, ok := includeBotsParam(c)
+		if !ok {
+			return
+		}
+
+		link, err := linkService.GetLinkByShortCode(shortCode)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Link not found"})
+				return
+			}
+			logger.ErrorContext(c.Request.Context(), "Error retrieving link", "short_code", shortCode, logging.Err(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		serveLive(c, clickStream, services.LiveFilter{LinkID: link.ID, IncludeBots: includeBots})
+	}
+}
+
+// TeamLiveHandler diffuse en temps réel les clics de tous les liens d'une équipe (?owner=),
+// comme LinkLiveHandler.
+func TeamLiveHandler(clickStream *services.ClickStream) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		includeBots, ok := includeBotsParam(c)
+		if !ok {
+			return
+		}
+		serveLive(c, clickStream, services.LiveFilter{Owner: c.Query("owner"), IncludeBots: includeBots})
+	}
+}
+
+// serveLive abonne la requête aux clics du filtre et les lui transmet jusqu'à sa déconnexion.
+func serveLive(c *gin.Context, clickStream *services.ClickStream, filter services.LiveFilter) {
+	sub := clickStream.Subscribe(filter)
+	defer clickStream.Unsubscribe(sub)
+
+	if strings.EqualFold(c.GetHeader("Upgrade"), "websocket") {
+		// Pas de vérification d'origine : l'API n'utilise pas de cookies d'authentification.
+		websocket.Server{Handler: func(ws *websocket.Conn) { streamWebSocket(ws, sub) }}.ServeHTTP(c.Writer, c.Request)
+		return
+	}
+	streamSSE(c, sub)
+}
+
+// streamSSE transmet les clics en Server-Sent Events (événements "click", puis "dropped" si
+// l'abonné est trop lent), avec un commentaire périodique pour garder la connexion ouverte.
+func streamSSE(c *gin.Context, sub *pubsub.Subscription[services.LiveClick]) {
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no") // Désactive la mise en buffer des proxies nginx
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(liveHeartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case click, open := <-sub.Events():
+			if !open {
+				if sub.Dropped() {
+					c.SSEvent("dropped", gin.H{"error": liveDroppedMessage})
+					c.Writer.Flush()
+				}
+				return
+			}
+			c.SSEvent("click", click)
+		case <-heartbeat.C:
+			fmt.Fprint(c.Writer, ": ping\n\n")
+		}
+		c.Writer.Flush()
+	}
+}
+
+// streamWebSocket transmet les clics sous forme de messages JSON (voir liveMessage).
+// Les messages du client sont ignorés ; sa fermeture de la connexion met fin au flux.
+func streamWebSocket(ws *websocket.Conn, sub *pubsub.Subscription[services.LiveClick]) {
+	defer ws.Close()
+
+	disconnected := make(chan struct{})
+	go func() {
+		defer close(disconnected)
+		var discard string
+		for websocket.Message.Receive(ws, &discard) == nil {
+		}
+	}()
+
+	for {
+		select {
+		case <-disconnected:
+			return
+		case click, open := <-sub.Events():
+			if !open {
+				if sub.Dropped() {
+					websocket.JSON.Send(ws, liveMessage{Type: "dropped", Error: liveDroppedMessage})
+				}
+				return
+			}
+			if err := websocket.JSON.Send(ws, liveMessage{Type: "click", Click: &click}); err != nil {
+				return
+			}
+		}
+	}
+}
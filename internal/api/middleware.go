@@ -0,0 +1,77 @@
+package api
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/antoine-granier/urlshortener/internal/logging"
+	"github.com/antoine-granier/urlshortener/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// requestIDHeader est l'en-tête portant l'identifiant de requête, repris d'un proxy ou généré.
+const requestIDHeader = "X-Request-ID"
+
+// quietAccessLogKey marque les requêtes journalisées en debug par AccessLogMiddleware (voir quietAccessLog).
+const quietAccessLogKey = "quiet_access_log"
+
+// Loggers des handlers : erreurs et journal des requêtes (http), traitement des redirections (redirect).
+var (
+	logger         = logging.For("http")
+	redirectLogger = logging.For("redirect")
+)
+
+// RequestIDMiddleware attribue un identifiant à chaque requête : celui de l'en-tête X-Request-ID s'il est
+// valide (posé par un proxy), sinon un identifiant généré. Il est renvoyé dans l'en-tête X-Request-ID et
+// porté par le contexte de la requête, d'où il est repris dans les logs des handlers et des services.
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(requestIDHeader)
+		if !logging.ValidRequestID(id) {
+			id = logging.NewRequestID()
+		}
+		c.Header(requestIDHeader, id)
+		c.Request = c.Request.WithContext(logging.WithRequestID(c.Request.Context(), id))
+		c.Next()
+	}
+}
+
+// quietAccessLog fait journaliser la requête en cours en debug plutôt qu'en info : à réserver aux routes
+// des visiteurs (redirections, pixel de conversion), dont le volume suit celui des clics.
+func quietAccessLog(c *gin.Context) {
+	c.Set(quietAccessLogKey, true)
+}
+
+// AccessLogMiddleware journalise chaque requête (méthode, chemin, statut, durée). Les réponses 5xx sont
+// journalisées en erreur ; celles des visiteurs (voir quietAccessLog) en debug ; les autres en info,
+// soumises à l'échantillonnage comme tout message répété. L'IP du client n'apparaît qu'anonymisée selon
+// privacy.ip_mode, et pas du tout pour un visiteur demandant à ne pas être suivi (voir PrivacyService.LogIP).
+func AccessLogMiddleware(privacyService *services.PrivacyService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= 500:
+			level = slog.LevelError
+		case c.GetBool(quietAccessLogKey):
+			level = slog.LevelDebug
+		}
+		ctx := c.Request.Context()
+		if !logger.Enabled(ctx, level) {
+			return
+		}
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("path", c.Request.URL.Path),
+			slog.Int("status", status),
+			slog.Duration("duration", time.Since(start)),
+		}
+		if ip := privacyService.LogIP(c.ClientIP(), doNotTrackRequested(c.Request.Header)); ip != "" {
+			attrs = append(attrs, slog.String("client_ip", ip))
+		}
+		logger.LogAttrs(ctx, level, "Request handled", attrs...)
+	}
+}
@@ -0,0 +1,32 @@
+package api
+
+import (
+	"embed"
+	"html/template"
+	"net/http"
+
+	"github.com/antoine-granier/urlshortener/internal/logging"
+	"github.com/gin-gonic/gin"
+)
+
+// templatesFS embarque les pages HTML servies à la place d'une redirection directe.
+//
+//go:embed templates/*.html
+var templatesFS embed.FS
+
+// pageTemplates contient les templates HTML, analysés une seule fois au démarrage.
+var pageTemplates = template.Must(template.ParseFS(templatesFS, "templates/*.html"))
+
+// renderPage exécute le template 'name' et l'écrit dans la réponse.
+// Les pages intermédiaires ne doivent pas être mises en cache ni indexées.
+func renderPage(c *gin.Context, status int, name string, data any) {
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Header("Cache-Control", "no-store")
+	c.Status(status)
+	if err := pageTemplates.ExecuteTemplate(c.Writer, name, data); err != nil {
+		logger.ErrorContext(c.Request.Context(), "Error rendering page", "page", name, logging.Err(err))
+		if !c.Writer.Written() {
+			c.String(http.StatusInternalServerError, "Internal server error")
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/antoine-granier/urlshortener/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+// previewSuffix, ajouté à un code court (/abc123+), demande l'aperçu du lien au lieu de la redirection.
+const previewSuffix = "+"
+
+// previewDateLayout est le format des dates affichées sur la page d'aperçu.
+const previewDateLayout = "02/01/2006 15:04 MST"
+
+// ShortCodeHandler aiguille les requêtes /:shortCode : aperçu pour /code+, redirection sinon.
+// Gin ne permet pas de déclarer une route /:shortCode+ distincte de /:shortCode.
+func ShortCodeHandler(redirect, preview gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if strings.HasSuffix(c.Param("shortCode"), previewSuffix) {
+			preview(c)
+			return
+		}
+		redirect(c)
+	}
+}
+
+// PreviewHandler affiche la page d'aperçu d'un lien (/code+) : destination, titre et icône de la page,
+// date de création, état de santé et nombre de clics. Aucun clic n'est enregistré.
+func PreviewHandler(previewService *services.PreviewService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		shortCode := strings.TrimSuffix(c.Param("shortCode"), previewSuffix)
+
+		remembered, _ := c.Cookie(variantCookieName(shortCode))
+		preview, err := previewService.Preview(c.Request.Context(), shortCode, services.RedirectRequest{
+			IP:                c.ClientIP(),
+			UserAgent:         c.GetHeader("User-Agent"),
+			AcceptLanguage:    c.GetHeader("Accept-Language"),
+			Referrer:          c.GetHeader("Referer"),
+			RememberedVariant: remembered,
+			Query:             c.Request.URL.Query(),
+		})
+		if err != nil {
+			if errors.Is(err, services.ErrPathNotAllowed) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Link not found"})
+				return
+			}
+			writeLookupError(c, shortCode, err)
+			return
+		}
+
+		shortURL := fmt.Sprintf("%s/%s", viper.GetString("server.base_url"), shortCode)
+		renderPage(c, http.StatusOK, "preview.html", gin.H{
+			"ShortURL":        shortURL,
+			"ContinueURL":     shortURL,
+			"Destination":     preview.Destination,
+			"HiddenReason":    preview.HiddenReason,
+			"VariesByVisitor": preview.VariesByVisitor,
+			"Title":           preview.Page.Title,
+			"FaviconURL":      preview.Page.FaviconURL,
+			"CreatedAt":       preview.Link.CreatedAt.Local().Format(previewDateLayout),
+			"HealthKnown":     preview.HealthKnown,
+			"Accessible":      preview.Health.Accessible,
+			"CheckedAt":       preview.Health.CheckedAt.Local().Format(previewDateLayout),
+			"Clicks":          preview.Clicks,
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/antoine-granier/urlshortener/internal/logging"
+	"github.com/antoine-granier/urlshortener/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// PurgeClicksRequest représente le corps JSON d'une demande d'effacement des données personnelles des clics.
+// Au moins un des critères est requis ; s'ils sont tous deux fournis, ils se cumulent.
+type PurgeClicksRequest struct {
+	IP     string `json:"ip"`     // Adresse IP du visiteur
+	Before string `json:"before"` // Clics antérieurs à ce jour (AAAA-MM-JJ, UTC)
+}
+
+// PurgeClicksHandler efface l'adresse IP et le User-Agent des clics visés (droit à l'effacement).
+// Les clics restent comptés dans les statistiques ; l'effacement est tracé dans le journal d'audit.
+func PurgeClicksHandler(privacyService *services.PrivacyService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req PurgeClicksRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		purge, err := services.ParseClickPurge(req.IP, req.Before)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		erased, err := privacyService.PurgeClicks(c.Request.Context(), services.ActorAPI, purge)
+		if err != nil {
+			if errors.Is(err, services.ErrInvalidPurge) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			logger.ErrorContext(c.Request.Context(), "Error purging clicks", logging.Err(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"erased": erased})
+	}
+}
+
+// doNotTrackRequested indique si le visiteur demande à ne pas être suivi (DNT: 1 ou Sec-GPC: 1).
+// La demande est respectée ou non selon la configuration (voir PrivacyService.Scrub).
+func doNotTrackRequested(header http.Header) bool {
+	return strings.TrimSpace(header.Get("DNT")) == "1" || strings.TrimSpace(header.Get("Sec-GPC")) == "1"
+}
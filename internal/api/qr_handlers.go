@@ -0,0 +1,78 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"net/http"
+	"strconv"
+
+	"github.com/antoine-granier/urlshortener/internal/logging"
+	"github.com/antoine-granier/urlshortener/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+)
+
+// QRCodeHandler génère le QR code de l'URL courte complète d'un lien.
+// Paramètres optionnels : size (pixels), format (png|svg), ecc (L|M|Q|H),
+// fg et bg (couleurs hexadécimales) et logo=true pour superposer le logo configuré.
+func QRCodeHandler(linkService *services.LinkService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		shortCode := c.Param("shortCode")
+
+		link, err := linkService.GetLinkByShortCode(shortCode)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Link not found"})
+				return
+			}
+			logger.ErrorContext(c.Request.Context(), "Error retrieving link", "short_code", shortCode, logging.Err(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		size := viper.GetInt("qrcode.default_size")
+		if raw := c.Query("size"); raw != "" {
+			if size, err = strconv.Atoi(raw); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid size"})
+				return
+			}
+		}
+
+		var logo image.Image
+		if withLogo, _ := strconv.ParseBool(c.Query("logo")); withLogo {
+			logoPath := viper.GetString("qrcode.logo_path")
+			if logoPath == "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "No logo configured"})
+				return
+			}
+			if logo, err = services.LoadQRLogo(logoPath); err != nil {
+				logger.ErrorContext(c.Request.Context(), "Error loading QR code logo", logging.Err(err))
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+				return
+			}
+		}
+
+		opts, err := services.NewQROptions(size, c.Query("format"), c.Query("ecc"), c.Query("fg"), c.Query("bg"), logo)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Le rendu est fait en mémoire pour pouvoir renvoyer une erreur JSON en cas d'échec.
+		var buf bytes.Buffer
+		content := services.QRScanURL(viper.GetString("server.base_url"), link.ShortCode)
+		if err := services.WriteQRCode(&buf, content, opts); err != nil {
+			logger.ErrorContext(c.Request.Context(), "Error generating QR code", "short_code", shortCode, logging.Err(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		contentType := "image/png"
+		if opts.Format == "svg" {
+			contentType = "image/svg+xml"
+		}
+		c.Data(http.StatusOK, contentType, buf.Bytes())
+	}
+}
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/antoine-granier/urlshortener/internal/logging"
+	"github.com/antoine-granier/urlshortener/internal/repository"
+	"github.com/antoine-granier/urlshortener/internal/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// auditLogLimit est le nombre maximal d'entrées d'audit retournées par l'API.
+const auditLogLimit = 100
+
+// ScheduleChangeRequest représente le corps JSON de la programmation d'un changement de destination.
+type ScheduleChangeRequest struct {
+	LongURL string    `json:"long_url" binding:"required,url"`
+	ApplyAt time.Time `json:"apply_at" binding:"required"` // Date d'application (RFC 3339)
+}
+
+// ScheduleChangeHandler programme le remplacement de la destination d'un lien à une date future.
+func ScheduleChangeHandler(scheduleService *services.ScheduleService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		shortCode := c.Param("shortCode")
+
+		var req ScheduleChangeRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		change, err := scheduleService.ScheduleChange(c.Request.Context(), shortCode, req.LongURL, req.ApplyAt, services.ActorAPI)
+		if err != nil {
+			switch {
+			case errors.Is(err, gorm.ErrRecordNotFound):
+				c.JSON(http.StatusNotFound, gin.H{"error": "Link not found"})
+			case errors.Is(err, services.ErrInvalidSchedule):
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			default:
+				logger.ErrorContext(c.Request.Context(), "Error scheduling change", "short_code", shortCode, logging.Err(err))
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			}
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{"scheduled_change": change})
+	}
+}
+
+// ListScheduledChangesHandler retourne les changements programmés d'un lien.
+func ListScheduledChangesHandler(scheduleService *services.ScheduleService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		shortCode := c.Param("shortCode")
+
+		changes, err := scheduleService.ListScheduledChanges(shortCode)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Link not found"})
+				return
+			}
+			logger.ErrorContext(c.Request.Context(), "Error listing scheduled changes", "short_code", shortCode, logging.Err(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"scheduled_changes": changes})
+	}
+}
+
+// CancelScheduledChangeHandler annule un changement programmé encore en attente.
+func CancelScheduledChangeHandler(scheduleService *services.ScheduleService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		shortCode := c.Param("shortCode")
+		id, err := strconv.ParseUint(c.Param("id"), 10, 0)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scheduled change id"})
+			return
+		}
+
+		if err := scheduleService.CancelScheduledChange(c.Request.Context(), shortCode, uint(id), services.ActorAPI); err != nil {
+			switch {
+			case errors.Is(err, gorm.ErrRecordNotFound):
+				c.JSON(http.StatusNotFound, gin.H{"error": "Scheduled change not found"})
+			case errors.Is(err, repository.ErrChangeNotPending):
+				c.JSON(http.StatusConflict, gin.H{"error": "Scheduled change is no longer pending"})
+			default:
+				logger.ErrorContext(c.Request.Context(), "Error cancelling scheduled change", "id", id, "short_code", shortCode, logging.Err(err))
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			}
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// GetAuditLogHandler retourne les dernières entrées du journal d'audit d'un lien.
+func GetAuditLogHandler(linkService *services.LinkService, auditService *services.AuditService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		shortCode := c.Param("shortCode")
+
+		link, err := linkService.GetLinkByShortCode(shortCode)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Link not found"})
+				return
+			}
+			logger.ErrorContext(c.Request.Context(), "Error retrieving link", "short_code", shortCode, logging.Err(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		entries, err := auditService.ListLinkEntries(link.ID, auditLogLimit)
+		if err != nil {
+			logger.ErrorContext(c.Request.Context(), "Error retrieving audit log", "short_code", shortCode, logging.Err(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"audit": entries})
+	}
+}
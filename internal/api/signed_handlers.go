@@ -0,0 +1,67 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/antoine-granier/urlshortener/internal/logging"
+	"github.com/antoine-granier/urlshortener/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+)
+
+// SignLinkRequest représente le corps JSON de l'émission d'un lien signé.
+// La validité est donnée soit par une date d'expiration, soit par une durée en secondes.
+type SignLinkRequest struct {
+	ExpiresAt  *time.Time `json:"expires_at"`  // Date d'expiration (RFC 3339)
+	TTLSeconds int        `json:"ttl_seconds"` // Durée de validité à partir de maintenant
+}
+
+// SignLinkHandler émet un lien signé à durée limitée vers un lien existant.
+// Le code signé se vérifie sans base de données : rien n'est enregistré.
+func SignLinkHandler(linkService *services.LinkService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		shortCode := c.Param("shortCode")
+
+		var req SignLinkRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		var expiresAt time.Time
+		switch {
+		case req.ExpiresAt != nil && req.TTLSeconds == 0:
+			expiresAt = *req.ExpiresAt
+		case req.ExpiresAt == nil && req.TTLSeconds > 0:
+			expiresAt = time.Now().Add(time.Duration(req.TTLSeconds) * time.Second)
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Exactly one of expires_at or a positive ttl_seconds is required"})
+			return
+		}
+
+		code, err := linkService.SignLink(shortCode, expiresAt)
+		if err != nil {
+			switch {
+			case errors.Is(err, gorm.ErrRecordNotFound):
+				c.JSON(http.StatusNotFound, gin.H{"error": "Link not found"})
+			case errors.Is(err, services.ErrInvalidSignedLink):
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			case errors.Is(err, services.ErrSigningDisabled):
+				c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Link signing is not configured"})
+			default:
+				logger.ErrorContext(c.Request.Context(), "Error signing link", "short_code", shortCode, logging.Err(err))
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			}
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{
+			"shortCode": code,
+			"fullUrl":   fmt.Sprintf("%s/%s", viper.GetString("server.base_url"), code),
+			"expiresAt": time.Unix(expiresAt.Unix(), 0).UTC(),
+		})
+	}
+}
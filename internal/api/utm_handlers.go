@@ -0,0 +1,104 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/antoine-granier/urlshortener/internal/logging"
+	"github.com/antoine-granier/urlshortener/internal/models"
+	"github.com/antoine-granier/urlshortener/internal/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// SaveUTMPresetRequest représente le corps de la requête d'enregistrement d'un jeu de paramètres UTM.
+type SaveUTMPresetRequest struct {
+	Owner string `json:"owner"` // Équipe propriétaire du jeu de paramètres
+	models.UTM
+}
+
+// ListUTMPresetsHandler retourne les jeux de paramètres UTM d'un propriétaire (?owner=).
+func ListUTMPresetsHandler(linkService *services.LinkService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		presets, err := linkService.ListUTMPresets(c.Query("owner"))
+		if err != nil {
+			logger.ErrorContext(c.Request.Context(), "Error listing utm presets", logging.Err(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"presets": presets})
+	}
+}
+
+// SaveUTMPresetHandler crée ou remplace le jeu de paramètres UTM :name d'un propriétaire.
+func SaveUTMPresetHandler(linkService *services.LinkService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		name := c.Param("name")
+
+		var req SaveUTMPresetRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		preset, err := linkService.SaveUTMPreset(req.Owner, name, req.UTM)
+		if err != nil {
+			if errors.Is(err, services.ErrInvalidUTM) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			logger.ErrorContext(c.Request.Context(), "Error saving utm preset", "name", name, logging.Err(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+		c.JSON(http.StatusOK, preset)
+	}
+}
+
+// DeleteUTMPresetHandler supprime le jeu de paramètres UTM :name d'un propriétaire (?owner=).
+func DeleteUTMPresetHandler(linkService *services.LinkService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		name := c.Param("name")
+
+		if err := linkService.DeleteUTMPreset(c.Query("owner"), name); err != nil {
+			if errors.Is(err, services.ErrUTMPresetNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "UTM preset not found"})
+				return
+			}
+			logger.ErrorContext(c.Request.Context(), "Error deleting utm preset", "name", name, logging.Err(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// GetUTMStatsHandler retourne les clics regroupés par valeur de chaque paramètre UTM,
+// pour les liens d'un propriétaire (?owner=) et éventuellement d'une de ses campagnes (?campaign=).
+func GetUTMStatsHandler(linkService *services.LinkService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		view, ok := statsView(c, linkService)
+		if !ok {
+			return
+		}
+
+		stats, err := view.GetUTMStats(c.Query("owner"), c.Query("campaign"))
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Campaign not found"})
+				return
+			}
+			logger.ErrorContext(c.Request.Context(), "Error retrieving utm stats", logging.Err(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"by_utm_source":   stats["source"],
+			"by_utm_medium":   stats["medium"],
+			"by_utm_campaign": stats["campaign"],
+			"by_utm_term":     stats["term"],
+			"by_utm_content":  stats["content"],
+		})
+	}
+}
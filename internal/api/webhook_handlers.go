@@ -0,0 +1,173 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/antoine-granier/urlshortener/internal/logging"
+	"github.com/antoine-granier/urlshortener/internal/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// CreateWebhookRequest représente le corps JSON de l'enregistrement d'un webhook.
+type CreateWebhookRequest struct {
+	URL    string   `json:"url" binding:"required"`
+	Owner  string   `json:"owner"`  // Equipe dont les liens sont suivis (vide : tous les liens)
+	Events []string `json:"events"` // Types d'événements souscrits (vide ou ["*"] : tous sauf click.recorded, à lister explicitement)
+	Secret string   `json:"secret"` // Clé de signature (générée si absente)
+}
+
+// CreateWebhookHandler enregistre un webhook. Le secret de signature n'est retourné qu'ici.
+func CreateWebhookHandler(webhookService *services.WebhookService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req CreateWebhookRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		webhook, err := webhookService.CreateWebhook(req.Owner, req.URL, req.Events, req.Secret)
+		if err != nil {
+			if errors.Is(err, services.ErrInvalidWebhook) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			logger.ErrorContext(c.Request.Context(), "Error creating webhook", logging.Err(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{"webhook": webhook, "secret": webhook.Secret})
+	}
+}
+
+// ListWebhooksHandler retourne les webhooks d'une équipe (?owner=), ou tous les webhooks.
+func ListWebhooksHandler(webhookService *services.WebhookService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var owner *string
+		if v, ok := c.GetQuery("owner"); ok {
+			owner = &v
+		}
+		webhooks, err := webhookService.ListWebhooks(owner)
+		if err != nil {
+			logger.ErrorContext(c.Request.Context(), "Error listing webhooks", logging.Err(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"webhooks": webhooks})
+	}
+}
+
+// DeleteWebhookHandler supprime un webhook et son journal de livraisons.
+func DeleteWebhookHandler(webhookService *services.WebhookService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := webhookIDParam(c)
+		if !ok {
+			return
+		}
+		if err := webhookService.DeleteWebhook(id); err != nil {
+			webhookError(c, id, err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// ListWebhookDeliveriesHandler retourne les dernières livraisons d'un webhook (?status=pending|succeeded|dead).
+func ListWebhookDeliveriesHandler(webhookService *services.WebhookService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := webhookIDParam(c)
+		if !ok {
+			return
+		}
+		deliveries, err := webhookService.ListDeliveries(id, c.Query("status"))
+		if err != nil {
+			webhookError(c, id, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"deliveries": deliveries})
+	}
+}
+
+// ReplayWebhookDeliveryHandler renvoie une livraison d'un webhook, quel que soit son statut.
+func ReplayWebhookDeliveryHandler(webhookService *services.WebhookService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := webhookIDParam(c)
+		if !ok {
+			return
+		}
+		deliveryID, err := strconv.ParseUint(c.Param("deliveryID"), 10, 0)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid delivery id"})
+			return
+		}
+
+		found, err := webhookService.ReplayDelivery(id, uint(deliveryID))
+		if err != nil {
+			webhookError(c, id, err)
+			return
+		}
+		if !found {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Delivery not found"})
+			return
+		}
+		c.JSON(http.StatusAccepted, gin.H{"replayed": 1})
+	}
+}
+
+// ReplayDeadLettersHandler remet en file toutes les livraisons abandonnées d'un webhook.
+func ReplayDeadLettersHandler(webhookService *services.WebhookService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := webhookIDParam(c)
+		if !ok {
+			return
+		}
+		replayed, err := webhookService.ReplayDeadLetters(id)
+		if err != nil {
+			webhookError(c, id, err)
+			return
+		}
+		c.JSON(http.StatusAccepted, gin.H{"replayed": replayed})
+	}
+}
+
+// PingWebhookHandler envoie un événement de test (webhook.ping) à un webhook.
+func PingWebhookHandler(webhookService *services.WebhookService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := webhookIDParam(c)
+		if !ok {
+			return
+		}
+		delivery, err := webhookService.PingWebhook(id)
+		if err != nil {
+			webhookError(c, id, err)
+			return
+		}
+		c.JSON(http.StatusAccepted, gin.H{"delivery": delivery})
+	}
+}
+
+// webhookIDParam lit l'identifiant de webhook du chemin ; il répond 400 s'il est invalide.
+func webhookIDParam(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook id"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// webhookError traduit une erreur du WebhookService en réponse HTTP.
+func webhookError(c *gin.Context, id uint, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+	case errors.Is(err, services.ErrInvalidWebhook):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		logger.ErrorContext(c.Request.Context(), "Error handling webhook", "id", id, logging.Err(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+	}
+}
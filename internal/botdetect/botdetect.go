@@ -0,0 +1,160 @@
+// Package botdetect classe les visiteurs des liens en humains ou robots (aperçus de liens,
+// passerelles de sécurité, robots d'indexation...) afin de les exclure des statistiques.
+package botdetect
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/antoine-granier/urlshortener/internal/ratelimit"
+)
+
+// Catégories de robots.
+const (
+	CategoryPreview   = "preview"   // Aperçus de liens des messageries et réseaux sociaux
+	CategoryScanner   = "scanner"   // Passerelles de sécurité des e-mails, analyse d'URLs
+	CategoryMonitor   = "monitor"   // Surveillance de disponibilité
+	CategoryTool      = "tool"      // Clients HTTP, navigateurs automatisés
+	CategoryCrawler   = "crawler"   // Moteurs de recherche et collecteurs
+	CategoryHeuristic = "heuristic" // En-têtes incompatibles avec un navigateur
+	CategoryBurst     = "burst"     // Rafale de clics depuis une même IP
+)
+
+//go:embed signatures.txt
+var defaultSignatures string
+
+// Signature associe un fragment de User-Agent (en minuscules) à une catégorie de robots.
+type Signature struct {
+	Category string
+	Fragment string
+}
+
+// Request regroupe les informations d'une requête utiles à la classification.
+type Request struct {
+	Method    string
+	UserAgent string
+	Header    http.Header
+	IP        string
+}
+
+// Result est la classification d'une requête. Reason précise le critère retenu
+// (fragment de signature, en-tête manquant...).
+type Result struct {
+	Bot      bool
+	Category string
+	Reason   string
+}
+
+// Classifier classe les requêtes selon, dans l'ordre : les signatures de User-Agent,
+// des heuristiques sur les en-têtes, puis la détection de rafales par IP.
+// Il est sûr pour un usage concurrent.
+type Classifier struct {
+	signatures []Signature
+	bursts     *ratelimit.Counter
+	burstLimit int
+}
+
+// NewClassifier crée un classificateur. Une IP qui dépasse burstLimit clics sur la durée burstWindow
+// est considérée comme un robot (ex: passerelle de sécurité qui suit tous les liens d'un e-mail
+// à sa réception) ; burstLimit <= 0 désactive la détection de rafales.
+func NewClassifier(signatures []Signature, burstLimit int, burstWindow time.Duration) *Classifier {
+	c := &Classifier{signatures: signatures, burstLimit: burstLimit}
+	if burstLimit > 0 {
+		c.bursts = ratelimit.NewCounter(burstLimit, burstWindow)
+	}
+	return c
+}
+
+// DefaultSignatures retourne la liste de signatures intégrée (fichier signatures.txt).
+func DefaultSignatures() []Signature {
+	signatures, err := ParseSignatures(strings.NewReader(defaultSignatures))
+	if err != nil {
+		panic(fmt.Sprintf("botdetect: invalid embedded signatures: %v", err))
+	}
+	return signatures
+}
+
+// LoadSignatures lit un fichier de signatures au format de signatures.txt.
+func LoadSignatures(path string) ([]Signature, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bot signatures: %w", err)
+	}
+	defer f.Close()
+	return ParseSignatures(f)
+}
+
+// ParseSignatures lit des signatures "catégorie fragment", une par ligne.
+// Les lignes vides et celles qui commencent par '#' sont ignorées.
+func ParseSignatures(r io.Reader) ([]Signature, error) {
+	var signatures []Signature
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		category, fragment, ok := strings.Cut(text, " ")
+		fragment = strings.TrimSpace(fragment)
+		if !ok || fragment == "" {
+			return nil, fmt.Errorf("bot signatures line %d: expected \"category fragment\"", line)
+		}
+		signatures = append(signatures, Signature{Category: category, Fragment: strings.ToLower(fragment)})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read bot signatures: %w", err)
+	}
+	return signatures, nil
+}
+
+// Classify classe une requête. Chaque appel compte pour la détection de rafales de son IP,
+// y compris quand la requête est déjà reconnue comme un robot par ailleurs.
+func (c *Classifier) Classify(req Request) Result {
+	burst := false
+	if c.bursts != nil && req.IP != "" {
+		burst = c.bursts.Add(req.IP) > c.burstLimit
+	}
+
+	if req.UserAgent == "" {
+		return Result{Bot: true, Category: CategoryHeuristic, Reason: "empty user-agent"}
+	}
+	lower := strings.ToLower(req.UserAgent)
+	for _, s := range c.signatures {
+		if strings.Contains(lower, s.Fragment) {
+			return Result{Bot: true, Category: s.Category, Reason: s.Fragment}
+		}
+	}
+	if reason := headerAnomaly(req, lower); reason != "" {
+		return Result{Bot: true, Category: CategoryHeuristic, Reason: reason}
+	}
+	if burst {
+		return Result{Bot: true, Category: CategoryBurst, Reason: "ip burst"}
+	}
+	return Result{}
+}
+
+// headerAnomaly retourne la raison pour laquelle les en-têtes d'une requête ne peuvent pas être
+// ceux d'une navigation, vide s'ils sont plausibles. Les navigateurs envoient toujours Accept et
+// Accept-Encoding et ne suivent jamais un lien avec HEAD ; les robots qui imitent leur User-Agent
+// oublient souvent ces en-têtes.
+func headerAnomaly(req Request, lowerUA string) string {
+	if req.Method == http.MethodHead {
+		return "head request"
+	}
+	if !strings.HasPrefix(lowerUA, "mozilla/") || req.Header == nil {
+		return ""
+	}
+	switch {
+	case req.Header.Get("Accept") == "":
+		return "browser user-agent without accept"
+	case req.Header.Get("Accept-Encoding") == "":
+		return "browser user-agent without accept-encoding"
+	}
+	return ""
+}
@@ -2,7 +2,7 @@ package config
 
 import (
 	"fmt"
-	"log" // Pour logger les informations ou erreurs de chargement de config
+	"log/slog" // Pour logger les informations ou erreurs de chargement de config
 
 	"github.com/spf13/viper" // La bibliothèque pour la gestion de configuration
 )
@@ -21,12 +21,116 @@ type Config struct {
 	} `mapstructure:"database"`
 
 	Analytics struct {
-		BufferSize int `mapstructure:"buffer_size"`
+		BufferSize          int    `mapstructure:"buffer_size"`
+		BatchSize           int    `mapstructure:"batch_size"`
+		VisitorSalt         string `mapstructure:"visitor_salt"`
+		VisitorFlushSeconds int    `mapstructure:"visitor_flush_seconds"`
+
+		BotSignaturesPath     string `mapstructure:"bot_signatures_path"`
+		BotBurstLimit         int    `mapstructure:"bot_burst_limit"`
+		BotBurstWindowSeconds int    `mapstructure:"bot_burst_window_seconds"`
+
+		RollupIntervalSeconds int `mapstructure:"rollup_interval_seconds"`
+		RawClickRetentionDays int `mapstructure:"raw_click_retention_days"`
+
+		LiveBufferSize int `mapstructure:"live_buffer_size"`
 	} `mapstructure:"analytics"`
 
+	Privacy struct {
+		IPMode   string `mapstructure:"ip_mode"`
+		HonorDNT bool   `mapstructure:"honor_dnt"`
+	} `mapstructure:"privacy"`
+
+	Conversions struct {
+		ClickIDMode     string `mapstructure:"click_id_mode"`
+		QueryParam      string `mapstructure:"query_param"`
+		CookieName      string `mapstructure:"cookie_name"`
+		AttributionDays int    `mapstructure:"attribution_days"`
+	} `mapstructure:"conversions"`
+
+	Logging struct {
+		Format     string            `mapstructure:"format"`
+		Level      string            `mapstructure:"level"`
+		Components map[string]string `mapstructure:"components"`
+		Sampling   struct {
+			Initial         int `mapstructure:"initial"`
+			Thereafter      int `mapstructure:"thereafter"`
+			IntervalSeconds int `mapstructure:"interval_seconds"`
+		} `mapstructure:"sampling"`
+	} `mapstructure:"logging"`
+
+	Metrics struct {
+		Enabled       bool   `mapstructure:"enabled"`
+		ListenAddress string `mapstructure:"listen_address"`
+	} `mapstructure:"metrics"`
+
 	Monitor struct {
 		IntervalMinutes int `mapstructure:"interval_minutes"`
 	} `mapstructure:"monitor"`
+
+	Webhooks struct {
+		MaxAttempts         int   `mapstructure:"max_attempts"`
+		RetryBaseSeconds    int   `mapstructure:"retry_base_seconds"`
+		TimeoutSeconds      int   `mapstructure:"timeout_seconds"`
+		PollIntervalSeconds int   `mapstructure:"poll_interval_seconds"`
+		ClickThresholds     []int `mapstructure:"click_thresholds"`
+		// Jours de conservation des livraisons terminées (acquittées ou lettres mortes), 0 pour les garder
+		DeliveryRetentionDays int `mapstructure:"delivery_retention_days"`
+	} `mapstructure:"webhooks"`
+
+	Alerts struct {
+		IntervalSeconds     int `mapstructure:"interval_seconds"`
+		AnomalyBaselineDays int `mapstructure:"anomaly_baseline_days"`
+	} `mapstructure:"alerts"`
+
+	Scheduler struct {
+		PollIntervalSeconds int `mapstructure:"poll_interval_seconds"`
+	} `mapstructure:"scheduler"`
+
+	Links struct {
+		StripTrackingParams bool     `mapstructure:"strip_tracking_params"`
+		TrackingParams      []string `mapstructure:"tracking_params"`
+	} `mapstructure:"links"`
+
+	ShortCode struct {
+		Strategy  string `mapstructure:"strategy"`
+		Length    int    `mapstructure:"length"`
+		Obfuscate bool   `mapstructure:"obfuscate"`
+		Secret    string `mapstructure:"secret"`
+	} `mapstructure:"shortcode"`
+
+	QRCode struct {
+		DefaultSize int    `mapstructure:"default_size"`
+		LogoPath    string `mapstructure:"logo_path"`
+	} `mapstructure:"qrcode"`
+
+	GeoIP struct {
+		DatabasePath          string `mapstructure:"database_path"`
+		ReloadIntervalMinutes int    `mapstructure:"reload_interval_minutes"`
+	} `mapstructure:"geoip"`
+
+	Security struct {
+		PasswordMaxAttempts    int `mapstructure:"password_max_attempts"`
+		PasswordLockoutMinutes int `mapstructure:"password_lockout_minutes"`
+	} `mapstructure:"security"`
+
+	Metadata struct {
+		Enabled     bool `mapstructure:"enabled"`
+		WorkerCount int  `mapstructure:"worker_count"`
+	} `mapstructure:"metadata"`
+
+	Preview struct {
+		InterstitialSeconds int `mapstructure:"interstitial_seconds"`
+	} `mapstructure:"preview"`
+
+	Signing struct {
+		ActiveKey   string `mapstructure:"active_key"`
+		MaxTTLHours int    `mapstructure:"max_ttl_hours"`
+		Keys        []struct {
+			ID     string `mapstructure:"id"`
+			Secret string `mapstructure:"secret"`
+		} `mapstructure:"keys"`
+	} `mapstructure:"signing"`
 }
 
 // LoadConfig charge la configuration de l'application en utilisant Viper.
@@ -52,11 +156,75 @@ func LoadConfig() (*Config, error) {
 
 	viper.SetDefault("analytics.buffer_size", 100)
 	viper.SetDefault("analytics.worker_count", 5)
+	viper.SetDefault("analytics.batch_size", 100)
+	viper.SetDefault("analytics.visitor_salt", "")
+	viper.SetDefault("analytics.visitor_flush_seconds", 10)
+	viper.SetDefault("analytics.bot_signatures_path", "")
+	viper.SetDefault("analytics.bot_burst_limit", 10)
+	viper.SetDefault("analytics.bot_burst_window_seconds", 5)
+	viper.SetDefault("analytics.rollup_interval_seconds", 60)
+	viper.SetDefault("analytics.raw_click_retention_days", 0)
+	viper.SetDefault("analytics.live_buffer_size", 100)
+
+	viper.SetDefault("privacy.ip_mode", "full")
+	viper.SetDefault("privacy.honor_dnt", true)
+
+	viper.SetDefault("conversions.click_id_mode", "cookie")
+	viper.SetDefault("conversions.query_param", "cid")
+	viper.SetDefault("conversions.cookie_name", "us_cid")
+	viper.SetDefault("conversions.attribution_days", 30)
+
+	viper.SetDefault("logging.format", "text")
+	viper.SetDefault("logging.level", "info")
+	viper.SetDefault("logging.components", map[string]string{})
+	viper.SetDefault("logging.sampling.initial", 20)
+	viper.SetDefault("logging.sampling.thereafter", 100)
+	viper.SetDefault("logging.sampling.interval_seconds", 1)
+
+	viper.SetDefault("metrics.enabled", true)
+	viper.SetDefault("metrics.listen_address", "")
 
 	viper.SetDefault("monitor.interval_minutes", 5)
+
+	viper.SetDefault("webhooks.max_attempts", 8)
+	viper.SetDefault("webhooks.retry_base_seconds", 30)
+	viper.SetDefault("webhooks.timeout_seconds", 10)
+	viper.SetDefault("webhooks.poll_interval_seconds", 5)
+	viper.SetDefault("webhooks.click_thresholds", []int{100, 1000, 10000})
+	viper.SetDefault("webhooks.delivery_retention_days", 7)
+
+	viper.SetDefault("alerts.interval_seconds", 60)
+	viper.SetDefault("alerts.anomaly_baseline_days", 7)
+
+	viper.SetDefault("scheduler.poll_interval_seconds", 30)
+
+	viper.SetDefault("links.strip_tracking_params", false)
+	viper.SetDefault("links.tracking_params", []string{})
+
+	viper.SetDefault("shortcode.strategy", "random")
+	viper.SetDefault("shortcode.length", 6)
+	viper.SetDefault("shortcode.obfuscate", true)
+	viper.SetDefault("shortcode.secret", "")
+
+	viper.SetDefault("qrcode.default_size", 256)
+	viper.SetDefault("qrcode.logo_path", "")
+
+	viper.SetDefault("geoip.database_path", "")
+	viper.SetDefault("geoip.reload_interval_minutes", 60)
+
+	viper.SetDefault("security.password_max_attempts", 5)
+	viper.SetDefault("security.password_lockout_minutes", 15)
+
+	viper.SetDefault("metadata.enabled", true)
+	viper.SetDefault("metadata.worker_count", 2)
+
+	viper.SetDefault("preview.interstitial_seconds", 5)
+
+	viper.SetDefault("signing.active_key", "")
+	viper.SetDefault("signing.max_ttl_hours", 720)
 	// TODO : Lire le fichier de configuration.
 	if err := viper.ReadInConfig(); err != nil {
-		fmt.Printf("Impossible de lire le fichier de configuration : %v\n", err)
+		slog.Warn("Failed to read configuration file, using defaults", "error", err)
 	}
 	// TODO 4: Démapper (unmarshal) la configuration lue (ou les valeurs par défaut) dans la structure Config.
 	var cfg Config
@@ -64,8 +232,8 @@ func LoadConfig() (*Config, error) {
 		return nil, fmt.Errorf("Erreur lors du démappage de la configuration : %w", err)
 	}
 	// Log  pour vérifier la config chargée
-	log.Printf("Configuration loaded: Server Port=%d, DB Name=%s, Analytics Buffer=%d, Monitor Interval=%dmin",
-		cfg.Server.Port, cfg.Database.Name, cfg.Analytics.BufferSize, cfg.Monitor.IntervalMinutes)
+	slog.Info("Configuration loaded", "server_port", cfg.Server.Port, "db_name", cfg.Database.Name,
+		"analytics_buffer", cfg.Analytics.BufferSize, "monitor_interval_minutes", cfg.Monitor.IntervalMinutes)
 
 	return &cfg, nil // Retourne la configuration chargée
 }
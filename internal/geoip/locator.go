@@ -0,0 +1,136 @@
+package geoip
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/antoine-granier/urlshortener/internal/logging"
+)
+
+// logger journalise les rechargements de la base et les échecs de recherche.
+var logger = logging.For("geoip")
+
+// Location est la localisation d'une adresse IP. Les champs sont vides quand ils sont inconnus.
+type Location struct {
+	Country   string // Code pays ISO 3166-1 alpha-2 (ex: "FR")
+	Continent string // Code continent MaxMind (AF, AN, AS, EU, NA, OC, SA)
+	Region    string // Subdivision ISO 3166-2 (ex: "FR-IDF")
+}
+
+// Locator localise des adresses IP à partir d'une base locale au format MaxMind DB
+// (GeoLite2/GeoIP2 Country ou City). La base peut être rechargée à chaud sans interrompre les recherches.
+type Locator struct {
+	path    string
+	reader  atomic.Pointer[Reader]
+	mu      sync.Mutex // Sérialise les rechargements
+	modTime time.Time
+}
+
+// NewLocator charge la base située à path.
+func NewLocator(path string) (*Locator, error) {
+	l := &Locator{path: path}
+	if err := l.Reload(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Reload relit la base depuis le disque. En cas d'erreur, la base précédente reste utilisée.
+func (l *Locator) Reload() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	info, err := os.Stat(l.path)
+	if err != nil {
+		return fmt.Errorf("geoip: failed to stat database %s: %w", l.path, err)
+	}
+	reader, err := Open(l.path)
+	if err != nil {
+		return err
+	}
+	l.reader.Store(reader)
+	l.modTime = info.ModTime()
+	return nil
+}
+
+// Watch vérifie périodiquement la date de modification de la base et la recharge quand elle change
+// (ex: après une mise à jour par geoipupdate). Cette méthode est bloquante et doit être lancée dans une goroutine.
+func (l *Locator) Watch(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		info, err := os.Stat(l.path)
+		if err != nil {
+			logger.Warn("Cannot stat GeoIP database", "path", l.path, logging.Err(err))
+			continue
+		}
+		l.mu.Lock()
+		changed := !info.ModTime().Equal(l.modTime)
+		l.mu.Unlock()
+		if !changed {
+			continue
+		}
+		if err := l.Reload(); err != nil {
+			logger.Error("Failed to reload GeoIP database", "path", l.path, logging.Err(err))
+			continue
+		}
+		logger.Info("GeoIP database reloaded", "path", l.path)
+	}
+}
+
+// Lookup localise une adresse IP textuelle (telle que retournée par gin.Context.ClientIP).
+// Une adresse invalide, privée ou absente de la base donne une Location vide.
+func (l *Locator) Lookup(ip string) Location {
+	if l == nil {
+		return Location{}
+	}
+	reader := l.reader.Load()
+	parsed := net.ParseIP(ip)
+	if reader == nil || parsed == nil {
+		return Location{}
+	}
+	record, err := reader.Lookup(parsed)
+	if err != nil {
+		logger.Warn("GeoIP lookup failed", logging.Err(err))
+		return Location{}
+	}
+	return locationFromRecord(record)
+}
+
+// locationFromRecord extrait pays, continent et région d'un enregistrement GeoIP2.
+// Le pays d'enregistrement du bloc d'adresses sert de repli quand le pays n'est pas connu.
+func locationFromRecord(record map[string]any) Location {
+	var loc Location
+	loc.Country = strings.ToUpper(stringAt(record, "country", "iso_code"))
+	if loc.Country == "" {
+		loc.Country = strings.ToUpper(stringAt(record, "registered_country", "iso_code"))
+	}
+	loc.Continent = strings.ToUpper(stringAt(record, "continent", "code"))
+	if subdivisions, ok := record["subdivisions"].([]any); ok && len(subdivisions) > 0 {
+		if first, ok := subdivisions[0].(map[string]any); ok {
+			if code := strings.ToUpper(stringAt(first, "iso_code")); code != "" && loc.Country != "" {
+				loc.Region = loc.Country + "-" + code
+			}
+		}
+	}
+	return loc
+}
+
+// stringAt suit un chemin de clés dans des maps imbriquées et retourne la chaîne trouvée, ou "".
+func stringAt(m map[string]any, keys ...string) string {
+	var current any = m
+	for _, key := range keys {
+		next, ok := current.(map[string]any)
+		if !ok {
+			return ""
+		}
+		current = next[key]
+	}
+	s, _ := current.(string)
+	return s
+}
@@ -0,0 +1,322 @@
+package geoip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"os"
+)
+
+// metadataMarker précède les métadonnées, à la fin d'un fichier au format MaxMind DB.
+var metadataMarker = []byte("\xAB\xCD\xEFMaxMind.com")
+
+// dataSectionSeparator est la taille du séparateur entre l'arbre de recherche et la section de données.
+const dataSectionSeparator = 16
+
+// ErrInvalidDatabase est retournée quand un fichier n'est pas une base MaxMind DB valide.
+var ErrInvalidDatabase = errors.New("geoip: invalid MaxMind DB file")
+
+// Metadata regroupe les métadonnées utiles d'une base MaxMind DB.
+type Metadata struct {
+	DatabaseType string
+	IPVersion    uint
+	NodeCount    uint
+	RecordSize   uint
+	BuildEpoch   uint64
+}
+
+// Reader lit une base au format MaxMind DB (.mmdb) entièrement chargée en mémoire.
+// Seules les fonctionnalités nécessaires aux recherches de pays/région sont implémentées.
+type Reader struct {
+	buf         []byte
+	data        []byte // Section de données
+	Metadata    Metadata
+	ipv4Start   uint
+	nodeByteLen uint
+}
+
+// Open charge une base MaxMind DB depuis le disque.
+func Open(path string) (*Reader, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("geoip: failed to read database %s: %w", path, err)
+	}
+	return FromBytes(buf)
+}
+
+// FromBytes construit un Reader à partir du contenu d'une base MaxMind DB.
+func FromBytes(buf []byte) (*Reader, error) {
+	idx := bytes.LastIndex(buf, metadataMarker)
+	if idx < 0 {
+		return nil, ErrInvalidDatabase
+	}
+	metaStart := idx + len(metadataMarker)
+	raw, _, err := (&decoder{buf: buf[metaStart:]}).decode(0)
+	if err != nil {
+		return nil, fmt.Errorf("%w: metadata: %v", ErrInvalidDatabase, err)
+	}
+	meta, ok := raw.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: metadata is not a map", ErrInvalidDatabase)
+	}
+
+	r := &Reader{buf: buf}
+	r.Metadata.DatabaseType, _ = meta["database_type"].(string)
+	r.Metadata.IPVersion = uint(asUint(meta["ip_version"]))
+	r.Metadata.NodeCount = uint(asUint(meta["node_count"]))
+	r.Metadata.RecordSize = uint(asUint(meta["record_size"]))
+	r.Metadata.BuildEpoch = asUint(meta["build_epoch"])
+
+	switch r.Metadata.RecordSize {
+	case 24, 28, 32:
+	default:
+		return nil, fmt.Errorf("%w: unsupported record size %d", ErrInvalidDatabase, r.Metadata.RecordSize)
+	}
+	r.nodeByteLen = r.Metadata.RecordSize / 4
+	treeSize := r.Metadata.NodeCount * r.nodeByteLen
+	if treeSize+dataSectionSeparator > uint(idx) {
+		return nil, fmt.Errorf("%w: search tree larger than file", ErrInvalidDatabase)
+	}
+	r.data = buf[treeSize+dataSectionSeparator : idx]
+
+	// Dans une base IPv6, les adresses IPv4 sont sous ::/96 : on mémorise le nœud atteint après 96 bits à zéro.
+	if r.Metadata.IPVersion == 6 {
+		node := uint(0)
+		for i := 0; i < 96 && node < r.Metadata.NodeCount; i++ {
+			node = r.readNode(node, 0)
+		}
+		r.ipv4Start = node
+	}
+	return r, nil
+}
+
+// Lookup recherche l'adresse IP et retourne l'enregistrement associé (nil si absente de la base).
+func (r *Reader) Lookup(ip net.IP) (map[string]any, error) {
+	var bits []byte
+	node := uint(0)
+	if ip4 := ip.To4(); ip4 != nil {
+		bits = ip4
+		if r.Metadata.IPVersion == 6 {
+			node = r.ipv4Start
+		}
+	} else if ip16 := ip.To16(); ip16 != nil {
+		if r.Metadata.IPVersion == 4 {
+			return nil, nil // Une base IPv4 ne contient pas d'adresses IPv6
+		}
+		bits = ip16
+	} else {
+		return nil, fmt.Errorf("geoip: invalid IP address %v", ip)
+	}
+
+	nodeCount := r.Metadata.NodeCount
+	for i := 0; i < len(bits)*8 && node < nodeCount; i++ {
+		bit := uint(bits[i>>3]>>(7-uint(i&7))) & 1
+		node = r.readNode(node, bit)
+	}
+	switch {
+	case node == nodeCount:
+		return nil, nil // Adresse absente de la base
+	case node < nodeCount:
+		return nil, fmt.Errorf("%w: search tree exhausted", ErrInvalidDatabase)
+	}
+
+	offset := node - nodeCount - dataSectionSeparator
+	if offset >= uint(len(r.data)) {
+		return nil, fmt.Errorf("%w: data pointer out of range", ErrInvalidDatabase)
+	}
+	value, _, err := (&decoder{buf: r.data}).decode(offset)
+	if err != nil {
+		return nil, err
+	}
+	record, _ := value.(map[string]any)
+	return record, nil
+}
+
+// readNode lit l'enregistrement gauche (bit 0) ou droit (bit 1) d'un nœud de l'arbre.
+func (r *Reader) readNode(node, bit uint) uint {
+	b := r.buf[node*r.nodeByteLen:]
+	switch r.Metadata.RecordSize {
+	case 24:
+		o := bit * 3
+		return uint(b[o])<<16 | uint(b[o+1])<<8 | uint(b[o+2])
+	case 28:
+		if bit == 0 {
+			return (uint(b[3])&0xF0)<<20 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+		}
+		return (uint(b[3])&0x0F)<<24 | uint(b[4])<<16 | uint(b[5])<<8 | uint(b[6])
+	default:
+		o := bit * 4
+		return uint(binary.BigEndian.Uint32(b[o:]))
+	}
+}
+
+// decoder décode la section de données (format binaire typé de MaxMind DB).
+type decoder struct {
+	buf []byte
+}
+
+// Types de la section de données.
+const (
+	typeExtended = iota
+	typePointer
+	typeString
+	typeDouble
+	typeBytes
+	typeUint16
+	typeUint32
+	typeMap
+	typeInt32
+	typeUint64
+	typeUint128
+	typeArray
+	typeContainer
+	typeEndMarker
+	typeBool
+	typeFloat
+)
+
+// decode décode la valeur située à offset et retourne l'offset qui la suit.
+func (d *decoder) decode(offset uint) (any, uint, error) {
+	if offset >= uint(len(d.buf)) {
+		return nil, 0, fmt.Errorf("%w: unexpected end of data", ErrInvalidDatabase)
+	}
+	ctrl := d.buf[offset]
+	offset++
+	typ := uint(ctrl >> 5)
+
+	if typ == typePointer {
+		ptr, next, err := d.decodePointer(ctrl, offset)
+		if err != nil {
+			return nil, 0, err
+		}
+		value, _, err := d.decode(ptr)
+		return value, next, err
+	}
+
+	if typ == typeExtended {
+		if offset >= uint(len(d.buf)) {
+			return nil, 0, fmt.Errorf("%w: unexpected end of data", ErrInvalidDatabase)
+		}
+		typ = 7 + uint(d.buf[offset])
+		offset++
+	}
+
+	size := uint(ctrl & 0x1F)
+	if size >= 29 {
+		n := size - 28
+		if offset+n > uint(len(d.buf)) {
+			return nil, 0, fmt.Errorf("%w: unexpected end of data", ErrInvalidDatabase)
+		}
+		v := uint(0)
+		for _, b := range d.buf[offset : offset+n] {
+			v = v<<8 | uint(b)
+		}
+		offset += n
+		size = [...]uint{29, 285, 65821}[n-1] + v
+	}
+
+	switch typ {
+	case typeMap:
+		m := make(map[string]any, size)
+		for i := uint(0); i < size; i++ {
+			key, next, err := d.decode(offset)
+			if err != nil {
+				return nil, 0, err
+			}
+			k, ok := key.(string)
+			if !ok {
+				return nil, 0, fmt.Errorf("%w: map key is not a string", ErrInvalidDatabase)
+			}
+			value, next, err := d.decode(next)
+			if err != nil {
+				return nil, 0, err
+			}
+			m[k] = value
+			offset = next
+		}
+		return m, offset, nil
+	case typeArray:
+		a := make([]any, 0, size)
+		for i := uint(0); i < size; i++ {
+			value, next, err := d.decode(offset)
+			if err != nil {
+				return nil, 0, err
+			}
+			a = append(a, value)
+			offset = next
+		}
+		return a, offset, nil
+	case typeBool:
+		return size != 0, offset, nil
+	}
+
+	if offset+size > uint(len(d.buf)) {
+		return nil, 0, fmt.Errorf("%w: unexpected end of data", ErrInvalidDatabase)
+	}
+	raw := d.buf[offset : offset+size]
+	next := offset + size
+	switch typ {
+	case typeString:
+		return string(raw), next, nil
+	case typeBytes, typeUint128:
+		return append([]byte(nil), raw...), next, nil
+	case typeDouble:
+		if size != 8 {
+			return nil, 0, fmt.Errorf("%w: invalid double size", ErrInvalidDatabase)
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(raw)), next, nil
+	case typeFloat:
+		if size != 4 {
+			return nil, 0, fmt.Errorf("%w: invalid float size", ErrInvalidDatabase)
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(raw))), next, nil
+	case typeUint16, typeUint32, typeUint64:
+		v := uint64(0)
+		for _, b := range raw {
+			v = v<<8 | uint64(b)
+		}
+		return v, next, nil
+	case typeInt32:
+		v := uint32(0)
+		for _, b := range raw {
+			v = v<<8 | uint32(b)
+		}
+		return int64(int32(v)), next, nil
+	case typeContainer, typeEndMarker:
+		return nil, next, nil
+	}
+	return nil, 0, fmt.Errorf("%w: unknown data type %d", ErrInvalidDatabase, typ)
+}
+
+// decodePointer lit un pointeur vers un autre emplacement de la section de données.
+func (d *decoder) decodePointer(ctrl byte, offset uint) (uint, uint, error) {
+	size := uint(ctrl>>3) & 0x3
+	n := size + 1
+	if offset+n > uint(len(d.buf)) {
+		return 0, 0, fmt.Errorf("%w: unexpected end of data", ErrInvalidDatabase)
+	}
+	v := uint(0)
+	if size != 3 {
+		v = uint(ctrl & 0x7)
+	}
+	for _, b := range d.buf[offset : offset+n] {
+		v = v<<8 | uint(b)
+	}
+	return v + [...]uint{0, 2048, 526336, 0}[size], offset + n, nil
+}
+
+// asUint convertit une valeur numérique décodée en uint64.
+func asUint(v any) uint64 {
+	switch n := v.(type) {
+	case uint64:
+		return n
+	case int64:
+		if n >= 0 {
+			return uint64(n)
+		}
+	}
+	return 0
+}
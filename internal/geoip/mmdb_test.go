@@ -0,0 +1,367 @@
+package geoip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// Les encodages attendus suivent la spécification du format MaxMind DB 2.0
+// (https://maxmind.github.io/MaxMind-DB/) ; le générateur de bases de test ci-dessous
+// en est une implémentation minimale, indépendante du lecteur.
+
+func TestDecodeKnownEncodings(t *testing.T) {
+	long := strings.Repeat("x", 300)
+	cases := []struct {
+		name string
+		data []byte
+		want any
+	}{
+		{"string", []byte{0x43, 'a', 'b', 'c'}, "abc"},
+		{"empty string", []byte{0x40}, ""},
+		{"long string", append([]byte{0x5E, 0x00, 0x0F}, long...), long}, // 300 = 285 + 15
+		{"uint16", []byte{0xA2, 0x12, 0x34}, uint64(0x1234)},
+		{"uint32", []byte{0xC3, 0x01, 0x00, 0x00}, uint64(0x10000)},
+		{"uint64", []byte{0x08, 0x02, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}, uint64(math.MaxUint64)},
+		{"int32 negative", []byte{0x04, 0x01, 0xFF, 0xFF, 0xFF, 0xFE}, int64(-2)},
+		{"double", append([]byte{0x68}, binary.BigEndian.AppendUint64(nil, math.Float64bits(42.5))...), 42.5},
+		{"float", append([]byte{0x04, 0x08}, binary.BigEndian.AppendUint32(nil, math.Float32bits(1.5))...), 1.5},
+		{"bool", []byte{0x01, 0x07}, true},
+		{"bytes", []byte{0x82, 0xCA, 0xFE}, []byte{0xCA, 0xFE}},
+		{"array", []byte{0x02, 0x04, 0x41, 'a', 0xA1, 0x07}, []any{"a", uint64(7)}},
+		{"map", []byte{0xE1, 0x42, 'i', 'd', 0xA1, 0x2A}, map[string]any{"id": uint64(42)}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, next, err := (&decoder{buf: tc.data}).decode(0)
+			if err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("decoded %#v, want %#v", got, tc.want)
+			}
+			if next != uint(len(tc.data)) {
+				t.Errorf("next offset = %d, want %d", next, len(tc.data))
+			}
+		})
+	}
+}
+
+func TestDecodePointers(t *testing.T) {
+	// "hello" à l'offset 0, puis un pointeur de taille 0 vers lui : la lecture se poursuit après le pointeur.
+	buf := []byte{0x45, 'h', 'e', 'l', 'l', 'o', 0x20, 0x00, 0xE0}
+	got, next, err := (&decoder{buf: buf}).decode(6)
+	if err != nil || got != "hello" || next != 8 {
+		t.Errorf("decode(pointer) = %v, %d, %v; want hello, 8", got, next, err)
+	}
+
+	for _, tc := range []struct {
+		ctrl  byte
+		bytes []byte
+		want  uint
+	}{
+		{0x21, []byte{0x02}, 0x102},
+		{0x28, []byte{0x00, 0x01}, 2048 + 1},
+		{0x31, []byte{0x00, 0x00, 0x00}, 526336 + 0x1000000},
+		{0x38, []byte{0x00, 0x10, 0x00, 0x00}, 0x100000},
+	} {
+		d := &decoder{buf: append([]byte{tc.ctrl}, tc.bytes...)}
+		ptr, next, err := d.decodePointer(tc.ctrl, 1)
+		if err != nil || ptr != tc.want || next != uint(1+len(tc.bytes)) {
+			t.Errorf("pointer %#x %x = %d (next %d, err %v), want %d", tc.ctrl, tc.bytes, ptr, next, err, tc.want)
+		}
+	}
+}
+
+func TestDecodeRejectsTruncatedData(t *testing.T) {
+	for name, data := range map[string][]byte{
+		"string":      {0x45, 'a'},
+		"map value":   {0xE1, 0x41, 'k'},
+		"size bytes":  {0x5E, 0x00},
+		"extended":    {0x01},
+		"pointer":     {0x28, 0x00},
+		"double size": {0x64, 0, 0, 0, 0},
+		"unknown":     {0x00, 0x09},
+	} {
+		if _, _, err := (&decoder{buf: data}).decode(0); !errors.Is(err, ErrInvalidDatabase) {
+			t.Errorf("%s: err = %v, want ErrInvalidDatabase", name, err)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	france := map[string]any{
+		"country":      map[string]any{"iso_code": "fr"},
+		"continent":    map[string]any{"code": "EU"},
+		"subdivisions": []any{map[string]any{"iso_code": "IDF"}},
+	}
+	registeredOnly := map[string]any{"registered_country": map[string]any{"iso_code": "DE"}}
+	documentation := map[string]any{"country": map[string]any{"iso_code": "US"}}
+
+	for _, ipVersion := range []int{4, 6} {
+		for _, recordSize := range []int{24, 28, 32} {
+			networks := []testNetwork{
+				{"1.0.0.0/8", france},
+				{"2.16.0.0/13", registeredOnly},
+			}
+			if ipVersion == 6 {
+				networks = append(networks, testNetwork{"2001:db8::/32", documentation})
+			}
+			db := buildTestDB(t, ipVersion, recordSize, networks)
+			r, err := FromBytes(db)
+			if err != nil {
+				t.Fatalf("v%d/%d: FromBytes: %v", ipVersion, recordSize, err)
+			}
+			if r.Metadata.IPVersion != uint(ipVersion) || r.Metadata.RecordSize != uint(recordSize) || r.Metadata.DatabaseType != "Test-City" {
+				t.Errorf("v%d/%d: metadata = %+v", ipVersion, recordSize, r.Metadata)
+			}
+
+			for _, tc := range []struct {
+				ip   string
+				want map[string]any
+			}{
+				{"1.2.3.4", france},
+				{"1.255.255.255", france},
+				{"2.23.1.1", registeredOnly},
+				{"2.24.0.1", nil},
+				{"8.8.8.8", nil},
+				{"2001:db8::1", map[bool]map[string]any{true: documentation}[ipVersion == 6]},
+				{"2001:db9::1", nil},
+			} {
+				got, err := r.Lookup(net.ParseIP(tc.ip))
+				if err != nil {
+					t.Errorf("v%d/%d: Lookup(%s): %v", ipVersion, recordSize, tc.ip, err)
+					continue
+				}
+				if !reflect.DeepEqual(got, tc.want) {
+					t.Errorf("v%d/%d: Lookup(%s) = %v, want %v", ipVersion, recordSize, tc.ip, got, tc.want)
+				}
+			}
+		}
+	}
+}
+
+func TestLocator(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.mmdb")
+	db := buildTestDB(t, 6, 28, []testNetwork{
+		{"1.0.0.0/8", map[string]any{
+			"country":      map[string]any{"iso_code": "fr"},
+			"continent":    map[string]any{"code": "eu"},
+			"subdivisions": []any{map[string]any{"iso_code": "idf"}},
+		}},
+		{"2.16.0.0/13", map[string]any{"registered_country": map[string]any{"iso_code": "DE"}}},
+	})
+	if err := os.WriteFile(path, db, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	l, err := NewLocator(path)
+	if err != nil {
+		t.Fatalf("NewLocator: %v", err)
+	}
+	for ip, want := range map[string]Location{
+		"1.2.3.4":   {Country: "FR", Continent: "EU", Region: "FR-IDF"},
+		"2.17.0.1":  {Country: "DE"},
+		"9.9.9.9":   {},
+		"not-an-ip": {},
+	} {
+		if got := l.Lookup(ip); got != want {
+			t.Errorf("Lookup(%s) = %+v, want %+v", ip, got, want)
+		}
+	}
+	if got := (*Locator)(nil).Lookup("1.2.3.4"); got != (Location{}) {
+		t.Errorf("nil locator Lookup = %+v", got)
+	}
+}
+
+func TestFromBytesRejectsInvalidFiles(t *testing.T) {
+	valid := buildTestDB(t, 4, 24, []testNetwork{{"1.0.0.0/8", map[string]any{"a": "b"}}})
+	marker := bytes.LastIndex(valid, metadataMarker)
+
+	badRecordSize := slices.Clone(valid[:marker+len(metadataMarker)])
+	badRecordSize = append(badRecordSize, encodeValue(map[string]any{
+		"node_count": uint32(1), "record_size": uint16(20), "ip_version": uint16(4),
+	})...)
+	hugeTree := slices.Clone(valid[:marker+len(metadataMarker)])
+	hugeTree = append(hugeTree, encodeValue(map[string]any{
+		"node_count": uint32(1 << 20), "record_size": uint16(24), "ip_version": uint16(4),
+	})...)
+
+	for name, data := range map[string][]byte{
+		"no metadata":         valid[:marker],
+		"truncated metadata":  valid[:len(valid)-3],
+		"record size":         badRecordSize,
+		"tree larger than db": hugeTree,
+	} {
+		if _, err := FromBytes(data); !errors.Is(err, ErrInvalidDatabase) {
+			t.Errorf("%s: err = %v, want ErrInvalidDatabase", name, err)
+		}
+	}
+}
+
+// testNetwork associe un réseau CIDR à l'enregistrement de la base de test.
+type testNetwork struct {
+	cidr   string
+	record map[string]any
+}
+
+// buildTestDB génère une base MaxMind DB : arbre de recherche binaire, séparateur de 16 octets,
+// section de données puis métadonnées. Les sous-maps identiques d'un enregistrement à l'autre
+// ne sont pas dédupliquées, mais chaque enregistrement est référencé par un pointeur.
+func buildTestDB(t *testing.T, ipVersion, recordSize int, networks []testNetwork) []byte {
+	t.Helper()
+	const empty, leaf = -1, -2
+
+	type node struct{ child, data [2]int }
+	nodes := []node{{child: [2]int{empty, empty}}}
+	var data []byte
+	for _, n := range networks {
+		_, ipNet, err := net.ParseCIDR(n.cidr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ones, _ := ipNet.Mask.Size()
+		ip := []byte(ipNet.IP)
+		if ipVersion == 6 && len(ip) == net.IPv4len {
+			ip = append(make([]byte, 12), ip...) // Les adresses IPv4 sont sous ::/96
+			ones += 96
+		}
+
+		// L'enregistrement est écrit une fois, puis désigné par un pointeur (type 1, taille 0 à 2)
+		recordOffset := len(data)
+		data = append(data, encodeValue(n.record)...)
+		pointerOffset := len(data)
+		data = append(data, encodePointer(recordOffset)...)
+
+		current := 0
+		for i := 0; i < ones; i++ {
+			bit := int(ip[i/8]>>(7-i%8)) & 1
+			if i == ones-1 {
+				nodes[current].child[bit] = leaf
+				nodes[current].data[bit] = pointerOffset
+				break
+			}
+			if nodes[current].child[bit] == empty {
+				nodes = append(nodes, node{child: [2]int{empty, empty}})
+				nodes[current].child[bit] = len(nodes) - 1
+			}
+			current = nodes[current].child[bit]
+		}
+	}
+
+	nodeCount := len(nodes)
+	var tree []byte
+	for _, n := range nodes {
+		var records [2]uint32
+		for bit := 0; bit < 2; bit++ {
+			switch n.child[bit] {
+			case empty:
+				records[bit] = uint32(nodeCount)
+			case leaf:
+				records[bit] = uint32(nodeCount + dataSectionSeparator + n.data[bit])
+			default:
+				records[bit] = uint32(n.child[bit])
+			}
+		}
+		left, right := records[0], records[1]
+		switch recordSize {
+		case 24:
+			tree = append(tree, byte(left>>16), byte(left>>8), byte(left), byte(right>>16), byte(right>>8), byte(right))
+		case 28:
+			tree = append(tree, byte(left>>16), byte(left>>8), byte(left),
+				byte(left>>24)<<4|byte(right>>24)&0x0F, byte(right>>16), byte(right>>8), byte(right))
+		case 32:
+			tree = binary.BigEndian.AppendUint32(tree, left)
+			tree = binary.BigEndian.AppendUint32(tree, right)
+		}
+	}
+
+	db := append(tree, make([]byte, dataSectionSeparator)...)
+	db = append(db, data...)
+	db = append(db, metadataMarker...)
+	return append(db, encodeValue(map[string]any{
+		"binary_format_major_version": uint16(2),
+		"database_type":               "Test-City",
+		"ip_version":                  uint16(ipVersion),
+		"node_count":                  uint32(nodeCount),
+		"record_size":                 uint16(recordSize),
+		"build_epoch":                 uint64(1_700_000_000),
+	})...)
+}
+
+// encodeValue encode une valeur de la section de données (types utilisés par les bases de test).
+func encodeValue(v any) []byte {
+	switch v := v.(type) {
+	case string:
+		return append(encodeControl(2, len(v)), v...)
+	case uint16:
+		return append(encodeControl(5, 2), byte(v>>8), byte(v))
+	case uint32:
+		return append(encodeControl(6, 4), binary.BigEndian.AppendUint32(nil, v)...)
+	case uint64:
+		return append(encodeControl(9, 8), binary.BigEndian.AppendUint64(nil, v)...)
+	case []any:
+		out := encodeControl(11, len(v))
+		for _, item := range v {
+			out = append(out, encodeValue(item)...)
+		}
+		return out
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		out := encodeControl(7, len(keys))
+		for _, k := range keys {
+			out = append(out, encodeValue(k)...)
+			out = append(out, encodeValue(v[k])...)
+		}
+		return out
+	}
+	panic("unsupported test value")
+}
+
+// encodeControl encode l'octet de contrôle (type et taille), avec le type étendu et la taille longue au besoin.
+func encodeControl(typ, size int) []byte {
+	var sizeBits byte
+	var extra []byte
+	switch {
+	case size < 29:
+		sizeBits = byte(size)
+	case size < 285:
+		sizeBits, extra = 29, []byte{byte(size - 29)}
+	case size < 65821:
+		sizeBits, extra = 30, []byte{byte((size - 285) >> 8), byte(size - 285)}
+	default:
+		sizeBits, extra = 31, []byte{byte((size - 65821) >> 16), byte((size - 65821) >> 8), byte(size - 65821)}
+	}
+	var head []byte
+	if typ <= 7 {
+		head = []byte{byte(typ)<<5 | sizeBits}
+	} else {
+		head = []byte{sizeBits, byte(typ - 7)}
+	}
+	return append(head, extra...)
+}
+
+// encodePointer encode un pointeur vers un offset de la section de données.
+func encodePointer(offset int) []byte {
+	switch {
+	case offset < 2048:
+		return []byte{0x20 | byte(offset>>8), byte(offset)}
+	case offset < 526336:
+		o := offset - 2048
+		return []byte{0x28 | byte(o>>16), byte(o >> 8), byte(o)}
+	default:
+		o := offset - 526336
+		return []byte{0x30 | byte(o>>24), byte(o >> 16), byte(o >> 8), byte(o)}
+	}
+}
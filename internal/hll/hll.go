@@ -0,0 +1,162 @@
+// Package hll implémente des sketches HyperLogLog : une estimation du nombre d'éléments distincts
+// (ex: visiteurs uniques) en mémoire constante, fusionnable sans perte (l'union de deux sketches
+// est le sketch de l'union des ensembles).
+package hll
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+)
+
+// Precision est le nombre de bits de hash qui choisissent le registre : 2^12 = 4096 registres,
+// soit une erreur type d'environ 1,6 %.
+const Precision = 12
+
+// registerCount est le nombre de registres d'un sketch.
+const registerCount = 1 << Precision
+
+// Formats de l'encodage binaire. Un sketch peu rempli est encodé de manière creuse
+// (registres non nuls uniquement), ce qui est le cas de la plupart des liens sur une journée.
+const (
+	formatDense  byte = 1
+	formatSparse byte = 2
+)
+
+// ErrInvalidSketch est retournée quand un encodage binaire ne peut pas être décodé.
+var ErrInvalidSketch = errors.New("invalid hyperloglog sketch")
+
+// Sketch est un sketch HyperLogLog. La valeur zéro n'est pas utilisable : utiliser New.
+// Un Sketch n'est pas sûr pour un usage concurrent.
+type Sketch struct {
+	registers []uint8
+}
+
+// New crée un sketch vide.
+func New() *Sketch {
+	return &Sketch{registers: make([]uint8, registerCount)}
+}
+
+// Add ajoute un élément, représenté par un hash 64 bits uniformément distribué.
+func (s *Sketch) Add(hash uint64) {
+	index := hash >> (64 - Precision)
+	// Le bit de garde borne le rang quand les bits restants sont tous nuls.
+	rank := uint8(bits.LeadingZeros64(hash<<Precision|1<<(Precision-1))) + 1
+	if rank > s.registers[index] {
+		s.registers[index] = rank
+	}
+}
+
+// Merge ajoute à s les éléments de other (union).
+func (s *Sketch) Merge(other *Sketch) {
+	for i, r := range other.registers {
+		if r > s.registers[i] {
+			s.registers[i] = r
+		}
+	}
+}
+
+// Estimate retourne le nombre estimé d'éléments distincts ajoutés au sketch.
+func (s *Sketch) Estimate() uint64 {
+	const m = float64(registerCount)
+	sum, zeros := 0.0, 0
+	for _, r := range s.registers {
+		sum += math.Ldexp(1, -int(r))
+		if r == 0 {
+			zeros++
+		}
+	}
+	alpha := 0.7213 / (1 + 1.079/m)
+	estimate := alpha * m * m / sum
+	// Correction des petites cardinalités (linear counting), plus précise tant que des registres sont vides.
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(m/float64(zeros))
+	}
+	return uint64(estimate + 0.5)
+}
+
+// MarshalBinary encode le sketch, de manière creuse s'il est peu rempli.
+// Format : [format][précision] puis les 4096 registres (dense), ou le nombre de registres non nuls
+// suivi, pour chacun, de l'écart d'index (uvarint) et du rang (creux).
+func (s *Sketch) MarshalBinary() ([]byte, error) {
+	nonZero := 0
+	for _, r := range s.registers {
+		if r != 0 {
+			nonZero++
+		}
+	}
+	// Un registre creux coûte au plus 3 octets (écart d'index sur 2 octets + rang).
+	if nonZero*3 >= registerCount {
+		out := make([]byte, 2, 2+registerCount)
+		out[0], out[1] = formatDense, Precision
+		return append(out, s.registers...), nil
+	}
+
+	out := make([]byte, 2, 2+binary.MaxVarintLen16+nonZero*3)
+	out[0], out[1] = formatSparse, Precision
+	out = binary.AppendUvarint(out, uint64(nonZero))
+	previous := 0
+	for i, r := range s.registers {
+		if r == 0 {
+			continue
+		}
+		out = binary.AppendUvarint(out, uint64(i-previous))
+		out = append(out, r)
+		previous = i
+	}
+	return out, nil
+}
+
+// UnmarshalBinary remplace le contenu du sketch par celui de l'encodage data (voir MarshalBinary).
+func (s *Sketch) UnmarshalBinary(data []byte) error {
+	if len(data) < 2 {
+		return fmt.Errorf("%w: truncated header", ErrInvalidSketch)
+	}
+	if data[1] != Precision {
+		return fmt.Errorf("%w: unsupported precision %d", ErrInvalidSketch, data[1])
+	}
+	registers := make([]uint8, registerCount)
+	payload := data[2:]
+
+	switch data[0] {
+	case formatDense:
+		if len(payload) != registerCount {
+			return fmt.Errorf("%w: %d dense registers, expected %d", ErrInvalidSketch, len(payload), registerCount)
+		}
+		copy(registers, payload)
+	case formatSparse:
+		count, n := binary.Uvarint(payload)
+		if n <= 0 || count > registerCount {
+			return fmt.Errorf("%w: bad sparse register count", ErrInvalidSketch)
+		}
+		payload = payload[n:]
+		index := uint64(0)
+		for i := uint64(0); i < count; i++ {
+			delta, n := binary.Uvarint(payload)
+			if n <= 0 || len(payload) < n+1 {
+				return fmt.Errorf("%w: truncated sparse register", ErrInvalidSketch)
+			}
+			index += delta
+			if index >= registerCount {
+				return fmt.Errorf("%w: register index %d out of range", ErrInvalidSketch, index)
+			}
+			registers[index] = payload[n]
+			payload = payload[n+1:]
+		}
+		if len(payload) != 0 {
+			return fmt.Errorf("%w: trailing data", ErrInvalidSketch)
+		}
+	default:
+		return fmt.Errorf("%w: unknown format %d", ErrInvalidSketch, data[0])
+	}
+
+	for _, r := range registers {
+		if r > 64-Precision+1 {
+			return fmt.Errorf("%w: register rank %d out of range", ErrInvalidSketch, r)
+		}
+	}
+	s.registers = registers
+	return nil
+}
@@ -0,0 +1,140 @@
+package hll
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"slices"
+	"testing"
+)
+
+// mix est le finaliseur de splitmix64 : il produit des hash uniformément distribués à partir d'un compteur.
+func mix(x uint64) uint64 {
+	x += 0x9E3779B97F4A7C15
+	x = (x ^ x>>30) * 0xBF58476D1CE4E5B9
+	x = (x ^ x>>27) * 0x94D049BB133111EB
+	return x ^ x>>31
+}
+
+func sketchOf(from, to uint64) *Sketch {
+	s := New()
+	for i := from; i < to; i++ {
+		s.Add(mix(i))
+	}
+	return s
+}
+
+func TestEstimateAccuracy(t *testing.T) {
+	// L'erreur type est d'environ 1,6 % : 5 % laisse une marge de trois écarts types.
+	for _, n := range []uint64{1, 10, 100, 1_000, 10_000, 100_000, 1_000_000} {
+		got := sketchOf(0, n).Estimate()
+		if diff := math.Abs(float64(got) - float64(n)); diff > math.Max(1, 0.05*float64(n)) {
+			t.Errorf("Estimate() of %d distinct items = %d (%.2f%% off)", n, got, 100*diff/float64(n))
+		}
+	}
+	if got := New().Estimate(); got != 0 {
+		t.Errorf("empty sketch estimate = %d, want 0", got)
+	}
+}
+
+func TestDuplicatesDoNotCount(t *testing.T) {
+	s := sketchOf(0, 5_000)
+	before := s.Estimate()
+	for i := uint64(0); i < 5_000; i++ {
+		s.Add(mix(i))
+	}
+	if after := s.Estimate(); after != before {
+		t.Errorf("estimate after re-adding = %d, want %d", after, before)
+	}
+}
+
+func TestMergeIsUnion(t *testing.T) {
+	a, b := sketchOf(0, 30_000), sketchOf(20_000, 50_000)
+	union := sketchOf(0, 50_000)
+
+	merged := New()
+	merged.Merge(a)
+	merged.Merge(b)
+	if !slices.Equal(merged.registers, union.registers) {
+		t.Fatal("merged registers differ from the sketch of the union")
+	}
+	reversed := New()
+	reversed.Merge(b)
+	reversed.Merge(a)
+	if !slices.Equal(reversed.registers, merged.registers) {
+		t.Error("merge is not commutative")
+	}
+}
+
+func TestMarshalKnownEncoding(t *testing.T) {
+	s := New()
+	// Registre 1 (12 bits de poids fort = 0x001), premier bit suivant à 1 : rang 1.
+	s.Add(0x0018_0000_0000_0000)
+	// Registre 3, bits suivants tous nuls : le bit de garde borne le rang à 53.
+	s.Add(0x0030_0000_0000_0000)
+	got, err := s.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary: %v", err)
+	}
+	want := []byte{formatSparse, Precision, 2, 1, 1, 2, 53}
+	if !bytes.Equal(got, want) {
+		t.Errorf("encoding = %v, want %v", got, want)
+	}
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	for _, tc := range []struct {
+		n      uint64
+		format byte
+	}{
+		{0, formatSparse},
+		{50, formatSparse},
+		{1_000, formatSparse},
+		{100_000, formatDense},
+	} {
+		s := sketchOf(0, tc.n)
+		data, err := s.MarshalBinary()
+		if err != nil {
+			t.Fatalf("MarshalBinary(%d items): %v", tc.n, err)
+		}
+		if data[0] != tc.format {
+			t.Errorf("%d items encoded with format %d, want %d", tc.n, data[0], tc.format)
+		}
+		decoded := New()
+		if err := decoded.UnmarshalBinary(data); err != nil {
+			t.Fatalf("UnmarshalBinary(%d items): %v", tc.n, err)
+		}
+		if !slices.Equal(decoded.registers, s.registers) {
+			t.Errorf("%d items: registers differ after round trip", tc.n)
+		}
+		if decoded.Estimate() != s.Estimate() {
+			t.Errorf("%d items: estimate %d after round trip, want %d", tc.n, decoded.Estimate(), s.Estimate())
+		}
+	}
+}
+
+func TestUnmarshalRejectsInvalidData(t *testing.T) {
+	dense := append([]byte{formatDense, Precision}, make([]byte, registerCount)...)
+	tooHighRank := slices.Clone(dense)
+	tooHighRank[10] = 64
+
+	for name, data := range map[string][]byte{
+		"empty":             nil,
+		"wrong precision":   {formatSparse, 14, 0},
+		"unknown format":    {9, Precision},
+		"short dense":       dense[:100],
+		"truncated sparse":  {formatSparse, Precision, 2, 1, 1},
+		"index overflow":    {formatSparse, Precision, 1, 0x80, 0x20, 1},
+		"trailing data":     {formatSparse, Precision, 1, 1, 1, 7},
+		"rank out of range": tooHighRank,
+	} {
+		s := sketchOf(0, 10)
+		before := slices.Clone(s.registers)
+		if err := s.UnmarshalBinary(data); !errors.Is(err, ErrInvalidSketch) {
+			t.Errorf("%s: err = %v, want ErrInvalidSketch", name, err)
+		}
+		if !slices.Equal(s.registers, before) {
+			t.Errorf("%s: sketch modified by a failed decode", name)
+		}
+	}
+}
@@ -0,0 +1,267 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Formats de sortie des logs.
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Options configure les logs de l'application.
+type Options struct {
+	Format     string            // FormatText (par défaut) ou FormatJSON
+	Level      string            // Niveau par défaut : debug, info (par défaut), warn ou error
+	Components map[string]string // Niveau par composant (ex: "clicks": "warn"), prioritaire sur Level
+	Sampling   SamplingOptions
+	Output     io.Writer // Sortie des logs (os.Stderr par défaut)
+}
+
+// SamplingOptions limite le volume des messages répétés : sur chaque intervalle, les Initial premières
+// occurrences d'un même message (même composant, niveau et texte) sont écrites, puis une sur Thereafter.
+// Les erreurs ne sont jamais échantillonnées. Un intervalle nul désactive l'échantillonnage.
+type SamplingOptions struct {
+	Initial    int
+	Thereafter int
+	Interval   time.Duration
+}
+
+// settings est la configuration courante, remplacée d'un bloc par Setup.
+type settings struct {
+	base       slog.Handler
+	level      slog.Level
+	components map[string]slog.Level
+	sampler    *sampler
+}
+
+var current atomic.Pointer[settings]
+
+func init() {
+	current.Store(&settings{base: slog.NewTextHandler(os.Stderr, nil), level: slog.LevelInfo})
+}
+
+// Setup applique la configuration des logs. Les loggers déjà obtenus par For l'utilisent aussitôt ;
+// le logger par défaut de slog et les messages du package log passent aussi par elle.
+func Setup(opts Options) error {
+	level, err := ParseLevel(opts.Level)
+	if err != nil {
+		return err
+	}
+	components := make(map[string]slog.Level, len(opts.Components))
+	for component, raw := range opts.Components {
+		if components[strings.ToLower(component)], err = ParseLevel(raw); err != nil {
+			return fmt.Errorf("composant '%s' : %w", component, err)
+		}
+	}
+
+	out := opts.Output
+	if out == nil {
+		out = os.Stderr
+	}
+	// Le handler de base laisse tout passer : le niveau est filtré par composant.
+	handlerOpts := &slog.HandlerOptions{Level: slog.LevelDebug}
+	var base slog.Handler
+	switch strings.ToLower(opts.Format) {
+	case "", FormatText:
+		base = slog.NewTextHandler(out, handlerOpts)
+	case FormatJSON:
+		base = slog.NewJSONHandler(out, handlerOpts)
+	default:
+		return fmt.Errorf("format de logs inconnu '%s' (text ou json)", opts.Format)
+	}
+
+	current.Store(&settings{
+		base:       base,
+		level:      level,
+		components: components,
+		sampler:    newSampler(opts.Sampling),
+	})
+	slog.SetDefault(For(""))
+	// slog.SetDefault redirige aussi le package log (bibliothèques, Gin en mode debug) vers slog.
+	log.SetFlags(0)
+	return nil
+}
+
+// ParseLevel lit un niveau de log (debug, info, warn ou error) ; vide vaut info.
+func ParseLevel(raw string) (slog.Level, error) {
+	if strings.TrimSpace(raw) == "" {
+		return slog.LevelInfo, nil
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(strings.TrimSpace(raw))); err != nil {
+		return 0, fmt.Errorf("niveau de log invalide '%s' (debug, info, warn ou error)", raw)
+	}
+	return level, nil
+}
+
+// For retourne le logger d'un composant (ex: "redirect", "clicks", "monitor"). Ses messages portent
+// l'attribut component et sont filtrés selon le niveau configuré pour ce composant.
+func For(component string) *slog.Logger {
+	return slog.New(&handler{component: strings.ToLower(component)})
+}
+
+// handler applique le niveau du composant, l'échantillonnage et l'identifiant de requête,
+// puis délègue au handler de base de la configuration courante.
+type handler struct {
+	component string
+	ops       []func(slog.Handler) slog.Handler // WithAttrs et WithGroup, rejoués sur le handler de base
+
+	mu          sync.Mutex
+	resolvedFor *settings    // Configuration pour laquelle target a été construit
+	target      slog.Handler // Handler de base avec le composant et ops appliqués
+}
+
+func (h *handler) Enabled(_ context.Context, level slog.Level) bool {
+	st := current.Load()
+	minLevel, ok := st.components[h.component]
+	if !ok {
+		minLevel = st.level
+	}
+	return level >= minLevel
+}
+
+func (h *handler) Handle(ctx context.Context, r slog.Record) error {
+	st := current.Load()
+	if !st.sampler.allow(h.component, r.Level, r.Message) {
+		return nil
+	}
+	if id := RequestID(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	return h.resolve(st).Handle(ctx, r)
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.with(func(next slog.Handler) slog.Handler { return next.WithAttrs(attrs) })
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	return h.with(func(next slog.Handler) slog.Handler { return next.WithGroup(name) })
+}
+
+func (h *handler) with(op func(slog.Handler) slog.Handler) *handler {
+	ops := make([]func(slog.Handler) slog.Handler, len(h.ops), len(h.ops)+1)
+	copy(ops, h.ops)
+	return &handler{component: h.component, ops: append(ops, op)}
+}
+
+// resolve retourne le handler de base de st avec le composant et les attributs du logger appliqués,
+// reconstruit seulement après un changement de configuration.
+func (h *handler) resolve(st *settings) slog.Handler {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.resolvedFor != st {
+		target := st.base
+		if h.component != "" {
+			target = target.WithAttrs([]slog.Attr{slog.String("component", h.component)})
+		}
+		for _, op := range h.ops {
+			target = op(target)
+		}
+		h.resolvedFor, h.target = st, target
+	}
+	return h.target
+}
+
+// sampler compte les occurrences de chaque message sur l'intervalle en cours.
+type sampler struct {
+	opts SamplingOptions
+
+	mu          sync.Mutex
+	windowStart time.Time
+	counts      map[string]int
+	dropped     atomic.Uint64
+}
+
+// newSampler retourne un sampler, ou nil si l'échantillonnage est désactivé.
+func newSampler(opts SamplingOptions) *sampler {
+	if opts.Interval <= 0 || opts.Initial <= 0 {
+		return nil
+	}
+	return &sampler{opts: opts, counts: make(map[string]int)}
+}
+
+// allow indique si l'occurrence d'un message doit être écrite.
+func (s *sampler) allow(component string, level slog.Level, msg string) bool {
+	if s == nil || level >= slog.LevelError {
+		return true
+	}
+	key := component + "\xff" + level.String() + "\xff" + msg
+
+	s.mu.Lock()
+	now := time.Now()
+	if now.Sub(s.windowStart) >= s.opts.Interval {
+		s.windowStart = now
+		clear(s.counts)
+	}
+	s.counts[key]++
+	n := s.counts[key]
+	s.mu.Unlock()
+
+	if n <= s.opts.Initial || (s.opts.Thereafter > 0 && (n-s.opts.Initial)%s.opts.Thereafter == 0) {
+		return true
+	}
+	s.dropped.Add(1)
+	return false
+}
+
+// Dropped retourne le nombre de messages écartés par l'échantillonnage depuis le démarrage.
+func Dropped() uint64 {
+	if s := current.Load().sampler; s != nil {
+		return s.dropped.Load()
+	}
+	return 0
+}
+
+type requestIDKey struct{}
+
+// requestIDPattern valide les identifiants de requête reçus : ils sont recopiés dans les logs et les réponses.
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,64}$`)
+
+// NewRequestID génère un identifiant de requête aléatoire.
+func NewRequestID() string {
+	raw := make([]byte, 8)
+	_, _ = rand.Read(raw)
+	return hex.EncodeToString(raw)
+}
+
+// ValidRequestID indique si un identifiant de requête reçu d'un client ou d'un proxy peut être repris.
+func ValidRequestID(id string) bool {
+	return requestIDPattern.MatchString(id)
+}
+
+// WithRequestID retourne un contexte portant l'identifiant de requête, ajouté aux logs écrits avec ce contexte.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	if id == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID retourne l'identifiant de requête porté par ctx, vide s'il n'y en a pas.
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// Err retourne l'attribut "error" d'une erreur.
+func Err(err error) slog.Attr {
+	return slog.Any("error", err)
+}
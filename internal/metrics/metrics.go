@@ -0,0 +1,347 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// labelSeparator sépare les valeurs de labels dans la clé d'une série (il ne peut pas apparaître dans du texte UTF-8).
+const labelSeparator = "\xff"
+
+// collector est une famille de métriques exposée par un Registry.
+type collector interface {
+	name() string
+	write(w *bufio.Writer)
+}
+
+// Registry regroupe des métriques et les expose au format texte de Prometheus.
+// Il est sûr pour un usage concurrent.
+type Registry struct {
+	mu         sync.Mutex
+	collectors map[string]collector
+}
+
+// NewRegistry crée et retourne un Registry vide.
+func NewRegistry() *Registry {
+	return &Registry{collectors: make(map[string]collector)}
+}
+
+// register ajoute une famille de métriques. Un nom déjà enregistré est une erreur de programmation.
+func (r *Registry) register(c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.collectors[c.name()]; exists {
+		panic(fmt.Sprintf("metrics: métrique %q déjà enregistrée", c.name()))
+	}
+	r.collectors[c.name()] = c
+}
+
+// WriteTo écrit toutes les métriques au format texte de Prometheus, triées par nom.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	collectors := make([]collector, 0, len(r.collectors))
+	for _, c := range r.collectors {
+		collectors = append(collectors, c)
+	}
+	r.mu.Unlock()
+	sort.Slice(collectors, func(i, j int) bool { return collectors[i].name() < collectors[j].name() })
+
+	cw := &countingWriter{w: w}
+	bw := bufio.NewWriter(cw)
+	for _, c := range collectors {
+		c.write(bw)
+	}
+	err := bw.Flush()
+	return cw.n, err
+}
+
+// ServeHTTP expose les métriques (GET /metrics).
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	_, _ = r.WriteTo(w)
+}
+
+// Counter est un compteur croissant, éventuellement ventilé par labels.
+type Counter struct {
+	family
+}
+
+// NewCounter enregistre un compteur. Les valeurs de labels sont passées à Add et Inc, dans l'ordre de labels.
+func (r *Registry) NewCounter(name, help string, labels ...string) *Counter {
+	c := &Counter{family{metricName: name, help: help, kind: "counter", labels: labels}}
+	r.register(c)
+	return c
+}
+
+// Inc incrémente le compteur de la série désignée par labelValues.
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add ajoute v (positif) au compteur de la série désignée par labelValues.
+func (c *Counter) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		return
+	}
+	c.update(labelValues, func(s *series) { s.value += v })
+}
+
+// Value retourne la valeur du compteur de la série désignée par labelValues.
+func (c *Counter) Value(labelValues ...string) float64 {
+	return c.get(labelValues)
+}
+
+// Gauge est une valeur qui monte ou descend, éventuellement ventilée par labels.
+type Gauge struct {
+	family
+}
+
+// NewGauge enregistre une jauge.
+func (r *Registry) NewGauge(name, help string, labels ...string) *Gauge {
+	g := &Gauge{family{metricName: name, help: help, kind: "gauge", labels: labels}}
+	r.register(g)
+	return g
+}
+
+// Set fixe la valeur de la série désignée par labelValues.
+func (g *Gauge) Set(v float64, labelValues ...string) {
+	g.update(labelValues, func(s *series) { s.value = v })
+}
+
+// Add ajoute v (éventuellement négatif) à la série désignée par labelValues.
+func (g *Gauge) Add(v float64, labelValues ...string) {
+	g.update(labelValues, func(s *series) { s.value += v })
+}
+
+// Histogram répartit des observations (ex: durées en secondes) dans des intervalles cumulatifs.
+type Histogram struct {
+	family
+	buckets []float64
+}
+
+// DefaultBuckets sont les bornes par défaut des histogrammes de durée, en secondes (de 1 ms à 10 s).
+var DefaultBuckets = []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// NewHistogram enregistre un histogramme aux bornes données (triées par ordre croissant, DefaultBuckets si nil).
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+	h := &Histogram{family: family{metricName: name, help: help, kind: "histogram", labels: labels}, buckets: buckets}
+	r.register(h)
+	return h
+}
+
+// Observe ajoute une observation à la série désignée par labelValues.
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	h.update(labelValues, func(s *series) {
+		if s.counts == nil {
+			s.counts = make([]uint64, len(h.buckets))
+		}
+		for i, bound := range h.buckets {
+			if v <= bound {
+				s.counts[i]++
+			}
+		}
+		s.count++
+		s.value += v
+	})
+}
+
+// write écrit les intervalles cumulatifs, la somme et le nombre d'observations de chaque série.
+func (h *Histogram) write(w *bufio.Writer) {
+	h.writeHeader(w)
+	for _, s := range h.snapshot() {
+		for i, bound := range h.buckets {
+			var n uint64
+			if s.counts != nil {
+				n = s.counts[i]
+			}
+			writeSample(w, h.metricName+"_bucket", h.labels, s.labelValues, "le", formatFloat(bound), float64(n))
+		}
+		writeSample(w, h.metricName+"_bucket", h.labels, s.labelValues, "le", "+Inf", float64(s.count))
+		writeSample(w, h.metricName+"_sum", h.labels, s.labelValues, "", "", s.value)
+		writeSample(w, h.metricName+"_count", h.labels, s.labelValues, "", "", float64(s.count))
+	}
+}
+
+// Func est une métrique sans label dont la valeur est lue au moment de l'exposition
+// (ex: profondeur d'un channel, statistiques d'un pool de connexions).
+type Func struct {
+	metricName, help, kind string
+	fn                     func() float64
+}
+
+// NewGaugeFunc enregistre une jauge dont la valeur est retournée par fn à chaque exposition.
+func (r *Registry) NewGaugeFunc(name, help string, fn func() float64) {
+	r.register(&Func{metricName: name, help: help, kind: "gauge", fn: fn})
+}
+
+// NewCounterFunc enregistre un compteur tenu ailleurs (ex: sql.DBStats), lu par fn à chaque exposition.
+func (r *Registry) NewCounterFunc(name, help string, fn func() float64) {
+	r.register(&Func{metricName: name, help: help, kind: "counter", fn: fn})
+}
+
+func (f *Func) name() string { return f.metricName }
+
+func (f *Func) write(w *bufio.Writer) {
+	writeHeader(w, f.metricName, f.help, f.kind)
+	writeSample(w, f.metricName, nil, nil, "", "", f.fn())
+}
+
+// family est l'ensemble des séries d'une métrique, une par combinaison de valeurs de labels.
+type family struct {
+	metricName, help, kind string
+	labels                 []string
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+// series est une série d'une famille. value est la valeur (compteur, jauge) ou la somme des observations (histogramme).
+type series struct {
+	labelValues []string
+	value       float64
+	count       uint64   // Histogrammes : nombre d'observations
+	counts      []uint64 // Histogrammes : observations par intervalle cumulatif
+}
+
+func (f *family) name() string { return f.metricName }
+
+// update applique fn à la série désignée par labelValues, créée au besoin.
+// Les valeurs manquantes valent "" et les valeurs en trop sont ignorées.
+func (f *family) update(labelValues []string, fn func(s *series)) {
+	values := make([]string, len(f.labels))
+	copy(values, labelValues)
+	key := strings.Join(values, labelSeparator)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.series == nil {
+		f.series = make(map[string]*series)
+	}
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{labelValues: values}
+		f.series[key] = s
+	}
+	fn(s)
+}
+
+// get retourne la valeur de la série désignée par labelValues (0 si elle n'existe pas).
+func (f *family) get(labelValues []string) float64 {
+	values := make([]string, len(f.labels))
+	copy(values, labelValues)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if s, ok := f.series[strings.Join(values, labelSeparator)]; ok {
+		return s.value
+	}
+	return 0
+}
+
+// snapshot retourne une copie des séries, triées par valeurs de labels. Une métrique sans label
+// a toujours une série (à zéro avant la première mise à jour), pour être exposée dès le démarrage.
+func (f *family) snapshot() []series {
+	f.mu.Lock()
+	if len(f.labels) == 0 && len(f.series) == 0 {
+		f.mu.Unlock()
+		return []series{{}}
+	}
+	out := make([]series, 0, len(f.series))
+	for _, s := range f.series {
+		c := *s
+		c.counts = append([]uint64(nil), s.counts...)
+		out = append(out, c)
+	}
+	f.mu.Unlock()
+	sort.Slice(out, func(i, j int) bool {
+		return strings.Join(out[i].labelValues, labelSeparator) < strings.Join(out[j].labelValues, labelSeparator)
+	})
+	return out
+}
+
+func (f *family) writeHeader(w *bufio.Writer) {
+	writeHeader(w, f.metricName, f.help, f.kind)
+}
+
+// write écrit la valeur de chaque série (compteurs et jauges).
+func (f *family) write(w *bufio.Writer) {
+	f.writeHeader(w)
+	for _, s := range f.snapshot() {
+		writeSample(w, f.metricName, f.labels, s.labelValues, "", "", s.value)
+	}
+}
+
+// writeHeader écrit les lignes HELP et TYPE d'une métrique.
+func writeHeader(w *bufio.Writer, name, help, kind string) {
+	help = strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help)
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// writeSample écrit une ligne de valeur, avec un label supplémentaire optionnel (le "le" des histogrammes).
+func writeSample(w *bufio.Writer, name string, labels, values []string, extraLabel, extraValue string, v float64) {
+	w.WriteString(name)
+	if len(labels) > 0 || extraLabel != "" {
+		w.WriteByte('{')
+		for i, label := range labels {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			writeLabel(w, label, values[i])
+		}
+		if extraLabel != "" {
+			if len(labels) > 0 {
+				w.WriteByte(',')
+			}
+			writeLabel(w, extraLabel, extraValue)
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(v))
+	w.WriteByte('\n')
+}
+
+// labelEscaper échappe les valeurs de labels selon le format texte de Prometheus.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func writeLabel(w *bufio.Writer, label, value string) {
+	w.WriteString(label)
+	w.WriteString(`="`)
+	labelEscaper.WriteString(w, value)
+	w.WriteByte('"')
+}
+
+// formatFloat formate une valeur selon le format texte de Prometheus.
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// countingWriter compte les octets écrits (valeur de retour de WriteTo).
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,124 @@
+package metrics
+
+import (
+	"bufio"
+	"database/sql"
+	"strconv"
+	"time"
+)
+
+// Default est le Registry des métriques de l'application, exposé par le serveur sur /metrics.
+var Default = NewRegistry()
+
+// Métriques de la chaîne de traitement : redirections, créations de liens, événements de clic,
+// workers d'enregistrement, moniteur d'URLs et caches en mémoire.
+var (
+	RedirectDuration = Default.NewHistogram("urlshortener_redirect_duration_seconds",
+		"Durée de traitement des redirections, par code HTTP de la réponse (_count : nombre de redirections).", nil, "status")
+	LinksCreated = Default.NewCounter("urlshortener_links_created_total",
+		"Liens créés (les liens existants réutilisés ne sont pas comptés).")
+
+	ClickEventsEnqueued = Default.NewCounter("urlshortener_click_events_enqueued_total",
+		"Evénements de clic placés dans le channel des workers.")
+	ClickEventsDropped = Default.NewCounter("urlshortener_click_events_dropped_total",
+		"Evénements de clic perdus car le channel des workers était plein.")
+
+	EventsDropped = Default.NewCounter("urlshortener_events_dropped_total",
+		"Evénements perdus car la file du bus d'événements était pleine, par type d'événement.", "event")
+
+	ClickBatchDuration = Default.NewHistogram("urlshortener_click_batch_duration_seconds",
+		"Durée d'enregistrement d'un lot de clics en base par un worker, par résultat (ok ou error).", nil, "result")
+	ClickBatchSize = Default.NewHistogram("urlshortener_click_batch_size",
+		"Nombre de clics par lot enregistré par un worker.", []float64{1, 2, 5, 10, 25, 50, 100, 250, 500, 1000})
+	ClickInsertDuration = Default.NewHistogram("urlshortener_click_insert_duration_seconds",
+		"Durée d'enregistrement individuel d'un clic, après l'échec de son lot, par résultat (ok ou error).", nil, "result")
+
+	MonitorPassDuration = Default.NewHistogram("urlshortener_monitor_pass_duration_seconds",
+		"Durée d'une vérification complète des URLs longues par le moniteur.",
+		[]float64{.1, .5, 1, 2.5, 5, 10, 30, 60, 120, 300, 600})
+	MonitorChecks = Default.NewCounter("urlshortener_monitor_checks_total",
+		"URLs longues vérifiées par le moniteur, par résultat (up ou down : vérification en échec).", "result")
+	MonitorLinks = Default.NewGauge("urlshortener_monitor_links",
+		"Liens accessibles (up) ou non (down) lors de la dernière vérification du moniteur.", "state")
+
+	Caches = newCacheCounter("urlshortener_cache")
+)
+
+// ObserveRedirect enregistre la durée d'une redirection et son code HTTP.
+func ObserveRedirect(status int, elapsed time.Duration) {
+	RedirectDuration.Observe(elapsed.Seconds(), strconv.Itoa(status))
+}
+
+// RegisterDBStats expose les statistiques du pool de connexions de db.
+func RegisterDBStats(db *sql.DB) {
+	stats := func(field func(s sql.DBStats) float64) func() float64 {
+		return func() float64 { return field(db.Stats()) }
+	}
+	Default.NewGaugeFunc("urlshortener_db_connections_max_open", "Nombre maximal de connexions ouvertes (0 : illimité).",
+		stats(func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) }))
+	Default.NewGaugeFunc("urlshortener_db_connections_open", "Connexions ouvertes à la base de données.",
+		stats(func(s sql.DBStats) float64 { return float64(s.OpenConnections) }))
+	Default.NewGaugeFunc("urlshortener_db_connections_in_use", "Connexions en cours d'utilisation.",
+		stats(func(s sql.DBStats) float64 { return float64(s.InUse) }))
+	Default.NewGaugeFunc("urlshortener_db_connections_idle", "Connexions inactives.",
+		stats(func(s sql.DBStats) float64 { return float64(s.Idle) }))
+	Default.NewCounterFunc("urlshortener_db_connections_wait_total", "Attentes d'une connexion libre.",
+		stats(func(s sql.DBStats) float64 { return float64(s.WaitCount) }))
+	Default.NewCounterFunc("urlshortener_db_connections_wait_seconds_total", "Temps total passé à attendre une connexion libre.",
+		stats(func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() }))
+	Default.NewCounterFunc("urlshortener_db_connections_closed_total", "Connexions fermées (inactivité ou durée de vie maximale).",
+		stats(func(s sql.DBStats) float64 {
+			return float64(s.MaxIdleClosed + s.MaxIdleTimeClosed + s.MaxLifetimeClosed)
+		}))
+}
+
+// CacheCounter compte les lectures des caches en mémoire, par cache, et expose leur taux de succès.
+type CacheCounter struct {
+	requests  *Counter
+	ratioName string
+}
+
+// newCacheCounter enregistre les métriques <prefix>_requests_total{cache,result} et <prefix>_hit_ratio{cache}.
+func newCacheCounter(prefix string) *CacheCounter {
+	c := &CacheCounter{
+		requests: &Counter{family{metricName: prefix + "_requests_total", kind: "counter",
+			help: "Lectures des caches en mémoire, par cache et par résultat (hit ou miss).", labels: []string{"cache", "result"}}},
+		ratioName: prefix + "_hit_ratio",
+	}
+	Default.register(c)
+	return c
+}
+
+// Hit compte une lecture réussie du cache nommé.
+func (c *CacheCounter) Hit(cache string) {
+	c.requests.Inc(cache, "hit")
+}
+
+// Miss compte une lecture du cache nommé qui a dû recalculer ou récupérer la valeur.
+func (c *CacheCounter) Miss(cache string) {
+	c.requests.Inc(cache, "miss")
+}
+
+func (c *CacheCounter) name() string { return c.requests.metricName }
+
+// write écrit les lectures, puis le taux de succès de chaque cache depuis le démarrage.
+func (c *CacheCounter) write(w *bufio.Writer) {
+	c.requests.write(w)
+
+	hits, totals := make(map[string]float64), make(map[string]float64)
+	var caches []string
+	for _, s := range c.requests.snapshot() {
+		cache := s.labelValues[0]
+		if _, seen := totals[cache]; !seen {
+			caches = append(caches, cache)
+		}
+		totals[cache] += s.value
+		if s.labelValues[1] == "hit" {
+			hits[cache] += s.value
+		}
+	}
+	writeHeader(w, c.ratioName, "Taux de succès des lectures de chaque cache en mémoire depuis le démarrage.", "gauge")
+	for _, cache := range caches {
+		writeSample(w, c.ratioName, []string{"cache"}, []string{cache}, "", "", hits[cache]/totals[cache])
+	}
+}
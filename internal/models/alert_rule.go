@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// Types de règles d'alerte.
+const (
+	AlertClickSpike = "click_spike" // Plus de Threshold clics en WindowMinutes minutes
+	AlertNoClicks   = "no_clicks"   // Aucun clic depuis WindowMinutes minutes
+	AlertNewCountry = "new_country" // Clics venant d'un pays jamais vu jusque-là
+	AlertAnomaly    = "anomaly"     // Trafic des WindowMinutes dernières minutes supérieur à Threshold fois la normale
+)
+
+// AlertRule est une règle d'alerte sur le trafic d'un lien (LinkID) ou d'une campagne (CampaignID),
+// évaluée périodiquement par le serveur. Owner et Target (code court ou nom de campagne) sont recopiés
+// à la création pour router et libeller les notifications.
+// Une règle notifie quand sa condition devient vraie (Firing), puis se réarme quand elle redevient fausse.
+type AlertRule struct {
+	ID              uint      `gorm:"primaryKey"`
+	LinkID          *uint     `gorm:"index"`
+	CampaignID      *uint     `gorm:"index"`
+	Owner           string    `gorm:"size:100;index"`
+	Target          string    `gorm:"size:100;not null"`
+	Kind            string    `gorm:"size:20;not null"`
+	Threshold       int       // Nombre de clics (click_spike) ou multiple du trafic habituel (anomaly)
+	WindowMinutes   int       `gorm:"not null"`
+	Firing          bool      `gorm:"not null;default:false"`
+	CheckedAt       time.Time // Dernière évaluation (les nouveaux pays sont cherchés depuis cette date)
+	LastTriggeredAt *time.Time
+	CreatedAt       time.Time `gorm:"autoCreateTime"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// AuditEntry trace une opération effectuée sur un lien (par l'API, la CLI ou un processus de fond).
+type AuditEntry struct {
+	ID        uint      `gorm:"primaryKey"`
+	CreatedAt time.Time `gorm:"autoCreateTime;index"`
+	Actor     string    `gorm:"size:100;not null"`      // Auteur: "api", "cli", "scheduler"...
+	Action    string    `gorm:"size:50;not null;index"` // Ex: "link.destination_changed"
+	LinkID    uint      `gorm:"index"`                  // Lien concerné (0 si aucun)
+	Details   string    // Description lisible de l'opération
+	RequestID string    `gorm:"size:64"` // Requête HTTP à l'origine de l'opération, vide hors API
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// Campaign regroupe des liens d'un même propriétaire (ex: tous les liens d'une opération marketing)
+// pour les filtrer ensemble et en agréger les statistiques. Un lien appartient à une campagne au plus.
+type Campaign struct {
+	ID        uint      `gorm:"primaryKey"`
+	Owner     string    `gorm:"size:100;uniqueIndex:idx_campaigns_owner_name,priority:1"`          // Propriétaire des liens de la campagne
+	Name      string    `gorm:"size:100;not null;uniqueIndex:idx_campaigns_owner_name,priority:2"` // Nom unique par propriétaire
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
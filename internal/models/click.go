@@ -9,8 +9,24 @@ type Click struct {
 	LinkID    uint      `gorm:"index"`             // Clé étrangère vers la table 'links', indexée pour des requêtes efficaces
 	Link      Link      `gorm:"foreignKey:LinkID"` // Relation GORM: indique que LinkID est une FK vers le champ ID de Link
 	Timestamp time.Time // Horodatage précis du clic
-	UserAgent string    `gorm:"size:255"` // User-Agent de l'utilisateur qui a cliqué (informations sur le navigateur/OS)
-	IPAddress string    `gorm:"size:50"`  // Adresse IP de l'utilisateur
+	UserAgent string    `gorm:"size:255"`      // User-Agent de l'utilisateur qui a cliqué (informations sur le navigateur/OS)
+	IPAddress string    `gorm:"size:50"`       // Adresse IP de l'utilisateur
+	Source    string    `gorm:"size:32;index"` // Provenance du clic (ex: "qr" pour un scan de QR code), vide pour un clic direct
+	Variant   string    `gorm:"size:50"`       // Variante A/B servie, vide si le lien n'a pas de variantes
+	Rule      string    `gorm:"size:50"`       // Règle de ciblage appliquée, vide si aucune
+	Country   string    `gorm:"size:2;index"`  // Pays du visiteur (ISO 3166-1 alpha-2), vide si inconnu
+	Region    string    `gorm:"size:16"`       // Région du visiteur (ISO 3166-2, ex: FR-IDF), vide si inconnue
+
+	// Classification du visiteur : les clics de robots sont exclus des statistiques par défaut
+	Bot         bool   `gorm:"not null;default:false;index"`
+	BotCategory string `gorm:"size:20"`  // Catégorie du robot (preview, scanner, crawler...), vide pour un humain
+	BotReason   string `gorm:"size:100"` // Critère de la classification (signature, en-tête manquant, rafale)
+
+	// Visiteur ayant demandé à ne pas être suivi (DNT / Sec-GPC) : le clic n'est qu'un décompte anonyme
+	DoNotTrack bool `gorm:"not null;default:false"`
+
+	// Identifiant transmis à la destination pour lui rattacher les conversions, vide si le clic n'est pas suivi
+	ClickID string `gorm:"size:32;index"`
 }
 
 // TODO créer la struct pour ClickEvent
@@ -19,7 +35,22 @@ type Click struct {
 // Un Click event a un LinkID(uint), un Timestamp (Time.Time), un UserAgent (string) et un IP (stringà
 type ClickEvent struct {
 	LinkID    uint
+	ShortCode string // Code et propriétaire du lien, pour le suivi en temps réel
+	Owner     string
 	Timestamp time.Time
 	UserAgent string
 	IPAddress string
+	Source    string
+	Variant   string
+	Rule      string
+	Country   string
+	Region    string
+
+	Bot         bool
+	BotCategory string
+	BotReason   string
+	DoNotTrack  bool
+	ClickID     string
+
+	RequestID string // Identifiant de la requête de redirection, repris dans les logs du worker
 }
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// HourlyClickRollup est le nombre de clics d'un lien sur une heure, pour une valeur d'une dimension
+// (ex: dimension "country", valeur "FR"). Chaque clic est compté une fois dans chaque dimension.
+// Les agrégats sont conservés quand les clics bruts sont purgés.
+type HourlyClickRollup struct {
+	ID        uint      `gorm:"primaryKey"`
+	LinkID    uint      `gorm:"not null;uniqueIndex:idx_hourly_click_rollups_key,priority:1"`
+	Hour      time.Time `gorm:"not null;uniqueIndex:idx_hourly_click_rollups_key,priority:2"`          // Début de l'heure, en UTC
+	Dimension string    `gorm:"size:16;not null;uniqueIndex:idx_hourly_click_rollups_key,priority:3"`  // source, variant, rule, country, region ou bot
+	Value     string    `gorm:"size:100;not null;uniqueIndex:idx_hourly_click_rollups_key,priority:4"` // Vide pour les clics sans valeur
+	Bot       bool      `gorm:"not null;uniqueIndex:idx_hourly_click_rollups_key,priority:5"`
+	Count     int64     `gorm:"not null"`
+}
+
+// DailyClickRollup est l'équivalent journalier de HourlyClickRollup.
+type DailyClickRollup struct {
+	ID        uint   `gorm:"primaryKey"`
+	LinkID    uint   `gorm:"not null;uniqueIndex:idx_daily_click_rollups_key,priority:1"`
+	Day       string `gorm:"size:10;not null;uniqueIndex:idx_daily_click_rollups_key,priority:2"` // Jour UTC, au format AAAA-MM-JJ
+	Dimension string `gorm:"size:16;not null;uniqueIndex:idx_daily_click_rollups_key,priority:3"`
+	Value     string `gorm:"size:100;not null;uniqueIndex:idx_daily_click_rollups_key,priority:4"`
+	Bot       bool   `gorm:"not null;uniqueIndex:idx_daily_click_rollups_key,priority:5"`
+	Count     int64  `gorm:"not null"`
+}
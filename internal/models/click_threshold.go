@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// ClickThreshold trace le franchissement d'un palier de clics par un lien (événement click.threshold_reached),
+// pour que chaque palier ne soit notifié qu'une fois.
+type ClickThreshold struct {
+	ID        uint      `gorm:"primaryKey"`
+	LinkID    uint      `gorm:"not null;uniqueIndex:idx_click_thresholds_link_threshold,priority:1"`
+	Threshold int       `gorm:"not null;uniqueIndex:idx_click_thresholds_link_threshold,priority:2"`
+	ReachedAt time.Time `gorm:"autoCreateTime"`
+}
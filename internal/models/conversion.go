@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// Conversion est une conversion (achat, inscription...) signalée par un site de destination
+// et rattachée au clic d'origine par son identifiant de clic (Click.ClickID).
+// Une même commande (OrderID) n'est comptée qu'une fois par clic ; sans OrderID, un clic convertit au plus une fois.
+type Conversion struct {
+	ID        uint      `gorm:"primaryKey"`
+	ClickID   string    `gorm:"size:32;not null;uniqueIndex:idx_conversions_click_order,priority:1"`
+	OrderID   string    `gorm:"size:100;not null;default:'';uniqueIndex:idx_conversions_click_order,priority:2"`
+	LinkID    uint      `gorm:"index"`
+	ClickedAt time.Time // Horodatage du clic d'origine
+	Value     float64   // Montant de la conversion, 0 si non renseigné
+	Currency  string    `gorm:"size:3"`  // Devise ISO 4217 du montant, vide si non renseignée
+	Source    string    `gorm:"size:16"` // Canal de signalement : "pixel" ou "postback"
+	CreatedAt time.Time `gorm:"autoCreateTime;index"`
+}
@@ -9,9 +9,53 @@ import "time"
 // Shortcode : doit être unique, indexé pour des recherches rapide (voir doc), taille max 10 caractères
 // LongURL : doit pas être null
 // CreateAt : Horodatage de la créatino du lien
+// Owner : propriétaire du lien (équipe ou système appelant), vide si non renseigné
+// CanonicalURL : forme canonique de LongURL, utilisée pour retrouver un lien identique du même propriétaire
+// RedirectType : code HTTP de redirection (301, 302, 307 ou 308), 302 si non renseigné
+// ForwardQuery : transmet la query string de la requête entrante à la destination
+// PathPassthrough : lien "joker", /code/suite ajoute 'suite' au chemin de la destination
+// Variants : destinations pondérées pour les tests A/B (LongURL est ignorée s'il y en a)
+// ActivatesAt : date de mise en service ; avant cette date le lien répond 404 (ou une page "bientôt disponible")
+// ComingSoon : affiche une page "bientôt disponible" au lieu d'une 404 avant ActivatesAt
+// PasswordHash : hash bcrypt du mot de passe demandé aux visiteurs, vide si le lien n'est pas protégé
+// OneTime / ConsumedAt : lien à usage unique et date de sa première (et seule) redirection
+// SignedOnly : le code court seul ne redirige pas, seuls les liens signés (à durée limitée) y donnent accès
+// Interstitial : affiche une page "vous quittez…" avec compte à rebours avant la redirection
+// CardTitle / CardDescription / CardImageURL : personnalisation de la carte de partage (Open Graph),
+// prioritaire sur les informations récupérées sur la destination (Metadata)
+// RoutingRules : règles conditionnelles (langue, plateforme, pays, horaires, provenance...) stockées en JSON,
+// prioritaires sur les variantes et sur LongURL ; les règles de plateforme et géographiques en font partie (voir RoutingRule.Origin)
+// UTM : paramètres utm_* présents dans LongURL, enregistrés à part pour les statistiques
+// Tags : étiquettes du lien (table de jointure link_tags) ; CampaignID / Campaign : campagne du lien (optionnelle)
 type Link struct {
-	ID        uint      `gorm:"primaryKey"`
-	ShortCode string    `gorm:"size:10;uniqueIndex;not null"`
-	LongURL   string    `gorm:"not null"`
-	CreatedAt time.Time `gorm:"autoCreateTime"`
+	ID              uint      `gorm:"primaryKey"`
+	ShortCode       string    `gorm:"size:10;uniqueIndex;not null"`
+	LongURL         string    `gorm:"not null"`
+	Owner           string    `gorm:"size:100;index:idx_links_owner_canonical"`
+	CanonicalURL    string    `gorm:"index:idx_links_owner_canonical"`
+	RedirectType    int       `gorm:"not null;default:302"`
+	ForwardQuery    bool      `gorm:"not null;default:false"`
+	PathPassthrough bool      `gorm:"not null;default:false"`
+	CreatedAt       time.Time `gorm:"autoCreateTime"`
+	ActivatesAt     *time.Time
+	ComingSoon      bool   `gorm:"not null;default:false"`
+	PasswordHash    string `json:"-"`
+	OneTime         bool   `gorm:"not null;default:false"`
+	ConsumedAt      *time.Time
+	SignedOnly      bool `gorm:"not null;default:false"`
+	Interstitial    bool `gorm:"not null;default:false"`
+	CardTitle       string
+	CardDescription string
+	CardImageURL    string
+	CampaignID      *uint `gorm:"index"`
+	UTM             UTM   `gorm:"embedded;embeddedPrefix:utm_"`
+
+	RoutingRules RoutingRules `gorm:"type:text" json:",omitempty"`
+
+	Variants []LinkVariant `gorm:"foreignKey:LinkID" json:",omitempty"`
+
+	Metadata *LinkMetadata `gorm:"foreignKey:LinkID" json:",omitempty"`
+
+	Tags     []Tag     `gorm:"many2many:link_tags" json:",omitempty"`
+	Campaign *Campaign `json:",omitempty"`
 }
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// LinkMetadata contient les informations récupérées sur la page de destination d'un lien
+// (titre, description, balises Open Graph, icône). Elles sont récupérées de manière asynchrone
+// après la création du lien ou un changement de destination.
+// FetchedAt est nil tant qu'aucune récupération n'a abouti ; FetchError décrit le dernier échec.
+type LinkMetadata struct {
+	ID          uint   `gorm:"primaryKey"`
+	LinkID      uint   `gorm:"uniqueIndex;not null"`
+	URL         string `gorm:"not null"` // Destination dont proviennent les informations
+	Title       string
+	Description string
+	ImageURL    string
+	SiteName    string
+	FaviconURL  string
+	OpenGraph   OpenGraphTags `gorm:"type:text" json:",omitempty"`
+	FetchedAt   *time.Time
+	FetchError  string
+	UpdatedAt   time.Time
+}
+
+// TableName force le nom de la table (le pluriel de "metadata" est invariable).
+func (LinkMetadata) TableName() string {
+	return "link_metadata"
+}
+
+// OpenGraphTags associe les propriétés og:* d'une page à leur valeur.
+type OpenGraphTags map[string]string
+
+// Value sérialise les balises en JSON pour la base de données (NULL si aucune balise).
+func (t OpenGraphTags) Value() (driver.Value, error) {
+	if len(t) == 0 {
+		return nil, nil
+	}
+	b, err := json.Marshal(t)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan lit les balises depuis leur représentation JSON en base de données.
+func (t *OpenGraphTags) Scan(value any) error {
+	var raw []byte
+	switch v := value.(type) {
+	case nil:
+		*t = nil
+		return nil
+	case string:
+		raw = []byte(v)
+	case []byte:
+		raw = v
+	default:
+		return fmt.Errorf("unsupported type %T for open graph tags", value)
+	}
+	if len(raw) == 0 {
+		*t = nil
+		return nil
+	}
+	return json.Unmarshal(raw, t)
+}
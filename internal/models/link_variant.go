@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// LinkVariant est une destination alternative d'un lien pour les tests A/B.
+// Quand un lien possède des variantes, chaque visiteur est orienté vers l'une d'elles
+// au prorata des poids, et y reste attaché (cookie ou hash IP+User-Agent).
+type LinkVariant struct {
+	ID        uint      `gorm:"primaryKey"`
+	LinkID    uint      `gorm:"index;not null"`   // Lien auquel appartient la variante
+	Name      string    `gorm:"size:50;not null"` // Nom de la variante, enregistré sur les clics (ex: "A", "B")
+	URL       string    `gorm:"not null"`         // Destination de la variante
+	Weight    int       `gorm:"not null"`         // Poids relatif ; 0 désactive la variante sans la supprimer
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
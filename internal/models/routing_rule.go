@@ -0,0 +1,116 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"slices"
+)
+
+// Origines des règles de routage. Les règles de plateforme et les règles géographiques sont
+// des règles de routage comme les autres, écrites par leurs propres raccourcis (API, CLI).
+const (
+	RuleOriginDevice = "device" // Règle de plateforme : une seule condition Platforms, lien profond éventuel
+	RuleOriginGeo    = "geo"    // Règle géographique : une seule condition Countries ou Continents
+)
+
+// RoutingRule associe un ensemble de conditions à une destination.
+// Les règles d'un lien sont évaluées dans l'ordre ; la première dont toutes les conditions
+// sont remplies l'emporte.
+type RoutingRule struct {
+	Name     string         `json:"name"`                // Nom enregistré sur les clics quand la règle s'applique
+	When     RuleConditions `json:"when"`                // Conditions (toutes doivent être remplies) ; vide = toujours
+	URL      string         `json:"url"`                 // Destination
+	DeepLink string         `json:"deep_link,omitempty"` // URI de l'application tentée avant de rediriger vers URL (optionnel)
+	Origin   string         `json:"origin,omitempty"`    // RuleOriginDevice ou RuleOriginGeo, vide pour une règle de routage directe
+}
+
+// RuleConditions regroupe les conditions d'une règle de routage. Une condition vide est ignorée ;
+// dans une liste, il suffit qu'une valeur corresponde.
+type RuleConditions struct {
+	Languages       []string          `json:"languages,omitempty"`        // Langues acceptées (Accept-Language), ex: "fr", "en-GB"
+	Platforms       []string          `json:"platforms,omitempty"`        // Plateformes (ios, android, mobile, desktop...)
+	Countries       []string          `json:"countries,omitempty"`        // Pays ISO 3166-1 alpha-2 (nécessite la base GeoIP)
+	Continents      []string          `json:"continents,omitempty"`       // Continents (nécessite la base GeoIP)
+	ReferrerDomains []string          `json:"referrer_domains,omitempty"` // Domaines du Referer (sous-domaines inclus)
+	Query           map[string]string `json:"query,omitempty"`            // Paramètres de requête exigés ; valeur vide ou "*" = présence
+	Timezone        string            `json:"timezone,omitempty"`         // Fuseau IANA des conditions horaires (UTC par défaut)
+	Days            []string          `json:"days,omitempty"`             // Jours de la semaine: mon, tue, wed, thu, fri, sat, sun
+	TimeFrom        string            `json:"time_from,omitempty"`        // Heure de début incluse "HH:MM"
+	TimeTo          string            `json:"time_to,omitempty"`          // Heure de fin exclue "HH:MM" (plage pouvant passer minuit)
+	After           string            `json:"after,omitempty"`            // Début de la fenêtre de dates (RFC 3339, ou date/heure locale au fuseau)
+	Before          string            `json:"before,omitempty"`           // Fin exclue de la fenêtre de dates
+}
+
+// RoutingRules est la liste ordonnée des règles d'un lien, stockée en JSON dans une colonne texte.
+type RoutingRules []RoutingRule
+
+// ReplaceOrigin retourne les règles dont celles de l'origine donnée sont remplacées par rules.
+// L'ordre d'évaluation qui en résulte est toujours : règles directes, règles de plateforme,
+// règles de pays, puis règles de continent (un pays l'emporte sur son continent), chaque groupe
+// gardant son ordre propre.
+func (r RoutingRules) ReplaceOrigin(origin string, rules []RoutingRule) RoutingRules {
+	out := make(RoutingRules, 0, len(r)+len(rules))
+	for _, rule := range r {
+		if rule.Origin != origin {
+			out = append(out, rule)
+		}
+	}
+	for _, rule := range rules {
+		rule.Origin = origin
+		out = append(out, rule)
+	}
+	slices.SortStableFunc(out, func(a, b RoutingRule) int { return a.rank() - b.rank() })
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+// rank est le rang d'évaluation du groupe d'une règle (voir ReplaceOrigin).
+func (r RoutingRule) rank() int {
+	switch r.Origin {
+	case RuleOriginDevice:
+		return 1
+	case RuleOriginGeo:
+		if len(r.When.Countries) > 0 {
+			return 2
+		}
+		return 3
+	default:
+		return 0
+	}
+}
+
+// Value sérialise les règles en JSON pour la base de données (NULL si aucune règle).
+func (r RoutingRules) Value() (driver.Value, error) {
+	if len(r) == 0 {
+		return nil, nil
+	}
+	b, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan lit les règles depuis leur représentation JSON en base de données.
+func (r *RoutingRules) Scan(value any) error {
+	var raw []byte
+	switch v := value.(type) {
+	case nil:
+		*r = nil
+		return nil
+	case string:
+		raw = []byte(v)
+	case []byte:
+		raw = v
+	default:
+		return fmt.Errorf("unsupported type %T for routing rules", value)
+	}
+	if len(raw) == 0 {
+		*r = nil
+		return nil
+	}
+	return json.Unmarshal(raw, r)
+}
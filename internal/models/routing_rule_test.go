@@ -0,0 +1,47 @@
+package models
+
+import (
+	"slices"
+	"testing"
+)
+
+func ruleNames(rules RoutingRules) []string {
+	names := make([]string, len(rules))
+	for i, rule := range rules {
+		names[i] = rule.Name
+	}
+	return names
+}
+
+func TestReplaceOriginKeepsEvaluationOrder(t *testing.T) {
+	var rules RoutingRules
+	rules = rules.ReplaceOrigin(RuleOriginGeo, []RoutingRule{
+		{Name: "eu", When: RuleConditions{Continents: []string{"EU"}}},
+		{Name: "fr", When: RuleConditions{Countries: []string{"FR"}}},
+	})
+	rules = rules.ReplaceOrigin(RuleOriginDevice, []RoutingRule{{Name: "ios"}, {Name: "android"}})
+	rules = rules.ReplaceOrigin("", []RoutingRule{{Name: "lang"}, {Name: "night"}})
+
+	want := []string{"lang", "night", "ios", "android", "fr", "eu"}
+	if got := ruleNames(rules); !slices.Equal(got, want) {
+		t.Fatalf("order = %v, want %v", got, want)
+	}
+	for _, rule := range rules[2:4] {
+		if rule.Origin != RuleOriginDevice {
+			t.Errorf("rule %q origin = %q, want %q", rule.Name, rule.Origin, RuleOriginDevice)
+		}
+	}
+
+	// Remplacer un groupe ne touche pas aux autres ; une liste vide le supprime.
+	rules = rules.ReplaceOrigin(RuleOriginDevice, []RoutingRule{{Name: "desktop"}})
+	if got, want := ruleNames(rules), []string{"lang", "night", "desktop", "fr", "eu"}; !slices.Equal(got, want) {
+		t.Errorf("after device replace = %v, want %v", got, want)
+	}
+	rules = rules.ReplaceOrigin("", nil)
+	if got, want := ruleNames(rules), []string{"desktop", "fr", "eu"}; !slices.Equal(got, want) {
+		t.Errorf("after direct removal = %v, want %v", got, want)
+	}
+	if rules = rules.ReplaceOrigin(RuleOriginDevice, nil).ReplaceOrigin(RuleOriginGeo, nil); rules != nil {
+		t.Errorf("all groups removed = %v, want nil", rules)
+	}
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// Statuts d'un changement programmé.
+const (
+	ScheduledChangePending   = "pending"
+	ScheduledChangeApplied   = "applied"
+	ScheduledChangeCancelled = "cancelled"
+	ScheduledChangeFailed    = "failed"
+)
+
+// ScheduledChange remplace la destination (URL longue) d'un lien à une date donnée.
+// Les changements en attente sont appliqués par le planificateur lancé avec le serveur.
+type ScheduledChange struct {
+	ID        uint       `gorm:"primaryKey"`
+	LinkID    uint       `gorm:"index;not null"`                                                          // Lien concerné
+	LongURL   string     `gorm:"not null"`                                                                // Nouvelle destination
+	ApplyAt   time.Time  `gorm:"not null;index:idx_scheduled_pending,priority:2"`                         // Date d'application
+	Status    string     `gorm:"size:20;not null;index:idx_scheduled_pending,priority:1;default:pending"` // pending, applied, cancelled ou failed
+	AppliedAt *time.Time // Date effective d'application (ou d'échec)
+	Error     string     // Cause de l'échec éventuel
+	CreatedAt time.Time  `gorm:"autoCreateTime"`
+}
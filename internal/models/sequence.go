@@ -0,0 +1,9 @@
+package models
+
+// Sequence est un compteur monotone nommé, persisté en base.
+// Il fournit les identifiants de la stratégie de génération de codes courts "sequence",
+// et mémorise l'ID du dernier clic agrégé dans les statistiques ("click_rollups").
+type Sequence struct {
+	Name  string `gorm:"primaryKey;size:50"` // Nom du compteur (ex: "links")
+	Value uint64 `gorm:"not null"`           // Dernière valeur distribuée
+}
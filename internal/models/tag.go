@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// Tag est une étiquette libre posée sur des liens (relation many-to-many via la table link_tags),
+// qui permet de les filtrer et de les retrouver parmi des milliers d'autres.
+type Tag struct {
+	ID        uint      `gorm:"primaryKey"`
+	Name      string    `gorm:"size:50;uniqueIndex;not null"` // Nom normalisé (minuscules), ex: "newsletter"
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
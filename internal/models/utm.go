@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// UTM regroupe les paramètres de suivi de campagne (utm_*) ajoutés à l'URL longue d'un lien.
+// Ils sont enregistrés à part (colonnes utm_source, utm_medium...) pour regrouper les statistiques.
+type UTM struct {
+	Source   string `gorm:"size:200" json:"utm_source,omitempty"`
+	Medium   string `gorm:"size:200" json:"utm_medium,omitempty"`
+	Campaign string `gorm:"size:200" json:"utm_campaign,omitempty"`
+	Term     string `gorm:"size:200" json:"utm_term,omitempty"`
+	Content  string `gorm:"size:200" json:"utm_content,omitempty"`
+}
+
+// IsZero indique qu'aucun paramètre UTM n'est renseigné.
+func (u UTM) IsZero() bool {
+	return u == UTM{}
+}
+
+// UTMPreset est un jeu de paramètres UTM enregistré par une équipe (propriétaire) et réutilisable
+// à la création des liens, pour que tous ses liens soient marqués de la même façon.
+type UTMPreset struct {
+	ID        uint      `gorm:"primaryKey"`
+	Owner     string    `gorm:"size:100;uniqueIndex:idx_utm_presets_owner_name,priority:1"`
+	Name      string    `gorm:"size:100;not null;uniqueIndex:idx_utm_presets_owner_name,priority:2"`
+	UTM       UTM       `gorm:"embedded;embeddedPrefix:utm_"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// VisitorSketch est le sketch HyperLogLog des visiteurs (hash salé IP + User-Agent) d'un lien sur une journée.
+// Les sketches de plusieurs jours se fusionnent pour estimer les visiteurs uniques d'une période
+// sans relire les clics bruts.
+type VisitorSketch struct {
+	ID        uint      `gorm:"primaryKey"`
+	LinkID    uint      `gorm:"not null;uniqueIndex:idx_visitor_sketches_link_day,priority:1"`
+	Day       string    `gorm:"size:10;not null;uniqueIndex:idx_visitor_sketches_link_day,priority:2"` // Jour UTC, au format AAAA-MM-JJ
+	Sketch    []byte    `gorm:"not null"`                                                              // Encodage binaire (voir hll.Sketch)
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+}
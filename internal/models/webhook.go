@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// Webhook est un point de terminaison HTTP notifié des événements des liens (création, clics...).
+// Owner restreint les événements à ceux des liens d'une équipe ; vide, le webhook reçoit ceux de tous les liens.
+// Events est la liste des types d'événements souscrits, séparés par des virgules ("*" pour tous,
+// sauf click.recorded qui doit être souscrit explicitement, ex: "*,click.recorded").
+type Webhook struct {
+	ID        uint      `gorm:"primaryKey"`
+	Owner     string    `gorm:"size:100;index"`
+	URL       string    `gorm:"not null"`
+	Secret    string    `gorm:"size:64;not null" json:"-"` // Clé HMAC des signatures (X-Webhook-Signature)
+	Events    string    `gorm:"not null"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+// Statuts d'une livraison de webhook.
+const (
+	DeliveryPending   = "pending"   // En attente d'envoi (première tentative ou nouvel essai)
+	DeliverySucceeded = "succeeded" // Acquittée par une réponse 2xx
+	DeliveryDead      = "dead"      // Abandonnée après le nombre maximal de tentatives (file des lettres mortes)
+)
+
+// WebhookDelivery est la livraison d'un événement à un webhook, conservée comme journal des envois.
+// Les livraisons abandonnées (DeliveryDead) peuvent être rejouées. Les livraisons terminées sont supprimées
+// après la durée de conservation webhooks.delivery_retention_days.
+type WebhookDelivery struct {
+	ID             uint      `gorm:"primaryKey"`
+	WebhookID      uint      `gorm:"not null;index"`
+	EventID        string    `gorm:"size:32;not null"`
+	EventType      string    `gorm:"size:50;not null"`
+	Payload        string    `gorm:"not null"` // Corps JSON envoyé, identique à chaque tentative
+	Status         string    `gorm:"size:16;not null;index:idx_webhook_deliveries_due,priority:1"`
+	Attempts       int       `gorm:"not null;default:0"`
+	NextAttemptAt  time.Time `gorm:"index:idx_webhook_deliveries_due,priority:2"`
+	ResponseStatus int       // Code HTTP de la dernière tentative (0 si aucune réponse)
+	LastError      string
+	DeliveredAt    *time.Time
+	CreatedAt      time.Time `gorm:"autoCreateTime"`
+	UpdatedAt      time.Time `gorm:"autoUpdateTime"`
+}
@@ -1,21 +1,32 @@
 package monitor
 
 import (
-	"log"
 	"net/http"
 	"sync" // Pour protéger l'accès concurrentiel à knownStates
 	"time"
 
-	_ "github.com/antoine-granier/urlshortener/internal/models"   // Importe les modèles de liens
+	"github.com/antoine-granier/urlshortener/internal/logging"
+	"github.com/antoine-granier/urlshortener/internal/metrics"    // Métriques exposées sur /metrics
+	"github.com/antoine-granier/urlshortener/internal/models"     // Importe les modèles de liens
 	"github.com/antoine-granier/urlshortener/internal/repository" // Importe le repository de liens
 )
 
+// logger journalise les vérifications du moniteur ; les changements d'état sont des avertissements.
+var logger = logging.For("monitor")
+
+// Health est l'état d'une URL longue lors de sa dernière vérification.
+type Health struct {
+	Accessible bool
+	CheckedAt  time.Time
+}
+
 // UrlMonitor gère la surveillance périodique des URLs longues.
 type UrlMonitor struct {
-	linkRepo    repository.LinkRepository // Pour récupérer les URLs à surveiller
-	interval    time.Duration             // Intervalle entre chaque vérification (ex: 5 minutes)
-	knownStates map[uint]bool             // État connu de chaque URL: map[LinkID]estAccessible (true/false)
-	mu          sync.Mutex                // Mutex pour protéger l'accès concurrentiel à knownStates
+	linkRepo    repository.LinkRepository               // Pour récupérer les URLs à surveiller
+	interval    time.Duration                           // Intervalle entre chaque vérification (ex: 5 minutes)
+	knownStates map[uint]Health                         // État connu de chaque URL: map[LinkID]état de la dernière vérification
+	mu          sync.Mutex                              // Mutex pour protéger l'accès concurrentiel à knownStates
+	onChange    func(link models.Link, accessible bool) // Notifié à chaque changement d'état (optionnel)
 }
 
 // NewUrlMonitor crée et retourne une nouvelle instance de UrlMonitor.
@@ -23,14 +34,20 @@ func NewUrlMonitor(linkRepo repository.LinkRepository, interval time.Duration) *
 	return &UrlMonitor{
 		linkRepo:    linkRepo,
 		interval:    interval,
-		knownStates: make(map[uint]bool),
+		knownStates: make(map[uint]Health),
 	}
 }
 
+// OnHealthChange enregistre la fonction appelée quand l'URL longue d'un lien change d'état
+// (accessible ou non), en plus de la notification dans les logs.
+func (m *UrlMonitor) OnHealthChange(fn func(link models.Link, accessible bool)) {
+	m.onChange = fn
+}
+
 // Start lance la boucle de surveillance périodique des URLs.
 // Cette fonction est conçue pour être lancée dans une goroutine séparée.
 func (m *UrlMonitor) Start() {
-	log.Printf("[MONITOR] Démarrage du moniteur d'URLs avec un intervalle de %v...", m.interval)
+	logger.Info("Starting URL monitor", "interval", m.interval)
 	ticker := time.NewTicker(m.interval) // Crée un ticker qui envoie un signal à chaque intervalle
 	defer ticker.Stop()                  // S'assure que le ticker est arrêté quand Start se termine
 
@@ -44,42 +61,66 @@ func (m *UrlMonitor) Start() {
 
 // checkUrls effectue une vérification de l'état de toutes les URLs longues enregistrées.
 func (m *UrlMonitor) checkUrls() {
-	log.Println("[MONITOR] Lancement de la vérification de l'état des URLs...")
+	logger.Debug("Checking long URLs")
+	start := time.Now()
 
 	links, err := m.linkRepo.GetAllLinks()
 	if err != nil {
-		log.Printf("[MONITOR] ERREUR lors de la récupération des liens pour la surveillance : %v", err)
+		logger.Error("Failed to load links to monitor", logging.Err(err))
 		return
 	}
 
+	var up, down int
 	for _, link := range links {
 		currentState := m.isUrlAccessible(link.LongURL)
+		if currentState {
+			up++
+			metrics.MonitorChecks.Inc("up")
+		} else {
+			down++
+			metrics.MonitorChecks.Inc("down")
+		}
 
 		// Protéger l'accès à la map 'knownStates' car 'checkUrls' peut être exécuté concurremment
 		m.mu.Lock()
-		previousState, exists := m.knownStates[link.ID] // Récupère l'état précédent
-		m.knownStates[link.ID] = currentState           // Met à jour l'état actuel
+		previous, exists := m.knownStates[link.ID]                                       // Récupère l'état précédent
+		m.knownStates[link.ID] = Health{Accessible: currentState, CheckedAt: time.Now()} // Met à jour l'état actuel
 		m.mu.Unlock()
+		previousState := previous.Accessible
 
 		// Si c'est la première vérification pour ce lien, on initialise l'état sans notifier.
 		if !exists {
-			log.Printf("[MONITOR] État initial pour le lien %s (%s) : %s",
-				link.ShortCode, link.LongURL, formatState(currentState))
+			logger.Debug("Initial link state", "short_code", link.ShortCode, "long_url", link.LongURL,
+				"state", formatState(currentState))
 			continue
 		}
 
 		// Si l'état a changé, générer une fausse notification dans les logs.
 		if currentState != previousState {
-			log.Printf(
-				"[NOTIFICATION] Le lien %s (%s) est passé de %s à %s !",
-				link.ShortCode, link.LongURL,
-				formatState(previousState),
-				formatState(currentState),
-			)
+			logger.Warn("Link state changed", "short_code", link.ShortCode, "long_url", link.LongURL,
+				"from", formatState(previousState), "to", formatState(currentState))
+			if m.onChange != nil {
+				m.onChange(link, currentState)
+			}
 		}
 	}
 
-	log.Println("[MONITOR] Vérification de l'état des URLs terminée.")
+	metrics.MonitorLinks.Set(float64(up), "up")
+	metrics.MonitorLinks.Set(float64(down), "down")
+	metrics.MonitorPassDuration.Observe(time.Since(start).Seconds())
+	logger.Info("URL check finished", "up", up, "down", down, "duration", time.Since(start))
+}
+
+// Health retourne l'état de l'URL longue d'un lien lors de la dernière vérification.
+// Le booléen vaut false si le lien n'a pas encore été vérifié (ou si le moniteur est nil).
+func (m *UrlMonitor) Health(linkID uint) (Health, bool) {
+	if m == nil {
+		return Health{}, false
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	h, ok := m.knownStates[linkID]
+	return h, ok
 }
 
 // isUrlAccessible effectue une requête HTTP HEAD pour vérifier l'accessibilité d'une URL.
@@ -90,7 +131,7 @@ func (m *UrlMonitor) isUrlAccessible(url string) bool {
 	//Effectuer une requête HEAD (plus légère que GET) sur l'URL.
 	resp, err := client.Head(url)
 	if err != nil {
-		log.Printf("[MONITOR] Erreur d'accès à l'URL '%s': %v", url, err)
+		logger.Debug("Long URL unreachable", "url", url, logging.Err(err))
 		return false
 	}
 	defer resp.Body.Close()
@@ -0,0 +1,281 @@
+package pageinfo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// maxBodySize borne la quantité de HTML lue : le <head> se trouve en début de document.
+const maxBodySize = 512 << 10
+
+// Longueurs maximales des textes conservés.
+const (
+	maxTitleLength       = 300
+	maxDescriptionLength = 1000
+	maxOpenGraphTags     = 50
+)
+
+// defaultTimeout est la durée maximale d'une récupération quand le contexte n'en impose pas.
+const defaultTimeout = 5 * time.Second
+
+// userAgent identifie les requêtes du raccourcisseur auprès des sites de destination.
+const userAgent = "url-shortener-preview/1.0"
+
+// ErrNotHTML est retournée quand la destination ne sert pas une page HTML.
+var ErrNotHTML = errors.New("destination is not an HTML page")
+
+// ErrBlockedAddress est retournée quand la destination (ou l'une de ses redirections) se résout vers
+// une adresse non publique : boucle locale, réseau privé, lien local (dont 169.254.169.254) ou non spécifiée.
+var ErrBlockedAddress = errors.New("destination resolves to a non-public address")
+
+// Info regroupe les informations d'une page web affichées dans les aperçus de liens.
+// OpenGraph contient les balises og:* de la page, indexées par propriété (ex: "og:image") ;
+// la première occurrence d'une propriété est conservée.
+type Info struct {
+	Title       string            `json:"title,omitempty"`
+	Description string            `json:"description,omitempty"`
+	FaviconURL  string            `json:"favicon_url,omitempty"`
+	OpenGraph   map[string]string `json:"open_graph,omitempty"`
+}
+
+// ImageURL retourne l'image de partage de la page (og:image), vide si elle n'en déclare pas.
+func (i Info) ImageURL() string {
+	return i.OpenGraph["og:image"]
+}
+
+// client est le client HTTP des récupérations ; il suit au plus 5 redirections.
+// N'importe qui pouvant créer un lien, l'adresse de chaque connexion est contrôlée après la résolution DNS
+// (redirections comprises) : le serveur ne doit pas servir de relais vers son réseau interne.
+// Aucun proxy n'est utilisé, le contrôle devant porter sur l'adresse réellement contactée.
+var client = &http.Client{
+	Timeout: defaultTimeout,
+	Transport: &http.Transport{
+		DialContext:           PublicDialer(defaultTimeout).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          20,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   defaultTimeout,
+		ExpectContinueTimeout: time.Second,
+	},
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 5 {
+			return errors.New("stopped after 5 redirects")
+		}
+		return nil
+	},
+}
+
+// PublicDialer retourne un dialer qui refuse les connexions vers une adresse non publique (ErrBlockedAddress).
+// Le contrôle porte sur l'adresse résolue : il s'applique aussi aux noms d'hôte et aux redirections.
+// Il sert à tout client HTTP dont la destination est fournie par un utilisateur (aperçus, webhooks).
+func PublicDialer(timeout time.Duration) *net.Dialer {
+	return &net.Dialer{
+		Timeout:   timeout,
+		KeepAlive: 30 * time.Second,
+		Control:   rejectNonPublicAddress,
+	}
+}
+
+// sharedAddressSpace est la plage partagée des opérateurs (RFC 6598), non routable sur Internet.
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// rejectNonPublicAddress refuse les connexions vers une adresse non publique. Appelée juste avant
+// chaque connexion, elle voit l'adresse IP résolue et non le nom d'hôte de l'URL.
+func rejectNonPublicAddress(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrBlockedAddress, address)
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !IsPublicIP(ip) {
+		return fmt.Errorf("%w: %s", ErrBlockedAddress, host)
+	}
+	return nil
+}
+
+// IsPublicIP indique si ip est une adresse joignable sur Internet : ni boucle locale, ni réseau privé,
+// ni lien local, ni multicast, ni non spécifiée.
+func IsPublicIP(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+		if ip4[0] == 0 || sharedAddressSpace.Contains(ip4) || ip4.Equal(net.IPv4bcast) {
+			return false
+		}
+	}
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsUnspecified() &&
+		!ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() && !ip.IsMulticast()
+}
+
+// Fetch récupère la page pageURL et en extrait le titre, la description, les balises og:* et l'icône.
+// Sans <link rel="icon">, l'icône par défaut /favicon.ico de l'hôte final est retournée.
+// Une destination se résolvant vers une adresse non publique retourne ErrBlockedAddress.
+func Fetch(ctx context.Context, pageURL string) (Info, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		return Info{}, fmt.Errorf("failed to build request for %s: %w", pageURL, err)
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return Info{}, fmt.Errorf("failed to fetch %s: %w", pageURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return Info{}, fmt.Errorf("failed to fetch %s: status %d", pageURL, resp.StatusCode)
+	}
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return Info{}, ErrNotHTML
+	}
+
+	// Les liens relatifs se résolvent par rapport à l'URL finale (après redirections).
+	info := parse(io.LimitReader(resp.Body, maxBodySize), resp.Request.URL)
+	return info, nil
+}
+
+// parse extrait les informations d'un document HTML ; la lecture s'arrête à la fin du <head>.
+func parse(r io.Reader, base *url.URL) Info {
+	var info Info
+	var inTitle bool
+	var title strings.Builder
+
+	z := html.NewTokenizer(r)
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return finish(info, title.String(), base)
+		case html.TextToken:
+			if inTitle {
+				title.Write(z.Text())
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			switch string(name) {
+			case "title":
+				inTitle = false
+			case "head":
+				return finish(info, title.String(), base)
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			switch string(name) {
+			case "title":
+				inTitle = title.Len() == 0
+			case "body":
+				return finish(info, title.String(), base)
+			case "link":
+				if info.FaviconURL != "" || !hasAttr {
+					continue
+				}
+				attrs := attributes(z)
+				if isIconRel(attrs["rel"]) && attrs["href"] != "" {
+					info.FaviconURL = resolve(base, attrs["href"])
+				}
+			case "meta":
+				if hasAttr {
+					info = addMeta(info, attributes(z), base)
+				}
+			}
+		}
+	}
+}
+
+// addMeta prend en compte une balise <meta> : description ou propriété og:*.
+func addMeta(info Info, attrs map[string]string, base *url.URL) Info {
+	content := attrs["content"]
+	if content == "" {
+		return info
+	}
+	if strings.EqualFold(attrs["name"], "description") && info.Description == "" {
+		info.Description = truncate(content, maxDescriptionLength)
+		return info
+	}
+	// La plupart des sites utilisent property="og:...", certains name="og:...".
+	prop := strings.ToLower(attrs["property"])
+	if prop == "" {
+		prop = strings.ToLower(attrs["name"])
+	}
+	if !strings.HasPrefix(prop, "og:") || len(info.OpenGraph) >= maxOpenGraphTags {
+		return info
+	}
+	if _, seen := info.OpenGraph[prop]; seen {
+		return info
+	}
+	switch prop {
+	case "og:image", "og:image:url", "og:image:secure_url", "og:url", "og:video", "og:audio":
+		content = resolve(base, content)
+	default:
+		content = truncate(content, maxDescriptionLength)
+	}
+	if content == "" {
+		return info
+	}
+	if info.OpenGraph == nil {
+		info.OpenGraph = make(map[string]string)
+	}
+	info.OpenGraph[prop] = content
+	return info
+}
+
+// finish complète Info : titre normalisé et icône par défaut.
+func finish(info Info, title string, base *url.URL) Info {
+	info.Title = truncate(title, maxTitleLength)
+	if info.FaviconURL == "" {
+		info.FaviconURL = resolve(base, "/favicon.ico")
+	}
+	return info
+}
+
+// truncate normalise les espaces de s et le tronque à max octets (sans couper un caractère).
+func truncate(s string, max int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if len(s) > max {
+		s = strings.ToValidUTF8(s[:max], "") + "…"
+	}
+	return s
+}
+
+// attributes retourne les attributs de l'élément courant (noms en minuscules).
+func attributes(z *html.Tokenizer) map[string]string {
+	attrs := make(map[string]string)
+	for {
+		key, val, more := z.TagAttr()
+		attrs[strings.ToLower(string(key))] = string(val)
+		if !more {
+			return attrs
+		}
+	}
+}
+
+// isIconRel indique si la valeur d'un attribut rel désigne une icône ("icon", "shortcut icon"...).
+func isIconRel(rel string) bool {
+	for _, v := range strings.Fields(strings.ToLower(rel)) {
+		if v == "icon" {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve résout ref par rapport à base ; seules les URLs http(s) sont retournées.
+func resolve(base *url.URL, ref string) string {
+	u, err := base.Parse(strings.TrimSpace(ref))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+	return u.String()
+}
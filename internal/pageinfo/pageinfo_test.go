@@ -0,0 +1,51 @@
+package pageinfo
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIsPublicIP(t *testing.T) {
+	cases := map[string]bool{
+		"93.184.216.34":   true,
+		"2606:4700::1111": true,
+		"127.0.0.1":       false,
+		"::1":             false,
+		"10.1.2.3":        false,
+		"172.16.0.1":      false,
+		"192.168.1.1":     false,
+		"169.254.169.254": false,
+		"fe80::1":         false,
+		"fd00::1":         false,
+		"0.0.0.0":         false,
+		"::":              false,
+		"100.64.0.1":      false,
+		"::ffff:10.0.0.1": false,
+		"224.0.0.1":       false,
+	}
+	for raw, want := range cases {
+		if got := IsPublicIP(net.ParseIP(raw)); got != want {
+			t.Errorf("IsPublicIP(%s) = %v, want %v", raw, got, want)
+		}
+	}
+}
+
+func TestFetchRejectsLoopback(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte("<title>internal</title>"))
+	}))
+	defer srv.Close()
+
+	info, err := Fetch(context.Background(), srv.URL)
+	if !errors.Is(err, ErrBlockedAddress) {
+		t.Fatalf("Fetch(%s) error = %v, want ErrBlockedAddress", srv.URL, err)
+	}
+	if info.Title != "" {
+		t.Errorf("Fetch(%s) leaked title %q", srv.URL, info.Title)
+	}
+}
@@ -0,0 +1,101 @@
+package pubsub
+
+import "sync"
+
+// Broker diffuse des messages en mémoire à des abonnés. Chaque abonné dispose d'un buffer :
+// un abonné trop lent dont le buffer est plein est désabonné (son channel est fermé) plutôt
+// que de bloquer la publication ou de lui faire manquer des messages sans le savoir.
+// Il est sûr pour un usage concurrent.
+type Broker[T any] struct {
+	mu     sync.Mutex
+	subs   map[*Subscription[T]]struct{}
+	closed bool
+}
+
+// Subscription est l'abonnement d'un consommateur. Ses messages arrivent sur Events ; le channel
+// est fermé au désabonnement, à la fermeture du broker, ou si le consommateur est trop lent (Dropped).
+type Subscription[T any] struct {
+	events  chan T
+	filter  func(T) bool
+	dropped bool // Protégé par le mutex du broker
+}
+
+// NewBroker crée et retourne un nouveau Broker.
+func NewBroker[T any]() *Broker[T] {
+	return &Broker[T]{subs: make(map[*Subscription[T]]struct{})}
+}
+
+// Subscribe abonne un consommateur aux messages acceptés par filter (tous si filter est nil),
+// avec un buffer de 'buffer' messages. Sur un broker fermé, le channel retourné est déjà fermé.
+func (b *Broker[T]) Subscribe(buffer int, filter func(T) bool) *Subscription[T] {
+	sub := &Subscription[T]{events: make(chan T, max(buffer, 1)), filter: filter}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		close(sub.events)
+		return sub
+	}
+	b.subs[sub] = struct{}{}
+	return sub
+}
+
+// Unsubscribe désabonne un consommateur. L'appel est sans effet s'il l'a déjà été.
+func (b *Broker[T]) Unsubscribe(sub *Subscription[T]) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.remove(sub)
+}
+
+// Publish envoie un message à chaque abonné intéressé, sans jamais bloquer.
+func (b *Broker[T]) Publish(msg T) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for sub := range b.subs {
+		if sub.filter != nil && !sub.filter(msg) {
+			continue
+		}
+		select {
+		case sub.events <- msg:
+		default:
+			sub.dropped = true
+			b.remove(sub)
+		}
+	}
+}
+
+// Close désabonne tous les consommateurs ; les abonnements suivants sont refusés.
+func (b *Broker[T]) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for sub := range b.subs {
+		b.remove(sub)
+	}
+}
+
+// Subscribers retourne le nombre d'abonnés actifs.
+func (b *Broker[T]) Subscribers() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subs)
+}
+
+// remove retire un abonné et ferme son channel. Le mutex doit être détenu.
+func (b *Broker[T]) remove(sub *Subscription[T]) {
+	if _, ok := b.subs[sub]; !ok {
+		return
+	}
+	delete(b.subs, sub)
+	close(sub.events)
+}
+
+// Events retourne le channel des messages de l'abonnement.
+func (s *Subscription[T]) Events() <-chan T {
+	return s.events
+}
+
+// Dropped indique, une fois Events fermé, si l'abonnement a été interrompu parce que
+// le consommateur ne suivait pas le rythme des messages.
+func (s *Subscription[T]) Dropped() bool {
+	return s.dropped
+}
@@ -0,0 +1,270 @@
+package qrcode
+
+// newCode crée une grille vide de la version donnée et y dessine les motifs fonctionnels
+// (repères de position, motifs d'alignement, lignes de synchronisation, informations de version).
+func newCode(version int, level Level) *Code {
+	size := version*4 + 17
+	c := &Code{Version: version, Level: level, Size: size}
+	c.modules = make([][]bool, size)
+	isFunction := make([][]bool, size)
+	for i := range c.modules {
+		c.modules[i] = make([]bool, size)
+		isFunction[i] = make([]bool, size)
+	}
+	c.isFunction = isFunction
+
+	for i := 0; i < size; i++ {
+		c.setFunction(6, i, i%2 == 0)
+		c.setFunction(i, 6, i%2 == 0)
+	}
+
+	c.drawFinder(3, 3)
+	c.drawFinder(size-4, 3)
+	c.drawFinder(3, size-4)
+
+	positions := alignmentPositions(version)
+	last := len(positions) - 1
+	for i, x := range positions {
+		for j, y := range positions {
+			// Les coins occupés par les repères de position ne reçoivent pas de motif d'alignement.
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+			c.drawAlignment(x, y)
+		}
+	}
+
+	// Les informations de format sont réservées ici et réécrites une fois le masque choisi.
+	c.drawFormatBits(0)
+	c.drawVersion()
+	return c
+}
+
+func (c *Code) setFunction(x, y int, dark bool) {
+	c.modules[y][x] = dark
+	c.isFunction[y][x] = true
+}
+
+func (c *Code) drawFinder(x, y int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			xx, yy := x+dx, y+dy
+			if xx < 0 || xx >= c.Size || yy < 0 || yy >= c.Size {
+				continue
+			}
+			dist := max(abs(dx), abs(dy))
+			c.setFunction(xx, yy, dist != 2 && dist != 4)
+		}
+	}
+}
+
+func (c *Code) drawAlignment(x, y int) {
+	for dy := -2; dy <= 2; dy++ {
+		for dx := -2; dx <= 2; dx++ {
+			c.setFunction(x+dx, y+dy, max(abs(dx), abs(dy)) != 1)
+		}
+	}
+}
+
+// drawFormatBits écrit les 15 bits de format (niveau + masque, protégés par BCH) en double exemplaire.
+func (c *Code) drawFormatBits(mask int) {
+	data := c.Level.formatBits()<<3 | mask
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = (rem << 1) ^ ((rem >> 9) * 0x537)
+	}
+	bits := (data<<10 | rem) ^ 0x5412
+
+	for i := 0; i <= 5; i++ {
+		c.setFunction(8, i, bit(bits, i))
+	}
+	c.setFunction(8, 7, bit(bits, 6))
+	c.setFunction(8, 8, bit(bits, 7))
+	c.setFunction(7, 8, bit(bits, 8))
+	for i := 9; i < 15; i++ {
+		c.setFunction(14-i, 8, bit(bits, i))
+	}
+
+	for i := 0; i < 8; i++ {
+		c.setFunction(c.Size-1-i, 8, bit(bits, i))
+	}
+	for i := 8; i < 15; i++ {
+		c.setFunction(8, c.Size-15+i, bit(bits, i))
+	}
+	c.setFunction(8, c.Size-8, true) // Module toujours sombre
+}
+
+// drawVersion écrit les 18 bits de version (versions 7 et plus uniquement).
+func (c *Code) drawVersion() {
+	if c.Version < 7 {
+		return
+	}
+	rem := c.Version
+	for i := 0; i < 12; i++ {
+		rem = (rem << 1) ^ ((rem >> 11) * 0x1F25)
+	}
+	bits := c.Version<<12 | rem
+	for i := 0; i < 18; i++ {
+		a, b := c.Size-11+i%3, i/3
+		c.setFunction(a, b, bit(bits, i))
+		c.setFunction(b, a, bit(bits, i))
+	}
+}
+
+// drawCodewords place les mots de code en zigzag, par colonnes de deux modules,
+// en partant du coin inférieur droit.
+func (c *Code) drawCodewords(data []byte) {
+	i := 0
+	for right := c.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5 // La colonne de synchronisation verticale est sautée
+		}
+		for vert := 0; vert < c.Size; vert++ {
+			for j := 0; j < 2; j++ {
+				x := right - j
+				y := vert
+				if (right+1)&2 == 0 {
+					y = c.Size - 1 - vert
+				}
+				if !c.isFunction[y][x] && i < len(data)*8 {
+					c.modules[y][x] = bit(int(data[i>>3]), 7-(i&7))
+					i++
+				}
+			}
+		}
+	}
+}
+
+// applyMask inverse les modules de données selon l'un des 8 masques de la norme.
+// Appliquer deux fois le même masque l'annule.
+func (c *Code) applyMask(mask int) {
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			var invert bool
+			switch mask {
+			case 0:
+				invert = (x+y)%2 == 0
+			case 1:
+				invert = y%2 == 0
+			case 2:
+				invert = x%3 == 0
+			case 3:
+				invert = (x+y)%3 == 0
+			case 4:
+				invert = (x/3+y/2)%2 == 0
+			case 5:
+				invert = x*y%2+x*y%3 == 0
+			case 6:
+				invert = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				invert = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if invert && !c.isFunction[y][x] {
+				c.modules[y][x] = !c.modules[y][x]
+			}
+		}
+	}
+}
+
+// applyBestMask essaie les 8 masques et conserve celui de plus faible pénalité.
+func (c *Code) applyBestMask() {
+	best, bestPenalty := 0, -1
+	for mask := 0; mask < 8; mask++ {
+		c.applyMask(mask)
+		c.drawFormatBits(mask)
+		if p := c.penalty(); bestPenalty < 0 || p < bestPenalty {
+			best, bestPenalty = mask, p
+		}
+		c.applyMask(mask)
+	}
+	c.applyMask(best)
+	c.drawFormatBits(best)
+	c.isFunction = nil
+}
+
+// penalty calcule le score de pénalité de la grille courante (règles N1 à N4 de la norme).
+func (c *Code) penalty() int {
+	result := 0
+	finderLike := [2][11]bool{
+		{true, false, true, true, true, false, true, false, false, false, false},
+		{false, false, false, false, true, false, true, true, true, false, true},
+	}
+
+	line := func(get func(i int) bool) {
+		runColor, runLen := false, 0
+		for i := 0; i < c.Size; i++ {
+			if i > 0 && get(i) == runColor {
+				runLen++
+				if runLen == 5 {
+					result += 3
+				} else if runLen > 5 {
+					result++
+				}
+			} else {
+				runColor, runLen = get(i), 1
+			}
+			if i >= 10 {
+				for _, pattern := range finderLike {
+					match := true
+					for k := 0; k < 11 && match; k++ {
+						match = get(i-10+k) == pattern[k]
+					}
+					if match {
+						result += 40
+					}
+				}
+			}
+		}
+	}
+	for y := 0; y < c.Size; y++ {
+		line(func(i int) bool { return c.modules[y][i] })
+	}
+	for x := 0; x < c.Size; x++ {
+		line(func(i int) bool { return c.modules[i][x] })
+	}
+
+	dark := 0
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if c.modules[y][x] {
+				dark++
+			}
+			if x+1 < c.Size && y+1 < c.Size {
+				v := c.modules[y][x]
+				if v == c.modules[y][x+1] && v == c.modules[y+1][x] && v == c.modules[y+1][x+1] {
+					result += 3
+				}
+			}
+		}
+	}
+	total := c.Size * c.Size
+	k := (abs(dark*20-total*10)+total-1)/total - 1
+	result += k * 10
+	return result
+}
+
+// alignmentPositions retourne les coordonnées des centres des motifs d'alignement.
+func alignmentPositions(version int) []int {
+	if version == 1 {
+		return nil
+	}
+	numAlign := version/7 + 2
+	step := (version*8 + numAlign*3 + 5) / (numAlign*4 - 4) * 2
+	result := make([]int, numAlign)
+	result[0] = 6
+	for i, pos := numAlign-1, version*4+17-7; i >= 1; i, pos = i-1, pos-step {
+		result[i] = pos
+	}
+	return result
+}
+
+func bit(x, i int) bool {
+	return (x>>uint(i))&1 != 0
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,117 @@
+package qrcode
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Level représente le niveau de correction d'erreur d'un QR code.
+// Plus le niveau est élevé, plus le code résiste aux dégradations (ou à un logo superposé),
+// au prix d'une capacité de stockage réduite.
+type Level int
+
+const (
+	LevelL Level = iota // ~7% de récupération
+	LevelM              // ~15% de récupération
+	LevelQ              // ~25% de récupération
+	LevelH              // ~30% de récupération
+)
+
+// formatBits retourne les 2 bits identifiant le niveau dans les informations de format.
+func (l Level) formatBits() int {
+	return [...]int{1, 0, 3, 2}[l]
+}
+
+// ErrDataTooLong est retournée quand le contenu ne tient pas dans un QR code de version 40.
+var ErrDataTooLong = errors.New("qrcode: data too long")
+
+// ParseLevel convertit une chaîne ("L", "M", "Q", "H", insensible à la casse) en Level.
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToUpper(s) {
+	case "L":
+		return LevelL, nil
+	case "M":
+		return LevelM, nil
+	case "Q":
+		return LevelQ, nil
+	case "H":
+		return LevelH, nil
+	}
+	return 0, fmt.Errorf("qrcode: invalid error correction level %q", s)
+}
+
+// Code est un QR code encodé : une grille carrée de modules (true = module sombre).
+type Code struct {
+	Version int
+	Level   Level
+	Size    int
+	modules [][]bool
+
+	isFunction [][]bool // Modules réservés aux motifs fonctionnels, utilisé uniquement pendant l'encodage
+}
+
+// Dark indique si le module (x, y) est sombre.
+func (c *Code) Dark(x, y int) bool {
+	return c.modules[y][x]
+}
+
+// Encode encode le texte en mode octet (UTF-8) dans le plus petit QR code
+// capable de le contenir au niveau de correction demandé.
+func Encode(text string, level Level) (*Code, error) {
+	data := []byte(text)
+
+	version := 0
+	for v := 1; v <= 40; v++ {
+		if 4+charCountBits(v)+len(data)*8 <= numDataCodewords(v, level)*8 {
+			version = v
+			break
+		}
+	}
+	if version == 0 {
+		return nil, ErrDataTooLong
+	}
+
+	// Construction du flux de bits : mode octet, longueur, données, terminateur et remplissage.
+	var bb bitBuffer
+	bb.append(0x4, 4)
+	bb.append(len(data), charCountBits(version))
+	for _, b := range data {
+		bb.append(int(b), 8)
+	}
+	capacity := numDataCodewords(version, level) * 8
+	bb.append(0, min(4, capacity-len(bb)))
+	bb.append(0, (8-len(bb)%8)%8)
+	for pad := 0xEC; len(bb) < capacity; pad ^= 0xEC ^ 0x11 {
+		bb.append(pad, 8)
+	}
+
+	codewords := make([]byte, len(bb)/8)
+	for i, bit := range bb {
+		if bit {
+			codewords[i>>3] |= 1 << (7 - uint(i&7))
+		}
+	}
+
+	c := newCode(version, level)
+	c.drawCodewords(addEccAndInterleave(codewords, version, level))
+	c.applyBestMask()
+	return c, nil
+}
+
+// bitBuffer accumule des bits dans l'ordre de poids fort à poids faible.
+type bitBuffer []bool
+
+func (bb *bitBuffer) append(val, n int) {
+	for i := n - 1; i >= 0; i-- {
+		*bb = append(*bb, (val>>uint(i))&1 != 0)
+	}
+}
+
+// charCountBits retourne la taille du champ "longueur" en mode octet pour une version.
+func charCountBits(version int) int {
+	if version <= 9 {
+		return 8
+	}
+	return 16
+}
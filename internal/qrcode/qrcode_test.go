@@ -0,0 +1,355 @@
+package qrcode
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// Les valeurs attendues ci-dessous proviennent de la norme ISO/IEC 18004 (tables et exemples),
+// et le décodeur de test est écrit indépendamment de l'encodeur : il ne réutilise ni son placement
+// des modules, ni ses masques, ni son calcul de correction.
+
+// formatWords est la table des 15 bits de format (après masquage 0x5412), par niveau puis par masque.
+var formatWords = map[Level][8]string{
+	LevelL: {"111011111000100", "111001011110011", "111110110101010", "111100010011101", "110011000101111", "110001100011000", "110110001000001", "110100101110110"},
+	LevelM: {"101010000010010", "101000100100101", "101111001111100", "101101101001011", "100010111111001", "100000011001110", "100111110010111", "100101010100000"},
+	LevelQ: {"011010101011111", "011000001101000", "011111100110001", "011101000000110", "010010010110100", "010000110000011", "010111011011010", "010101111101101"},
+	LevelH: {"001011010001001", "001001110111110", "001110011100111", "001100111010000", "000011101100010", "000001001010101", "000110100001100", "000100000111011"},
+}
+
+// versionWords est la table des 18 bits d'information de version.
+var versionWords = map[int]int{7: 0x07C94, 8: 0x085BC, 9: 0x09A99, 10: 0x0A4D3, 11: 0x0BBF6, 12: 0x0C762, 13: 0x0D847, 14: 0x0E60D}
+
+// alignmentCenters est la table des centres des motifs d'alignement (annexe E).
+var alignmentCenters = map[int][]int{
+	1: nil, 2: {6, 18}, 3: {6, 22}, 4: {6, 26}, 5: {6, 30}, 6: {6, 34},
+	7: {6, 22, 38}, 8: {6, 24, 42}, 9: {6, 26, 46}, 10: {6, 28, 50},
+	11: {6, 30, 54}, 12: {6, 32, 58}, 13: {6, 34, 62}, 14: {6, 26, 46, 66},
+	32: {6, 34, 60, 86, 112, 138}, 40: {6, 30, 58, 86, 114, 142, 170},
+}
+
+func TestReedSolomonKnownVector(t *testing.T) {
+	// "HELLO WORLD" en version 1-M : mots de code de données et de correction attendus.
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	want := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+
+	if got := reedSolomonRemainder(data, reedSolomonDivisor(len(want))); !bytes.Equal(got, want) {
+		t.Errorf("ecc = %v, want %v", got, want)
+	}
+	if got := addEccAndInterleave(data, 1, LevelM); !bytes.Equal(got, append(slices.Clone(data), want...)) {
+		t.Errorf("codewords = %v", got)
+	}
+}
+
+func TestAlignmentPositions(t *testing.T) {
+	for version, want := range alignmentCenters {
+		if got := alignmentPositions(version); !slices.Equal(got, want) {
+			t.Errorf("version %d: alignment centers = %v, want %v", version, got, want)
+		}
+	}
+}
+
+func TestByteModeCapacity(t *testing.T) {
+	// Capacité en mode octet (table 7) : le plus long contenu de chaque version, puis un octet de plus.
+	capacities := []struct {
+		version int
+		level   Level
+		bytes   int
+	}{
+		{1, LevelL, 17}, {1, LevelM, 14}, {1, LevelQ, 11}, {1, LevelH, 7},
+		{2, LevelL, 32}, {2, LevelM, 26}, {2, LevelQ, 20}, {2, LevelH, 14},
+		{5, LevelL, 106}, {5, LevelM, 84}, {5, LevelQ, 60}, {5, LevelH, 44},
+		{10, LevelL, 271}, {10, LevelM, 213}, {10, LevelQ, 151}, {10, LevelH, 119},
+		{40, LevelL, 2953}, {40, LevelM, 2331}, {40, LevelQ, 1663}, {40, LevelH, 1273},
+	}
+	for _, tc := range capacities {
+		code, err := Encode(strings.Repeat("a", tc.bytes), tc.level)
+		if err != nil || code.Version != tc.version {
+			t.Errorf("%d bytes at level %d: version %v, err %v, want version %d", tc.bytes, tc.level, versionOf(code), err, tc.version)
+			continue
+		}
+		code, err = Encode(strings.Repeat("a", tc.bytes+1), tc.level)
+		if tc.version == 40 {
+			if err != ErrDataTooLong {
+				t.Errorf("%d bytes at level %d: err = %v, want ErrDataTooLong", tc.bytes+1, tc.level, err)
+			}
+		} else if err != nil || code.Version != tc.version+1 {
+			t.Errorf("%d bytes at level %d: version %v, err %v, want version %d", tc.bytes+1, tc.level, versionOf(code), err, tc.version+1)
+		}
+	}
+}
+
+func versionOf(c *Code) any {
+	if c == nil {
+		return nil
+	}
+	return c.Version
+}
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	var inputs []string
+	for _, n := range []int{0, 1, 7, 14, 17, 26, 42, 60, 85, 120, 151, 200, 271, 330, 458} {
+		var sb strings.Builder
+		for i := 0; i < n; i++ {
+			sb.WriteByte(byte('!' + (i*7)%90))
+		}
+		inputs = append(inputs, sb.String())
+	}
+	inputs = append(inputs, "https://sho.rt/4kfFIE", "https://example.com/é?q=ünïcode")
+
+	for _, level := range []Level{LevelL, LevelM, LevelQ, LevelH} {
+		for _, text := range inputs {
+			code, err := Encode(text, level)
+			if err == ErrDataTooLong || err == nil && code.Version > 14 {
+				continue // Le décodeur de test ne connaît les alignements que jusqu'à la version 14
+			}
+			if err != nil {
+				t.Fatalf("Encode(%d bytes, level %d): %v", len(text), level, err)
+			}
+			t.Run(fmt.Sprintf("v%d-level%d-%dB", code.Version, level, len(text)), func(t *testing.T) {
+				got, gotLevel, err := decode(code)
+				if err != nil {
+					t.Fatalf("decode: %v", err)
+				}
+				if got != text || gotLevel != level {
+					t.Errorf("decoded %q at level %d, want %q at level %d", got, gotLevel, text, level)
+				}
+			})
+		}
+	}
+}
+
+// decode lit un QR code produit par Encode : informations de format et de version, démasquage,
+// lecture en zigzag, désentrelacement, vérification Reed-Solomon de chaque bloc et mode octet.
+func decode(c *Code) (string, Level, error) {
+	size := c.Size
+	version := (size - 17) / 4
+	if size != version*4+17 || version < 1 {
+		return "", 0, fmt.Errorf("invalid size %d", size)
+	}
+
+	// Informations de format (premier exemplaire), comparées à la table de la norme
+	var format strings.Builder
+	formatCoords := [][2]int{{0, 8}, {1, 8}, {2, 8}, {3, 8}, {4, 8}, {5, 8}, {7, 8}, {8, 8}, {8, 7}, {8, 5}, {8, 4}, {8, 3}, {8, 2}, {8, 1}, {8, 0}}
+	for _, p := range formatCoords {
+		format.WriteString(map[bool]string{true: "1", false: "0"}[c.Dark(p[0], p[1])])
+	}
+	level, mask := Level(-1), -1
+	for l, words := range formatWords {
+		if i := slices.Index(words[:], format.String()); i >= 0 {
+			level, mask = l, i
+		}
+	}
+	if mask < 0 {
+		return "", 0, fmt.Errorf("unknown format information %s", format.String())
+	}
+	// Second exemplaire : bits 14 à 8 en bas de la colonne 8, bits 7 à 0 à droite de la ligne 8
+	for i := 0; i < 15; i++ {
+		x, y := 8, size-1-i
+		if i >= 7 {
+			x, y = size-15+i, 8
+		}
+		if c.Dark(x, y) != (format.String()[i] == '1') {
+			return "", 0, fmt.Errorf("format copies differ at bit %d", 14-i)
+		}
+	}
+	if !c.Dark(8, size-8) {
+		return "", 0, fmt.Errorf("dark module missing")
+	}
+
+	if version >= 7 {
+		want, ok := versionWords[version]
+		if !ok {
+			return "", 0, fmt.Errorf("no version word for version %d", version)
+		}
+		var top, left int
+		for i := 17; i >= 0; i-- {
+			top, left = top<<1, left<<1
+			if c.Dark(size-11+i%3, i/3) {
+				top |= 1
+			}
+			if c.Dark(i/3, size-11+i%3) {
+				left |= 1
+			}
+		}
+		if top != want || left != want {
+			return "", 0, fmt.Errorf("version information %#x/%#x, want %#x", top, left, want)
+		}
+	}
+
+	// Modules réservés aux motifs fonctionnels
+	reserved := func(x, y int) bool {
+		switch {
+		case x < 9 && y < 9, x >= size-8 && y < 9, x < 9 && y >= size-8:
+			return true
+		case x == 6 || y == 6:
+			return true
+		case version >= 7 && (x >= size-11 && x < size-8 && y < 6 || y >= size-11 && y < size-8 && x < 6):
+			return true
+		}
+		centers := alignmentCenters[version]
+		for i, ax := range centers {
+			for j, ay := range centers {
+				last := len(centers) - 1
+				if i == 0 && j == 0 || i == 0 && j == last || i == last && j == 0 {
+					continue
+				}
+				if x >= ax-2 && x <= ax+2 && y >= ay-2 && y <= ay+2 {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	masks := []func(i, j int) bool{
+		func(i, j int) bool { return (i+j)%2 == 0 },
+		func(i, j int) bool { return i%2 == 0 },
+		func(i, j int) bool { return j%3 == 0 },
+		func(i, j int) bool { return (i+j)%3 == 0 },
+		func(i, j int) bool { return (i/2+j/3)%2 == 0 },
+		func(i, j int) bool { return (i*j)%2+(i*j)%3 == 0 },
+		func(i, j int) bool { return ((i*j)%2+(i*j)%3)%2 == 0 },
+		func(i, j int) bool { return ((i+j)%2+(i*j)%3)%2 == 0 },
+	}
+
+	// Lecture en zigzag depuis le coin inférieur droit, démasquée (i = ligne, j = colonne)
+	var bits []bool
+	upward := true
+	for right := size - 1; right > 0; right -= 2 {
+		if right == 6 {
+			right--
+		}
+		for k := 0; k < size; k++ {
+			y := k
+			if upward {
+				y = size - 1 - k
+			}
+			for _, x := range []int{right, right - 1} {
+				if !reserved(x, y) {
+					bits = append(bits, c.Dark(x, y) != masks[mask](y, x))
+				}
+			}
+		}
+		upward = !upward
+	}
+	raw := make([]byte, len(bits)/8)
+	for i := range raw {
+		for _, b := range bits[i*8 : i*8+8] {
+			raw[i] <<= 1
+			if b {
+				raw[i] |= 1
+			}
+		}
+	}
+
+	// Désentrelacement : blocs courts en premier, un mot de données de plus dans les blocs longs
+	numBlocks := numErrorCorrectionBlocks[level][version]
+	eccLen := eccCodewordsPerBlock[level][version]
+	numShort := numBlocks - len(raw)%numBlocks
+	shortData := len(raw)/numBlocks - eccLen
+	blocks := make([][]byte, numBlocks)
+	k := 0
+	for i := 0; i <= shortData; i++ {
+		for b := range blocks {
+			if i < shortData || b >= numShort {
+				blocks[b] = append(blocks[b], raw[k])
+				k++
+			}
+		}
+	}
+	for i := 0; i < eccLen; i++ {
+		for b := range blocks {
+			blocks[b] = append(blocks[b], raw[k])
+			k++
+		}
+	}
+	var data []byte
+	for b, block := range blocks {
+		if err := checkSyndromes(block, eccLen); err != nil {
+			return "", 0, fmt.Errorf("block %d: %v", b, err)
+		}
+		data = append(data, block[:len(block)-eccLen]...)
+	}
+
+	// Mode octet, longueur, contenu, terminateur et octets de remplissage
+	r := bitReader{data: data}
+	if mode := r.read(4); mode != 0x4 {
+		return "", 0, fmt.Errorf("mode %#x, want byte mode", mode)
+	}
+	countBits := 8
+	if version > 9 {
+		countBits = 16
+	}
+	n := r.read(countBits)
+	text := make([]byte, n)
+	for i := range text {
+		text[i] = byte(r.read(8))
+	}
+	if r.read(min(4, len(data)*8-r.pos)) != 0 {
+		return "", 0, fmt.Errorf("missing terminator")
+	}
+	r.pos = (r.pos + 7) / 8 * 8
+	for pad := 0xEC; r.pos < len(data)*8; pad ^= 0xEC ^ 0x11 {
+		if got := r.read(8); got != pad {
+			return "", 0, fmt.Errorf("padding byte %#x, want %#x", got, pad)
+		}
+	}
+	return string(text), level, nil
+}
+
+// checkSyndromes vérifie qu'un bloc est un mot de code Reed-Solomon : le polynôme s'annule
+// en α^0 … α^(eccLen-1) dans GF(2^8) (polynôme primitif 0x11D).
+func checkSyndromes(block []byte, eccLen int) error {
+	var exp [255]int
+	x := 1
+	for i := range exp {
+		exp[i] = x
+		x <<= 1
+		if x&0x100 != 0 {
+			x ^= 0x11D
+		}
+	}
+	mul := func(a, b int) int {
+		result := 0
+		for ; b > 0; b >>= 1 {
+			if b&1 != 0 {
+				result ^= a
+			}
+			a <<= 1
+			if a&0x100 != 0 {
+				a ^= 0x11D
+			}
+		}
+		return result
+	}
+	for i := 0; i < eccLen; i++ {
+		s := 0
+		for _, coef := range block {
+			s = mul(s, exp[i]) ^ int(coef)
+		}
+		if s != 0 {
+			return fmt.Errorf("syndrome %d is %#x", i, s)
+		}
+	}
+	return nil
+}
+
+type bitReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *bitReader) read(n int) int {
+	v := 0
+	for i := 0; i < n; i++ {
+		v <<= 1
+		if r.data[r.pos/8]>>(7-r.pos%8)&1 != 0 {
+			v |= 1
+		}
+		r.pos++
+	}
+	return v
+}
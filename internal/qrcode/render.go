@@ -0,0 +1,185 @@
+package qrcode
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// quietZone est la marge blanche (en modules) exigée autour du code par la norme.
+const quietZone = 4
+
+// RenderOptions regroupe les paramètres de rendu d'un QR code.
+type RenderOptions struct {
+	Size       int         // Largeur cible de l'image en pixels (arrondie à un multiple du nombre de modules)
+	Foreground color.Color // Couleur des modules sombres
+	Background color.Color // Couleur du fond
+	Logo       image.Image // Logo optionnel superposé au centre du code
+}
+
+// withDefaults complète les options non renseignées.
+func (o RenderOptions) withDefaults() RenderOptions {
+	if o.Size <= 0 {
+		o.Size = 256
+	}
+	if o.Foreground == nil {
+		o.Foreground = color.Black
+	}
+	if o.Background == nil {
+		o.Background = color.White
+	}
+	return o
+}
+
+// logoModules retourne la largeur (en modules) réservée au logo : environ 20% du code,
+// ce qu'un niveau de correction Q ou H absorbe sans perte de lisibilité.
+func (c *Code) logoModules() int {
+	n := c.Size / 5
+	if n%2 != c.Size%2 {
+		n++ // Même parité que le code pour rester centré sur la grille
+	}
+	return n
+}
+
+// Image produit une image du QR code, marge comprise.
+func (c *Code) Image(opts RenderOptions) image.Image {
+	opts = opts.withDefaults()
+	total := c.Size + 2*quietZone
+	scale := max(1, opts.Size/total)
+
+	img := image.NewRGBA(image.Rect(0, 0, total*scale, total*scale))
+	fg, bg := color.RGBAModel.Convert(opts.Foreground), color.RGBAModel.Convert(opts.Background)
+	for py := 0; py < total*scale; py++ {
+		for px := 0; px < total*scale; px++ {
+			x, y := px/scale-quietZone, py/scale-quietZone
+			if x >= 0 && x < c.Size && y >= 0 && y < c.Size && c.modules[y][x] {
+				img.Set(px, py, fg)
+			} else {
+				img.Set(px, py, bg)
+			}
+		}
+	}
+
+	if opts.Logo != nil {
+		n := c.logoModules()
+		offset := (quietZone + (c.Size-n)/2) * scale
+		box := image.Rect(offset, offset, offset+n*scale, offset+n*scale)
+		for py := box.Min.Y; py < box.Max.Y; py++ {
+			for px := box.Min.X; px < box.Max.X; px++ {
+				img.Set(px, py, bg)
+			}
+		}
+		// Le logo est redimensionné (plus proche voisin) avec une marge d'un module.
+		inner := box.Inset(scale)
+		drawScaled(img, inner, opts.Logo)
+	}
+	return img
+}
+
+// WritePNG écrit le QR code au format PNG.
+func (c *Code) WritePNG(w io.Writer, opts RenderOptions) error {
+	return png.Encode(w, c.Image(opts))
+}
+
+// WriteSVG écrit le QR code au format SVG. Chaque ligne de modules sombres contigus
+// est regroupée dans un seul rectangle pour limiter la taille du document.
+func (c *Code) WriteSVG(w io.Writer, opts RenderOptions) error {
+	opts = opts.withDefaults()
+	total := c.Size + 2*quietZone
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<?xml version="1.0" encoding="UTF-8"?>`+"\n")
+	fmt.Fprintf(&sb, `<svg xmlns="http://www.w3.org/2000/svg" version="1.1" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`+"\n",
+		opts.Size, opts.Size, total, total)
+	fmt.Fprintf(&sb, `<rect width="100%%" height="100%%" fill="%s"/>`+"\n", hexColor(opts.Background))
+
+	var path strings.Builder
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; {
+			if !c.modules[y][x] {
+				x++
+				continue
+			}
+			start := x
+			for x < c.Size && c.modules[y][x] {
+				x++
+			}
+			fmt.Fprintf(&path, "M%d,%dh%dv1h-%dz", start+quietZone, y+quietZone, x-start, x-start)
+		}
+	}
+	fmt.Fprintf(&sb, `<path d="%s" fill="%s"/>`+"\n", path.String(), hexColor(opts.Foreground))
+
+	if opts.Logo != nil {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, opts.Logo); err != nil {
+			return fmt.Errorf("qrcode: failed to encode logo: %w", err)
+		}
+		n := c.logoModules()
+		offset := quietZone + (c.Size-n)/2
+		fmt.Fprintf(&sb, `<rect x="%d" y="%d" width="%d" height="%d" fill="%s"/>`+"\n",
+			offset, offset, n, n, hexColor(opts.Background))
+		fmt.Fprintf(&sb, `<image x="%d" y="%d" width="%d" height="%d" preserveAspectRatio="xMidYMid meet" href="data:image/png;base64,%s"/>`+"\n",
+			offset+1, offset+1, n-2, n-2, base64.StdEncoding.EncodeToString(buf.Bytes()))
+	}
+	sb.WriteString("</svg>\n")
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// drawScaled copie src dans la zone dst de img en le redimensionnant au plus proche voisin,
+// en conservant son ratio. La transparence du logo laisse apparaître le fond.
+func drawScaled(img *image.RGBA, dst image.Rectangle, src image.Image) {
+	sb := src.Bounds()
+	if sb.Dx() == 0 || sb.Dy() == 0 || dst.Dx() <= 0 {
+		return
+	}
+	w, h := dst.Dx(), dst.Dy()
+	if sb.Dx() > sb.Dy() {
+		h = h * sb.Dy() / sb.Dx()
+	} else {
+		w = w * sb.Dx() / sb.Dy()
+	}
+	ox, oy := dst.Min.X+(dst.Dx()-w)/2, dst.Min.Y+(dst.Dy()-h)/2
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			sc := color.NRGBAModel.Convert(src.At(sb.Min.X+x*sb.Dx()/w, sb.Min.Y+y*sb.Dy()/h)).(color.NRGBA)
+			if sc.A == 0 {
+				continue
+			}
+			under := img.RGBAAt(ox+x, oy+y)
+			a := uint32(sc.A)
+			blend := func(s uint8, d uint8) uint8 {
+				return uint8((uint32(s)*a + uint32(d)*(255-a)) / 255)
+			}
+			img.SetRGBA(ox+x, oy+y, color.RGBA{blend(sc.R, under.R), blend(sc.G, under.G), blend(sc.B, under.B), 255})
+		}
+	}
+}
+
+// ParseColor convertit une couleur hexadécimale ("#RRGGBB", "RRGGBB" ou "#RGB") en color.Color.
+func ParseColor(s string) (color.Color, error) {
+	hex := strings.TrimPrefix(s, "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) != 6 {
+		return nil, fmt.Errorf("qrcode: invalid color %q", s)
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return nil, fmt.Errorf("qrcode: invalid color %q", s)
+	}
+	return color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 255}, nil
+}
+
+func hexColor(c color.Color) string {
+	r, g, b, _ := c.RGBA()
+	return fmt.Sprintf("#%02x%02x%02x", r>>8, g>>8, b>>8)
+}
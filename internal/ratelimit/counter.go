@@ -0,0 +1,121 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// cleanupEvery est le nombre d'opérations entre deux purges des clés inactives.
+const cleanupEvery = 1024
+
+// Counter compte des événements par clé sur une fenêtre glissante (ex: échecs de mot de passe par IP).
+// Il est sûr pour un usage concurrent et ne conserve que les événements encore dans la fenêtre.
+type Counter struct {
+	mu     sync.Mutex
+	limit  int
+	window time.Duration
+	events map[string][]time.Time
+	ops    int
+}
+
+// NewCounter crée un compteur autorisant 'limit' événements par clé sur la durée 'window'.
+func NewCounter(limit int, window time.Duration) *Counter {
+	return &Counter{
+		limit:  limit,
+		window: window,
+		events: make(map[string][]time.Time),
+	}
+}
+
+// Exceeded indique si la clé a atteint la limite d'événements sur la fenêtre en cours.
+func (c *Counter) Exceeded(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.trim(key, time.Now())) >= c.limit
+}
+
+// Add enregistre un événement pour la clé et retourne le nombre d'événements dans la fenêtre.
+func (c *Counter) Add(key string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	events := append(c.trim(key, now), now)
+	c.events[key] = events
+	c.cleanup(now)
+	return len(events)
+}
+
+// Reserve enregistre un événement pour la clé si la limite n'est pas atteinte, en une seule opération :
+// des appels simultanés ne peuvent pas dépasser la limite, contrairement à Exceeded suivi de Add.
+// Retourne false sans rien enregistrer si la limite est atteinte ; l'horodatage retourné permet
+// d'annuler la réservation avec Release.
+func (c *Counter) Reserve(key string) (time.Time, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	events := c.trim(key, now)
+	if len(events) >= c.limit {
+		return time.Time{}, false
+	}
+	c.events[key] = append(events, now)
+	c.cleanup(now)
+	return now, true
+}
+
+// Release annule l'événement réservé à l'instant at pour la clé (ex: tentative finalement réussie).
+func (c *Counter) Release(key string, at time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cleanup(time.Now())
+	events := c.events[key]
+	for i := len(events) - 1; i >= 0; i-- {
+		if events[i].Equal(at) {
+			events = append(events[:i], events[i+1:]...)
+			break
+		}
+	}
+	if len(events) == 0 {
+		delete(c.events, key)
+		return
+	}
+	c.events[key] = events
+}
+
+// Reset oublie les événements d'une clé (ex: après une authentification réussie).
+func (c *Counter) Reset(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.events, key)
+}
+
+// cleanup purge toutes les clés sans événement dans la fenêtre, une fois toutes les cleanupEvery
+// opérations : sans elle, chaque clé vue une fois resterait en mémoire. Appelé sous verrou.
+func (c *Counter) cleanup(now time.Time) {
+	c.ops++
+	if c.ops%cleanupEvery != 0 {
+		return
+	}
+	for k := range c.events {
+		c.trim(k, now)
+	}
+}
+
+// trim retire les événements sortis de la fenêtre et retourne ceux qui restent. Appelé sous verrou.
+func (c *Counter) trim(key string, now time.Time) []time.Time {
+	events := c.events[key]
+	cutoff := now.Add(-c.window)
+	i := 0
+	for i < len(events) && !events[i].After(cutoff) {
+		i++
+	}
+	if i == 0 {
+		return events
+	}
+	events = events[i:]
+	if len(events) == 0 {
+		delete(c.events, key)
+		return nil
+	}
+	c.events[key] = events
+	return events
+}
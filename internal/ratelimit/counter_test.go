@@ -0,0 +1,78 @@
+package ratelimit
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestReserveIsAtomic(t *testing.T) {
+	c := NewCounter(5, time.Minute)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	granted := 0
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, ok := c.Reserve("k"); ok {
+				mu.Lock()
+				granted++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if granted != 5 {
+		t.Fatalf("granted %d reservations, want 5", granted)
+	}
+	if !c.Exceeded("k") {
+		t.Fatal("Exceeded = false after reaching the limit")
+	}
+}
+
+func TestReleaseFreesReservation(t *testing.T) {
+	c := NewCounter(1, time.Minute)
+	at, ok := c.Reserve("k")
+	if !ok {
+		t.Fatal("first reservation refused")
+	}
+	if _, ok := c.Reserve("k"); ok {
+		t.Fatal("second reservation accepted over the limit")
+	}
+	c.Release("k", at)
+	if _, ok := c.Reserve("k"); !ok {
+		t.Fatal("reservation refused after Release")
+	}
+}
+
+func TestWindowExpiry(t *testing.T) {
+	c := NewCounter(1, 20*time.Millisecond)
+	c.Add("k")
+	if !c.Exceeded("k") {
+		t.Fatal("Exceeded = false right after Add")
+	}
+	time.Sleep(30 * time.Millisecond)
+	if c.Exceeded("k") {
+		t.Fatal("Exceeded = true after the window elapsed")
+	}
+}
+
+func TestReserveOnlyTrafficPurgesExpiredKeys(t *testing.T) {
+	c := NewCounter(3, 10*time.Millisecond)
+	for i := 0; i < cleanupEvery-1; i++ {
+		c.Reserve(fmt.Sprintf("link-%d", i))
+	}
+	time.Sleep(20 * time.Millisecond)
+	c.Reserve("last") // Déclenche la purge périodique
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.events) != 1 {
+		t.Fatalf("%d keys kept after the purge, want 1", len(c.events))
+	}
+	if _, ok := c.events["last"]; !ok {
+		t.Fatal("the live key was purged")
+	}
+}
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/antoine-granier/urlshortener/internal/models"
+	"gorm.io/gorm"
+)
+
+// AlertRepository définit l'accès aux règles d'alerte sur le trafic des liens.
+type AlertRepository interface {
+	CreateRule(rule *models.AlertRule) error
+	ListRules(filter AlertRuleFilter) ([]models.AlertRule, error)
+	DeleteRule(id uint) error
+	RecordEvaluation(id uint, checkedAt time.Time, firing bool, triggeredAt *time.Time) error
+}
+
+// AlertRuleFilter restreint les règles retournées par ListRules. Les critères vides sont ignorés.
+type AlertRuleFilter struct {
+	LinkID     uint
+	CampaignID uint
+	Owner      string
+}
+
+// GormAlertRepository est l'implémentation de AlertRepository utilisant GORM.
+type GormAlertRepository struct {
+	db *gorm.DB
+}
+
+// NewAlertRepository crée et retourne une nouvelle instance de GormAlertRepository.
+func NewAlertRepository(db *gorm.DB) *GormAlertRepository {
+	return &GormAlertRepository{db: db}
+}
+
+// CreateRule enregistre une nouvelle règle d'alerte.
+func (r *GormAlertRepository) CreateRule(rule *models.AlertRule) error {
+	if err := r.db.Create(rule).Error; err != nil {
+		return fmt.Errorf("failed to create alert rule: %w", err)
+	}
+	return nil
+}
+
+// ListRules retourne les règles d'alerte correspondant au filtre, par ordre de création.
+func (r *GormAlertRepository) ListRules(filter AlertRuleFilter) ([]models.AlertRule, error) {
+	query := r.db.Order("id")
+	if filter.LinkID != 0 {
+		query = query.Where("link_id = ?", filter.LinkID)
+	}
+	if filter.CampaignID != 0 {
+		query = query.Where("campaign_id = ?", filter.CampaignID)
+	}
+	if filter.Owner != "" {
+		query = query.Where("owner = ?", filter.Owner)
+	}
+	var rules []models.AlertRule
+	if err := query.Find(&rules).Error; err != nil {
+		return nil, fmt.Errorf("failed to list alert rules: %w", err)
+	}
+	return rules, nil
+}
+
+// DeleteRule supprime une règle d'alerte. Il renvoie gorm.ErrRecordNotFound si elle n'existe pas.
+func (r *GormAlertRepository) DeleteRule(id uint) error {
+	res := r.db.Delete(&models.AlertRule{}, id)
+	if res.Error != nil {
+		return fmt.Errorf("failed to delete alert rule %d: %w", id, res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("failed to delete alert rule %d: %w", id, gorm.ErrRecordNotFound)
+	}
+	return nil
+}
+
+// RecordEvaluation enregistre le résultat de l'évaluation d'une règle ; triggeredAt n'est renseigné
+// que si la règle vient de notifier. Une règle supprimée entre-temps est ignorée.
+func (r *GormAlertRepository) RecordEvaluation(id uint, checkedAt time.Time, firing bool, triggeredAt *time.Time) error {
+	updates := map[string]any{"checked_at": checkedAt, "firing": firing}
+	if triggeredAt != nil {
+		updates["last_triggered_at"] = *triggeredAt
+	}
+	if err := r.db.Model(&models.AlertRule{}).Where("id = ?", id).Updates(updates).Error; err != nil {
+		return fmt.Errorf("failed to record evaluation of alert rule %d: %w", id, err)
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"fmt"
+
+	"github.com/antoine-granier/urlshortener/internal/models"
+	"gorm.io/gorm"
+)
+
+// AuditRepository définit l'accès au journal d'audit.
+type AuditRepository interface {
+	CreateEntry(entry *models.AuditEntry) error
+	ListEntriesByLinkID(linkID uint, limit int) ([]models.AuditEntry, error)
+}
+
+// GormAuditRepository est l'implémentation de AuditRepository utilisant GORM.
+type GormAuditRepository struct {
+	db *gorm.DB
+}
+
+// NewAuditRepository crée et retourne une nouvelle instance de GormAuditRepository.
+func NewAuditRepository(db *gorm.DB) *GormAuditRepository {
+	return &GormAuditRepository{db: db}
+}
+
+// CreateEntry ajoute une entrée au journal d'audit.
+func (r *GormAuditRepository) CreateEntry(entry *models.AuditEntry) error {
+	if err := r.db.Create(entry).Error; err != nil {
+		return fmt.Errorf("failed to create audit entry %s: %w", entry.Action, err)
+	}
+	return nil
+}
+
+// ListEntriesByLinkID retourne les entrées d'audit d'un lien, les plus récentes en premier.
+func (r *GormAuditRepository) ListEntriesByLinkID(linkID uint, limit int) ([]models.AuditEntry, error) {
+	var entries []models.AuditEntry
+	if err := r.db.Where("link_id = ?", linkID).Order("id DESC").Limit(limit).Find(&entries).Error; err != nil {
+		return nil, fmt.Errorf("failed to list audit entries for link %d: %w", linkID, err)
+	}
+	return entries, nil
+}
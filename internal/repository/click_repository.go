@@ -1,23 +1,94 @@
 package repository
 
 import (
+	"errors"
 	"fmt"
+	"sort"
+	"time"
 
 	"github.com/antoine-granier/urlshortener/internal/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // ClickRepository est une interface qui définit les méthodes d'accès aux données
 // pour les opérations sur les clics. Cette abstraction permet à la couche service
 // de rester indépendante de l'implémentation spécifique de la base de données.
+// Les décomptes excluent les clics classés comme robots, sauf sur la vue retournée par IncludingBots.
+// Ils sont lus dans les agrégats horaires et journaliers (voir CompactClicks), complétés par les clics
+// bruts pas encore agrégés : ils restent exacts après la purge des clics bruts.
 type ClickRepository interface {
+	IncludingBots() ClickRepository
 	CreateClick(click *models.Click) error
+	CreateClicks(clicks []*models.Click) error
 	CountClicksByLinkID(linkID uint) (int, error) // Utilisé par LinkService pour les stats
+	CountClicksByDimension(linkID uint, dimension string) (map[string]int, error)
+	CountClicksByLinkIDs(linkIDs []uint) (map[uint]int, error)
+	CountClicksByCampaign(campaignID uint) ([]LinkClickCount, error)
+	CountCampaignClicksByDimension(campaignID uint, dimension string) (map[string]int, error)
+	CountClicksByUTM(filter UTMFilter, field string) (map[string]int, error)
+	CountClicksInRange(linkID uint, from, to time.Time) (int, error)
+
+	CountScopeClicksInRange(scope ClickScope, from, to time.Time) (int, error)
+	CountScopeClicksByDimension(scope ClickScope, dimension string) (map[string]int, error)
+	CountRecentClicks(scope ClickScope, since time.Time) (int, error)
+	CountRecentClicksByCountry(scope ClickScope, since time.Time) (map[string]int, error)
+
+	CompactClicks(batchSize int) (int, error)
+	PurgeRawClicks(before time.Time) (int64, error)
+	EraseClickPersonalData(filter ClickErasureFilter) (int64, error)
+
+	GetVisitorSketches(linkID uint, fromDay, toDay string) ([]models.VisitorSketch, error)
+	MergeVisitorSketch(linkID uint, day string, merge func(existing []byte) ([]byte, error)) error
+}
+
+// UTMFilter restreint les liens pris en compte par CountClicksByUTM. Les critères vides sont ignorés.
+type UTMFilter struct {
+	Owner      string
+	CampaignID uint
+}
+
+// ClickErasureFilter sélectionne les clics dont les données personnelles sont effacées.
+// Les critères renseignés se cumulent.
+type ClickErasureFilter struct {
+	IPAddresses []string  // Valeurs de la colonne ip_address visées
+	Before      time.Time // Clics antérieurs à cette date
+}
+
+// LinkClickCount est le nombre de clics d'un lien, utilisé pour les statistiques de campagne.
+type LinkClickCount struct {
+	LinkID    uint   `json:"-"`
+	ShortCode string `json:"short_code"`
+	LongURL   string `json:"long_url"`
+	Clicks    int    `json:"clicks"`
+	// Conversions est renseigné par le service de suivi des conversions (0 sans suivi).
+	Conversions int `json:"conversions"`
+}
+
+// clickDimensions associe chaque dimension de ventilation des statistiques à sa colonne SQL.
+// Seules ces dimensions sont acceptées, ce qui évite toute injection via le nom de colonne.
+var clickDimensions = map[string]string{
+	"source":  "source",
+	"variant": "variant",
+	"rule":    "rule",
+	"country": "country",
+	"region":  "region",
+	"bot":     "bot_category",
+}
+
+// utmColumns associe chaque paramètre UTM à sa colonne dans la table des liens.
+var utmColumns = map[string]string{
+	"source":   "utm_source",
+	"medium":   "utm_medium",
+	"campaign": "utm_campaign",
+	"term":     "utm_term",
+	"content":  "utm_content",
 }
 
 // GormClickRepository est l'implémentation de l'interface ClickRepository utilisant GORM.
 type GormClickRepository struct {
-	db *gorm.DB // Référence à l'instance de la base de données GORM
+	db          *gorm.DB // Référence à l'instance de la base de données GORM
+	includeBots bool     // Les décomptes incluent les clics classés comme robots
 }
 
 // NewClickRepository crée et retourne une nouvelle instance de GormClickRepository.
@@ -26,6 +97,12 @@ func NewClickRepository(db *gorm.DB) *GormClickRepository {
 	return &GormClickRepository{db: db}
 }
 
+// IncludingBots retourne une vue du dépôt dont les décomptes incluent les clics de robots,
+// exclus par défaut.
+func (r *GormClickRepository) IncludingBots() ClickRepository {
+	return &GormClickRepository{db: r.db, includeBots: true}
+}
+
 // CreateClick insère un nouvel enregistrement de clic dans la base de données.
 // Elle reçoit un pointeur vers une structure models.Click et la persiste en utilisant GORM.
 func (r *GormClickRepository) CreateClick(click *models.Click) error {
@@ -35,16 +112,239 @@ func (r *GormClickRepository) CreateClick(click *models.Click) error {
 	return nil
 }
 
+// CreateClicks insère un lot de clics en une seule requête : le lot est enregistré en entier ou pas du tout.
+func (r *GormClickRepository) CreateClicks(clicks []*models.Click) error {
+	if len(clicks) == 0 {
+		return nil
+	}
+	if err := r.db.Create(clicks).Error; err != nil {
+		return fmt.Errorf("failed to create %d click records: %w", len(clicks), err)
+	}
+	return nil
+}
+
 // CountClicksByLinkID compte le nombre total de clics pour un ID de lien donné.
 // Cette méthode est utilisée pour fournir des statistiques pour une URL courte.
 func (r *GormClickRepository) CountClicksByLinkID(linkID uint) (int, error) {
-	var count int64 // GORM retourne un int64 pour les décomptes
+	counts, err := r.CountClicksByLinkIDs([]uint{linkID})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count clicks for link %d: %w", linkID, err)
+	}
+	return counts[linkID], nil
+}
+
+// CountClicksByDimension compte les clics d'un lien regroupés par valeur d'une dimension (ex: "source").
+// Les clics sans valeur pour cette dimension sont regroupés sous la clé "".
+func (r *GormClickRepository) CountClicksByDimension(linkID uint, dimension string) (map[string]int, error) {
+	counts, err := r.countByDimension(dimension, "link_id = ?", linkID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count clicks by %s for link %d: %w", dimension, linkID, err)
+	}
+	return counts, nil
+}
+
+// CountClicksByLinkIDs compte les clics de plusieurs liens en une requête (ex: une page de liste).
+// Les liens sans clic sont absents de la map retournée.
+func (r *GormClickRepository) CountClicksByLinkIDs(linkIDs []uint) (map[uint]int, error) {
+	counts := make(map[uint]int, len(linkIDs))
+	if len(linkIDs) == 0 {
+		return counts, nil
+	}
+
+	err := r.snapshot(func(tx *gorm.DB, cursor uint64) error {
+		if err := addCounts(counts, r.dailyRollups(tx).
+			Select("link_id AS value, SUM(count) AS count").
+			Where("link_id IN ? AND dimension = ?", linkIDs, rollupTotalDimension).
+			Group("link_id")); err != nil {
+			return err
+		}
+		return addCounts(counts, r.pendingClicks(tx, cursor).
+			Select("link_id AS value, COUNT(*) AS count").
+			Where("link_id IN ?", linkIDs).
+			Group("link_id"))
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to count clicks for %d links: %w", len(linkIDs), err)
+	}
+	return counts, nil
+}
+
+// CountClicksByCampaign compte les clics de chaque lien d'une campagne, liens sans clic compris,
+// du plus cliqué au moins cliqué.
+func (r *GormClickRepository) CountClicksByCampaign(campaignID uint) ([]LinkClickCount, error) {
+	var rows []LinkClickCount
 	if err := r.db.
-		Model(&models.Click{}).
-		Where("link_id = ?", linkID).
-		Count(&count).
+		Model(&models.Link{}).
+		Select("id AS link_id, short_code, long_url").
+		Where("campaign_id = ?", campaignID).
+		Scan(&rows).
 		Error; err != nil {
-		return 0, fmt.Errorf("failed to count clicks for link %d: %w", linkID, err)
+		return nil, fmt.Errorf("failed to count clicks for campaign %d: %w", campaignID, err)
+	}
+
+	ids := make([]uint, len(rows))
+	for i, row := range rows {
+		ids[i] = row.LinkID
 	}
-	return int(count), nil // Convert the int64 count to an int
+	counts, err := r.CountClicksByLinkIDs(ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count clicks for campaign %d: %w", campaignID, err)
+	}
+	for i := range rows {
+		rows[i].Clicks = counts[rows[i].LinkID]
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].Clicks != rows[j].Clicks {
+			return rows[i].Clicks > rows[j].Clicks
+		}
+		return rows[i].LinkID < rows[j].LinkID
+	})
+	return rows, nil
+}
+
+// CountCampaignClicksByDimension compte les clics de l'ensemble des liens d'une campagne,
+// regroupés par valeur d'une dimension (voir CountClicksByDimension).
+func (r *GormClickRepository) CountCampaignClicksByDimension(campaignID uint, dimension string) (map[string]int, error) {
+	counts, err := r.countByDimension(dimension, "link_id IN (SELECT id FROM links WHERE campaign_id = ?)", campaignID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count clicks by %s for campaign %d: %w", dimension, campaignID, err)
+	}
+	return counts, nil
+}
+
+// countByDimension compte les clics des liens sélectionnés par la condition linkCond,
+// regroupés par valeur d'une dimension : agrégats journaliers, plus les clics pas encore agrégés.
+func (r *GormClickRepository) countByDimension(dimension, linkCond string, args ...any) (map[string]int, error) {
+	column, ok := clickDimensions[dimension]
+	if !ok {
+		return nil, fmt.Errorf("unknown click dimension %q", dimension)
+	}
+
+	counts := make(map[string]int)
+	err := r.snapshot(func(tx *gorm.DB, cursor uint64) error {
+		if err := addCounts(counts, r.dailyRollups(tx).
+			Select("value, SUM(count) AS count").
+			Where(linkCond, args...).
+			Where("dimension = ?", dimension).
+			Group("value")); err != nil {
+			return err
+		}
+		return addCounts(counts, r.pendingClicks(tx, cursor).
+			Select("COALESCE("+column+", '') AS value, COUNT(*) AS count").
+			Where(linkCond, args...).
+			Group("value"))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+// CountClicksByUTM compte les clics des liens correspondant au filtre, regroupés par valeur
+// d'un paramètre UTM du lien ("source", "medium", "campaign", "term" ou "content").
+// Les clics des liens sans ce paramètre sont regroupés sous la clé "".
+func (r *GormClickRepository) CountClicksByUTM(filter UTMFilter, field string) (map[string]int, error) {
+	column, ok := utmColumns[field]
+	if !ok {
+		return nil, fmt.Errorf("unknown utm field %q", field)
+	}
+
+	filterLinks := func(query *gorm.DB) *gorm.DB {
+		if filter.Owner != "" {
+			query = query.Where("links.owner = ?", filter.Owner)
+		}
+		if filter.CampaignID != 0 {
+			query = query.Where("links.campaign_id = ?", filter.CampaignID)
+		}
+		return query
+	}
+
+	counts := make(map[string]int)
+	err := r.snapshot(func(tx *gorm.DB, cursor uint64) error {
+		if err := addCounts(counts, filterLinks(r.dailyRollups(tx).
+			Select("COALESCE(links."+column+", '') AS value, SUM(daily_click_rollups.count) AS count").
+			Joins("JOIN links ON links.id = daily_click_rollups.link_id").
+			Where("daily_click_rollups.dimension = ?", rollupTotalDimension)).
+			Group("COALESCE(links."+column+", '')")); err != nil {
+			return err
+		}
+		return addCounts(counts, filterLinks(r.pendingClicks(tx, cursor).
+			Select("COALESCE(links."+column+", '') AS value, COUNT(*) AS count").
+			Joins("JOIN links ON links.id = clicks.link_id")).
+			Group("COALESCE(links."+column+", '')"))
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to count clicks by utm %s: %w", field, err)
+	}
+	return counts, nil
+}
+
+// CountClicksInRange compte les clics d'un lien dont l'horodatage est dans [from, to[,
+// à partir des agrégats horaires : les bornes doivent tomber sur des heures pleines.
+// Une borne zéro n'est pas appliquée.
+func (r *GormClickRepository) CountClicksInRange(linkID uint, from, to time.Time) (int, error) {
+	count, err := r.CountScopeClicksInRange(ClickScope{LinkID: linkID}, from, to)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count clicks in range for link %d: %w", linkID, err)
+	}
+	return count, nil
+}
+
+// EraseClickPersonalData efface l'adresse IP et le User-Agent des clics correspondant au filtre,
+// sans supprimer les clics eux-mêmes. Elle retourne le nombre de clics modifiés.
+func (r *GormClickRepository) EraseClickPersonalData(filter ClickErasureFilter) (int64, error) {
+	query := r.db.Model(&models.Click{}).Where("ip_address <> '' OR user_agent <> ''")
+	if len(filter.IPAddresses) > 0 {
+		query = query.Where("ip_address IN ?", filter.IPAddresses)
+	}
+	if !filter.Before.IsZero() {
+		query = query.Where("timestamp < ?", filter.Before)
+	}
+	res := query.Updates(map[string]any{"ip_address": "", "user_agent": ""})
+	if res.Error != nil {
+		return 0, fmt.Errorf("failed to erase click personal data: %w", res.Error)
+	}
+	return res.RowsAffected, nil
+}
+
+// GetVisitorSketches retourne les sketches de visiteurs d'un lien pour les jours de [fromDay, toDay]
+// (format AAAA-MM-JJ, bornes incluses).
+func (r *GormClickRepository) GetVisitorSketches(linkID uint, fromDay, toDay string) ([]models.VisitorSketch, error) {
+	var sketches []models.VisitorSketch
+	if err := r.db.
+		Where("link_id = ? AND day >= ? AND day <= ?", linkID, fromDay, toDay).
+		Order("day").
+		Find(&sketches).
+		Error; err != nil {
+		return nil, fmt.Errorf("failed to retrieve visitor sketches for link %d: %w", linkID, err)
+	}
+	return sketches, nil
+}
+
+// MergeVisitorSketch met à jour, dans une transaction, le sketch de visiteurs d'un lien pour un jour :
+// merge reçoit l'encodage existant (nil s'il n'y en a pas) et retourne l'encodage à enregistrer.
+func (r *GormClickRepository) MergeVisitorSketch(linkID uint, day string, merge func(existing []byte) ([]byte, error)) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var sketch models.VisitorSketch
+		err := tx.Where("link_id = ? AND day = ?", linkID, day).First(&sketch).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		merged, err := merge(sketch.Sketch)
+		if err != nil {
+			return err
+		}
+		if sketch.ID != 0 {
+			return tx.Model(&sketch).Update("sketch", merged).Error
+		}
+		sketch = models.VisitorSketch{LinkID: linkID, Day: day, Sketch: merged}
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "link_id"}, {Name: "day"}},
+			DoUpdates: clause.AssignmentColumns([]string{"sketch", "updated_at"}),
+		}).Create(&sketch).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to merge visitor sketch for link %d on %s: %w", linkID, day, err)
+	}
+	return nil
 }
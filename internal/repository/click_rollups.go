@@ -0,0 +1,216 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/antoine-granier/urlshortener/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// rollupCursor est le nom de la séquence qui mémorise l'ID du dernier clic agrégé.
+// SQLite sérialise les écritures : les clics sont visibles dans l'ordre de leurs ID,
+// aucun clic ne peut donc apparaître sous le curseur après son avancée.
+const rollupCursor = "click_rollups"
+
+// rollupTotalDimension est la dimension sur laquelle sont lus les totaux : chaque clic étant
+// agrégé une fois par dimension, n'importe laquelle donne le nombre de clics.
+const rollupTotalDimension = "source"
+
+// rollupInsertBatch borne le nombre de lignes par INSERT (limite de variables de SQLite).
+const rollupInsertBatch = 500
+
+// hourlyRollupKey et dailyRollupKey identifient une ligne d'agrégat pendant la compaction.
+type hourlyRollupKey struct {
+	linkID           uint
+	hour             time.Time
+	dimension, value string
+	bot              bool
+}
+
+type dailyRollupKey struct {
+	linkID           uint
+	day              string
+	dimension, value string
+	bot              bool
+}
+
+// CompactClicks agrège dans les tables horaires et journalières jusqu'à batchSize clics pas encore agrégés,
+// puis avance le curseur, le tout dans une transaction. Elle retourne le nombre de clics agrégés.
+func (r *GormClickRepository) CompactClicks(batchSize int) (int, error) {
+	compacted := 0
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		cursor, err := rollupCursorValue(tx)
+		if err != nil {
+			return err
+		}
+
+		var clicks []models.Click
+		if err := tx.
+			Select("id", "link_id", "timestamp", "source", "variant", "rule", "country", "region", "bot", "bot_category").
+			Where("id > ?", cursor).
+			Order("id").
+			Limit(batchSize).
+			Find(&clicks).
+			Error; err != nil {
+			return err
+		}
+		if len(clicks) == 0 {
+			return nil
+		}
+
+		hourly := make(map[hourlyRollupKey]int64)
+		daily := make(map[dailyRollupKey]int64)
+		for _, click := range clicks {
+			hour := click.Timestamp.UTC().Truncate(time.Hour)
+			day := hour.Format(time.DateOnly)
+			for dimension, value := range clickDimensionValues(&click) {
+				hourly[hourlyRollupKey{click.LinkID, hour, dimension, value, click.Bot}]++
+				daily[dailyRollupKey{click.LinkID, day, dimension, value, click.Bot}]++
+			}
+		}
+
+		hourlyRows := make([]models.HourlyClickRollup, 0, len(hourly))
+		for k, count := range hourly {
+			hourlyRows = append(hourlyRows, models.HourlyClickRollup{
+				LinkID: k.linkID, Hour: k.hour, Dimension: k.dimension, Value: k.value, Bot: k.bot, Count: count,
+			})
+		}
+		if err := tx.Clauses(rollupUpsert("hourly_click_rollups", "hour")).
+			CreateInBatches(hourlyRows, rollupInsertBatch).Error; err != nil {
+			return err
+		}
+
+		dailyRows := make([]models.DailyClickRollup, 0, len(daily))
+		for k, count := range daily {
+			dailyRows = append(dailyRows, models.DailyClickRollup{
+				LinkID: k.linkID, Day: k.day, Dimension: k.dimension, Value: k.value, Bot: k.bot, Count: count,
+			})
+		}
+		if err := tx.Clauses(rollupUpsert("daily_click_rollups", "day")).
+			CreateInBatches(dailyRows, rollupInsertBatch).Error; err != nil {
+			return err
+		}
+
+		compacted = len(clicks)
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "name"}},
+			DoUpdates: clause.AssignmentColumns([]string{"value"}),
+		}).Create(&models.Sequence{Name: rollupCursor, Value: uint64(clicks[len(clicks)-1].ID)}).Error
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to compact clicks: %w", err)
+	}
+	return compacted, nil
+}
+
+// PurgeRawClicks supprime les clics bruts antérieurs à before, en se limitant aux clics déjà agrégés :
+// les statistiques restent disponibles via les agrégats. Elle retourne le nombre de clics supprimés.
+func (r *GormClickRepository) PurgeRawClicks(before time.Time) (int64, error) {
+	var purged int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		cursor, err := rollupCursorValue(tx)
+		if err != nil {
+			return err
+		}
+		res := tx.Where("id <= ? AND timestamp < ?", cursor, before).Delete(&models.Click{})
+		purged = res.RowsAffected
+		return res.Error
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge raw clicks before %s: %w", before.Format(time.RFC3339), err)
+	}
+	return purged, nil
+}
+
+// clickDimensionValues retourne la valeur du clic pour chaque dimension de clickDimensions.
+func clickDimensionValues(click *models.Click) map[string]string {
+	return map[string]string{
+		"source":  click.Source,
+		"variant": click.Variant,
+		"rule":    click.Rule,
+		"country": click.Country,
+		"region":  click.Region,
+		"bot":     click.BotCategory,
+	}
+}
+
+// rollupUpsert ajoute les décomptes d'un lot aux lignes d'agrégat existantes.
+func rollupUpsert(table, bucket string) clause.OnConflict {
+	return clause.OnConflict{
+		Columns: []clause.Column{{Name: "link_id"}, {Name: bucket}, {Name: "dimension"}, {Name: "value"}, {Name: "bot"}},
+		DoUpdates: clause.Assignments(map[string]any{
+			"count": gorm.Expr(table + ".count + excluded.count"),
+		}),
+	}
+}
+
+// rollupCursorValue lit l'ID du dernier clic agrégé (0 avant la première compaction).
+func rollupCursorValue(tx *gorm.DB) (uint64, error) {
+	var sequence models.Sequence
+	err := tx.Where("name = ?", rollupCursor).First(&sequence).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read rollup cursor: %w", err)
+	}
+	return sequence.Value, nil
+}
+
+// snapshot exécute read dans une transaction, pour que les agrégats et le curseur soient lus de façon cohérente
+// avec une compaction concurrente. Les clics d'ID supérieur au curseur ne sont pas encore agrégés :
+// les décomptes les lisent dans la table brute (voir pendingClicks).
+func (r *GormClickRepository) snapshot(read func(tx *gorm.DB, cursor uint64) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		cursor, err := rollupCursorValue(tx)
+		if err != nil {
+			return err
+		}
+		return read(tx, cursor)
+	})
+}
+
+// dailyRollups retourne la requête de base sur les agrégats journaliers, sans les robots sauf avec IncludingBots.
+func (r *GormClickRepository) dailyRollups(tx *gorm.DB) *gorm.DB {
+	query := tx.Model(&models.DailyClickRollup{})
+	if !r.includeBots {
+		query = query.Where("daily_click_rollups.bot = ?", false)
+	}
+	return query
+}
+
+// hourlyRollups retourne la requête de base sur les agrégats horaires, sans les robots sauf avec IncludingBots.
+func (r *GormClickRepository) hourlyRollups(tx *gorm.DB) *gorm.DB {
+	query := tx.Model(&models.HourlyClickRollup{})
+	if !r.includeBots {
+		query = query.Where("hourly_click_rollups.bot = ?", false)
+	}
+	return query
+}
+
+// pendingClicks retourne la requête de base sur les clics bruts pas encore agrégés.
+func (r *GormClickRepository) pendingClicks(tx *gorm.DB, cursor uint64) *gorm.DB {
+	query := tx.Model(&models.Click{}).Where("clicks.id > ?", cursor)
+	if !r.includeBots {
+		query = query.Where("clicks.bot = ?", false)
+	}
+	return query
+}
+
+// addCounts ajoute à counts les décomptes d'une requête sélectionnant les colonnes "value" et "count".
+func addCounts[K comparable](counts map[K]int, query *gorm.DB) error {
+	var rows []struct {
+		Value K
+		Count int64
+	}
+	if err := query.Scan(&rows).Error; err != nil {
+		return err
+	}
+	for _, row := range rows {
+		counts[row.Value] += int(row.Count)
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/antoine-granier/urlshortener/internal/models"
+)
+
+// clickStats regroupe les décomptes servis par le dépôt, pour comparer leur valeur avant et après compaction.
+type clickStats struct {
+	Totals        map[uint]int
+	WithBots      map[uint]int
+	Sources       map[string]int
+	Countries     map[string]int
+	BotCategories map[string]int
+	CampaignRange int
+	Hours         []int
+	Days          []int
+}
+
+var rollupStart = time.Date(2026, 2, 27, 22, 0, 0, 0, time.UTC)
+
+func readClickStats(t *testing.T, repo *GormClickRepository, linkIDs []uint, campaignID uint) clickStats {
+	t.Helper()
+	var stats clickStats
+	var err error
+	must := func(e error) {
+		t.Helper()
+		if e != nil {
+			t.Fatal(e)
+		}
+	}
+	stats.Totals, err = repo.CountClicksByLinkIDs(linkIDs)
+	must(err)
+	stats.WithBots, err = repo.IncludingBots().CountClicksByLinkIDs(linkIDs)
+	must(err)
+	stats.Sources, err = repo.CountClicksByDimension(linkIDs[0], "source")
+	must(err)
+	stats.Countries, err = repo.CountCampaignClicksByDimension(campaignID, "country")
+	must(err)
+	stats.BotCategories, err = repo.IncludingBots().CountClicksByDimension(linkIDs[0], "bot")
+	must(err)
+	stats.CampaignRange, err = repo.CountScopeClicksInRange(ClickScope{CampaignID: campaignID}, rollupStart.Add(time.Hour), rollupStart.Add(5*time.Hour))
+	must(err)
+	for h := 0; h < 6; h++ {
+		count, err := repo.CountClicksInRange(linkIDs[0], rollupStart.Add(time.Duration(h)*time.Hour), rollupStart.Add(time.Duration(h+1)*time.Hour))
+		must(err)
+		stats.Hours = append(stats.Hours, count)
+	}
+	// Les journées UTC du 27 et du 28 février, de part et d'autre de minuit.
+	for _, day := range []time.Time{rollupStart.Add(-22 * time.Hour), rollupStart.Add(2 * time.Hour)} {
+		count, err := repo.CountClicksInRange(linkIDs[0], day, day.Add(24*time.Hour))
+		must(err)
+		stats.Days = append(stats.Days, count)
+	}
+	return stats
+}
+
+func TestCompactAndPurgeKeepStats(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.Link{}, &models.Click{}, &models.Sequence{},
+		&models.HourlyClickRollup{}, &models.DailyClickRollup{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	campaign := models.Campaign{Owner: "alice", Name: "spring"}
+	if err := db.Create(&campaign).Error; err != nil {
+		t.Fatalf("create campaign: %v", err)
+	}
+	links := []models.Link{
+		{ShortCode: "first", LongURL: "https://example.com/1", CampaignID: &campaign.ID},
+		{ShortCode: "second", LongURL: "https://example.com/2", CampaignID: &campaign.ID},
+		{ShortCode: "other", LongURL: "https://example.com/3"},
+	}
+	if err := db.Create(&links).Error; err != nil {
+		t.Fatalf("create links: %v", err)
+	}
+	linkIDs := []uint{links[0].ID, links[1].ID, links[2].ID}
+
+	// 40 clics sur six heures autour de minuit, répartis entre les liens, sources, pays et robots.
+	sources := []string{"", "qr", "email"}
+	countries := []string{"FR", "US", "", "DE"}
+	for i := 0; i < 40; i++ {
+		click := models.Click{
+			LinkID:    linkIDs[i%len(linkIDs)],
+			Timestamp: rollupStart.Add(time.Duration(i*9) * time.Minute),
+			Source:    sources[i%len(sources)],
+			Country:   countries[i%len(countries)],
+		}
+		if i%7 == 0 {
+			click.Bot, click.BotCategory = true, "preview"
+		}
+		if err := db.Create(&click).Error; err != nil {
+			t.Fatalf("create click: %v", err)
+		}
+	}
+
+	repo := NewClickRepository(db)
+	want := readClickStats(t, repo, linkIDs, campaign.ID)
+	if want.WithBots[linkIDs[0]] != 14 || want.Totals[linkIDs[0]] != 12 {
+		t.Fatalf("raw totals = %v (with bots %v), want 12 (14) for the first link", want.Totals, want.WithBots)
+	}
+
+	purgeAll := rollupStart.Add(24 * time.Hour)
+	check := func(step string) {
+		t.Helper()
+		if got := readClickStats(t, repo, linkIDs, campaign.ID); !reflect.DeepEqual(got, want) {
+			t.Errorf("after %s:\n got  %+v\n want %+v", step, got, want)
+		}
+	}
+
+	// Compaction partielle : le curseur s'arrête au milieu des clics.
+	if n, err := repo.CompactClicks(17); err != nil || n != 17 {
+		t.Fatalf("CompactClicks(17) = %d, %v; want 17", n, err)
+	}
+	check("partial compaction")
+
+	// La purge ne supprime que les clics agrégés (ID <= curseur), y compris celui du curseur.
+	if n, err := repo.PurgeRawClicks(purgeAll); err != nil || n != 17 {
+		t.Fatalf("PurgeRawClicks = %d, %v; want 17", n, err)
+	}
+	check("purge below the cursor")
+	var remaining int64
+	db.Model(&models.Click{}).Count(&remaining)
+	if remaining != 23 {
+		t.Errorf("%d raw clicks left, want 23", remaining)
+	}
+
+	// Nouveau clic pendant la compaction suivante : compté une seule fois, brut puis agrégé.
+	late := models.Click{LinkID: linkIDs[0], Timestamp: rollupStart.Add(30 * time.Minute), Source: "qr", Country: "FR"}
+	if err := db.Create(&late).Error; err != nil {
+		t.Fatalf("create click: %v", err)
+	}
+	want.Totals[linkIDs[0]]++
+	want.WithBots[linkIDs[0]]++
+	want.Sources["qr"]++
+	want.Countries["FR"]++
+	want.BotCategories[""]++
+	want.Hours[0]++
+	want.Days[0]++
+	check("a new raw click")
+
+	for {
+		n, err := repo.CompactClicks(10)
+		if err != nil {
+			t.Fatalf("CompactClicks: %v", err)
+		}
+		if n == 0 {
+			break
+		}
+		check("incremental compaction")
+	}
+	if n, err := repo.PurgeRawClicks(purgeAll); err != nil || n != 24 {
+		t.Fatalf("PurgeRawClicks = %d, %v; want 24", n, err)
+	}
+	check("full purge")
+}
@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ClickScope désigne les liens dont les clics sont comptés ensemble : un lien (LinkID)
+// ou tous les liens d'une campagne (CampaignID).
+type ClickScope struct {
+	LinkID     uint
+	CampaignID uint
+}
+
+// condition retourne la condition SQL sur link_id sélectionnant les clics du périmètre.
+func (s ClickScope) condition() (string, any) {
+	if s.CampaignID != 0 {
+		return "link_id IN (SELECT id FROM links WHERE campaign_id = ?)", s.CampaignID
+	}
+	return "link_id = ?", s.LinkID
+}
+
+// String retourne une description du périmètre pour les messages d'erreur.
+func (s ClickScope) String() string {
+	if s.CampaignID != 0 {
+		return fmt.Sprintf("campaign %d", s.CampaignID)
+	}
+	return fmt.Sprintf("link %d", s.LinkID)
+}
+
+// CountScopeClicksInRange compte les clics du périmètre dont l'horodatage est dans [from, to[,
+// à partir des agrégats horaires : les bornes doivent tomber sur des heures pleines.
+// Une borne zéro n'est pas appliquée.
+func (r *GormClickRepository) CountScopeClicksInRange(scope ClickScope, from, to time.Time) (int, error) {
+	cond, arg := scope.condition()
+	counts := make(map[uint]int)
+	err := r.snapshot(func(tx *gorm.DB, cursor uint64) error {
+		rollups := r.hourlyRollups(tx).
+			Select("link_id AS value, SUM(count) AS count").
+			Where(cond, arg).
+			Where("dimension = ?", rollupTotalDimension)
+		pending := r.pendingClicks(tx, cursor).
+			Select("link_id AS value, COUNT(*) AS count").
+			Where(cond, arg)
+		if !from.IsZero() {
+			rollups = rollups.Where("hour >= ?", from.UTC())
+			pending = pending.Where("timestamp >= ?", from.UTC())
+		}
+		if !to.IsZero() {
+			rollups = rollups.Where("hour < ?", to.UTC())
+			pending = pending.Where("timestamp < ?", to.UTC())
+		}
+		if err := addCounts(counts, rollups.Group("link_id")); err != nil {
+			return err
+		}
+		return addCounts(counts, pending.Group("link_id"))
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count clicks in range for %s: %w", scope, err)
+	}
+	total := 0
+	for _, count := range counts {
+		total += count
+	}
+	return total, nil
+}
+
+// CountScopeClicksByDimension compte tous les clics du périmètre regroupés par valeur d'une dimension
+// (voir CountClicksByDimension).
+func (r *GormClickRepository) CountScopeClicksByDimension(scope ClickScope, dimension string) (map[string]int, error) {
+	cond, arg := scope.condition()
+	counts, err := r.countByDimension(dimension, cond, arg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count clicks by %s for %s: %w", dimension, scope, err)
+	}
+	return counts, nil
+}
+
+// CountRecentClicks compte les clics du périmètre postérieurs à since, à la minute près.
+// Les clics bruts étant la source, since doit rester dans la durée de conservation des clics bruts.
+func (r *GormClickRepository) CountRecentClicks(scope ClickScope, since time.Time) (int, error) {
+	cond, arg := scope.condition()
+	var count int64
+	if err := r.rawClicks().Where(cond, arg).Where("timestamp >= ?", since.UTC()).Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("failed to count recent clicks for %s: %w", scope, err)
+	}
+	return int(count), nil
+}
+
+// CountRecentClicksByCountry compte les clics du périmètre postérieurs à since, regroupés par pays
+// (clics bruts, comme CountRecentClicks). Les clics sans pays sont regroupés sous la clé "".
+func (r *GormClickRepository) CountRecentClicksByCountry(scope ClickScope, since time.Time) (map[string]int, error) {
+	cond, arg := scope.condition()
+	counts := make(map[string]int)
+	if err := addCounts(counts, r.rawClicks().
+		Select("COALESCE(country, '') AS value, COUNT(*) AS count").
+		Where(cond, arg).
+		Where("timestamp >= ?", since.UTC()).
+		Group("value")); err != nil {
+		return nil, fmt.Errorf("failed to count recent clicks by country for %s: %w", scope, err)
+	}
+	return counts, nil
+}
+
+// rawClicks retourne la requête de base sur la table des clics bruts, sans les robots sauf avec IncludingBots.
+func (r *GormClickRepository) rawClicks() *gorm.DB {
+	return r.pendingClicks(r.db, 0)
+}
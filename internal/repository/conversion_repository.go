@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/antoine-granier/urlshortener/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ConversionRepository définit l'accès aux conversions et aux clics auxquels elles sont rattachées.
+type ConversionRepository interface {
+	GetClickByClickID(clickID string) (*models.Click, error)
+	CreateConversion(conversion *models.Conversion) (bool, error)
+	GetConversion(clickID, orderID string) (*models.Conversion, error)
+	CountConversions(scope ClickScope, from, to time.Time) (ConversionTotals, error)
+	CountConversionsByLink(scope ClickScope, from, to time.Time) (map[uint]int, error)
+}
+
+// ConversionTotals est le nombre de conversions d'un périmètre et leur montant par devise.
+type ConversionTotals struct {
+	Conversions int
+	Value       map[string]float64 // Montant cumulé par devise ("" pour les montants sans devise)
+}
+
+// GormConversionRepository est l'implémentation de ConversionRepository utilisant GORM.
+type GormConversionRepository struct {
+	db *gorm.DB
+}
+
+// NewConversionRepository crée et retourne une nouvelle instance de GormConversionRepository.
+func NewConversionRepository(db *gorm.DB) *GormConversionRepository {
+	return &GormConversionRepository{db: db}
+}
+
+// GetClickByClickID récupère le clic portant un identifiant de clic.
+// Il renvoie gorm.ErrRecordNotFound si aucun clic ne le porte (identifiant inconnu ou clic brut purgé).
+func (r *GormConversionRepository) GetClickByClickID(clickID string) (*models.Click, error) {
+	var click models.Click
+	if err := r.db.Where("click_id = ?", clickID).First(&click).Error; err != nil {
+		return nil, fmt.Errorf("failed to find click %q: %w", clickID, err)
+	}
+	return &click, nil
+}
+
+// CreateConversion enregistre une conversion. Elle retourne false si la conversion
+// (même clic et même commande) était déjà enregistrée.
+func (r *GormConversionRepository) CreateConversion(conversion *models.Conversion) (bool, error) {
+	res := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(conversion)
+	if res.Error != nil {
+		return false, fmt.Errorf("failed to create conversion for click %q: %w", conversion.ClickID, res.Error)
+	}
+	return res.RowsAffected == 1, nil
+}
+
+// GetConversion récupère la conversion d'un clic pour une commande.
+func (r *GormConversionRepository) GetConversion(clickID, orderID string) (*models.Conversion, error) {
+	var conversion models.Conversion
+	if err := r.db.Where("click_id = ? AND order_id = ?", clickID, orderID).First(&conversion).Error; err != nil {
+		return nil, fmt.Errorf("failed to find conversion of click %q: %w", clickID, err)
+	}
+	return &conversion, nil
+}
+
+// CountConversions compte les conversions du périmètre enregistrées dans [from, to[ et cumule leurs montants
+// par devise. Une borne zéro n'est pas appliquée.
+func (r *GormConversionRepository) CountConversions(scope ClickScope, from, to time.Time) (ConversionTotals, error) {
+	var rows []struct {
+		Currency    string
+		Conversions int64
+		Value       float64
+	}
+	err := r.conversions(scope, from, to).
+		Select("currency, COUNT(*) AS conversions, SUM(value) AS value").
+		Group("currency").
+		Scan(&rows).Error
+	if err != nil {
+		return ConversionTotals{}, fmt.Errorf("failed to count conversions for %s: %w", scope, err)
+	}
+
+	totals := ConversionTotals{Value: make(map[string]float64)}
+	for _, row := range rows {
+		totals.Conversions += int(row.Conversions)
+		if row.Value != 0 {
+			totals.Value[row.Currency] += row.Value
+		}
+	}
+	return totals, nil
+}
+
+// CountConversionsByLink compte les conversions du périmètre enregistrées dans [from, to[, par lien.
+// Les liens sans conversion sont absents de la map retournée.
+func (r *GormConversionRepository) CountConversionsByLink(scope ClickScope, from, to time.Time) (map[uint]int, error) {
+	counts := make(map[uint]int)
+	if err := addCounts(counts, r.conversions(scope, from, to).
+		Select("link_id AS value, COUNT(*) AS count").
+		Group("link_id")); err != nil {
+		return nil, fmt.Errorf("failed to count conversions by link for %s: %w", scope, err)
+	}
+	return counts, nil
+}
+
+// conversions retourne la requête de base sur les conversions du périmètre enregistrées dans [from, to[.
+func (r *GormConversionRepository) conversions(scope ClickScope, from, to time.Time) *gorm.DB {
+	cond, arg := scope.condition()
+	query := r.db.Model(&models.Conversion{}).Where(cond, arg)
+	if !from.IsZero() {
+		query = query.Where("created_at >= ?", from.UTC())
+	}
+	if !to.IsZero() {
+		query = query.Where("created_at < ?", to.UTC())
+	}
+	return query
+}
+
+// IsUnknownClick indique si err signale un identifiant de clic inconnu (voir GetClickByClickID).
+func IsUnknownClick(err error) bool {
+	return errors.Is(err, gorm.ErrRecordNotFound)
+}
@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"fmt"
+
+	"github.com/antoine-granier/urlshortener/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LinkFilter décrit une recherche paginée de liens. Les critères vides sont ignorés.
+type LinkFilter struct {
+	Owner    string // Propriétaire exact
+	Tag      string // Nom (normalisé) d'un tag porté par le lien
+	Campaign string // Nom de la campagne du lien
+	Limit    int
+	Offset   int
+}
+
+// ListLinks retourne une page de liens correspondant au filtre, du plus récent au plus ancien,
+// avec leurs tags et leur campagne, ainsi que le nombre total de liens correspondants.
+func (r *GormLinkRepository) ListLinks(filter LinkFilter) ([]models.Link, int64, error) {
+	query := r.db.Model(&models.Link{})
+	if filter.Owner != "" {
+		query = query.Where("links.owner = ?", filter.Owner)
+	}
+	if filter.Tag != "" {
+		query = query.Where(
+			"EXISTS (SELECT 1 FROM link_tags JOIN tags ON tags.id = link_tags.tag_id WHERE link_tags.link_id = links.id AND tags.name = ?)",
+			filter.Tag)
+	}
+	if filter.Campaign != "" {
+		query = query.Where("links.campaign_id IN (SELECT id FROM campaigns WHERE name = ?)", filter.Campaign)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count links: %w", err)
+	}
+
+	var links []models.Link
+	if err := query.
+		Preload("Tags", func(db *gorm.DB) *gorm.DB { return db.Order("name") }).
+		Preload("Campaign").
+		Order("links.id DESC").
+		Limit(filter.Limit).
+		Offset(filter.Offset).
+		Find(&links).
+		Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list links: %w", err)
+	}
+	return links, total, nil
+}
+
+// FindOrCreateTags retourne les tags portant les noms donnés, en créant ceux qui n'existent pas encore.
+// Les créations concurrentes d'un même tag sont arbitrées par l'index unique sur le nom.
+func (r *GormLinkRepository) FindOrCreateTags(names []string) ([]models.Tag, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	var tags []models.Tag
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		missing := make([]models.Tag, len(names))
+		for i, name := range names {
+			missing[i] = models.Tag{Name: name}
+		}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&missing).Error; err != nil {
+			return err
+		}
+		return tx.Where("name IN ?", names).Order("name").Find(&tags).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find or create tags: %w", err)
+	}
+	return tags, nil
+}
+
+// ReplaceTags remplace l'ensemble des tags d'un lien. Une liste vide retire tous les tags.
+func (r *GormLinkRepository) ReplaceTags(linkID uint, tags []models.Tag) error {
+	association := r.db.Model(&models.Link{ID: linkID}).Association("Tags")
+	var err error
+	if len(tags) == 0 {
+		err = association.Clear()
+	} else {
+		err = association.Replace(tags)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to replace tags for link %d: %w", linkID, err)
+	}
+	return nil
+}
+
+// FindOrCreateCampaign retourne la campagne d'un propriétaire portant ce nom, en la créant si besoin.
+func (r *GormLinkRepository) FindOrCreateCampaign(owner, name string) (*models.Campaign, error) {
+	campaign := models.Campaign{Owner: owner, Name: name}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&campaign).Error; err != nil {
+			return err
+		}
+		return tx.First(&campaign, "owner = ? AND name = ?", owner, name).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find or create campaign %q for owner %q: %w", name, owner, err)
+	}
+	return &campaign, nil
+}
+
+// GetCampaign récupère la campagne d'un propriétaire via son nom.
+// Il renvoie gorm.ErrRecordNotFound si elle n'existe pas.
+func (r *GormLinkRepository) GetCampaign(owner, name string) (*models.Campaign, error) {
+	var campaign models.Campaign
+	if err := r.db.First(&campaign, "owner = ? AND name = ?", owner, name).Error; err != nil {
+		return nil, fmt.Errorf("failed to find campaign %q for owner %q: %w", name, owner, err)
+	}
+	return &campaign, nil
+}
@@ -1,21 +1,44 @@
 package repository
 
 import (
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/antoine-granier/urlshortener/internal/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // LinkRepository est une interface qui définit les méthodes d'accès aux données
 // pour les opérations CRUD sur les liens.
 type LinkRepository interface {
 	CreateLink(link *models.Link) error
+	UpdateLink(link *models.Link) error
+	ConsumeLink(id uint, at time.Time) (bool, error)
+	DeleteLink(id uint) error
+	ReplaceVariants(linkID uint, variants []models.LinkVariant) error
 	GetLinkByShortCode(shortCode string) (*models.Link, error)
+	GetLinkByID(id uint) (*models.Link, error)
+	ListLinksByCanonicalURL(owner, canonicalURL string) ([]models.Link, error)
 	GetAllLinks() ([]models.Link, error)
-	CountClicksByLinkID(linkID uint) (int, error)
+	ListLinks(filter LinkFilter) ([]models.Link, int64, error)
+
+	FindOrCreateTags(names []string) ([]models.Tag, error)
+	ReplaceTags(linkID uint, tags []models.Tag) error
+	FindOrCreateCampaign(owner, name string) (*models.Campaign, error)
+	GetCampaign(owner, name string) (*models.Campaign, error)
+
+	SaveUTMPreset(preset *models.UTMPreset) error
+	GetUTMPreset(owner, name string) (*models.UTMPreset, error)
+	ListUTMPresets(owner string) ([]models.UTMPreset, error)
+	DeleteUTMPreset(owner, name string) error
 }
 
+// ErrDuplicateShortCode est retournée par CreateLink quand le code court viole l'index unique.
+// La couche service s'en sert pour retenter avec un autre code (insert-and-retry).
+var ErrDuplicateShortCode = errors.New("short code already exists")
+
 // GormLinkRepository est l'implémentation de LinkRepository utilisant GORM.
 type GormLinkRepository struct {
 	db *gorm.DB
@@ -28,25 +51,136 @@ func NewLinkRepository(db *gorm.DB) *GormLinkRepository {
 }
 
 // CreateLink insère un nouveau lien dans la base de données.
+// L'unicité du code court est garantie par l'index unique : une violation est signalée par ErrDuplicateShortCode.
 func (r *GormLinkRepository) CreateLink(link *models.Link) error {
 	if err := r.db.Create(link).Error; err != nil {
+		if isDuplicateKey(r.db, err) {
+			return fmt.Errorf("failed to create link record %s: %w", link.ShortCode, ErrDuplicateShortCode)
+		}
 		return fmt.Errorf("failed to create link record: %w", err)
 	}
 	return nil
 }
 
+// UpdateLink enregistre toutes les colonnes d'un lien existant (ses associations ne sont pas modifiées).
+func (r *GormLinkRepository) UpdateLink(link *models.Link) error {
+	if err := r.db.Omit(clause.Associations).Save(link).Error; err != nil {
+		return fmt.Errorf("failed to update link %d: %w", link.ID, err)
+	}
+	return nil
+}
+
+// ConsumeLink marque un lien à usage unique comme utilisé, s'il ne l'est pas déjà.
+// La condition sur consumed_at rend l'opération atomique : un seul appelant obtient true.
+func (r *GormLinkRepository) ConsumeLink(id uint, at time.Time) (bool, error) {
+	res := r.db.Model(&models.Link{}).
+		Where("id = ? AND consumed_at IS NULL", id).
+		Update("consumed_at", at)
+	if res.Error != nil {
+		return false, fmt.Errorf("failed to consume link %d: %w", id, res.Error)
+	}
+	return res.RowsAffected == 1, nil
+}
+
+// DeleteLink supprime un lien et, dans la même transaction, les données qui lui sont rattachées : variantes,
+// règles de ciblage, tags, informations de page, changements programmés, clics, agrégats, visiteurs uniques,
+// paliers de clics, conversions et règles d'alerte. Le journal d'audit et les livraisons de webhooks sont conservés.
+// Retourne gorm.ErrRecordNotFound si le lien n'existe pas.
+func (r *GormLinkRepository) DeleteLink(id uint) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Delete(&models.Link{}, id)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		if err := tx.Model(&models.Link{ID: id}).Association("Tags").Clear(); err != nil {
+			return err
+		}
+		for _, dependent := range []any{
+			&models.LinkVariant{}, &models.LinkMetadata{}, &models.ScheduledChange{},
+			&models.Click{}, &models.HourlyClickRollup{}, &models.DailyClickRollup{}, &models.VisitorSketch{},
+			&models.ClickThreshold{}, &models.Conversion{}, &models.AlertRule{},
+		} {
+			if err := tx.Where("link_id = ?", id).Delete(dependent).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete link %d: %w", id, err)
+	}
+	return nil
+}
+
+// ReplaceVariants remplace, dans une transaction, l'ensemble des variantes A/B d'un lien.
+// Une liste vide supprime toutes les variantes.
+func (r *GormLinkRepository) ReplaceVariants(linkID uint, variants []models.LinkVariant) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("link_id = ?", linkID).Delete(&models.LinkVariant{}).Error; err != nil {
+			return err
+		}
+		if len(variants) == 0 {
+			return nil
+		}
+		for i := range variants {
+			variants[i].ID = 0
+			variants[i].LinkID = linkID
+		}
+		return tx.Create(&variants).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to replace variants for link %d: %w", linkID, err)
+	}
+	return nil
+}
+
 // GetLinkByShortCode récupère un lien de la base de données en utilisant son shortCode.
 // Il renvoie gorm.ErrRecordNotFound si aucun lien n'est trouvé avec ce shortCode.
 func (r *GormLinkRepository) GetLinkByShortCode(shortCode string) (*models.Link, error) {
 	var link models.Link
-	if err := r.db.
-		First(&link, "short_code = ?", shortCode).
-		Error; err != nil {
+	if err := r.withRedirectRules().First(&link, "short_code = ?", shortCode).Error; err != nil {
 		return nil, fmt.Errorf("failed to find link by code %s: %w", shortCode, err)
 	}
 	return &link, nil
 }
 
+// GetLinkByID récupère un lien via son identifiant, avec les mêmes associations que GetLinkByShortCode.
+// Il renvoie gorm.ErrRecordNotFound si aucun lien ne porte cet identifiant.
+func (r *GormLinkRepository) GetLinkByID(id uint) (*models.Link, error) {
+	var link models.Link
+	if err := r.withRedirectRules().First(&link, id).Error; err != nil {
+		return nil, fmt.Errorf("failed to find link %d: %w", id, err)
+	}
+	return &link, nil
+}
+
+// withRedirectRules précharge les variantes et les informations de page
+// utiles à la redirection (et aux cartes de partage), ainsi que les tags et la campagne du lien.
+func (r *GormLinkRepository) withRedirectRules() *gorm.DB {
+	return r.db.
+		Preload("Tags", func(db *gorm.DB) *gorm.DB { return db.Order("name") }).
+		Preload("Campaign").
+		Preload("Metadata").
+		Preload("Variants", func(db *gorm.DB) *gorm.DB { return db.Order("id") })
+}
+
+// ListLinksByCanonicalURL récupère, du plus ancien au plus récent, les liens d'un propriétaire
+// pour une URL canonique donnée, avec les mêmes associations que GetLinkByShortCode.
+func (r *GormLinkRepository) ListLinksByCanonicalURL(owner, canonicalURL string) ([]models.Link, error) {
+	var links []models.Link
+	if err := r.withRedirectRules().
+		Where("owner = ? AND canonical_url = ?", owner, canonicalURL).
+		Order("id").
+		Find(&links).
+		Error; err != nil {
+		return nil, fmt.Errorf("failed to list links by canonical url for owner %q: %w", owner, err)
+	}
+	return links, nil
+}
+
 // GetAllLinks récupère tous les liens de la base de données.
 // Cette méthode est utilisée par le moniteur d'URLs.
 func (r *GormLinkRepository) GetAllLinks() ([]models.Link, error) {
@@ -57,15 +191,14 @@ func (r *GormLinkRepository) GetAllLinks() ([]models.Link, error) {
 	return links, nil
 }
 
-// CountClicksByLinkID compte le nombre total de clics pour un ID de lien donné.
-func (r *GormLinkRepository) CountClicksByLinkID(linkID uint) (int, error) {
-	var count int64 // GORM retourne un int64 pour les comptes
-	if err := r.db.
-		Model(&models.Click{}).
-		Where("link_id = ?", linkID).
-		Count(&count).
-		Error; err != nil {
-		return 0, fmt.Errorf("failed to count clicks for link %d: %w", linkID, err)
+// isDuplicateKey indique si l'erreur correspond à une violation de contrainte d'unicité,
+// en s'appuyant sur la traduction d'erreurs fournie par le driver GORM.
+func isDuplicateKey(db *gorm.DB, err error) bool {
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return true
+	}
+	if translator, ok := db.Dialector.(gorm.ErrorTranslator); ok {
+		return errors.Is(translator.Translate(err), gorm.ErrDuplicatedKey)
 	}
-	return int(count), nil
+	return false
 }
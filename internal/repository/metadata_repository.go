@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"fmt"
+
+	"github.com/antoine-granier/urlshortener/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MetadataRepository définit l'accès aux informations récupérées sur les pages de destination.
+type MetadataRepository interface {
+	GetMetadataByLinkID(linkID uint) (*models.LinkMetadata, error)
+	SaveMetadata(meta *models.LinkMetadata) error
+	ListLinksWithoutMetadata(afterID uint, limit int) ([]models.Link, error)
+}
+
+// GormMetadataRepository est l'implémentation de MetadataRepository utilisant GORM.
+type GormMetadataRepository struct {
+	db *gorm.DB
+}
+
+// NewMetadataRepository crée et retourne une nouvelle instance de GormMetadataRepository.
+func NewMetadataRepository(db *gorm.DB) *GormMetadataRepository {
+	return &GormMetadataRepository{db: db}
+}
+
+// GetMetadataByLinkID retourne les informations de page d'un lien.
+// Il renvoie gorm.ErrRecordNotFound si elles n'ont pas encore été récupérées.
+func (r *GormMetadataRepository) GetMetadataByLinkID(linkID uint) (*models.LinkMetadata, error) {
+	var meta models.LinkMetadata
+	if err := r.db.First(&meta, "link_id = ?", linkID).Error; err != nil {
+		return nil, fmt.Errorf("failed to find metadata for link %d: %w", linkID, err)
+	}
+	return &meta, nil
+}
+
+// SaveMetadata crée ou remplace les informations de page d'un lien (une seule ligne par lien).
+func (r *GormMetadataRepository) SaveMetadata(meta *models.LinkMetadata) error {
+	err := r.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "link_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"url", "title", "description", "image_url", "site_name", "favicon_url",
+			"open_graph", "fetched_at", "fetch_error", "updated_at",
+		}),
+	}).Create(meta).Error
+	if err != nil {
+		return fmt.Errorf("failed to save metadata for link %d: %w", meta.LinkID, err)
+	}
+	return nil
+}
+
+// ListLinksWithoutMetadata retourne, par identifiant croissant à partir de afterID (exclu), des liens
+// dont les informations de page n'ont jamais été enregistrées (liens créés par la CLI par exemple).
+func (r *GormMetadataRepository) ListLinksWithoutMetadata(afterID uint, limit int) ([]models.Link, error) {
+	var links []models.Link
+	err := r.db.
+		Where("links.id > ?", afterID).
+		Where("NOT EXISTS (SELECT 1 FROM link_metadata m WHERE m.link_id = links.id)").
+		Order("id").
+		Limit(limit).
+		Find(&links).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list links without metadata: %w", err)
+	}
+	return links, nil
+}
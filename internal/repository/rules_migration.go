@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/antoine-granier/urlshortener/internal/models"
+)
+
+// legacyDeviceRule est une ligne de l'ancienne table des règles de plateforme (link_device_rules).
+type legacyDeviceRule struct {
+	LinkID   uint
+	Name     string
+	Platform string
+	URL      string
+	DeepLink string
+}
+
+func (legacyDeviceRule) TableName() string { return "link_device_rules" }
+
+// legacyGeoRule est une ligne de l'ancienne table des règles géographiques (link_geo_rules).
+type legacyGeoRule struct {
+	LinkID    uint
+	Name      string
+	Country   string
+	Continent string
+	URL       string
+}
+
+func (legacyGeoRule) TableName() string { return "link_geo_rules" }
+
+// MigrateTargetingRules reprend les règles des anciennes tables link_device_rules et link_geo_rules
+// dans les règles de routage de leur lien (origines "device" et "geo"), puis supprime ces tables.
+// L'ordre d'évaluation est conservé (voir models.RoutingRules.ReplaceOrigin). La reprise se fait
+// en une transaction ; sans anciennes tables, elle ne fait rien. Retourne le nombre de liens repris.
+func MigrateTargetingRules(db *gorm.DB) (int, error) {
+	var migrated int
+	err := db.Transaction(func(tx *gorm.DB) error {
+		migrator := tx.Migrator()
+		hasDevice, hasGeo := migrator.HasTable(&legacyDeviceRule{}), migrator.HasTable(&legacyGeoRule{})
+		if !hasDevice && !hasGeo {
+			return nil
+		}
+
+		deviceRules := make(map[uint][]models.RoutingRule)
+		if hasDevice {
+			var rows []legacyDeviceRule
+			if err := tx.Order("link_id, position").Find(&rows).Error; err != nil {
+				return err
+			}
+			for _, row := range rows {
+				deviceRules[row.LinkID] = append(deviceRules[row.LinkID], models.RoutingRule{
+					Name:     row.Name,
+					When:     models.RuleConditions{Platforms: []string{row.Platform}},
+					URL:      row.URL,
+					DeepLink: row.DeepLink,
+				})
+			}
+		}
+		geoRules := make(map[uint][]models.RoutingRule)
+		if hasGeo {
+			var rows []legacyGeoRule
+			if err := tx.Order("link_id, position").Find(&rows).Error; err != nil {
+				return err
+			}
+			for _, row := range rows {
+				rule := models.RoutingRule{Name: row.Name, URL: row.URL}
+				if row.Country != "" {
+					rule.When.Countries = []string{row.Country}
+				} else {
+					rule.When.Continents = []string{row.Continent}
+				}
+				geoRules[row.LinkID] = append(geoRules[row.LinkID], rule)
+			}
+		}
+
+		linkIDs := make(map[uint]bool, len(deviceRules)+len(geoRules))
+		for id := range deviceRules {
+			linkIDs[id] = true
+		}
+		for id := range geoRules {
+			linkIDs[id] = true
+		}
+		for id := range linkIDs {
+			var link models.Link
+			if err := tx.Select("id", "routing_rules").First(&link, id).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					continue // Règle orpheline d'un lien supprimé
+				}
+				return err
+			}
+			rules := link.RoutingRules.
+				ReplaceOrigin(models.RuleOriginDevice, deviceRules[id]).
+				ReplaceOrigin(models.RuleOriginGeo, geoRules[id])
+			if err := tx.Model(&link).Update("routing_rules", rules).Error; err != nil {
+				return err
+			}
+			migrated++
+		}
+
+		if hasDevice {
+			if err := migrator.DropTable(&legacyDeviceRule{}); err != nil {
+				return err
+			}
+		}
+		if hasGeo {
+			if err := migrator.DropTable(&legacyGeoRule{}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to migrate device and geo rules to routing rules: %w", err)
+	}
+	return migrated, nil
+}
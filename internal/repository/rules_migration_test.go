@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"slices"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/antoine-granier/urlshortener/internal/models"
+)
+
+func TestMigrateTargetingRules(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.Link{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	// Tables des anciennes règles, telles que créées par les versions précédentes
+	for _, stmt := range []string{
+		`CREATE TABLE link_device_rules (id integer PRIMARY KEY, link_id integer NOT NULL, position integer NOT NULL,
+			name text, platform text NOT NULL, url text NOT NULL, deep_link text)`,
+		`CREATE TABLE link_geo_rules (id integer PRIMARY KEY, link_id integer NOT NULL, position integer NOT NULL,
+			name text, country text, continent text, url text NOT NULL)`,
+	} {
+		if err := db.Exec(stmt).Error; err != nil {
+			t.Fatalf("create legacy table: %v", err)
+		}
+	}
+
+	link := &models.Link{ShortCode: "legacy", LongURL: "https://example.com", RoutingRules: models.RoutingRules{
+		{Name: "fr-speakers", When: models.RuleConditions{Languages: []string{"fr"}}, URL: "https://example.com/fr"},
+	}}
+	if err := db.Create(link).Error; err != nil {
+		t.Fatalf("create link: %v", err)
+	}
+	other := &models.Link{ShortCode: "plain", LongURL: "https://example.org"}
+	if err := db.Create(other).Error; err != nil {
+		t.Fatalf("create link: %v", err)
+	}
+	for _, stmt := range []string{
+		`INSERT INTO link_device_rules (link_id, position, name, platform, url, deep_link) VALUES
+			(?, 1, 'android', 'android', 'https://play.example.com', ''),
+			(?, 0, 'ios', 'ios', 'https://apps.example.com', 'myapp://home'),
+			(999, 0, 'orphan', 'ios', 'https://apps.example.com', '')`,
+		`INSERT INTO link_geo_rules (link_id, position, name, country, continent, url) VALUES
+			(?, 0, 'geo:EU', '', 'EU', 'https://shop.example.eu'),
+			(?, 1, 'geo:FR', 'FR', '', 'https://shop.example.fr')`,
+	} {
+		if err := db.Exec(stmt, link.ID, link.ID).Error; err != nil {
+			t.Fatalf("insert legacy rules: %v", err)
+		}
+	}
+
+	migrated, err := MigrateTargetingRules(db)
+	if err != nil {
+		t.Fatalf("MigrateTargetingRules: %v", err)
+	}
+	if migrated != 1 {
+		t.Errorf("migrated = %d, want 1", migrated)
+	}
+
+	var got models.Link
+	if err := db.First(&got, link.ID).Error; err != nil {
+		t.Fatalf("reload link: %v", err)
+	}
+	var names []string
+	for _, rule := range got.RoutingRules {
+		names = append(names, rule.Name)
+	}
+	if want := []string{"fr-speakers", "ios", "android", "geo:FR", "geo:EU"}; !slices.Equal(names, want) {
+		t.Fatalf("rules = %v, want %v", names, want)
+	}
+	ios := got.RoutingRules[1]
+	if ios.Origin != models.RuleOriginDevice || !slices.Equal(ios.When.Platforms, []string{"ios"}) || ios.DeepLink != "myapp://home" {
+		t.Errorf("ios rule = %+v", ios)
+	}
+	eu := got.RoutingRules[4]
+	if eu.Origin != models.RuleOriginGeo || !slices.Equal(eu.When.Continents, []string{"EU"}) || len(eu.When.Countries) != 0 {
+		t.Errorf("EU rule = %+v", eu)
+	}
+	var plain models.Link
+	if err := db.First(&plain, other.ID).Error; err != nil || plain.RoutingRules != nil {
+		t.Errorf("link without legacy rules: rules = %v, err = %v", plain.RoutingRules, err)
+	}
+
+	if db.Migrator().HasTable("link_device_rules") || db.Migrator().HasTable("link_geo_rules") {
+		t.Error("legacy tables still present after migration")
+	}
+	// Une seconde exécution ne fait rien.
+	if migrated, err := MigrateTargetingRules(db); err != nil || migrated != 0 {
+		t.Errorf("second run: migrated = %d, err = %v", migrated, err)
+	}
+}
@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/antoine-granier/urlshortener/internal/models"
+	"gorm.io/gorm"
+)
+
+// ErrChangeNotPending est retournée quand un changement programmé a déjà été appliqué ou annulé.
+var ErrChangeNotPending = errors.New("scheduled change is not pending")
+
+// ScheduledChangeRepository définit l'accès aux changements programmés de destination.
+type ScheduledChangeRepository interface {
+	CreateChange(change *models.ScheduledChange) error
+	GetChange(id uint) (*models.ScheduledChange, error)
+	ListChangesByLinkID(linkID uint) ([]models.ScheduledChange, error)
+	ListDueChanges(now time.Time, limit int) ([]models.ScheduledChange, error)
+	NextPendingChangeAt() (*time.Time, error)
+	ApplyChange(change *models.ScheduledChange, canonicalURL string, utm models.UTM, appliedAt time.Time) error
+	SetChangeStatus(id uint, status, reason string, at time.Time) error
+}
+
+// GormScheduledChangeRepository est l'implémentation de ScheduledChangeRepository utilisant GORM.
+type GormScheduledChangeRepository struct {
+	db *gorm.DB
+}
+
+// NewScheduledChangeRepository crée et retourne une nouvelle instance de GormScheduledChangeRepository.
+func NewScheduledChangeRepository(db *gorm.DB) *GormScheduledChangeRepository {
+	return &GormScheduledChangeRepository{db: db}
+}
+
+// CreateChange enregistre un nouveau changement programmé.
+func (r *GormScheduledChangeRepository) CreateChange(change *models.ScheduledChange) error {
+	if err := r.db.Create(change).Error; err != nil {
+		return fmt.Errorf("failed to create scheduled change for link %d: %w", change.LinkID, err)
+	}
+	return nil
+}
+
+// GetChange récupère un changement programmé par son ID.
+// Il renvoie gorm.ErrRecordNotFound si aucun changement ne correspond.
+func (r *GormScheduledChangeRepository) GetChange(id uint) (*models.ScheduledChange, error) {
+	var change models.ScheduledChange
+	if err := r.db.First(&change, id).Error; err != nil {
+		return nil, fmt.Errorf("failed to find scheduled change %d: %w", id, err)
+	}
+	return &change, nil
+}
+
+// ListChangesByLinkID retourne les changements programmés d'un lien, par date d'application.
+func (r *GormScheduledChangeRepository) ListChangesByLinkID(linkID uint) ([]models.ScheduledChange, error) {
+	var changes []models.ScheduledChange
+	if err := r.db.Where("link_id = ?", linkID).Order("apply_at, id").Find(&changes).Error; err != nil {
+		return nil, fmt.Errorf("failed to list scheduled changes for link %d: %w", linkID, err)
+	}
+	return changes, nil
+}
+
+// ListDueChanges retourne les changements en attente dont la date d'application est atteinte,
+// les plus anciens en premier.
+func (r *GormScheduledChangeRepository) ListDueChanges(now time.Time, limit int) ([]models.ScheduledChange, error) {
+	var changes []models.ScheduledChange
+	if err := r.db.
+		Where("status = ? AND apply_at <= ?", models.ScheduledChangePending, now).
+		Order("apply_at, id").
+		Limit(limit).
+		Find(&changes).
+		Error; err != nil {
+		return nil, fmt.Errorf("failed to list due scheduled changes: %w", err)
+	}
+	return changes, nil
+}
+
+// NextPendingChangeAt retourne la date d'application du prochain changement en attente, ou nil s'il n'y en a pas.
+func (r *GormScheduledChangeRepository) NextPendingChangeAt() (*time.Time, error) {
+	var change models.ScheduledChange
+	err := r.db.
+		Where("status = ?", models.ScheduledChangePending).
+		Order("apply_at").
+		Take(&change).
+		Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find next scheduled change: %w", err)
+	}
+	return &change.ApplyAt, nil
+}
+
+// ApplyChange remplace, dans une transaction, la destination du lien (et ses paramètres UTM)
+// et marque le changement comme appliqué.
+// Le changement n'est appliqué qu'une fois : s'il n'est plus en attente, ErrChangeNotPending est retournée.
+func (r *GormScheduledChangeRepository) ApplyChange(change *models.ScheduledChange, canonicalURL string, utm models.UTM, appliedAt time.Time) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&models.ScheduledChange{}).
+			Where("id = ? AND status = ?", change.ID, models.ScheduledChangePending).
+			Updates(map[string]any{"status": models.ScheduledChangeApplied, "applied_at": appliedAt})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrChangeNotPending
+		}
+		res = tx.Model(&models.Link{}).
+			Where("id = ?", change.LinkID).
+			Updates(map[string]any{
+				"long_url":      change.LongURL,
+				"canonical_url": canonicalURL,
+				"utm_source":    utm.Source,
+				"utm_medium":    utm.Medium,
+				"utm_campaign":  utm.Campaign,
+				"utm_term":      utm.Term,
+				"utm_content":   utm.Content,
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to apply scheduled change %d: %w", change.ID, err)
+	}
+	return nil
+}
+
+// SetChangeStatus passe un changement en attente au statut donné (annulation ou échec).
+func (r *GormScheduledChangeRepository) SetChangeStatus(id uint, status, reason string, at time.Time) error {
+	res := r.db.Model(&models.ScheduledChange{}).
+		Where("id = ? AND status = ?", id, models.ScheduledChangePending).
+		Updates(map[string]any{"status": status, "error": reason, "applied_at": at})
+	if res.Error != nil {
+		return fmt.Errorf("failed to update scheduled change %d: %w", id, res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("failed to update scheduled change %d: %w", id, ErrChangeNotPending)
+	}
+	return nil
+}
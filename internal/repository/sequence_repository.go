@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// SequenceRepository définit l'accès aux compteurs monotones.
+type SequenceRepository interface {
+	NextValue(name string) (uint64, error)
+}
+
+// GormSequenceRepository est l'implémentation de SequenceRepository utilisant GORM.
+type GormSequenceRepository struct {
+	db *gorm.DB
+}
+
+// NewSequenceRepository crée et retourne une nouvelle instance de GormSequenceRepository.
+func NewSequenceRepository(db *gorm.DB) *GormSequenceRepository {
+	return &GormSequenceRepository{db: db}
+}
+
+// NextValue incrémente le compteur 'name' (créé à la volée) et retourne sa nouvelle valeur.
+// L'upsert est réalisé en une seule requête, ce qui le rend atomique entre créateurs concurrents.
+func (r *GormSequenceRepository) NextValue(name string) (uint64, error) {
+	var value uint64
+	if err := r.db.
+		Raw(`INSERT INTO sequences (name, value) VALUES (?, 1)
+			ON CONFLICT(name) DO UPDATE SET value = value + 1
+			RETURNING value`, name).
+		Scan(&value).
+		Error; err != nil {
+		return 0, fmt.Errorf("failed to increment sequence %s: %w", name, err)
+	}
+	return value, nil
+}
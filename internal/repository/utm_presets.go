@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"fmt"
+
+	"github.com/antoine-granier/urlshortener/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SaveUTMPreset crée le jeu de paramètres UTM d'un propriétaire, ou remplace celui qui porte déjà ce nom.
+func (r *GormLinkRepository) SaveUTMPreset(preset *models.UTMPreset) error {
+	err := r.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "owner"}, {Name: "name"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"utm_source", "utm_medium", "utm_campaign", "utm_term", "utm_content", "updated_at",
+		}),
+	}).Create(preset).Error
+	if err != nil {
+		return fmt.Errorf("failed to save utm preset %q for owner %q: %w", preset.Name, preset.Owner, err)
+	}
+	return nil
+}
+
+// GetUTMPreset récupère un jeu de paramètres UTM d'un propriétaire via son nom.
+// Il renvoie gorm.ErrRecordNotFound s'il n'existe pas.
+func (r *GormLinkRepository) GetUTMPreset(owner, name string) (*models.UTMPreset, error) {
+	var preset models.UTMPreset
+	if err := r.db.First(&preset, "owner = ? AND name = ?", owner, name).Error; err != nil {
+		return nil, fmt.Errorf("failed to find utm preset %q for owner %q: %w", name, owner, err)
+	}
+	return &preset, nil
+}
+
+// ListUTMPresets retourne les jeux de paramètres UTM d'un propriétaire, triés par nom.
+func (r *GormLinkRepository) ListUTMPresets(owner string) ([]models.UTMPreset, error) {
+	var presets []models.UTMPreset
+	if err := r.db.Where("owner = ?", owner).Order("name").Find(&presets).Error; err != nil {
+		return nil, fmt.Errorf("failed to list utm presets for owner %q: %w", owner, err)
+	}
+	return presets, nil
+}
+
+// DeleteUTMPreset supprime un jeu de paramètres UTM d'un propriétaire.
+// Il renvoie gorm.ErrRecordNotFound s'il n'existe pas.
+func (r *GormLinkRepository) DeleteUTMPreset(owner, name string) error {
+	res := r.db.Where("owner = ? AND name = ?", owner, name).Delete(&models.UTMPreset{})
+	if res.Error != nil {
+		return fmt.Errorf("failed to delete utm preset %q for owner %q: %w", name, owner, res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("failed to delete utm preset %q for owner %q: %w", name, owner, gorm.ErrRecordNotFound)
+	}
+	return nil
+}
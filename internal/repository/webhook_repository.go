@@ -0,0 +1,208 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/antoine-granier/urlshortener/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// WebhookRepository définit l'accès aux webhooks, à leur journal de livraisons et aux paliers de clics notifiés.
+type WebhookRepository interface {
+	CreateWebhook(webhook *models.Webhook) error
+	GetWebhook(id uint) (*models.Webhook, error)
+	ListWebhooks(owner *string) ([]models.Webhook, error)
+	DeleteWebhook(id uint) error
+
+	CreateDeliveries(deliveries []models.WebhookDelivery) error
+	GetDelivery(id uint) (*models.WebhookDelivery, error)
+	DueDeliveries(now time.Time, limit int) ([]models.WebhookDelivery, error)
+	NextDeliveryAt() (*time.Time, error)
+	UpdateDelivery(delivery *models.WebhookDelivery) error
+	ListDeliveries(webhookID uint, status string, limit int) ([]models.WebhookDelivery, error)
+	RequeueDeliveries(webhookID uint, ids []uint, at time.Time) (int64, error)
+	PruneDeliveries(before time.Time) (int64, error)
+
+	MarkThresholdReached(linkID uint, threshold int) (bool, error)
+}
+
+// GormWebhookRepository est l'implémentation de WebhookRepository utilisant GORM.
+type GormWebhookRepository struct {
+	db *gorm.DB
+}
+
+// NewWebhookRepository crée et retourne une nouvelle instance de GormWebhookRepository.
+func NewWebhookRepository(db *gorm.DB) *GormWebhookRepository {
+	return &GormWebhookRepository{db: db}
+}
+
+// CreateWebhook enregistre un nouveau webhook.
+func (r *GormWebhookRepository) CreateWebhook(webhook *models.Webhook) error {
+	if err := r.db.Create(webhook).Error; err != nil {
+		return fmt.Errorf("failed to create webhook: %w", err)
+	}
+	return nil
+}
+
+// GetWebhook récupère un webhook. Il renvoie gorm.ErrRecordNotFound s'il n'existe pas.
+func (r *GormWebhookRepository) GetWebhook(id uint) (*models.Webhook, error) {
+	var webhook models.Webhook
+	if err := r.db.First(&webhook, id).Error; err != nil {
+		return nil, fmt.Errorf("failed to find webhook %d: %w", id, err)
+	}
+	return &webhook, nil
+}
+
+// ListWebhooks retourne les webhooks d'une équipe, ou tous les webhooks si owner est nil.
+func (r *GormWebhookRepository) ListWebhooks(owner *string) ([]models.Webhook, error) {
+	query := r.db.Order("id")
+	if owner != nil {
+		query = query.Where("owner = ?", *owner)
+	}
+	var webhooks []models.Webhook
+	if err := query.Find(&webhooks).Error; err != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %w", err)
+	}
+	return webhooks, nil
+}
+
+// DeleteWebhook supprime un webhook et son journal de livraisons.
+// Il renvoie gorm.ErrRecordNotFound si le webhook n'existe pas.
+func (r *GormWebhookRepository) DeleteWebhook(id uint) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Delete(&models.Webhook{}, id)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.Where("webhook_id = ?", id).Delete(&models.WebhookDelivery{}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook %d: %w", id, err)
+	}
+	return nil
+}
+
+// CreateDeliveries enregistre des livraisons à effectuer.
+func (r *GormWebhookRepository) CreateDeliveries(deliveries []models.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	if err := r.db.Create(&deliveries).Error; err != nil {
+		return fmt.Errorf("failed to create %d webhook deliveries: %w", len(deliveries), err)
+	}
+	return nil
+}
+
+// GetDelivery récupère une livraison. Il renvoie gorm.ErrRecordNotFound si elle n'existe pas.
+func (r *GormWebhookRepository) GetDelivery(id uint) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	if err := r.db.First(&delivery, id).Error; err != nil {
+		return nil, fmt.Errorf("failed to find webhook delivery %d: %w", id, err)
+	}
+	return &delivery, nil
+}
+
+// DueDeliveries retourne les livraisons en attente dont la date de tentative est atteinte, les plus anciennes d'abord.
+func (r *GormWebhookRepository) DueDeliveries(now time.Time, limit int) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	if err := r.db.
+		Where("status = ? AND next_attempt_at <= ?", models.DeliveryPending, now).
+		Order("next_attempt_at, id").
+		Limit(limit).
+		Find(&deliveries).
+		Error; err != nil {
+		return nil, fmt.Errorf("failed to retrieve due webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+// NextDeliveryAt retourne la date de la prochaine tentative de livraison, ou nil s'il n'y en a aucune.
+func (r *GormWebhookRepository) NextDeliveryAt() (*time.Time, error) {
+	var delivery models.WebhookDelivery
+	res := r.db.
+		Select("next_attempt_at").
+		Where("status = ?", models.DeliveryPending).
+		Order("next_attempt_at").
+		Limit(1).
+		Find(&delivery)
+	if res.Error != nil {
+		return nil, fmt.Errorf("failed to find next webhook delivery: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return nil, nil
+	}
+	return &delivery.NextAttemptAt, nil
+}
+
+// UpdateDelivery enregistre le résultat d'une tentative de livraison.
+func (r *GormWebhookRepository) UpdateDelivery(delivery *models.WebhookDelivery) error {
+	if err := r.db.Save(delivery).Error; err != nil {
+		return fmt.Errorf("failed to update webhook delivery %d: %w", delivery.ID, err)
+	}
+	return nil
+}
+
+// ListDeliveries retourne les dernières livraisons d'un webhook, éventuellement filtrées par statut.
+func (r *GormWebhookRepository) ListDeliveries(webhookID uint, status string, limit int) ([]models.WebhookDelivery, error) {
+	query := r.db.Where("webhook_id = ?", webhookID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var deliveries []models.WebhookDelivery
+	if err := query.Order("id DESC").Limit(limit).Find(&deliveries).Error; err != nil {
+		return nil, fmt.Errorf("failed to list deliveries of webhook %d: %w", webhookID, err)
+	}
+	return deliveries, nil
+}
+
+// RequeueDeliveries remet en attente, avec un compteur de tentatives à zéro, des livraisons d'un webhook :
+// celles d'identifiants donnés, ou toutes ses lettres mortes si ids est vide.
+// Elle retourne le nombre de livraisons remises en attente.
+func (r *GormWebhookRepository) RequeueDeliveries(webhookID uint, ids []uint, at time.Time) (int64, error) {
+	query := r.db.Model(&models.WebhookDelivery{}).Where("webhook_id = ?", webhookID)
+	if len(ids) > 0 {
+		query = query.Where("id IN ?", ids)
+	} else {
+		query = query.Where("status = ?", models.DeliveryDead)
+	}
+	res := query.Updates(map[string]any{
+		"status":          models.DeliveryPending,
+		"attempts":        0,
+		"next_attempt_at": at,
+		"last_error":      "",
+	})
+	if res.Error != nil {
+		return 0, fmt.Errorf("failed to requeue deliveries of webhook %d: %w", webhookID, res.Error)
+	}
+	return res.RowsAffected, nil
+}
+
+// PruneDeliveries supprime les livraisons terminées (acquittées ou lettres mortes) dont la dernière
+// tentative est antérieure à before. Les livraisons en attente sont conservées.
+// Elle retourne le nombre de livraisons supprimées.
+func (r *GormWebhookRepository) PruneDeliveries(before time.Time) (int64, error) {
+	res := r.db.
+		Where("status IN ? AND updated_at < ?", []string{models.DeliverySucceeded, models.DeliveryDead}, before).
+		Delete(&models.WebhookDelivery{})
+	if res.Error != nil {
+		return 0, fmt.Errorf("failed to prune webhook deliveries before %s: %w", before.Format(time.RFC3339), res.Error)
+	}
+	return res.RowsAffected, nil
+}
+
+// MarkThresholdReached enregistre le franchissement d'un palier de clics par un lien.
+// Elle retourne true si le palier n'avait pas encore été enregistré (un seul appelant l'obtient).
+func (r *GormWebhookRepository) MarkThresholdReached(linkID uint, threshold int) (bool, error) {
+	res := r.db.
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.ClickThreshold{LinkID: linkID, Threshold: threshold})
+	if res.Error != nil {
+		return false, fmt.Errorf("failed to mark threshold %d for link %d: %w", threshold, linkID, res.Error)
+	}
+	return res.RowsAffected == 1, nil
+}
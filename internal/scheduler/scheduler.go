@@ -0,0 +1,86 @@
+package scheduler
+
+import (
+	"time"
+
+	"github.com/antoine-granier/urlshortener/internal/logging"
+	"github.com/antoine-granier/urlshortener/internal/services"
+)
+
+// logger journalise les passes du planificateur.
+var logger = logging.For("scheduler")
+
+// defaultPollInterval est utilisé quand l'intervalle configuré n'est pas strictement positif.
+const defaultPollInterval = 30 * time.Second
+
+// retryDelay est l'attente avant une nouvelle tentative quand un changement échu n'a pas pu être appliqué.
+const retryDelay = 5 * time.Second
+
+// Scheduler applique les changements de destination programmés.
+// Il s'endort jusqu'au prochain changement connu ; pollInterval borne cette attente afin de
+// prendre en compte les changements programmés par un autre processus (ex: la CLI).
+type Scheduler struct {
+	scheduleService *services.ScheduleService
+	pollInterval    time.Duration
+}
+
+// NewScheduler crée et retourne une nouvelle instance de Scheduler.
+func NewScheduler(scheduleService *services.ScheduleService, pollInterval time.Duration) *Scheduler {
+	if pollInterval <= 0 {
+		pollInterval = defaultPollInterval
+	}
+	return &Scheduler{
+		scheduleService: scheduleService,
+		pollInterval:    pollInterval,
+	}
+}
+
+// Start lance la boucle du planificateur.
+// Cette fonction est conçue pour être lancée dans une goroutine séparée.
+func (s *Scheduler) Start() {
+	logger.Info("Starting scheduler", "poll_interval", s.pollInterval)
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	// Timer.Reset (Go 1.23+) purge toute valeur en attente : pas besoin de vider le channel.
+	for {
+		select {
+		case <-timer.C:
+		case <-s.scheduleService.Wake():
+		}
+		s.runDueChanges()
+		timer.Reset(s.nextWait())
+	}
+}
+
+// runDueChanges applique les changements dont la date est atteinte.
+func (s *Scheduler) runDueChanges() {
+	applied, err := s.scheduleService.ApplyDueChanges(time.Now())
+	if err != nil {
+		logger.Error("Failed to apply scheduled changes", logging.Err(err))
+	}
+	if applied > 0 {
+		logger.Info("Scheduled changes applied", "count", applied)
+	}
+}
+
+// nextWait calcule la durée d'attente jusqu'au prochain changement, bornée par pollInterval.
+func (s *Scheduler) nextWait() time.Duration {
+	next, err := s.scheduleService.NextChangeAt()
+	if err != nil {
+		logger.Error("Failed to find next scheduled change", logging.Err(err))
+		return s.pollInterval
+	}
+	if next == nil {
+		return s.pollInterval
+	}
+	wait := time.Until(*next)
+	switch {
+	case wait <= 0:
+		// Changement échu mais non appliqué (erreur transitoire) : nouvelle tentative un peu plus tard.
+		return retryDelay
+	case wait > s.pollInterval:
+		return s.pollInterval
+	}
+	return wait
+}
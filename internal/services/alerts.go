@@ -0,0 +1,304 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/antoine-granier/urlshortener/internal/logging"
+	"github.com/antoine-granier/urlshortener/internal/models"
+	"github.com/antoine-granier/urlshortener/internal/repository"
+)
+
+// ErrInvalidAlertRule est retournée quand une règle d'alerte est incohérente (type, seuil, fenêtre, cible).
+var ErrInvalidAlertRule = errors.New("règle d'alerte invalide")
+
+// AlertKinds est la liste des types de règles d'alerte.
+var AlertKinds = []string{models.AlertClickSpike, models.AlertNoClicks, models.AlertNewCountry, models.AlertAnomaly}
+
+const (
+	defaultSpikeWindow      = 5 * time.Minute
+	defaultNoClicksWindow   = 24 * time.Hour
+	defaultAnomalyWindow    = time.Hour
+	defaultAnomalyFactor    = 3
+	defaultAnomalyBaseline  = 7 // Jours de trafic servant de référence
+	alertMaxWindow          = 24 * time.Hour
+	alertAnomalyMinClicks   = 20 // En dessous, un pic n'est pas significatif
+	alertNewCountryMaxShown = 5
+)
+
+// AlertRuleInput décrit une règle d'alerte à créer. La cible est un lien (ShortCode)
+// ou une campagne (Campaign, de l'équipe Owner). Les valeurs nulles prennent leur valeur par défaut.
+type AlertRuleInput struct {
+	ShortCode     string
+	Campaign      string
+	Owner         string
+	Kind          string
+	Threshold     int
+	WindowMinutes int
+}
+
+// AlertEventData est la charge utile de l'événement alert.triggered.
+type AlertEventData struct {
+	RuleID    uint   `json:"rule_id"`
+	Kind      string `json:"kind"`
+	ShortCode string `json:"short_code,omitempty"`
+	Campaign  string `json:"campaign,omitempty"`
+	Message   string `json:"message"`
+	Clicks    int    `json:"clicks"`
+}
+
+// AlertService gère les règles d'alerte sur le trafic des liens et des campagnes, et les évalue
+// périodiquement. Les alertes sont notifiées comme celles du moniteur d'URLs : dans les logs
+// ([NOTIFICATION]) et par l'événement alert.triggered (webhooks).
+type AlertService struct {
+	linkService  *LinkService
+	alertRepo    repository.AlertRepository
+	events       *EventBus
+	baselineDays int
+}
+
+// NewAlertService crée et retourne une nouvelle instance de AlertService. baselineDays est la durée
+// du trafic de référence des règles "anomaly" (7 jours si <= 0).
+func NewAlertService(linkService *LinkService, alertRepo repository.AlertRepository, events *EventBus, baselineDays int) *AlertService {
+	if baselineDays <= 0 {
+		baselineDays = defaultAnomalyBaseline
+	}
+	return &AlertService{linkService: linkService, alertRepo: alertRepo, events: events, baselineDays: baselineDays}
+}
+
+// CreateRule valide et enregistre une règle d'alerte.
+func (s *AlertService) CreateRule(input AlertRuleInput) (*models.AlertRule, error) {
+	rule := &models.AlertRule{Kind: input.Kind, Threshold: input.Threshold, WindowMinutes: input.WindowMinutes}
+	switch input.Kind {
+	case models.AlertClickSpike:
+		if input.Threshold <= 0 {
+			return nil, fmt.Errorf("%w: un seuil de clics positif est requis", ErrInvalidAlertRule)
+		}
+		rule.WindowMinutes = defaultMinutes(input.WindowMinutes, defaultSpikeWindow)
+	case models.AlertNoClicks:
+		rule.WindowMinutes = defaultMinutes(input.WindowMinutes, defaultNoClicksWindow)
+	case models.AlertAnomaly:
+		if rule.Threshold == 0 {
+			rule.Threshold = defaultAnomalyFactor
+		}
+		if rule.Threshold < 2 {
+			return nil, fmt.Errorf("%w: le multiple du trafic habituel doit être d'au moins 2", ErrInvalidAlertRule)
+		}
+		rule.WindowMinutes = defaultMinutes(input.WindowMinutes, defaultAnomalyWindow)
+	case models.AlertNewCountry:
+		rule.WindowMinutes = 0 // Les nouveaux pays sont cherchés depuis la dernière évaluation
+	default:
+		return nil, fmt.Errorf("%w: type inconnu '%s' (attendus : %s)", ErrInvalidAlertRule, input.Kind, strings.Join(AlertKinds, ", "))
+	}
+	if rule.WindowMinutes < 0 || time.Duration(rule.WindowMinutes)*time.Minute > alertMaxWindow {
+		// Les fenêtres sont évaluées sur les clics bruts, conservés au moins un jour.
+		return nil, fmt.Errorf("%w: la fenêtre doit être comprise entre 1 minute et %v", ErrInvalidAlertRule, alertMaxWindow)
+	}
+
+	switch {
+	case input.ShortCode != "" && input.Campaign != "":
+		return nil, fmt.Errorf("%w: cibler un lien ou une campagne, pas les deux", ErrInvalidAlertRule)
+	case input.ShortCode != "":
+		link, err := s.linkService.GetLinkByShortCode(input.ShortCode)
+		if err != nil {
+			return nil, err
+		}
+		rule.LinkID, rule.Owner, rule.Target = &link.ID, link.Owner, link.ShortCode
+	case input.Campaign != "":
+		campaign, err := s.linkService.linkRepo.GetCampaign(input.Owner, strings.TrimSpace(input.Campaign))
+		if err != nil {
+			return nil, fmt.Errorf("Echec de la récupération de la campagne '%s': %w", input.Campaign, err)
+		}
+		rule.CampaignID, rule.Owner, rule.Target = &campaign.ID, campaign.Owner, campaign.Name
+	default:
+		return nil, fmt.Errorf("%w: un lien ou une campagne est requis", ErrInvalidAlertRule)
+	}
+
+	rule.CheckedAt = time.Now().UTC()
+	if err := s.alertRepo.CreateRule(rule); err != nil {
+		return nil, fmt.Errorf("Echec de l'enregistrement de la règle d'alerte: %w", err)
+	}
+	return rule, nil
+}
+
+// ListRules retourne les règles d'alerte d'un lien, d'une campagne (de l'équipe owner) ou d'une équipe.
+func (s *AlertService) ListRules(shortCode, campaign, owner string) ([]models.AlertRule, error) {
+	filter := repository.AlertRuleFilter{Owner: owner}
+	if shortCode != "" {
+		link, err := s.linkService.GetLinkByShortCode(shortCode)
+		if err != nil {
+			return nil, err
+		}
+		filter = repository.AlertRuleFilter{LinkID: link.ID}
+	} else if campaign != "" {
+		c, err := s.linkService.linkRepo.GetCampaign(owner, strings.TrimSpace(campaign))
+		if err != nil {
+			return nil, fmt.Errorf("Echec de la récupération de la campagne '%s': %w", campaign, err)
+		}
+		filter = repository.AlertRuleFilter{CampaignID: c.ID}
+	}
+	rules, err := s.alertRepo.ListRules(filter)
+	if err != nil {
+		return nil, fmt.Errorf("Echec de la récupération des règles d'alerte: %w", err)
+	}
+	return rules, nil
+}
+
+// DeleteRule supprime une règle d'alerte.
+func (s *AlertService) DeleteRule(id uint) error {
+	if err := s.alertRepo.DeleteRule(id); err != nil {
+		return fmt.Errorf("Echec de la suppression de la règle d'alerte %d: %w", id, err)
+	}
+	return nil
+}
+
+// Start lance l'évaluation périodique des règles d'alerte.
+// Cette fonction est conçue pour être lancée dans une goroutine séparée.
+func (s *AlertService) Start(interval time.Duration) {
+	alertsLogger.Info("Starting alert evaluation", "interval", interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if err := s.Evaluate(time.Now()); err != nil {
+			alertsLogger.Error("Failed to evaluate alert rules", logging.Err(err))
+		}
+	}
+}
+
+// Evaluate évalue toutes les règles d'alerte à la date now et notifie celles qui se déclenchent.
+func (s *AlertService) Evaluate(now time.Time) error {
+	rules, err := s.alertRepo.ListRules(repository.AlertRuleFilter{})
+	if err != nil {
+		return fmt.Errorf("Echec de la récupération des règles d'alerte: %w", err)
+	}
+	now = now.UTC()
+	for i := range rules {
+		rule := &rules[i]
+		firing, clicks, message, err := s.check(rule, now)
+		if err != nil {
+			alertsLogger.Error("Failed to evaluate alert rule", "rule_id", rule.ID, "kind", rule.Kind, "target", rule.Target, logging.Err(err))
+			continue
+		}
+
+		// Une règle ne notifie qu'à son déclenchement ; les nouveaux pays sont notifiés à chaque apparition.
+		var triggeredAt *time.Time
+		if firing && (!rule.Firing || rule.Kind == models.AlertNewCountry) {
+			triggeredAt = &now
+			s.notify(rule, clicks, message)
+		}
+		if err := s.alertRepo.RecordEvaluation(rule.ID, now, firing, triggeredAt); err != nil {
+			alertsLogger.Error("Failed to record alert rule evaluation", "rule_id", rule.ID, logging.Err(err))
+		}
+	}
+	return nil
+}
+
+// check évalue la condition d'une règle. Il retourne si elle est remplie, le nombre de clics
+// concernés et la description de l'alerte.
+func (s *AlertService) check(rule *models.AlertRule, now time.Time) (bool, int, string, error) {
+	clickRepo := s.linkService.clickRepo
+	scope := alertScope(rule)
+	window := time.Duration(rule.WindowMinutes) * time.Minute
+
+	switch rule.Kind {
+	case models.AlertClickSpike:
+		clicks, err := clickRepo.CountRecentClicks(scope, now.Add(-window))
+		if err != nil {
+			return false, 0, "", err
+		}
+		return clicks > rule.Threshold, clicks,
+			fmt.Sprintf("%d clics en %v (seuil : %d)", clicks, window, rule.Threshold), nil
+
+	case models.AlertNoClicks:
+		if now.Sub(rule.CreatedAt) < window {
+			return false, 0, "", nil // Pas encore assez de recul depuis la création de la règle
+		}
+		clicks, err := clickRepo.CountRecentClicks(scope, now.Add(-window))
+		if err != nil {
+			return false, 0, "", err
+		}
+		return clicks == 0, 0, fmt.Sprintf("aucun clic depuis %v", window), nil
+
+	case models.AlertAnomaly:
+		clicks, err := clickRepo.CountRecentClicks(scope, now.Add(-window))
+		if err != nil {
+			return false, 0, "", err
+		}
+		// Trafic habituel : moyenne sur les jours précédents (heures pleines), ramenée à la fenêtre.
+		to := now.Truncate(time.Hour)
+		baselineClicks, err := clickRepo.CountScopeClicksInRange(scope, to.AddDate(0, 0, -s.baselineDays), to)
+		if err != nil {
+			return false, 0, "", err
+		}
+		expected := float64(baselineClicks) * window.Hours() / float64(s.baselineDays*24)
+		firing := clicks >= alertAnomalyMinClicks && float64(clicks) > float64(rule.Threshold)*max(expected, 1)
+		return firing, clicks, fmt.Sprintf("%d clics en %v contre %.1f habituellement (x%d)", clicks, window, expected, rule.Threshold), nil
+
+	case models.AlertNewCountry:
+		recent, err := clickRepo.CountRecentClicksByCountry(scope, rule.CheckedAt)
+		if err != nil {
+			return false, 0, "", err
+		}
+		delete(recent, "")
+		if len(recent) == 0 {
+			return false, 0, "", nil
+		}
+		all, err := clickRepo.CountScopeClicksByDimension(scope, "country")
+		if err != nil {
+			return false, 0, "", err
+		}
+		// Un pays est nouveau si tous ses clics sont postérieurs à la dernière évaluation.
+		var countries []string
+		clicks := 0
+		for country, count := range recent {
+			if all[country] <= count {
+				countries = append(countries, country)
+				clicks += count
+			}
+		}
+		if len(countries) == 0 {
+			return false, 0, "", nil
+		}
+		sort.Strings(countries)
+		shown := countries[:min(len(countries), alertNewCountryMaxShown)]
+		message := "clics depuis un nouveau pays : " + strings.Join(shown, ", ")
+		if len(countries) > len(shown) {
+			message += fmt.Sprintf(" (+%d)", len(countries)-len(shown))
+		}
+		return true, clicks, message, nil
+	}
+	return false, 0, "", fmt.Errorf("%w: type inconnu '%s'", ErrInvalidAlertRule, rule.Kind)
+}
+
+// notify diffuse une alerte dans les logs et sur le bus d'événements.
+func (s *AlertService) notify(rule *models.AlertRule, clicks int, message string) {
+	target := "le lien " + rule.Target
+	data := AlertEventData{RuleID: rule.ID, Kind: rule.Kind, Message: message, Clicks: clicks}
+	if rule.CampaignID != nil {
+		target = "la campagne " + rule.Target
+		data.Campaign = rule.Target
+	} else {
+		data.ShortCode = rule.Target
+	}
+	alertsLogger.Warn("Alert triggered", "rule_id", rule.ID, "kind", rule.Kind, "target", target, "message", message)
+	s.events.Emit(EventAlertTriggered, rule.Owner, data)
+}
+
+// alertScope retourne le périmètre des clics évalués par une règle.
+func alertScope(rule *models.AlertRule) repository.ClickScope {
+	if rule.CampaignID != nil {
+		return repository.ClickScope{CampaignID: *rule.CampaignID}
+	}
+	return repository.ClickScope{LinkID: *rule.LinkID}
+}
+
+// defaultMinutes retourne minutes, ou la durée par défaut en minutes si minutes vaut 0.
+func defaultMinutes(minutes int, fallback time.Duration) int {
+	if minutes == 0 {
+		return int(fallback / time.Minute)
+	}
+	return minutes
+}
@@ -0,0 +1,52 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/antoine-granier/urlshortener/internal/logging"
+	"github.com/antoine-granier/urlshortener/internal/models"
+	"github.com/antoine-granier/urlshortener/internal/repository"
+)
+
+// Acteurs enregistrés dans le journal d'audit.
+const (
+	ActorAPI       = "api"
+	ActorCLI       = "cli"
+	ActorScheduler = "scheduler"
+)
+
+// AuditService enregistre les opérations sensibles dans le journal d'audit.
+type AuditService struct {
+	auditRepo repository.AuditRepository
+}
+
+// NewAuditService crée et retourne une nouvelle instance de AuditService.
+func NewAuditService(auditRepo repository.AuditRepository) *AuditService {
+	return &AuditService{auditRepo: auditRepo}
+}
+
+// Record ajoute une entrée au journal d'audit. L'opération auditée ayant déjà eu lieu,
+// un échec d'écriture est journalisé sans être remonté. L'identifiant de requête porté par ctx est conservé.
+func (s *AuditService) Record(ctx context.Context, actor, action string, linkID uint, details string) {
+	auditLogger.InfoContext(ctx, "Audit entry", "actor", actor, "action", action, "link_id", linkID, "details", details)
+	entry := &models.AuditEntry{
+		Actor:     actor,
+		Action:    action,
+		LinkID:    linkID,
+		Details:   details,
+		RequestID: logging.RequestID(ctx),
+	}
+	if err := s.auditRepo.CreateEntry(entry); err != nil {
+		auditLogger.ErrorContext(ctx, "Failed to save audit entry", "action", action, logging.Err(err))
+	}
+}
+
+// ListLinkEntries retourne les dernières entrées d'audit d'un lien.
+func (s *AuditService) ListLinkEntries(linkID uint, limit int) ([]models.AuditEntry, error) {
+	entries, err := s.auditRepo.ListEntriesByLinkID(linkID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("Echec de la récupération du journal d'audit: %w", err)
+	}
+	return entries, nil
+}
@@ -0,0 +1,43 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/antoine-granier/urlshortener/internal/botdetect"
+)
+
+// Détection de rafales par défaut : plus de 10 clics en 5 secondes depuis une même IP.
+const (
+	defaultBotBurstLimit  = 10
+	defaultBotBurstWindow = 5 * time.Second
+)
+
+// SetBotClassifier remplace le classificateur des visiteurs (signatures, détection de rafales).
+func (s *LinkService) SetBotClassifier(c *botdetect.Classifier) {
+	s.bots = c
+}
+
+// ClassifyVisitor indique si la requête d'un visiteur provient d'un robot.
+// Le résultat est enregistré avec le clic.
+func (s *LinkService) ClassifyVisitor(req botdetect.Request) botdetect.Result {
+	return s.bots.Classify(req)
+}
+
+// WithBots retourne une vue du service dont les statistiques incluent les clics de robots,
+// exclus par défaut. Les visiteurs uniques n'en comptent jamais.
+func (s *LinkService) WithBots() *LinkService {
+	view := *s
+	view.clickRepo = s.clickRepo.IncludingBots()
+	return &view
+}
+
+// GetBotBreakdown retourne le nombre de clics de robots d'un lien par catégorie (preview, scanner...).
+func (s *LinkService) GetBotBreakdown(linkID uint) (map[string]int, error) {
+	counts, err := s.clickRepo.IncludingBots().CountClicksByDimension(linkID, "bot")
+	if err != nil {
+		return nil, fmt.Errorf("Echec de la ventilation des clics de robots pour LinkID %d: %w", linkID, err)
+	}
+	delete(counts, "") // Clics humains
+	return counts, nil
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ErrInvalidURL est retournée quand une URL longue ne peut pas être canonicalisée.
+var ErrInvalidURL = errors.New("URL invalide")
+
+// DefaultTrackingParams liste les paramètres de suivi ignorés lors de la canonicalisation
+// quand leur suppression est activée. Une entrée terminée par '*' est un préfixe.
+var DefaultTrackingParams = []string{
+	"utm_*", "fbclid", "gclid", "dclid", "gbraid", "wbraid", "msclkid",
+	"mc_cid", "mc_eid", "igshid", "yclid", "_hsenc", "_hsmi", "mkt_tok",
+}
+
+// URLCanonicalizer ramène des URLs équivalentes à une forme unique afin de détecter les doublons.
+type URLCanonicalizer struct {
+	StripTracking  bool     // Supprime les paramètres de suivi de la forme canonique
+	TrackingParams []string // Paramètres considérés comme du suivi (DefaultTrackingParams si vide)
+}
+
+// Canonicalize retourne la forme canonique d'une URL absolue :
+// schéma et hôte en minuscules, ports par défaut supprimés, chemin vide remplacé par "/",
+// paramètres de requête triés et, si demandé, débarrassés des paramètres de suivi.
+// Le chemin et le fragment sont conservés tels quels car ils peuvent être sensibles à la casse.
+func (c *URLCanonicalizer) Canonicalize(rawURL string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return "", fmt.Errorf("%w '%s': %v", ErrInvalidURL, rawURL, err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("%w '%s': schéma et hôte requis", ErrInvalidURL, rawURL)
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	host, port := strings.ToLower(u.Hostname()), u.Port()
+	if (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		port = ""
+	}
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]" // Adresse IPv6
+	}
+	if port != "" {
+		host += ":" + port
+	}
+	u.Host = host
+
+	if u.Path == "" {
+		u.Path = "/"
+	}
+
+	query := u.Query()
+	if c.StripTracking {
+		for key := range query {
+			if c.isTrackingParam(key) {
+				query.Del(key)
+			}
+		}
+	}
+	// Encode trie les paramètres par clé ; l'ordre des valeurs d'une même clé est conservé.
+	u.RawQuery = query.Encode()
+	u.ForceQuery = false
+
+	return u.String(), nil
+}
+
+// isTrackingParam indique si un paramètre de requête est un paramètre de suivi.
+func (c *URLCanonicalizer) isTrackingParam(key string) bool {
+	params := c.TrackingParams
+	if len(params) == 0 {
+		params = DefaultTrackingParams
+	}
+	key = strings.ToLower(key)
+	for _, p := range params {
+		p = strings.ToLower(p)
+		if prefix, ok := strings.CutSuffix(p, "*"); ok {
+			if strings.HasPrefix(key, prefix) {
+				return true
+			}
+		} else if key == p {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/antoine-granier/urlshortener/internal/models"
+)
+
+// ErrInvalidCard est retournée quand la personnalisation de la carte de partage d'un lien est invalide.
+var ErrInvalidCard = errors.New("carte de partage invalide")
+
+// Longueurs maximales des textes personnalisés des cartes de partage.
+const (
+	maxCardTitleLength       = 300
+	maxCardDescriptionLength = 1000
+)
+
+// LinkCard est la carte de partage (Open Graph / Twitter) servie aux robots des réseaux sociaux.
+type LinkCard struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	ImageURL    string `json:"image_url,omitempty"`
+	SiteName    string `json:"site_name,omitempty"`
+	URL         string `json:"url"` // URL courte partagée
+}
+
+// validateCard vérifie la personnalisation de la carte de partage : textes bornés, image http(s).
+func validateCard(title, description, imageURL string) error {
+	if utf8.RuneCountInString(title) > maxCardTitleLength {
+		return fmt.Errorf("%w: titre trop long (%d caractères maximum)", ErrInvalidCard, maxCardTitleLength)
+	}
+	if utf8.RuneCountInString(description) > maxCardDescriptionLength {
+		return fmt.Errorf("%w: description trop longue (%d caractères maximum)", ErrInvalidCard, maxCardDescriptionLength)
+	}
+	if imageURL != "" {
+		u, err := url.Parse(imageURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("%w: l'image doit être une URL http(s) absolue", ErrInvalidCard)
+		}
+	}
+	return nil
+}
+
+// BuildLinkCard construit la carte de partage d'un lien. Chaque champ personnalisé remplace
+// l'information récupérée sur la destination (og:title puis <title>, og:description puis description, og:image).
+// Pour un lien protégé ou à usage unique, seuls les champs personnalisés sont utilisés :
+// les informations de la destination ne doivent pas être révélées.
+func BuildLinkCard(link *models.Link, shortURL string) LinkCard {
+	card := LinkCard{
+		Title:       link.CardTitle,
+		Description: link.CardDescription,
+		ImageURL:    link.CardImageURL,
+		URL:         shortURL,
+	}
+	if meta := link.Metadata; meta != nil && !DestinationHidden(link) {
+		card.Title = firstNonEmpty(card.Title, meta.OpenGraph["og:title"], meta.Title)
+		card.Description = firstNonEmpty(card.Description, meta.OpenGraph["og:description"], meta.Description)
+		card.ImageURL = firstNonEmpty(card.ImageURL, meta.ImageURL)
+		card.SiteName = meta.SiteName
+	}
+	if card.Title == "" {
+		card.Title = shortURL
+	}
+	return card
+}
+
+// DestinationHidden indique si la destination d'un lien (et ce qui en provient) doit rester cachée
+// jusqu'à la redirection : lien protégé par mot de passe ou à usage unique.
+func DestinationHidden(link *models.Link) bool {
+	return link.PasswordHash != "" || link.OneTime
+}
+
+// firstNonEmpty retourne la première valeur non vide (après suppression des espaces).
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/antoine-granier/urlshortener/internal/logging"
+	"github.com/antoine-granier/urlshortener/internal/repository"
+)
+
+// ClickThresholdEventData est la charge utile de l'événement click.threshold_reached.
+type ClickThresholdEventData struct {
+	ShortCode string `json:"short_code"`
+	Owner     string `json:"owner,omitempty"`
+	Threshold int    `json:"threshold"`
+	Clicks    int    `json:"clicks"`
+}
+
+// thresholdStateLimit borne le nombre de liens suivis en mémoire par WatchClickThresholds.
+const thresholdStateLimit = 100_000
+
+// linkThresholdState est l'état d'un lien suivi par WatchClickThresholds.
+type linkThresholdState struct {
+	clicks int // Clics connus : dernier comptage en base, plus les clics enregistrés depuis par ce serveur
+	next   int // Indice du prochain palier à atteindre (len(thresholds) : tous atteints)
+}
+
+// WatchClickThresholds émet click.threshold_reached quand le nombre de clics (hors robots) d'un lien
+// atteint un des paliers donnés. Chaque palier n'est notifié qu'une fois par lien, même entre plusieurs
+// instances du serveur, grâce à l'enregistrement des paliers franchis. Sans palier, rien n'est surveillé.
+//
+// Pour ne pas interroger la base à chaque clic, les clics de chaque lien sont comptés en mémoire à partir
+// d'un premier comptage en base ; la base n'est relue que lorsque le prochain palier semble atteint, et un
+// lien dont tous les paliers sont franchis n'est plus suivi. Avec plusieurs instances, chacune ne voit que
+// ses propres clics : un palier peut être notifié avec un peu de retard, jamais deux fois.
+// Les événements click.recorded étant émis par EmitAsync, ce suivi tourne dans la goroutine du bus.
+func WatchClickThresholds(bus *EventBus, clickRepo repository.ClickRepository, webhookRepo repository.WebhookRepository, thresholds []int) {
+	thresholds = slices.DeleteFunc(slices.Clone(thresholds), func(t int) bool { return t <= 0 })
+	if len(thresholds) == 0 {
+		return
+	}
+	slices.Sort(thresholds)
+	thresholds = slices.Compact(thresholds)
+
+	var mu sync.Mutex
+	states := make(map[uint]*linkThresholdState)
+
+	bus.Subscribe(func(event Event) {
+		click, ok := event.Data.(LiveClick)
+		if event.Type != EventClickRecorded || !ok || click.Bot {
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		state, known := states[click.LinkID]
+		if known {
+			if state.next >= len(thresholds) {
+				return
+			}
+			state.clicks++
+			if state.clicks < thresholds[state.next] {
+				return
+			}
+		} else {
+			if len(states) >= thresholdStateLimit {
+				clear(states)
+			}
+			state = &linkThresholdState{}
+			states[click.LinkID] = state
+		}
+
+		// Premier clic vu pour ce lien, ou palier apparemment atteint : comptage exact en base.
+		count, err := clickRepo.CountClicksByLinkID(click.LinkID)
+		if err != nil {
+			eventsLogger.Error("Failed to count link clicks", "short_code", click.ShortCode, logging.Err(err))
+			delete(states, click.LinkID)
+			return
+		}
+		state.clicks = count
+		for state.next < len(thresholds) && thresholds[state.next] <= count {
+			threshold := thresholds[state.next]
+			reached, err := webhookRepo.MarkThresholdReached(click.LinkID, threshold)
+			if err != nil {
+				eventsLogger.Error("Failed to record click threshold", "short_code", click.ShortCode, "threshold", threshold, logging.Err(err))
+				delete(states, click.LinkID)
+				return
+			}
+			state.next++
+			if reached {
+				bus.Emit(EventClickThresholdReached, event.Owner,
+					ClickThresholdEventData{ShortCode: click.ShortCode, Owner: click.Owner, Threshold: threshold, Clicks: count})
+			}
+		}
+	})
+}
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/antoine-granier/urlshortener/internal/models"
+	"github.com/antoine-granier/urlshortener/internal/repository"
+)
+
+// countingClickRepo compte les comptages de clics demandés à la base.
+type countingClickRepo struct {
+	repository.ClickRepository
+	counts int
+}
+
+func (r *countingClickRepo) CountClicksByLinkID(linkID uint) (int, error) {
+	r.counts++
+	return r.ClickRepository.CountClicksByLinkID(linkID)
+}
+
+func TestWatchClickThresholds(t *testing.T) {
+	db := newTestDB(t)
+	clickRepo := &countingClickRepo{ClickRepository: repository.NewClickRepository(db)}
+	bus := NewEventBus()
+	var reached []ClickThresholdEventData
+	bus.Subscribe(func(event Event) {
+		if event.Type == EventClickThresholdReached {
+			reached = append(reached, event.Data.(ClickThresholdEventData))
+		}
+	})
+	WatchClickThresholds(bus, clickRepo, repository.NewWebhookRepository(db), []int{5, 3, 0, 5})
+
+	link := &models.Link{ShortCode: "abc", LongURL: "https://example.com", CanonicalURL: "https://example.com"}
+	if err := db.Create(link).Error; err != nil {
+		t.Fatalf("create link: %v", err)
+	}
+	click := func(bot bool) {
+		if err := db.Create(&models.Click{LinkID: link.ID, Timestamp: time.Now(), Bot: bot}).Error; err != nil {
+			t.Fatalf("create click: %v", err)
+		}
+		bus.Emit(EventClickRecorded, "", LiveClick{LinkID: link.ID, ShortCode: link.ShortCode, Bot: bot})
+	}
+
+	for i := 0; i < 8; i++ {
+		click(false)
+		click(true) // Les robots ne comptent pas
+	}
+
+	if len(reached) != 2 || reached[0].Threshold != 3 || reached[1].Threshold != 5 {
+		t.Fatalf("thresholds reached = %+v, want 3 then 5", reached)
+	}
+	if reached[0].Clicks != 3 || reached[1].Clicks != 5 {
+		t.Errorf("clicks at thresholds = %d, %d, want 3, 5", reached[0].Clicks, reached[1].Clicks)
+	}
+	// Un comptage au premier clic, puis un par palier ; aucun une fois tous les paliers franchis.
+	if clickRepo.counts != 3 {
+		t.Errorf("CountClicksByLinkID called %d times for 8 clicks, want 3", clickRepo.counts)
+	}
+
+	// Un second watcher (autre instance, ou redémarrage) ne notifie pas de nouveau les paliers.
+	other := NewEventBus()
+	other.Subscribe(func(event Event) {
+		if event.Type == EventClickThresholdReached {
+			t.Errorf("threshold %v notified twice", event.Data)
+		}
+	})
+	WatchClickThresholds(other, clickRepo, repository.NewWebhookRepository(db), []int{3, 5})
+	other.Emit(EventClickRecorded, "", LiveClick{LinkID: link.ID, ShortCode: link.ShortCode})
+}
+
+func TestEmitAsyncRunsSubscribersOffCaller(t *testing.T) {
+	bus := NewEventBus()
+	done := make(chan Event, 1)
+	bus.Subscribe(func(event Event) { done <- event })
+	bus.EmitAsync(EventClickRecorded, "team", LiveClick{ShortCode: "abc"})
+	select {
+	case event := <-done:
+		if event.Type != EventClickRecorded || event.Owner != "team" || event.ID == "" {
+			t.Errorf("event = %+v", event)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("asynchronous event not delivered")
+	}
+}
@@ -0,0 +1,258 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/antoine-granier/urlshortener/internal/models"
+	"github.com/antoine-granier/urlshortener/internal/repository"
+)
+
+var (
+	// ErrInvalidConversion est retournée quand une conversion signalée est invalide.
+	ErrInvalidConversion = errors.New("conversion invalide")
+	// ErrUnknownClickID est retournée quand l'identifiant de clic d'une conversion ne correspond à aucun clic.
+	ErrUnknownClickID = errors.New("identifiant de clic inconnu")
+	// ErrAttributionExpired est retournée quand une conversion arrive après la fenêtre d'attribution du clic.
+	ErrAttributionExpired = errors.New("fenêtre d'attribution dépassée")
+)
+
+// Modes de transmission de l'identifiant de clic au site de destination.
+const (
+	ClickIDModeCookie = "cookie" // Cookie du domaine court, relu par le pixel
+	ClickIDModeQuery  = "query"  // Paramètre ajouté à l'URL de destination
+	ClickIDModeBoth   = "both"
+	ClickIDModeOff    = "off" // Aucun identifiant : pas de suivi des conversions
+)
+
+// Canaux de signalement d'une conversion.
+const (
+	ConversionSourcePixel    = "pixel"
+	ConversionSourcePostback = "postback"
+)
+
+// Valeurs par défaut des options de suivi des conversions.
+const (
+	defaultClickIDParam        = "cid"
+	defaultClickIDCookie       = "us_cid"
+	defaultAttributionDays     = 30
+	maxConversionOrderIDLength = 100
+)
+
+var (
+	clickIDPattern  = regexp.MustCompile(`^[0-9a-f]{32}$`)
+	currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+)
+
+// ConversionOptions configure le suivi des conversions.
+type ConversionOptions struct {
+	Mode            string // ClickIDModeCookie (par défaut), ClickIDModeQuery, ClickIDModeBoth ou ClickIDModeOff
+	QueryParam      string // Paramètre de destination portant l'identifiant de clic ("cid" par défaut)
+	CookieName      string // Cookie portant l'identifiant de clic ("us_cid" par défaut)
+	AttributionDays int    // Délai maximal entre le clic et la conversion (30 jours par défaut)
+	HonorDNT        bool   // Aucun identifiant de clic pour les visiteurs envoyant DNT: 1 ou Sec-GPC: 1
+}
+
+// ConversionService attribue un identifiant aux clics des visiteurs et rattache à ces clics
+// les conversions signalées par les sites de destination (pixel ou postback serveur à serveur).
+type ConversionService struct {
+	repo repository.ConversionRepository
+	opts ConversionOptions
+}
+
+// NewConversionService crée et retourne une nouvelle instance de ConversionService.
+func NewConversionService(repo repository.ConversionRepository, opts ConversionOptions) (*ConversionService, error) {
+	switch opts.Mode {
+	case "":
+		opts.Mode = ClickIDModeCookie
+	case ClickIDModeCookie, ClickIDModeQuery, ClickIDModeBoth, ClickIDModeOff:
+	default:
+		return nil, fmt.Errorf("mode de transmission de l'identifiant de clic inconnu '%s' (cookie, query, both ou off)", opts.Mode)
+	}
+	if opts.QueryParam == "" {
+		opts.QueryParam = defaultClickIDParam
+	}
+	if opts.CookieName == "" {
+		opts.CookieName = defaultClickIDCookie
+	}
+	if opts.AttributionDays <= 0 {
+		opts.AttributionDays = defaultAttributionDays
+	}
+	return &ConversionService{repo: repo, opts: opts}, nil
+}
+
+// CookieName retourne le nom du cookie portant l'identifiant de clic.
+func (s *ConversionService) CookieName() string {
+	return s.opts.CookieName
+}
+
+// AttributionWindow retourne le délai maximal entre un clic et sa conversion.
+func (s *ConversionService) AttributionWindow() time.Duration {
+	return time.Duration(s.opts.AttributionDays) * 24 * time.Hour
+}
+
+// ConversionTag est l'identifiant attribué à un clic et la façon de le transmettre au visiteur.
+type ConversionTag struct {
+	ClickID     string // Vide si le clic n'est pas suivi
+	Destination string // Destination, avec le paramètre d'identifiant de clic en mode "query"
+	Cookie      string // Nom du cookie à poser, vide si l'identifiant ne passe pas par un cookie
+	MaxAge      int    // Durée de vie du cookie en secondes
+}
+
+// Tag attribue un identifiant au clic d'un visiteur vers destination. Les robots et, si DNT est
+// respecté, les visiteurs ayant demandé à ne pas être suivis n'en reçoivent pas : la destination
+// est alors retournée telle quelle.
+func (s *ConversionService) Tag(destination string, bot, doNotTrack bool) (ConversionTag, error) {
+	tag := ConversionTag{Destination: destination}
+	if s.opts.Mode == ClickIDModeOff || bot || (doNotTrack && s.opts.HonorDNT) {
+		return tag, nil
+	}
+
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return tag, fmt.Errorf("Echec de la génération de l'identifiant de clic: %w", err)
+	}
+	tag.ClickID = hex.EncodeToString(raw)
+
+	if s.opts.Mode == ClickIDModeQuery || s.opts.Mode == ClickIDModeBoth {
+		u, err := url.Parse(destination)
+		if err != nil {
+			return ConversionTag{Destination: destination}, fmt.Errorf("Echec de l'ajout de l'identifiant de clic à '%s': %w", destination, err)
+		}
+		query := u.Query()
+		query.Set(s.opts.QueryParam, tag.ClickID)
+		u.RawQuery = query.Encode()
+		tag.Destination = u.String()
+	}
+	if s.opts.Mode == ClickIDModeCookie || s.opts.Mode == ClickIDModeBoth {
+		tag.Cookie = s.opts.CookieName
+		tag.MaxAge = int(s.AttributionWindow() / time.Second)
+	}
+	return tag, nil
+}
+
+// ConversionInput regroupe les informations d'une conversion signalée.
+type ConversionInput struct {
+	ClickID  string
+	Value    float64 // Montant, 0 si non renseigné
+	Currency string  // Devise ISO 4217 (ex: EUR), optionnelle
+	OrderID  string  // Identifiant de commande, pour compter plusieurs achats d'un même clic (optionnel)
+}
+
+// Record rattache une conversion au clic portant son identifiant. Une conversion déjà enregistrée
+// (même clic, même commande) n'est pas comptée deux fois : elle est retournée avec created à false.
+func (s *ConversionService) Record(input ConversionInput, source string) (conversion *models.Conversion, created bool, err error) {
+	input.ClickID = strings.ToLower(strings.TrimSpace(input.ClickID))
+	input.Currency = strings.ToUpper(strings.TrimSpace(input.Currency))
+	input.OrderID = strings.TrimSpace(input.OrderID)
+	switch {
+	case !clickIDPattern.MatchString(input.ClickID):
+		return nil, false, fmt.Errorf("%w: identifiant de clic '%s' mal formé", ErrInvalidConversion, input.ClickID)
+	case math.IsNaN(input.Value) || math.IsInf(input.Value, 0) || input.Value < 0:
+		return nil, false, fmt.Errorf("%w: le montant doit être un nombre positif", ErrInvalidConversion)
+	case input.Currency != "" && !currencyPattern.MatchString(input.Currency):
+		return nil, false, fmt.Errorf("%w: devise '%s' (code ISO 4217 attendu, ex: EUR)", ErrInvalidConversion, input.Currency)
+	case len(input.OrderID) > maxConversionOrderIDLength:
+		return nil, false, fmt.Errorf("%w: identifiant de commande trop long (%d caractères maximum)", ErrInvalidConversion, maxConversionOrderIDLength)
+	}
+
+	click, err := s.repo.GetClickByClickID(input.ClickID)
+	if err != nil {
+		if repository.IsUnknownClick(err) {
+			return nil, false, fmt.Errorf("%w: '%s'", ErrUnknownClickID, input.ClickID)
+		}
+		return nil, false, fmt.Errorf("Echec de la récupération du clic '%s': %w", input.ClickID, err)
+	}
+	if time.Since(click.Timestamp) > s.AttributionWindow() {
+		return nil, false, fmt.Errorf("%w: clic du %s", ErrAttributionExpired, click.Timestamp.UTC().Format(time.RFC3339))
+	}
+
+	conversion = &models.Conversion{
+		ClickID:   input.ClickID,
+		OrderID:   input.OrderID,
+		LinkID:    click.LinkID,
+		ClickedAt: click.Timestamp.UTC(),
+		Value:     input.Value,
+		Currency:  input.Currency,
+		Source:    source,
+	}
+	if created, err = s.repo.CreateConversion(conversion); err != nil {
+		return nil, false, fmt.Errorf("Echec de l'enregistrement de la conversion du clic '%s': %w", input.ClickID, err)
+	}
+	if !created {
+		if conversion, err = s.repo.GetConversion(input.ClickID, input.OrderID); err != nil {
+			return nil, false, fmt.Errorf("Echec de la récupération de la conversion du clic '%s': %w", input.ClickID, err)
+		}
+	}
+	return conversion, created, nil
+}
+
+// ConversionStats regroupe les conversions d'un lien ou d'une campagne sur une période.
+type ConversionStats struct {
+	Conversions int                `json:"conversions"`
+	Rate        float64            `json:"conversion_rate"`  // Conversions rapportées aux clics de la période
+	Value       map[string]float64 `json:"conversion_value"` // Montant cumulé par devise
+}
+
+// Stats compte les conversions du périmètre enregistrées sur la période et calcule leur taux
+// par rapport aux clics de la même période.
+func (s *ConversionService) Stats(scope repository.ClickScope, r DayRange, clicks int) (*ConversionStats, error) {
+	from, to := r.bounds()
+	totals, err := s.repo.CountConversions(scope, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("Echec du comptage des conversions pour %s: %w", scope, err)
+	}
+	return newConversionStats(totals, clicks), nil
+}
+
+// CountByLink compte les conversions du périmètre enregistrées sur la période, par lien.
+func (s *ConversionService) CountByLink(scope repository.ClickScope, r DayRange) (map[uint]int, error) {
+	from, to := r.bounds()
+	counts, err := s.repo.CountConversionsByLink(scope, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("Echec du comptage des conversions par lien pour %s: %w", scope, err)
+	}
+	return counts, nil
+}
+
+// newConversionStats calcule le taux de conversion (arrondi à 4 décimales) à partir des totaux.
+func newConversionStats(totals repository.ConversionTotals, clicks int) *ConversionStats {
+	stats := &ConversionStats{Conversions: totals.Conversions, Value: totals.Value}
+	if stats.Value == nil {
+		stats.Value = map[string]float64{}
+	}
+	if clicks > 0 {
+		stats.Rate = math.Round(float64(totals.Conversions)/float64(clicks)*10000) / 10000
+	}
+	return stats
+}
+
+// SetConversionService active le suivi des conversions des redirections et des statistiques.
+func (s *LinkService) SetConversionService(conversions *ConversionService) {
+	s.conversions = conversions
+}
+
+// TagConversion attribue un identifiant au clic d'un visiteur vers destination (voir ConversionService.Tag).
+// Sans suivi des conversions, ou en cas d'erreur (journalisée par l'appelant), la destination est inchangée.
+func (s *LinkService) TagConversion(destination string, bot, doNotTrack bool) (ConversionTag, error) {
+	if s.conversions == nil {
+		return ConversionTag{Destination: destination}, nil
+	}
+	return s.conversions.Tag(destination, bot, doNotTrack)
+}
+
+// GetConversionStats retourne les conversions d'un lien sur une période, rapportées à ses clics.
+// Sans suivi des conversions, elles sont à zéro.
+func (s *LinkService) GetConversionStats(linkID uint, r DayRange, clicks int) (*ConversionStats, error) {
+	if s.conversions == nil {
+		return newConversionStats(repository.ConversionTotals{}, clicks), nil
+	}
+	return s.conversions.Stats(repository.ClickScope{LinkID: linkID}, r, clicks)
+}
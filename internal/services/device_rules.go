@@ -0,0 +1,123 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/antoine-granier/urlshortener/internal/models"
+	"github.com/antoine-granier/urlshortener/internal/useragent"
+)
+
+// ErrInvalidDeviceRules est retournée quand une liste de règles de ciblage par plateforme est incohérente.
+var ErrInvalidDeviceRules = errors.New("règles de plateforme invalides")
+
+// devicePlatforms associe chaque plateforme ciblable au test correspondant sur le User-Agent analysé.
+var devicePlatforms = map[string]func(useragent.Info) bool{
+	"ios":     func(i useragent.Info) bool { return i.OS == useragent.OSiOS },
+	"android": func(i useragent.Info) bool { return i.OS == useragent.OSAndroid },
+	"windows": func(i useragent.Info) bool { return i.OS == useragent.OSWindows },
+	"macos":   func(i useragent.Info) bool { return i.OS == useragent.OSMacOS },
+	"linux":   func(i useragent.Info) bool { return i.OS == useragent.OSLinux || i.OS == useragent.OSChromeOS },
+	"mobile":  func(i useragent.Info) bool { return i.Device == useragent.DeviceMobile },
+	"tablet":  func(i useragent.Info) bool { return i.Device == useragent.DeviceTablet },
+	"desktop": func(i useragent.Info) bool { return i.Device == useragent.DeviceDesktop },
+}
+
+// forbiddenSchemes ne sont jamais acceptés comme destination ou lien profond.
+var forbiddenSchemes = []string{"javascript", "data", "vbscript", "file"}
+
+// DeviceRuleInput décrit une règle de ciblage par plateforme fournie par l'API ou la CLI.
+type DeviceRuleInput struct {
+	Name     string `json:"name"`
+	Platform string `json:"platform"`
+	URL      string `json:"url"`
+	DeepLink string `json:"deep_link"`
+}
+
+// ParseDeviceRule lit une règle au format CLI "plateforme=url".
+func ParseDeviceRule(s string) (DeviceRuleInput, error) {
+	platform, target, ok := strings.Cut(s, "=")
+	if !ok {
+		return DeviceRuleInput{}, fmt.Errorf("%w: %q (format attendu plateforme=url)", ErrInvalidDeviceRules, s)
+	}
+	return DeviceRuleInput{Platform: platform, URL: target}, nil
+}
+
+// buildDeviceRules valide des règles de plateforme et les convertit en règles de routage
+// (une condition Platforms, origine "device"), dans l'ordre fourni.
+// Les destinations peuvent utiliser n'importe quel schéma (https, intent, itms-apps...) hormis les schémas dangereux.
+func buildDeviceRules(inputs []DeviceRuleInput) ([]models.RoutingRule, error) {
+	rules := make([]models.RoutingRule, 0, len(inputs))
+	for _, in := range inputs {
+		platform := strings.ToLower(strings.TrimSpace(in.Platform))
+		if _, ok := devicePlatforms[platform]; !ok {
+			return nil, fmt.Errorf("%w: plateforme inconnue %q", ErrInvalidDeviceRules, in.Platform)
+		}
+		name := strings.TrimSpace(in.Name)
+		if name == "" {
+			name = platform
+		}
+		if len(name) > 50 {
+			return nil, fmt.Errorf("%w: nom de règle trop long", ErrInvalidDeviceRules)
+		}
+		if err := validateTargetURL(in.URL, false); err != nil {
+			return nil, fmt.Errorf("%w: règle %q: %v", ErrInvalidDeviceRules, name, err)
+		}
+		if in.DeepLink != "" {
+			if err := validateTargetURL(in.DeepLink, true); err != nil {
+				return nil, fmt.Errorf("%w: lien profond de la règle %q: %v", ErrInvalidDeviceRules, name, err)
+			}
+		}
+		rules = append(rules, models.RoutingRule{
+			Name:     name,
+			When:     models.RuleConditions{Platforms: []string{platform}},
+			URL:      in.URL,
+			DeepLink: in.DeepLink,
+			Origin:   models.RuleOriginDevice,
+		})
+	}
+	return rules, nil
+}
+
+// validateTargetURL vérifie qu'une destination est une URL absolue au schéma autorisé.
+// Un lien profond d'application (myapp://...) n'a pas forcément d'hôte.
+func validateTargetURL(raw string, allowNoHost bool) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme == "" {
+		return fmt.Errorf("URL absolue attendue: %q", raw)
+	}
+	for _, scheme := range forbiddenSchemes {
+		if strings.EqualFold(u.Scheme, scheme) {
+			return fmt.Errorf("schéma non autorisé: %q", u.Scheme)
+		}
+	}
+	if (u.Scheme == "http" || u.Scheme == "https") && u.Host == "" {
+		return fmt.Errorf("hôte manquant: %q", raw)
+	}
+	if !allowNoHost && u.Host == "" && u.Opaque == "" && u.Path == "" {
+		return fmt.Errorf("URL incomplète: %q", raw)
+	}
+	return nil
+}
+
+// SetLinkDeviceRules remplace les règles de plateforme d'un lien (ses règles de routage d'origine "device"),
+// sans toucher à ses autres règles de routage. Une liste vide supprime toutes les règles de plateforme.
+func (s *LinkService) SetLinkDeviceRules(ctx context.Context, shortCode string, inputs []DeviceRuleInput) (*models.Link, error) {
+	link, err := s.linkRepo.GetLinkByShortCode(shortCode)
+	if err != nil {
+		return nil, fmt.Errorf("Echec de la récupération du lien '%s': %w", shortCode, err)
+	}
+	rules, err := buildDeviceRules(inputs)
+	if err != nil {
+		return nil, err
+	}
+	link.RoutingRules = link.RoutingRules.ReplaceOrigin(models.RuleOriginDevice, rules)
+	if err := s.linkRepo.UpdateLink(link); err != nil {
+		return nil, fmt.Errorf("Echec de la mise à jour des règles du lien '%s': %w", shortCode, err)
+	}
+	s.emitLinkEvent(ctx, EventLinkUpdated, link)
+	return link, nil
+}
@@ -0,0 +1,171 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/antoine-granier/urlshortener/internal/logging"
+	"github.com/antoine-granier/urlshortener/internal/metrics"
+	"github.com/antoine-granier/urlshortener/internal/models"
+)
+
+// Types d'événements émis par le service.
+const (
+	EventLinkCreated           = "link.created"
+	EventLinkUpdated           = "link.updated"
+	EventLinkDeleted           = "link.deleted"
+	EventLinkExpired           = "link.expired" // Lien à usage unique utilisé
+	EventClickRecorded         = "click.recorded"
+	EventClickThresholdReached = "click.threshold_reached"
+	EventLinkHealthChanged     = "link.health_changed"
+	EventAlertTriggered        = "alert.triggered" // Règle d'alerte déclenchée
+)
+
+// EventTypes est la liste des types d'événements auxquels un webhook peut souscrire.
+var EventTypes = []string{
+	EventLinkCreated, EventLinkUpdated, EventLinkDeleted, EventLinkExpired,
+	EventClickRecorded, EventClickThresholdReached, EventLinkHealthChanged, EventAlertTriggered,
+}
+
+// Event est un événement survenu sur un lien. Owner est l'équipe propriétaire du lien,
+// utilisée pour router l'événement ; Data est sérialisée en JSON dans les notifications.
+type Event struct {
+	ID         string    `json:"id"`
+	Type       string    `json:"type"`
+	OccurredAt time.Time `json:"occurred_at"`
+	Owner      string    `json:"owner"`
+	Data       any       `json:"data"`
+
+	RequestID string `json:"-"` // Identifiant de la requête à l'origine de l'événement, repris dans les logs des abonnés
+}
+
+// eventQueueSize est la capacité de la file des événements émis par EmitAsync.
+const eventQueueSize = 4096
+
+// EventBus diffuse les événements du service aux composants abonnés (webhooks, alertes...).
+// Avec Emit, les abonnés sont appelés de façon synchrone dans la goroutine de l'émetteur ; avec EmitAsync,
+// dans la goroutine du bus, l'une après l'autre. Ils doivent rendre la main rapidement et peuvent
+// eux-mêmes émettre des événements.
+type EventBus struct {
+	mu       sync.RWMutex
+	handlers []func(Event)
+
+	queueOnce sync.Once
+	queue     chan Event // Evénements de EmitAsync, créée au premier appel
+}
+
+// NewEventBus crée et retourne une nouvelle instance de EventBus.
+func NewEventBus() *EventBus {
+	return &EventBus{}
+}
+
+// Subscribe abonne un composant à tous les événements émis.
+func (b *EventBus) Subscribe(handler func(Event)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, handler)
+}
+
+// Emit construit et diffuse un événement. Sans EventBus (nil), l'appel est ignoré.
+func (b *EventBus) Emit(eventType, owner string, data any) {
+	b.EmitContext(context.Background(), eventType, owner, data)
+}
+
+// EmitContext est l'équivalent de Emit pour un événement né d'une requête : son identifiant,
+// porté par ctx, accompagne l'événement jusqu'aux abonnés.
+func (b *EventBus) EmitContext(ctx context.Context, eventType, owner string, data any) {
+	if b == nil {
+		return
+	}
+	b.dispatch(Event{ID: newEventID(), Type: eventType, OccurredAt: time.Now().UTC(), Owner: owner, Data: data,
+		RequestID: logging.RequestID(ctx)})
+}
+
+// EmitAsync diffuse un événement depuis la goroutine du bus, sans attendre les abonnés. Elle est destinée
+// aux événements à fort volume (click.recorded), pour que les abonnés (paliers de clics, webhooks) ne
+// ralentissent pas les workers de clics. Comme pour le channel des clics, un événement est abandonné
+// (et compté) si la file est pleine ; les événements encore en file sont perdus à l'arrêt du serveur.
+func (b *EventBus) EmitAsync(eventType, owner string, data any) {
+	if b == nil {
+		return
+	}
+	b.queueOnce.Do(func() {
+		b.queue = make(chan Event, eventQueueSize)
+		go func() {
+			for event := range b.queue {
+				b.dispatch(event)
+			}
+		}()
+	})
+	event := Event{ID: newEventID(), Type: eventType, OccurredAt: time.Now().UTC(), Owner: owner, Data: data}
+	select {
+	case b.queue <- event:
+	default:
+		metrics.EventsDropped.Inc(eventType)
+		eventsLogger.Warn("Event queue full, dropping event", "event", eventType)
+	}
+}
+
+// dispatch appelle les abonnés avec l'événement.
+func (b *EventBus) dispatch(event Event) {
+	b.mu.RLock()
+	handlers := b.handlers
+	b.mu.RUnlock()
+	for _, handler := range handlers {
+		handler(event)
+	}
+}
+
+// LinkEventData est la charge utile des événements link.*.
+type LinkEventData struct {
+	ShortCode string `json:"short_code"`
+	LongURL   string `json:"long_url"`
+	Owner     string `json:"owner,omitempty"`
+}
+
+// NewLinkEventData construit la charge utile d'un événement portant sur un lien.
+func NewLinkEventData(link *models.Link) LinkEventData {
+	return LinkEventData{ShortCode: link.ShortCode, LongURL: link.LongURL, Owner: link.Owner}
+}
+
+// LinkHealthEventData est la charge utile de l'événement link.health_changed.
+type LinkHealthEventData struct {
+	LinkEventData
+	Accessible bool `json:"accessible"`
+}
+
+// SetEventBus active l'émission des événements de création et de modification des liens.
+func (s *LinkService) SetEventBus(bus *EventBus) {
+	s.events = bus
+}
+
+// emitLinkEvent émet un événement portant sur un lien.
+func (s *LinkService) emitLinkEvent(ctx context.Context, eventType string, link *models.Link) {
+	s.events.EmitContext(ctx, eventType, link.Owner, NewLinkEventData(link))
+}
+
+// emitLinkEventByID émet un événement portant sur un lien modifié hors du LinkService (changement programmé).
+func (s *LinkService) emitLinkEventByID(ctx context.Context, eventType string, linkID uint) {
+	if s.events == nil {
+		return
+	}
+	link, err := s.linkRepo.GetLinkByID(linkID)
+	if err != nil {
+		eventsLogger.ErrorContext(ctx, "Failed to emit link event", "event", eventType, "link_id", linkID, logging.Err(err))
+		return
+	}
+	s.emitLinkEvent(ctx, eventType, link)
+}
+
+// newEventID retourne un identifiant aléatoire d'événement (32 caractères hexadécimaux),
+// que les destinataires peuvent utiliser pour ignorer les doublons.
+func newEventID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic("events: impossible de générer un identifiant : " + err.Error())
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/antoine-granier/urlshortener/internal/models"
+)
+
+// ErrInvalidGeoRules est retournée quand une liste de règles de ciblage géographique est incohérente.
+var ErrInvalidGeoRules = errors.New("règles géographiques invalides")
+
+// geoContinents sont les codes de continent utilisés par les bases MaxMind.
+var geoContinents = map[string]bool{"AF": true, "AN": true, "AS": true, "EU": true, "NA": true, "OC": true, "SA": true}
+
+// GeoRuleInput décrit une règle de ciblage géographique fournie par l'API ou la CLI.
+// Exactement un des champs Country ou Continent doit être renseigné.
+type GeoRuleInput struct {
+	Name      string `json:"name"`
+	Country   string `json:"country"`
+	Continent string `json:"continent"`
+	URL       string `json:"url"`
+}
+
+// ParseGeoRule lit une règle au format CLI "PAYS=url" (ex: FR=https://...) ou "continent:CODE=url".
+func ParseGeoRule(s string) (GeoRuleInput, error) {
+	target, dest, ok := strings.Cut(s, "=")
+	if !ok {
+		return GeoRuleInput{}, fmt.Errorf("%w: %q (format attendu PAYS=url ou continent:CODE=url)", ErrInvalidGeoRules, s)
+	}
+	if code, isContinent := strings.CutPrefix(strings.ToLower(target), "continent:"); isContinent {
+		return GeoRuleInput{Continent: code, URL: dest}, nil
+	}
+	return GeoRuleInput{Country: target, URL: dest}, nil
+}
+
+// buildGeoRules valide des règles géographiques et les convertit en règles de routage
+// (une condition Countries ou Continents, origine "geo"), dans l'ordre fourni.
+func buildGeoRules(inputs []GeoRuleInput) ([]models.RoutingRule, error) {
+	rules := make([]models.RoutingRule, 0, len(inputs))
+	for _, in := range inputs {
+		country := strings.ToUpper(strings.TrimSpace(in.Country))
+		continent := strings.ToUpper(strings.TrimSpace(in.Continent))
+		switch {
+		case country != "" && continent != "":
+			return nil, fmt.Errorf("%w: une règle cible un pays ou un continent, pas les deux", ErrInvalidGeoRules)
+		case country != "":
+			if !isCountryCode(country) {
+				return nil, fmt.Errorf("%w: code pays invalide %q (ISO 3166-1 alpha-2 attendu)", ErrInvalidGeoRules, in.Country)
+			}
+		case continent != "":
+			if !geoContinents[continent] {
+				return nil, fmt.Errorf("%w: code continent inconnu %q", ErrInvalidGeoRules, in.Continent)
+			}
+		default:
+			return nil, fmt.Errorf("%w: pays ou continent requis", ErrInvalidGeoRules)
+		}
+
+		name := strings.TrimSpace(in.Name)
+		if name == "" {
+			name = "geo:" + country + continent
+		}
+		if len(name) > 50 {
+			return nil, fmt.Errorf("%w: nom de règle trop long", ErrInvalidGeoRules)
+		}
+		if err := validateTargetURL(in.URL, false); err != nil {
+			return nil, fmt.Errorf("%w: règle %q: %v", ErrInvalidGeoRules, name, err)
+		}
+		rule := models.RoutingRule{Name: name, URL: in.URL, Origin: models.RuleOriginGeo}
+		if country != "" {
+			rule.When.Countries = []string{country}
+		} else {
+			rule.When.Continents = []string{continent}
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func isCountryCode(code string) bool {
+	return len(code) == 2 && code[0] >= 'A' && code[0] <= 'Z' && code[1] >= 'A' && code[1] <= 'Z'
+}
+
+// SetLinkGeoRules remplace les règles géographiques d'un lien (ses règles de routage d'origine "geo"),
+// sans toucher à ses autres règles de routage. Une règle de pays reste prioritaire sur une règle
+// de continent (voir models.RoutingRules.ReplaceOrigin). Une liste vide supprime toutes les règles géographiques.
+func (s *LinkService) SetLinkGeoRules(ctx context.Context, shortCode string, inputs []GeoRuleInput) (*models.Link, error) {
+	link, err := s.linkRepo.GetLinkByShortCode(shortCode)
+	if err != nil {
+		return nil, fmt.Errorf("Echec de la récupération du lien '%s': %w", shortCode, err)
+	}
+	rules, err := buildGeoRules(inputs)
+	if err != nil {
+		return nil, err
+	}
+	link.RoutingRules = link.RoutingRules.ReplaceOrigin(models.RuleOriginGeo, rules)
+	if err := s.linkRepo.UpdateLink(link); err != nil {
+		return nil, fmt.Errorf("Echec de la mise à jour des règles géographiques du lien '%s': %w", shortCode, err)
+	}
+	s.emitLinkEvent(ctx, EventLinkUpdated, link)
+	return link, nil
+}
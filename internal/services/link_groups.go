@@ -0,0 +1,199 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/antoine-granier/urlshortener/internal/models"
+	"github.com/antoine-granier/urlshortener/internal/repository"
+)
+
+var (
+	// ErrInvalidTags est retournée quand les tags d'un lien sont invalides.
+	ErrInvalidTags = errors.New("tags invalides")
+	// ErrInvalidCampaign est retournée quand le nom d'une campagne est invalide.
+	ErrInvalidCampaign = errors.New("campagne invalide")
+)
+
+// Limites des tags, des campagnes et des listes de liens.
+const (
+	maxTagsPerLink        = 20
+	maxTagLength          = 50
+	maxCampaignNameLength = 100
+
+	defaultListLimit = 50
+	maxListLimit     = 500
+)
+
+// normalizeTags met les noms de tags en minuscules, retire les doublons et vérifie leur format :
+// lettres, chiffres et '-', '_', '.', '/', ':' uniquement.
+func normalizeTags(names []string) ([]string, error) {
+	seen := make(map[string]bool, len(names))
+	tags := make([]string, 0, len(names))
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || seen[name] {
+			continue
+		}
+		if utf8.RuneCountInString(name) > maxTagLength {
+			return nil, fmt.Errorf("%w: %q dépasse %d caractères", ErrInvalidTags, name, maxTagLength)
+		}
+		for _, r := range name {
+			if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune("-_./:", r) {
+				return nil, fmt.Errorf("%w: caractère %q non autorisé dans %q", ErrInvalidTags, r, name)
+			}
+		}
+		seen[name] = true
+		tags = append(tags, name)
+	}
+	if len(tags) > maxTagsPerLink {
+		return nil, fmt.Errorf("%w: %d tags maximum par lien", ErrInvalidTags, maxTagsPerLink)
+	}
+	return tags, nil
+}
+
+// normalizeCampaignName vérifie le nom d'une campagne (espaces de début et de fin retirés).
+// Un nom vide signifie "aucune campagne".
+func normalizeCampaignName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if utf8.RuneCountInString(name) > maxCampaignNameLength {
+		return "", fmt.Errorf("%w: nom trop long (%d caractères maximum)", ErrInvalidCampaign, maxCampaignNameLength)
+	}
+	if strings.IndexFunc(name, unicode.IsControl) >= 0 {
+		return "", fmt.Errorf("%w: caractères de contrôle interdits", ErrInvalidCampaign)
+	}
+	return name, nil
+}
+
+// resolveTags retourne les tags (existants ou créés) correspondant à des noms déjà normalisés.
+func (s *LinkService) resolveTags(names []string) ([]models.Tag, error) {
+	tags, err := s.linkRepo.FindOrCreateTags(names)
+	if err != nil {
+		return nil, fmt.Errorf("Echec de l'enregistrement des tags: %w", err)
+	}
+	return tags, nil
+}
+
+// resolveCampaign retourne la campagne (existante ou créée) d'un propriétaire, nil si name est vide.
+func (s *LinkService) resolveCampaign(owner, name string) (*models.Campaign, error) {
+	if name == "" {
+		return nil, nil
+	}
+	campaign, err := s.linkRepo.FindOrCreateCampaign(owner, name)
+	if err != nil {
+		return nil, fmt.Errorf("Echec de l'enregistrement de la campagne '%s': %w", name, err)
+	}
+	return campaign, nil
+}
+
+// LinkListOptions décrit une recherche de liens : critères facultatifs et pagination.
+type LinkListOptions struct {
+	Owner    string
+	Tag      string
+	Campaign string
+	Limit    int // 0 = valeur par défaut, plafonnée à maxListLimit
+	Offset   int
+}
+
+// LinkPage est une page de résultats d'une recherche de liens.
+type LinkPage struct {
+	Links  []models.Link
+	Clicks map[uint]int // Nombre de clics par ID de lien (absent = aucun clic)
+	Total  int64        // Nombre total de liens correspondant aux critères
+	Limit  int
+	Offset int
+}
+
+// ListLinks retourne une page de liens filtrés par propriétaire, tag et/ou campagne,
+// du plus récent au plus ancien, avec leur nombre de clics.
+func (s *LinkService) ListLinks(opts LinkListOptions) (*LinkPage, error) {
+	filter := repository.LinkFilter{
+		Owner:  opts.Owner,
+		Limit:  opts.Limit,
+		Offset: max(opts.Offset, 0),
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = defaultListLimit
+	}
+	filter.Limit = min(filter.Limit, maxListLimit)
+	// Un tag vide ou fait d'espaces ne filtre pas : normalizeTags l'écarte.
+	tags, err := normalizeTags([]string{opts.Tag})
+	if err != nil {
+		return nil, err
+	}
+	if len(tags) > 0 {
+		filter.Tag = tags[0]
+	}
+	campaign, err := normalizeCampaignName(opts.Campaign)
+	if err != nil {
+		return nil, err
+	}
+	filter.Campaign = campaign
+
+	links, total, err := s.linkRepo.ListLinks(filter)
+	if err != nil {
+		return nil, fmt.Errorf("Echec de la recherche des liens: %w", err)
+	}
+	ids := make([]uint, len(links))
+	for i, link := range links {
+		ids[i] = link.ID
+	}
+	clicks, err := s.clickRepo.CountClicksByLinkIDs(ids)
+	if err != nil {
+		return nil, fmt.Errorf("Echec du comptage des clics des liens: %w", err)
+	}
+	return &LinkPage{Links: links, Clicks: clicks, Total: total, Limit: filter.Limit, Offset: filter.Offset}, nil
+}
+
+// CampaignStats regroupe les statistiques agrégées des liens d'une campagne.
+type CampaignStats struct {
+	Campaign  *models.Campaign
+	Clicks    int                         // Somme des clics de tous les liens de la campagne
+	Links     []repository.LinkClickCount // Clics par lien, du plus cliqué au moins cliqué
+	BySource  map[string]int
+	ByCountry map[string]int
+
+	Conversions *ConversionStats // Conversions de tous les liens, rapportées aux clics de la campagne
+}
+
+// GetCampaignStats calcule les statistiques agrégées de la campagne d'un propriétaire.
+// Il renvoie gorm.ErrRecordNotFound (enveloppée) si la campagne n'existe pas.
+func (s *LinkService) GetCampaignStats(owner, name string) (*CampaignStats, error) {
+	campaign, err := s.linkRepo.GetCampaign(owner, strings.TrimSpace(name))
+	if err != nil {
+		return nil, fmt.Errorf("Echec de la récupération de la campagne '%s': %w", name, err)
+	}
+
+	stats := &CampaignStats{Campaign: campaign}
+	if stats.Links, err = s.clickRepo.CountClicksByCampaign(campaign.ID); err != nil {
+		return nil, fmt.Errorf("Echec du comptage des clics de la campagne '%s': %w", name, err)
+	}
+	for _, l := range stats.Links {
+		stats.Clicks += l.Clicks
+	}
+	if stats.BySource, err = s.clickRepo.CountCampaignClicksByDimension(campaign.ID, "source"); err != nil {
+		return nil, fmt.Errorf("Echec de la ventilation des clics de la campagne '%s': %w", name, err)
+	}
+	if stats.ByCountry, err = s.clickRepo.CountCampaignClicksByDimension(campaign.ID, "country"); err != nil {
+		return nil, fmt.Errorf("Echec de la ventilation des clics de la campagne '%s': %w", name, err)
+	}
+
+	stats.Conversions = newConversionStats(repository.ConversionTotals{}, stats.Clicks)
+	if s.conversions != nil {
+		scope := repository.ClickScope{CampaignID: campaign.ID}
+		if stats.Conversions, err = s.conversions.Stats(scope, DayRange{}, stats.Clicks); err != nil {
+			return nil, fmt.Errorf("Echec du comptage des conversions de la campagne '%s': %w", name, err)
+		}
+		byLink, err := s.conversions.CountByLink(scope, DayRange{})
+		if err != nil {
+			return nil, fmt.Errorf("Echec du comptage des conversions de la campagne '%s': %w", name, err)
+		}
+		for i := range stats.Links {
+			stats.Links[i].Conversions = byLink[stats.Links[i].LinkID]
+		}
+	}
+	return stats, nil
+}
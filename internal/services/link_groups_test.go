@@ -0,0 +1,31 @@
+package services
+
+import "testing"
+
+func TestListLinksBlankTagDoesNotFilter(t *testing.T) {
+	svc, _ := newTestLinkService(t)
+	if _, _, err := svc.CreateLinkWithOptions(t.Context(), CreateLinkOptions{LongURL: "https://example.com/a", Tags: []string{"docs"}}); err != nil {
+		t.Fatalf("create link: %v", err)
+	}
+	if _, _, err := svc.CreateLinkWithOptions(t.Context(), CreateLinkOptions{LongURL: "https://example.com/b"}); err != nil {
+		t.Fatalf("create link: %v", err)
+	}
+
+	for _, tag := range []string{"", " ", "\t"} {
+		page, err := svc.ListLinks(LinkListOptions{Tag: tag})
+		if err != nil {
+			t.Fatalf("ListLinks(Tag: %q): %v", tag, err)
+		}
+		if page.Total != 2 {
+			t.Errorf("ListLinks(Tag: %q) total = %d, want 2", tag, page.Total)
+		}
+	}
+
+	page, err := svc.ListLinks(LinkListOptions{Tag: " Docs "})
+	if err != nil {
+		t.Fatalf("ListLinks(Tag: docs): %v", err)
+	}
+	if page.Total != 1 {
+		t.Errorf("ListLinks(Tag: docs) total = %d, want 1", page.Total)
+	}
+}
@@ -1,15 +1,18 @@
 package services
 
 import (
+	"context"
 	"crypto/rand"
 	"errors"
 	"fmt"
-	"log"
 	"math/big"
+	"slices"
+	"strings"
 	"time"
 
-	"gorm.io/gorm" // Nécessaire pour la gestion spécifique de gorm.ErrRecordNotFound
-
+	"github.com/antoine-granier/urlshortener/internal/botdetect"
+	"github.com/antoine-granier/urlshortener/internal/geoip"
+	"github.com/antoine-granier/urlshortener/internal/metrics"
 	"github.com/antoine-granier/urlshortener/internal/models"
 	"github.com/antoine-granier/urlshortener/internal/repository" // Importe le package repository
 )
@@ -21,23 +24,64 @@ const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
 // LinkService est une structure qui g fournit des méthodes pour la logique métier des liens.
 // Elle détient linkRepo qui est une référence vers une interface LinkRepository.
 // IMPORTANT : Le champ doit être du type de l'interface (non-pointeur).
+// clickRepo sert aux statistiques détaillées (ventilation des clics).
+// codeGen est la stratégie de génération des codes courts (aléatoire par défaut).
+// canonicalizer calcule la forme canonique des URLs longues pour la déduplication.
+// geoLocator localise les visiteurs pour le ciblage géographique (optionnel).
+// passwordAttempts limite les échecs de mot de passe sur les liens protégés.
+// signer émet et vérifie les liens signés à durée limitée (optionnel).
+// metadata récupère les informations des pages de destination (optionnel).
+// bots classe les visiteurs en humains ou robots pour les statistiques.
+// events reçoit les événements de création et de modification des liens (optionnel, webhooks).
+// conversions attribue un identifiant aux clics et compte leurs conversions (optionnel).
 type LinkService struct {
-	linkRepo repository.LinkRepository
+	linkRepo         repository.LinkRepository
+	clickRepo        repository.ClickRepository
+	codeGen          CodeGenerator
+	canonicalizer    *URLCanonicalizer
+	geoLocator       *geoip.Locator
+	passwordAttempts *passwordLimiter
+	signer           *LinkSigner
+	metadata         *MetadataService
+	bots             *botdetect.Classifier
+	events           *EventBus
+	conversions      *ConversionService
 }
 
 // NewLinkService crée et retourne une nouvelle instance de LinkService.
-func NewLinkService(linkRepo repository.LinkRepository) *LinkService {
+func NewLinkService(linkRepo repository.LinkRepository, clickRepo repository.ClickRepository) *LinkService {
 	return &LinkService{
-		linkRepo: linkRepo,
+		linkRepo:      linkRepo,
+		clickRepo:     clickRepo,
+		codeGen:       &RandomCodeGenerator{Length: 6, MaxLength: maxShortCodeLength},
+		canonicalizer: &URLCanonicalizer{},
+
+		passwordAttempts: newPasswordLimiter(defaultPasswordMaxAttempts, defaultPasswordLockout),
+		bots:             botdetect.NewClassifier(botdetect.DefaultSignatures(), defaultBotBurstLimit, defaultBotBurstWindow),
 	}
 }
 
+// SetCodeGenerator remplace la stratégie de génération des codes courts (voir NewCodeGenerator).
+func (s *LinkService) SetCodeGenerator(gen CodeGenerator) {
+	s.codeGen = gen
+}
+
+// SetURLCanonicalizer remplace les règles de canonicalisation des URLs longues.
+func (s *LinkService) SetURLCanonicalizer(c *URLCanonicalizer) {
+	s.canonicalizer = c
+}
+
 // TODO Créer la méthode GenerateShortCode
 // GenerateShortCode est une méthode rattachée à LinkService
 // Elle génère un code court aléatoire d'une longueur spécifiée. Elle prend une longueur en paramètre et retourne une string et une erreur
 // Il utilise le package 'crypto/rand' pour éviter la prévisibilité.
 // Je vous laisse chercher un peu :) C'est faisable en une petite dizaine de ligne
 func (s *LinkService) GenerateShortCode(length int) (string, error) {
+	return randomCode(length)
+}
+
+// randomCode génère un code aléatoire de la longueur donnée à partir du charset.
+func randomCode(length int) (string, error) {
 	code := make([]byte, length)
 	for i := range code {
 		nBig, err := rand.Int(rand.Reader, big.NewInt(int64(len(charset))))
@@ -49,63 +93,380 @@ func (s *LinkService) GenerateShortCode(length int) (string, error) {
 	return string(code), nil
 }
 
-// CreateLink crée un nouveau lien raccourci.
-// Il génère un code court unique, puis persiste le lien dans la base de données.
-func (s *LinkService) CreateLink(longURL string) (*models.Link, error) {
-	// TODO 1: Implémenter la logique de retry pour générer un code court unique.
-	// Essayez de générer un code, vérifiez s'il existe déjà en base, et retentez si une collision est trouvée.
-	// Limitez le nombre de tentatives pour éviter une boucle infinie.
+// CreateLinkOptions regroupe les paramètres de création d'un lien.
+type CreateLinkOptions struct {
+	LongURL       string
+	Owner         string // Propriétaire du lien (équipe ou système appelant)
+	ReuseExisting bool   // Retourne le lien existant du même propriétaire pour une URL canonique et des réglages identiques
 
-	// TODO Créer une variable shortcode pour stocker le shortcode créé
+	RedirectType    int  // 301, 302, 307 ou 308 (0 = 302)
+	ForwardQuery    bool // Transmet la query string entrante à la destination
+	PathPassthrough bool // Lien "joker" : le suffixe de chemin est ajouté à la destination
 
-	// TODO Définir un nombre maximum (5) de tentative pour trouver un code unique  (maxRetries)
-	const maxRetries = 5
-	const codeLength = 6
-	var shortCode string
+	ActivatesAt *time.Time // Mise en service différée (optionnel)
+	ComingSoon  bool       // Page "bientôt disponible" au lieu d'une 404 avant ActivatesAt
 
-	for i := 0; i < maxRetries; i++ {
-		// TODO : Génère un code de 6 caractères (GenerateShortCode)
-		code, err := s.GenerateShortCode(codeLength)
-		if err != nil {
-			return nil, fmt.Errorf("Echec de la génération du shortcode: %w", err)
+	Password string // Mot de passe demandé aux visiteurs (stocké haché), vide = aucun
+	OneTime  bool   // Lien à usage unique, invalidé après la première redirection réussie
+
+	SignedOnly bool // Accessible uniquement via des liens signés à durée limitée
+
+	Interstitial bool // Page "vous quittez…" avec compte à rebours avant la redirection
+
+	CardTitle       string // Titre de la carte de partage (remplace celui de la destination)
+	CardDescription string // Description de la carte de partage
+	CardImageURL    string // Image de la carte de partage (URL http(s))
+
+	Variants    []VariantInput    // Destinations A/B pondérées (optionnel)
+	DeviceRules []DeviceRuleInput // Destinations par plateforme, évaluées dans l'ordre (optionnel)
+	GeoRules    []GeoRuleInput    // Destinations par pays ou continent (optionnel)
+
+	// Règles conditionnelles évaluées dans l'ordre (optionnel), avant celles issues de DeviceRules puis de GeoRules.
+	RoutingRules []models.RoutingRule
+
+	Tags     []string // Étiquettes du lien, créées au besoin (optionnel)
+	Campaign string   // Campagne du propriétaire, créée au besoin (optionnel)
+
+	UTM       models.UTM // Paramètres utm_* ajoutés à LongURL (optionnel)
+	UTMPreset string     // Jeu de paramètres UTM du propriétaire, complété par UTM (optionnel)
+}
+
+// CreateLink crée un nouveau lien raccourci pour l'URL longue donnée, sans propriétaire.
+func (s *LinkService) CreateLink(ctx context.Context, longURL string) (*models.Link, error) {
+	link, _, err := s.CreateLinkWithOptions(ctx, CreateLinkOptions{LongURL: longURL})
+	return link, err
+}
+
+// CreateLinkWithOptions crée un nouveau lien raccourci.
+// L'URL longue est canonicalisée ; avec ReuseExisting, un lien existant du même propriétaire
+// pour la même URL canonique et avec les mêmes réglages est retourné tel quel et le booléen
+// 'reused' vaut true. Si les réglages diffèrent, un nouveau lien est créé.
+//
+// Le code court est obtenu auprès de la stratégie de génération puis directement inséré :
+// l'index unique sur 'short_code' arbitre les collisions, y compris entre créateurs concurrents.
+// En cas de violation, une nouvelle tentative est faite avec un autre code.
+// L'identifiant de requête porté par ctx est repris dans les logs.
+func (s *LinkService) CreateLinkWithOptions(ctx context.Context, opts CreateLinkOptions) (link *models.Link, reused bool, err error) {
+	const maxAttempts = 10
+
+	if !opts.UTM.IsZero() || opts.UTMPreset != "" {
+		if opts.LongURL, err = s.BuildUTMURL(opts.Owner, opts.LongURL, opts.UTMPreset, opts.UTM); err != nil {
+			return nil, false, err
 		}
+	}
+	utm := ExtractUTM(opts.LongURL)
+
+	canonicalURL, err := s.canonicalizer.Canonicalize(opts.LongURL)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := ValidateRedirectType(opts.RedirectType); err != nil {
+		return nil, false, err
+	}
+	if opts.ActivatesAt != nil {
+		activatesAt := opts.ActivatesAt.UTC()
+		opts.ActivatesAt = &activatesAt
+	}
+	variants, err := buildVariants(opts.Variants)
+	if err != nil {
+		return nil, false, err
+	}
+	deviceRules, err := buildDeviceRules(opts.DeviceRules)
+	if err != nil {
+		return nil, false, err
+	}
+	geoRules, err := buildGeoRules(opts.GeoRules)
+	if err != nil {
+		return nil, false, err
+	}
+	routingRules, err := buildRoutingRules(opts.RoutingRules)
+	if err != nil {
+		return nil, false, err
+	}
+	// Les règles de plateforme et géographiques sont des règles de routage évaluées après les règles directes.
+	routingRules = routingRules.
+		ReplaceOrigin(models.RuleOriginDevice, deviceRules).
+		ReplaceOrigin(models.RuleOriginGeo, geoRules)
+	passwordHash, err := hashLinkPassword(opts.Password)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := validateCard(opts.CardTitle, opts.CardDescription, opts.CardImageURL); err != nil {
+		return nil, false, err
+	}
+	tagNames, err := normalizeTags(opts.Tags)
+	if err != nil {
+		return nil, false, err
+	}
+	campaignName, err := normalizeCampaignName(opts.Campaign)
+	if err != nil {
+		return nil, false, err
+	}
 
-		// TODO : Vérifie si le code généré existe déjà en base de données (GetLinkbyShortCode)
-		// On ignore la première valeur
-		_, err = s.linkRepo.GetLinkByShortCode(code)
+	// Un lien protégé ou à usage unique est toujours propre à sa demande : jamais de réutilisation.
+	// Sinon, seul un lien dont tous les réglages correspondent à la demande est réutilisé
+	// (y compris les paramètres UTM, que l'URL canonique peut ignorer, voir URLCanonicalizer.StripTracking) :
+	// si l'un d'eux diffère, un nouveau lien est créé plutôt que d'ignorer silencieusement la demande.
+	if opts.ReuseExisting && opts.Password == "" && !opts.OneTime {
+		candidates, err := s.linkRepo.ListLinksByCanonicalURL(opts.Owner, canonicalURL)
 		if err != nil {
-			// Si l'erreur est 'record not found' de GORM, cela signifie que le code est unique.
-			if errors.Is(err, gorm.ErrRecordNotFound) {
-				shortCode = code // Le code est unique, on peut l'utiliser
-				break            // Sort de la boucle de retry
+			return nil, false, fmt.Errorf("Echec de la recherche d'un lien existant: %w", err)
+		}
+		requested := &models.Link{
+			RedirectType:    normalizeRedirectType(opts.RedirectType),
+			ForwardQuery:    opts.ForwardQuery,
+			PathPassthrough: opts.PathPassthrough,
+			RoutingRules:    routingRules,
+			ActivatesAt:     opts.ActivatesAt,
+			ComingSoon:      opts.ComingSoon,
+			SignedOnly:      opts.SignedOnly,
+			Interstitial:    opts.Interstitial,
+			CardTitle:       strings.TrimSpace(opts.CardTitle),
+			CardDescription: strings.TrimSpace(opts.CardDescription),
+			CardImageURL:    opts.CardImageURL,
+			UTM:             utm,
+			Variants:        variants,
+		}
+		for i := range candidates {
+			if reusableLink(&candidates[i], requested, tagNames, campaignName) {
+				return &candidates[i], true, nil
 			}
-			// Si c'est une autre erreur de base de données, retourne l'erreur.
-			return nil, fmt.Errorf("database error checking short code uniqueness: %w", err)
+		}
+	}
+
+	tags, err := s.resolveTags(tagNames)
+	if err != nil {
+		return nil, false, err
+	}
+	campaign, err := s.resolveCampaign(opts.Owner, campaignName)
+	if err != nil {
+		return nil, false, err
+	}
+	var campaignID *uint
+	if campaign != nil {
+		campaignID = &campaign.ID
+	}
+
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		shortCode, err := s.codeGen.Generate(canonicalURL, attempt)
+		if err != nil {
+			return nil, false, fmt.Errorf("Echec de la génération du shortcode: %w", err)
+		}
+
+		link := &models.Link{
+			ShortCode:    shortCode,
+			LongURL:      opts.LongURL,
+			Owner:        opts.Owner,
+			CanonicalURL: canonicalURL,
+			CreatedAt:    time.Now(),
+
+			RedirectType:    normalizeRedirectType(opts.RedirectType),
+			ForwardQuery:    opts.ForwardQuery,
+			PathPassthrough: opts.PathPassthrough,
+			RoutingRules:    routingRules,
+			ActivatesAt:     opts.ActivatesAt,
+			ComingSoon:      opts.ComingSoon,
+			PasswordHash:    passwordHash,
+			OneTime:         opts.OneTime,
+			SignedOnly:      opts.SignedOnly,
+			Interstitial:    opts.Interstitial,
+			CardTitle:       strings.TrimSpace(opts.CardTitle),
+			CardDescription: strings.TrimSpace(opts.CardDescription),
+			CardImageURL:    opts.CardImageURL,
+			CampaignID:      campaignID,
+			UTM:             utm,
+
+			Variants: variants,
+			Tags:     tags,
+			Campaign: campaign,
 		}
 
-		// Si aucune erreur (le code a été trouvé), cela signifie une collision.
-		log.Printf("Short code '%s' already exists, retrying generation (%d/%d)...", code, i+1, maxRetries)
-		// La boucle continuera pour générer un nouveau code.
+		err = s.linkRepo.CreateLink(link)
+		if err == nil {
+			s.metadata.Enqueue(ctx, link.ID, link.LongURL)
+			s.emitLinkEvent(ctx, EventLinkCreated, link)
+			metrics.LinksCreated.Inc()
+			return link, false, nil
+		}
+		if !errors.Is(err, repository.ErrDuplicateShortCode) {
+			return nil, false, fmt.Errorf("Echec de la création du lien: %w", err)
+		}
+		linksLogger.WarnContext(ctx, "Short code already exists, retrying generation", "short_code", shortCode, "attempt", attempt+1, "max_attempts", maxAttempts)
 	}
 
-	// TODO : Si après toutes les tentatives, aucun code unique n'a été trouvé... Errors.New
-	if shortCode == "" {
-		return nil, errors.New("Echec de génération d’un shortcode unique")
+	return nil, false, errors.New("Echec de génération d’un shortcode unique")
+}
+
+// reusableLink indique si un lien existant peut être retourné pour une demande de création :
+// il ne doit être ni protégé ni à usage unique, et tous ses réglages (redirection, mise en service,
+// carte de partage, variantes, règles, tags, campagne, UTM) doivent être ceux demandés.
+func reusableLink(existing, requested *models.Link, tagNames []string, campaignName string) bool {
+	if existing.PasswordHash != "" || existing.OneTime {
+		return false
+	}
+	if existing.RedirectType != requested.RedirectType ||
+		existing.ForwardQuery != requested.ForwardQuery ||
+		existing.PathPassthrough != requested.PathPassthrough ||
+		existing.ComingSoon != requested.ComingSoon ||
+		existing.SignedOnly != requested.SignedOnly ||
+		existing.Interstitial != requested.Interstitial ||
+		existing.CardTitle != requested.CardTitle ||
+		existing.CardDescription != requested.CardDescription ||
+		existing.CardImageURL != requested.CardImageURL ||
+		existing.UTM != requested.UTM {
+		return false
+	}
+	if (existing.ActivatesAt == nil) != (requested.ActivatesAt == nil) ||
+		existing.ActivatesAt != nil && !existing.ActivatesAt.Equal(*requested.ActivatesAt) {
+		return false
 	}
 
-	// TODO Crée une nouvelle instance du modèle Link.
-	link := &models.Link{
-		ShortCode: shortCode,
-		LongURL:   longURL,
-		CreatedAt: time.Now(),
+	existingCampaign := ""
+	if existing.Campaign != nil {
+		existingCampaign = existing.Campaign.Name
+	}
+	if existingCampaign != campaignName {
+		return false
+	}
+	existingTags := make([]string, len(existing.Tags))
+	for i, tag := range existing.Tags {
+		existingTags[i] = tag.Name
 	}
+	wantTags := slices.Clone(tagNames)
+	slices.Sort(existingTags)
+	slices.Sort(wantTags)
+	if !slices.Equal(existingTags, wantTags) {
+		return false
+	}
+
+	if !slices.EqualFunc(existing.Variants, requested.Variants, func(a, b models.LinkVariant) bool {
+		return a.Name == b.Name && a.URL == b.URL && a.Weight == b.Weight
+	}) {
+		return false
+	}
+	// Les règles de routage (y compris de plateforme et géographiques) sont comparées sous leur forme stockée (JSON).
+	existingRules, err1 := existing.RoutingRules.Value()
+	requestedRules, err2 := requested.RoutingRules.Value()
+	return err1 == nil && err2 == nil && existingRules == requestedRules
+}
 
-	// TODO Persiste le nouveau lien dans la base de données via le repository (CreateLink)
-	err := s.linkRepo.CreateLink(link)
+// DeleteLink supprime un lien et ses données rattachées (clics, statistiques, règles, conversions...),
+// puis émet link.deleted. Le code court redevient disponible. Retourne le lien supprimé.
+func (s *LinkService) DeleteLink(ctx context.Context, shortCode string) (*models.Link, error) {
+	link, err := s.linkRepo.GetLinkByShortCode(shortCode)
 	if err != nil {
-		return nil, fmt.Errorf("Echec de la création du lien: %w", err)
+		return nil, fmt.Errorf("Echec de la récupération du lien '%s': %w", shortCode, err)
+	}
+	if err := s.linkRepo.DeleteLink(link.ID); err != nil {
+		return nil, fmt.Errorf("Echec de la suppression du lien '%s': %w", shortCode, err)
+	}
+	s.emitLinkEvent(ctx, EventLinkDeleted, link)
+	return link, nil
+}
+
+// LinkUpdate décrit une modification partielle d'un lien : seuls les champs non nil sont appliqués.
+type LinkUpdate struct {
+	RedirectType    *int
+	ForwardQuery    *bool
+	PathPassthrough *bool
+	ActivatesAt     *time.Time // Une date passée met le lien en service immédiatement
+	ComingSoon      *bool
+	Password        *string // Nouveau mot de passe ; une chaîne vide supprime la protection
+	SignedOnly      *bool
+	Interstitial    *bool
+
+	// Personnalisation de la carte de partage ; une chaîne vide rétablit l'information de la destination.
+	CardTitle       *string
+	CardDescription *string
+	CardImageURL    *string
+
+	Tags     *[]string // Remplace les tags du lien ; une liste vide les retire tous
+	Campaign *string   // Change la campagne du lien ; une chaîne vide le retire de sa campagne
+}
+
+// UpdateLink applique une modification partielle au lien identifié par son code court.
+func (s *LinkService) UpdateLink(ctx context.Context, shortCode string, upd LinkUpdate) (*models.Link, error) {
+	link, err := s.linkRepo.GetLinkByShortCode(shortCode)
+	if err != nil {
+		return nil, fmt.Errorf("Echec de la récupération du lien '%s': %w", shortCode, err)
+	}
+
+	if upd.RedirectType != nil {
+		if err := ValidateRedirectType(*upd.RedirectType); err != nil {
+			return nil, err
+		}
+		link.RedirectType = normalizeRedirectType(*upd.RedirectType)
 	}
-	// TODO Retourne le lien créé
+	if upd.ForwardQuery != nil {
+		link.ForwardQuery = *upd.ForwardQuery
+	}
+	if upd.PathPassthrough != nil {
+		link.PathPassthrough = *upd.PathPassthrough
+	}
+	if upd.ActivatesAt != nil {
+		activatesAt := upd.ActivatesAt.UTC()
+		link.ActivatesAt = &activatesAt
+	}
+	if upd.ComingSoon != nil {
+		link.ComingSoon = *upd.ComingSoon
+	}
+	if upd.CardTitle != nil {
+		link.CardTitle = strings.TrimSpace(*upd.CardTitle)
+	}
+	if upd.CardDescription != nil {
+		link.CardDescription = strings.TrimSpace(*upd.CardDescription)
+	}
+	if upd.CardImageURL != nil {
+		link.CardImageURL = *upd.CardImageURL
+	}
+	if err := validateCard(link.CardTitle, link.CardDescription, link.CardImageURL); err != nil {
+		return nil, err
+	}
+	if upd.Interstitial != nil {
+		link.Interstitial = *upd.Interstitial
+	}
+	if upd.SignedOnly != nil {
+		link.SignedOnly = *upd.SignedOnly
+	}
+	if upd.Password != nil {
+		hash, err := hashLinkPassword(*upd.Password)
+		if err != nil {
+			return nil, err
+		}
+		link.PasswordHash = hash
+	}
+	var tagNames []string
+	if upd.Tags != nil {
+		if tagNames, err = normalizeTags(*upd.Tags); err != nil {
+			return nil, err
+		}
+	}
+	if upd.Campaign != nil {
+		name, err := normalizeCampaignName(*upd.Campaign)
+		if err != nil {
+			return nil, err
+		}
+		if link.Campaign, err = s.resolveCampaign(link.Owner, name); err != nil {
+			return nil, err
+		}
+		link.CampaignID = nil
+		if link.Campaign != nil {
+			link.CampaignID = &link.Campaign.ID
+		}
+	}
+
+	if err := s.linkRepo.UpdateLink(link); err != nil {
+		return nil, fmt.Errorf("Echec de la mise à jour du lien '%s': %w", shortCode, err)
+	}
+	if upd.Tags != nil {
+		if link.Tags, err = s.resolveTags(tagNames); err != nil {
+			return nil, err
+		}
+		if err := s.linkRepo.ReplaceTags(link.ID, link.Tags); err != nil {
+			return nil, fmt.Errorf("Echec de la mise à jour des tags du lien '%s': %w", shortCode, err)
+		}
+	}
+	s.emitLinkEvent(ctx, EventLinkUpdated, link)
 	return link, nil
 }
 
@@ -130,10 +491,19 @@ func (s *LinkService) GetLinkStats(shortCode string) (*models.Link, int, error)
 		return nil, 0, fmt.Errorf("Echec de la récupération du lien '%s': %w", shortCode, err)
 	}
 	// TODO 4: Compter le nombre de clics pour ce LinkID
-	count, err := s.linkRepo.CountClicksByLinkID(link.ID)
+	count, err := s.clickRepo.CountClicksByLinkID(link.ID)
 	if err != nil {
 		return nil, 0, fmt.Errorf("Echec du comptage des clics pour LinkID %d: %w", link.ID, err)
 	}
 	// TODO : on retourne les 3 valeurs
 	return link, count, nil
 }
+
+// GetClickBreakdown retourne le nombre de clics d'un lien ventilé selon une dimension (ex: "source").
+func (s *LinkService) GetClickBreakdown(linkID uint, dimension string) (map[string]int, error) {
+	counts, err := s.clickRepo.CountClicksByDimension(linkID, dimension)
+	if err != nil {
+		return nil, fmt.Errorf("Echec de la ventilation des clics par %s pour LinkID %d: %w", dimension, linkID, err)
+	}
+	return counts, nil
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/antoine-granier/urlshortener/internal/logging"
+	"github.com/antoine-granier/urlshortener/internal/models"
+)
+
+func TestReuseExistingRequiresMatchingOptions(t *testing.T) {
+	svc, _ := newTestLinkService(t)
+	activatesAt := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	base := func() CreateLinkOptions {
+		return CreateLinkOptions{
+			LongURL:       "https://example.com/pricing?utm_source=mail",
+			Owner:         "team-a",
+			ReuseExisting: true,
+			RedirectType:  301,
+			Variants: []VariantInput{
+				{Name: "A", URL: "https://example.com/a", Weight: 1},
+				{Name: "B", URL: "https://example.com/b", Weight: 1},
+			},
+			RoutingRules: []models.RoutingRule{
+				{Name: "fr", When: models.RuleConditions{Languages: []string{"fr"}}, URL: "https://example.com/fr"},
+			},
+			Tags:     []string{"pricing", "mail"},
+			Campaign: "launch",
+		}
+	}
+
+	original, reused, err := svc.CreateLinkWithOptions(t.Context(), base())
+	if err != nil || reused {
+		t.Fatalf("first create: reused=%v err=%v", reused, err)
+	}
+
+	same := base()
+	same.Tags = []string{" Mail ", "pricing"}
+	link, reused, err := svc.CreateLinkWithOptions(t.Context(), same)
+	if err != nil {
+		t.Fatalf("identical create: %v", err)
+	}
+	if !reused || link.ID != original.ID {
+		t.Fatalf("identical options: got link %d reused=%v, want link %d reused", link.ID, reused, original.ID)
+	}
+
+	differing := map[string]func(*CreateLinkOptions){
+		"redirect type": func(o *CreateLinkOptions) { o.RedirectType = 302 },
+		"variants":      func(o *CreateLinkOptions) { o.Variants[1].Weight = 3 },
+		"no variants":   func(o *CreateLinkOptions) { o.Variants = nil },
+		"device rules": func(o *CreateLinkOptions) {
+			o.DeviceRules = []DeviceRuleInput{{Platform: "ios", URL: "https://apps.apple.com/app/id1"}}
+		},
+		"routing rules": func(o *CreateLinkOptions) { o.RoutingRules[0].URL = "https://example.com/fr-fr" },
+		"campaign":      func(o *CreateLinkOptions) { o.Campaign = "" },
+		"tags":          func(o *CreateLinkOptions) { o.Tags = []string{"pricing"} },
+		"signed only":   func(o *CreateLinkOptions) { o.SignedOnly = true },
+		"activates at":  func(o *CreateLinkOptions) { o.ActivatesAt = &activatesAt },
+		"utm":           func(o *CreateLinkOptions) { o.LongURL = "https://example.com/pricing?utm_source=ads" },
+		"forward query": func(o *CreateLinkOptions) { o.ForwardQuery = true },
+		"card title":    func(o *CreateLinkOptions) { o.CardTitle = "Tarifs" },
+	}
+	for name, change := range differing {
+		t.Run(name, func(t *testing.T) {
+			opts := base()
+			change(&opts)
+			created, reused, err := svc.CreateLinkWithOptions(t.Context(), opts)
+			if err != nil {
+				t.Fatalf("create: %v", err)
+			}
+			if reused || created.ID == original.ID {
+				t.Fatalf("differing %s: got link %d reused=%v, want a new link", name, created.ID, reused)
+			}
+
+			// Le nouveau lien est à son tour réutilisé pour une demande identique.
+			opts = base()
+			change(&opts)
+			again, reused, err := svc.CreateLinkWithOptions(t.Context(), opts)
+			if err != nil {
+				t.Fatalf("create again: %v", err)
+			}
+			if !reused || again.ID != created.ID {
+				t.Errorf("repeat %s: got link %d reused=%v, want link %d reused", name, again.ID, reused, created.ID)
+			}
+		})
+	}
+}
+
+func TestReuseExistingSkipsProtectedLinks(t *testing.T) {
+	svc, _ := newTestLinkService(t)
+	opts := CreateLinkOptions{LongURL: "https://example.com/private", Owner: "team-a"}
+
+	protected := opts
+	protected.Password = "hunter22"
+	if _, _, err := svc.CreateLinkWithOptions(t.Context(), protected); err != nil {
+		t.Fatalf("create protected: %v", err)
+	}
+
+	opts.ReuseExisting = true
+	link, reused, err := svc.CreateLinkWithOptions(t.Context(), opts)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if reused || link.PasswordHash != "" {
+		t.Errorf("got reused=%v protected=%v, want a new unprotected link", reused, link.PasswordHash != "")
+	}
+}
+
+func TestLinkEventsCarryRequestID(t *testing.T) {
+	svc, _ := newTestLinkService(t)
+	bus := NewEventBus()
+	svc.SetEventBus(bus)
+	var events []Event
+	bus.Subscribe(func(event Event) { events = append(events, event) })
+
+	ctx := logging.WithRequestID(t.Context(), "req-42")
+	link, _, err := svc.CreateLinkWithOptions(ctx, CreateLinkOptions{LongURL: "https://example.com/"})
+	if err != nil {
+		t.Fatalf("create link: %v", err)
+	}
+	forward := true
+	if _, err := svc.UpdateLink(ctx, link.ShortCode, LinkUpdate{ForwardQuery: &forward}); err != nil {
+		t.Fatalf("update link: %v", err)
+	}
+	if _, err := svc.DeleteLink(ctx, link.ShortCode); err != nil {
+		t.Fatalf("delete link: %v", err)
+	}
+
+	if len(events) != 3 {
+		t.Fatalf("%d events emitted, want 3", len(events))
+	}
+	for _, event := range events {
+		if event.RequestID != "req-42" {
+			t.Errorf("%s carries request id %q, want req-42", event.Type, event.RequestID)
+		}
+	}
+	bus.Emit(EventLinkHealthChanged, "", nil)
+	if last := events[len(events)-1]; last.RequestID != "" {
+		t.Errorf("event emitted outside a request carries request id %q", last.RequestID)
+	}
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"time"
+
+	"github.com/antoine-granier/urlshortener/internal/models"
+	"github.com/antoine-granier/urlshortener/internal/pubsub"
+)
+
+// defaultLiveBuffer est le nombre de clics en attente d'envoi au-delà duquel un abonné est jugé trop lent.
+const defaultLiveBuffer = 100
+
+// LiveClick est un clic diffusé en temps réel. Il est construit après l'application de la politique
+// de confidentialité et ne contient ni IP ni User-Agent.
+type LiveClick struct {
+	LinkID      uint      `json:"-"`
+	ShortCode   string    `json:"short_code"`
+	Owner       string    `json:"owner,omitempty"`
+	Timestamp   time.Time `json:"timestamp"`
+	Source      string    `json:"source,omitempty"`
+	Variant     string    `json:"variant,omitempty"`
+	Rule        string    `json:"rule,omitempty"`
+	Country     string    `json:"country,omitempty"`
+	Region      string    `json:"region,omitempty"`
+	Bot         bool      `json:"bot"`
+	BotCategory string    `json:"bot_category,omitempty"`
+	Anonymous   bool      `json:"anonymous,omitempty"` // Visiteur Do-Not-Track
+}
+
+// LiveFilter sélectionne les clics d'un abonnement : ceux d'un lien (LinkID) ou de tous les liens
+// d'une équipe (Owner). Les clics de robots ne sont transmis qu'avec IncludeBots.
+type LiveFilter struct {
+	LinkID      uint
+	Owner       string
+	IncludeBots bool
+}
+
+// ClickStream diffuse les clics enregistrés aux abonnés du suivi en temps réel (SSE, WebSocket).
+type ClickStream struct {
+	broker *pubsub.Broker[LiveClick]
+	buffer int
+}
+
+// NewClickStream crée et retourne une nouvelle instance de ClickStream.
+// buffer est le nombre de clics en attente par abonné (defaultLiveBuffer si <= 0).
+func NewClickStream(buffer int) *ClickStream {
+	if buffer <= 0 {
+		buffer = defaultLiveBuffer
+	}
+	return &ClickStream{broker: pubsub.NewBroker[LiveClick](), buffer: buffer}
+}
+
+// NewLiveClick construit la vue diffusée d'un clic enregistré (après application de la politique de confidentialité).
+func NewLiveClick(click *models.Click, shortCode, owner string) LiveClick {
+	return LiveClick{
+		LinkID:      click.LinkID,
+		ShortCode:   shortCode,
+		Owner:       owner,
+		Timestamp:   click.Timestamp,
+		Source:      click.Source,
+		Variant:     click.Variant,
+		Rule:        click.Rule,
+		Country:     click.Country,
+		Region:      click.Region,
+		Bot:         click.Bot,
+		BotCategory: click.BotCategory,
+		Anonymous:   click.DoNotTrack,
+	}
+}
+
+// Publish diffuse un clic enregistré. Sans ClickStream (nil), l'appel est ignoré.
+func (s *ClickStream) Publish(click LiveClick) {
+	if s == nil {
+		return
+	}
+	s.broker.Publish(click)
+}
+
+// Subscribe abonne un consommateur aux clics correspondant au filtre.
+// L'abonnement doit être libéré avec Unsubscribe.
+func (s *ClickStream) Subscribe(filter LiveFilter) *pubsub.Subscription[LiveClick] {
+	return s.broker.Subscribe(s.buffer, func(click LiveClick) bool {
+		if click.Bot && !filter.IncludeBots {
+			return false
+		}
+		if filter.LinkID != 0 {
+			return click.LinkID == filter.LinkID
+		}
+		return click.Owner == filter.Owner
+	})
+}
+
+// Unsubscribe libère un abonnement.
+func (s *ClickStream) Unsubscribe(sub *pubsub.Subscription[LiveClick]) {
+	s.broker.Unsubscribe(sub)
+}
+
+// Close interrompt tous les abonnements (arrêt du serveur).
+func (s *ClickStream) Close() {
+	s.broker.Close()
+}
@@ -0,0 +1,17 @@
+package services
+
+import "github.com/antoine-granier/urlshortener/internal/logging"
+
+// Loggers des composants du package (leur niveau se règle par composant, voir logging.For).
+var (
+	alertsLogger   = logging.For("alerts")
+	auditLogger    = logging.For("audit")
+	eventsLogger   = logging.For("events")
+	linksLogger    = logging.For("links")
+	metadataLogger = logging.For("metadata")
+	previewLogger  = logging.For("preview")
+	rollupsLogger  = logging.For("rollups")
+	scheduleLogger = logging.For("scheduler")
+	visitorsLogger = logging.For("visitors")
+	webhooksLogger = logging.For("webhooks")
+)
@@ -0,0 +1,45 @@
+package services
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/antoine-granier/urlshortener/internal/models"
+	"github.com/antoine-granier/urlshortener/internal/repository"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestDB ouvre une base SQLite en mémoire propre au test, avec le schéma complet.
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("open test database: %v", err)
+	}
+	if err := db.AutoMigrate(
+		&models.Link{}, &models.Click{}, &models.Sequence{}, &models.LinkVariant{},
+		&models.ScheduledChange{}, &models.AuditEntry{}, &models.LinkMetadata{},
+		&models.Tag{}, &models.Campaign{}, &models.UTMPreset{}, &models.VisitorSketch{},
+		&models.HourlyClickRollup{}, &models.DailyClickRollup{},
+		&models.Webhook{}, &models.WebhookDelivery{}, &models.ClickThreshold{}, &models.AlertRule{},
+		&models.Conversion{},
+	); err != nil {
+		t.Fatalf("migrate test database: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+// newTestLinkService retourne un LinkService sur une base de test.
+func newTestLinkService(t *testing.T) (*LinkService, *gorm.DB) {
+	t.Helper()
+	db := newTestDB(t)
+	return NewLinkService(repository.NewLinkRepository(db), repository.NewClickRepository(db)), db
+}
@@ -0,0 +1,169 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/antoine-granier/urlshortener/internal/logging"
+	"github.com/antoine-granier/urlshortener/internal/models"
+	"github.com/antoine-granier/urlshortener/internal/pageinfo"
+	"github.com/antoine-granier/urlshortener/internal/repository"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrMetadataDisabled est retournée quand la récupération des informations de page n'est pas active.
+	ErrMetadataDisabled = errors.New("récupération des informations de page désactivée")
+	// ErrMetadataQueueFull est retournée quand la file des récupérations est pleine.
+	ErrMetadataQueueFull = errors.New("file des récupérations pleine, réessayez plus tard")
+)
+
+// Paramètres de la récupération asynchrone des informations de pages.
+const (
+	metadataQueueSize     = 1000
+	metadataFetchTimeout  = 10 * time.Second
+	metadataBackfillBatch = 200
+)
+
+// metadataJob est une demande de récupération des informations de la page de destination d'un lien.
+type metadataJob struct {
+	linkID uint
+	url    string
+}
+
+// MetadataService récupère de manière asynchrone le titre, la description, les balises Open Graph
+// et l'icône des pages de destination, et les enregistre pour chaque lien.
+type MetadataService struct {
+	metaRepo repository.MetadataRepository
+	jobs     chan metadataJob
+}
+
+// NewMetadataService crée et retourne une nouvelle instance de MetadataService.
+func NewMetadataService(metaRepo repository.MetadataRepository) *MetadataService {
+	return &MetadataService{
+		metaRepo: metaRepo,
+		jobs:     make(chan metadataJob, metadataQueueSize),
+	}
+}
+
+// Start lance workerCount goroutines de récupération, puis une passe de rattrapage pour
+// les liens créés sans serveur démarré (ex: par la CLI).
+func (m *MetadataService) Start(workerCount int) {
+	for i := 0; i < max(workerCount, 1); i++ {
+		go m.worker()
+	}
+	go m.backfill()
+}
+
+// Enqueue demande la récupération des informations de la page url pour un lien, sans bloquer.
+// Si la file est pleine, la demande est abandonnée : le lien sera rattrapé au prochain démarrage
+// ou via une demande de rafraîchissement. Sans MetadataService (nil), l'appel est ignoré.
+func (m *MetadataService) Enqueue(ctx context.Context, linkID uint, url string) bool {
+	if m == nil {
+		return false
+	}
+	select {
+	case m.jobs <- metadataJob{linkID: linkID, url: url}:
+		return true
+	default:
+		metadataLogger.WarnContext(ctx, "Metadata queue full, fetch dropped", "link_id", linkID)
+		return false
+	}
+}
+
+// GetMetadata retourne les informations de page enregistrées pour un lien.
+func (m *MetadataService) GetMetadata(linkID uint) (*models.LinkMetadata, error) {
+	meta, err := m.metaRepo.GetMetadataByLinkID(linkID)
+	if err != nil {
+		return nil, fmt.Errorf("Echec de la récupération des informations de page du lien %d: %w", linkID, err)
+	}
+	return meta, nil
+}
+
+// worker traite les demandes de récupération une par une.
+func (m *MetadataService) worker() {
+	for job := range m.jobs {
+		if err := m.fetch(job.linkID, job.url); err != nil {
+			metadataLogger.Error("Failed to save link metadata", "link_id", job.linkID, logging.Err(err))
+		}
+	}
+}
+
+// fetch récupère et enregistre les informations de la page url d'un lien.
+// En cas d'échec, les informations déjà connues pour la même URL sont conservées et l'erreur est notée.
+func (m *MetadataService) fetch(linkID uint, url string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), metadataFetchTimeout)
+	defer cancel()
+	info, fetchErr := pageinfo.Fetch(ctx, url)
+
+	meta := &models.LinkMetadata{LinkID: linkID, URL: url}
+	if fetchErr != nil {
+		prev, err := m.metaRepo.GetMetadataByLinkID(linkID)
+		switch {
+		case err == nil && prev.URL == url:
+			meta = prev
+		case err != nil && !errors.Is(err, gorm.ErrRecordNotFound):
+			return err
+		}
+		meta.FetchError = fetchErr.Error()
+		metadataLogger.Info("Page information unavailable", "link_id", linkID, "url", url, logging.Err(fetchErr))
+	} else {
+		now := time.Now()
+		meta.Title = info.Title
+		meta.Description = info.Description
+		meta.ImageURL = info.ImageURL()
+		meta.SiteName = info.OpenGraph["og:site_name"]
+		meta.FaviconURL = info.FaviconURL
+		meta.OpenGraph = info.OpenGraph
+		meta.FetchedAt = &now
+	}
+	return m.metaRepo.SaveMetadata(meta)
+}
+
+// backfill met en file les liens dont les informations n'ont jamais été enregistrées.
+// L'envoi est bloquant : la passe avance au rythme des workers.
+func (m *MetadataService) backfill() {
+	var afterID uint
+	queued := 0
+	for {
+		links, err := m.metaRepo.ListLinksWithoutMetadata(afterID, metadataBackfillBatch)
+		if err != nil {
+			metadataLogger.Error("Failed to list links without metadata", logging.Err(err))
+			return
+		}
+		for _, link := range links {
+			m.jobs <- metadataJob{linkID: link.ID, url: link.LongURL}
+			afterID = link.ID
+		}
+		queued += len(links)
+		if len(links) < metadataBackfillBatch {
+			break
+		}
+	}
+	if queued > 0 {
+		metadataLogger.Info("Links without metadata queued", "count", queued)
+	}
+}
+
+// SetMetadataService active la récupération asynchrone des informations de page
+// à la création d'un lien et à chaque changement de destination.
+func (s *LinkService) SetMetadataService(m *MetadataService) {
+	s.metadata = m
+}
+
+// RefreshMetadata demande une nouvelle récupération des informations de la page de destination d'un lien.
+func (s *LinkService) RefreshMetadata(ctx context.Context, shortCode string) error {
+	link, err := s.linkRepo.GetLinkByShortCode(shortCode)
+	if err != nil {
+		return fmt.Errorf("Echec de la récupération du lien '%s': %w", shortCode, err)
+	}
+	if s.metadata == nil {
+		return ErrMetadataDisabled
+	}
+	if !s.metadata.Enqueue(ctx, link.ID, link.LongURL) {
+		return ErrMetadataQueueFull
+	}
+	return nil
+}
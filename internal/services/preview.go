@@ -0,0 +1,155 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/antoine-granier/urlshortener/internal/logging"
+	"github.com/antoine-granier/urlshortener/internal/metrics"
+	"github.com/antoine-granier/urlshortener/internal/models"
+	"github.com/antoine-granier/urlshortener/internal/monitor"
+	"github.com/antoine-granier/urlshortener/internal/pageinfo"
+)
+
+// Durées de conservation des informations de pages (titre, icône) des destinations.
+const (
+	pageInfoTTL        = time.Hour
+	pageInfoFailureTTL = 10 * time.Minute
+	pageInfoFetchLimit = 4 * time.Second
+	pageInfoCacheSize  = 1000
+)
+
+// LinkPreview regroupe les informations affichées sur la page d'aperçu d'un lien (/code+).
+type LinkPreview struct {
+	Link *models.Link
+
+	// Destination est l'URL vers laquelle le visiteur serait redirigé ; elle est masquée (vide)
+	// pour les liens protégés, à usage unique ou pas encore en service, HiddenReason expliquant pourquoi.
+	Destination  string
+	HiddenReason string
+	// VariesByVisitor indique que la destination dépend du visiteur (variantes, règles de ciblage).
+	VariesByVisitor bool
+
+	Page        pageinfo.Info  // Titre et icône de la destination, vides si indisponibles
+	Health      monitor.Health // Dernière vérification de l'URL longue par le moniteur
+	HealthKnown bool           // Faux si le moniteur n'a pas encore vérifié le lien
+	Clicks      int
+}
+
+// pageInfoEntry est une entrée du cache des informations de pages.
+type pageInfoEntry struct {
+	info      pageinfo.Info
+	expiresAt time.Time
+}
+
+// PreviewService construit les aperçus de liens, qui permettent d'inspecter un lien avant de le suivre.
+type PreviewService struct {
+	linkService *LinkService
+	urlMonitor  *monitor.UrlMonitor
+
+	mu    sync.Mutex
+	pages map[string]pageInfoEntry
+}
+
+// NewPreviewService crée et retourne une nouvelle instance de PreviewService.
+// urlMonitor peut être nil : l'état de santé des destinations est alors inconnu.
+func NewPreviewService(linkService *LinkService, urlMonitor *monitor.UrlMonitor) *PreviewService {
+	return &PreviewService{
+		linkService: linkService,
+		urlMonitor:  urlMonitor,
+		pages:       make(map[string]pageInfoEntry),
+	}
+}
+
+// Preview construit l'aperçu du lien désigné par code (code court ou code signé) pour un visiteur.
+// Aucun clic n'est enregistré et un lien à usage unique n'est pas consommé.
+func (p *PreviewService) Preview(ctx context.Context, code string, req RedirectRequest) (*LinkPreview, error) {
+	link, err := p.linkService.LookupLink(code)
+	if err != nil {
+		return nil, err
+	}
+
+	preview := &LinkPreview{
+		Link:            link,
+		VariesByVisitor: len(link.Variants) > 0 || len(link.RoutingRules) > 0,
+	}
+	preview.Health, preview.HealthKnown = p.urlMonitor.Health(link.ID)
+
+	if preview.Clicks, err = p.linkService.clickRepo.CountClicksByLinkID(link.ID); err != nil {
+		return nil, fmt.Errorf("Echec du comptage des clics du lien '%s': %w", link.ShortCode, err)
+	}
+
+	// La destination d'un lien protégé ou à usage unique n'est révélée qu'à la redirection.
+	switch {
+	case link.PasswordHash != "":
+		preview.HiddenReason = "Ce lien est protégé par un mot de passe."
+	case link.OneTime:
+		preview.HiddenReason = "Ce lien est à usage unique."
+	case !LinkActive(link, time.Now()):
+		preview.HiddenReason = "Ce lien n'est pas encore en service."
+	}
+	if preview.HiddenReason != "" {
+		return preview, nil
+	}
+
+	decision, err := p.linkService.ResolveRedirect(link, req)
+	if err != nil {
+		return nil, err
+	}
+	preview.Destination = decision.Destination
+	// Les informations enregistrées pour l'URL longue évitent une récupération à chaque aperçu.
+	if meta := link.Metadata; meta != nil && meta.FetchedAt != nil && meta.URL == decision.Destination {
+		preview.Page = pageinfo.Info{Title: meta.Title, FaviconURL: meta.FaviconURL}
+	} else {
+		preview.Page = p.pageInfo(ctx, decision.Destination)
+	}
+	return preview, nil
+}
+
+// pageInfo retourne le titre et l'icône d'une destination, depuis le cache ou en récupérant la page.
+// Les échecs sont aussi mis en cache (plus brièvement) pour ne pas solliciter une destination en panne.
+// Comme pour les informations de page des liens, une destination interne n'est jamais récupérée
+// (pageinfo.ErrBlockedAddress) : l'aperçu étant public, il exposerait le contenu du réseau du serveur.
+func (p *PreviewService) pageInfo(ctx context.Context, pageURL string) pageinfo.Info {
+	now := time.Now()
+	p.mu.Lock()
+	entry, ok := p.pages[pageURL]
+	p.mu.Unlock()
+	if ok && now.Before(entry.expiresAt) {
+		metrics.Caches.Hit("preview_pages")
+		return entry.info
+	}
+	metrics.Caches.Miss("preview_pages")
+
+	ctx, cancel := context.WithTimeout(ctx, pageInfoFetchLimit)
+	defer cancel()
+	info, err := pageinfo.Fetch(ctx, pageURL)
+	ttl := pageInfoTTL
+	switch {
+	case errors.Is(err, pageinfo.ErrBlockedAddress):
+		// Refus définitif : inutile de réessayer avant l'expiration normale du cache.
+		previewLogger.WarnContext(ctx, "Page information blocked for non-public destination", "url", pageURL, logging.Err(err))
+		info = pageinfo.Info{}
+	case err != nil:
+		previewLogger.InfoContext(ctx, "Page information unavailable", "url", pageURL, logging.Err(err))
+		info, ttl = pageinfo.Info{}, pageInfoFailureTTL
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.pages) >= pageInfoCacheSize {
+		for u, e := range p.pages {
+			if now.After(e.expiresAt) {
+				delete(p.pages, u)
+			}
+		}
+		if len(p.pages) >= pageInfoCacheSize {
+			clear(p.pages)
+		}
+	}
+	p.pages[pageURL] = pageInfoEntry{info: info, expiresAt: now.Add(ttl)}
+	return info
+}
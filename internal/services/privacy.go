@@ -0,0 +1,215 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/antoine-granier/urlshortener/internal/models"
+	"github.com/antoine-granier/urlshortener/internal/repository"
+)
+
+// ErrInvalidPurge est retournée quand une demande d'effacement de données de clics est invalide.
+var ErrInvalidPurge = errors.New("demande d'effacement invalide")
+
+// Modes d'enregistrement des adresses IP des visiteurs.
+const (
+	IPModeFull     = "full"     // Adresse complète
+	IPModeTruncate = "truncate" // /24 en IPv4, /48 en IPv6
+	IPModeHash     = "hash"     // Haché avec une clé aléatoire renouvelée chaque jour (UTC)
+)
+
+// ipHashPrefix distingue une IP hachée d'une adresse dans la colonne ip_address.
+const ipHashPrefix = "h:"
+
+// PrivacyService applique la politique de confidentialité aux clics enregistrés :
+// anonymisation des adresses IP, respect de Do-Not-Track / Global Privacy Control,
+// et effacement des données personnelles à la demande (droit à l'effacement).
+type PrivacyService struct {
+	clickRepo    repository.ClickRepository
+	auditService *AuditService
+	ipMode       string
+	honorDNT     bool
+
+	mu      sync.Mutex
+	hashDay string // Jour UTC de la clé de hachage courante
+	hashKey []byte // Jamais persistée : les hachés des jours passés ne peuvent plus être rapprochés d'une IP
+}
+
+// NewPrivacyService crée et retourne une nouvelle instance de PrivacyService.
+// ipMode vaut IPModeFull (ou vide), IPModeTruncate ou IPModeHash. Avec honorDNT, les clics des visiteurs
+// envoyant DNT: 1 ou Sec-GPC: 1 ne sont enregistrés que comme un décompte anonyme.
+func NewPrivacyService(clickRepo repository.ClickRepository, auditService *AuditService, ipMode string, honorDNT bool) (*PrivacyService, error) {
+	switch ipMode {
+	case "":
+		ipMode = IPModeFull
+	case IPModeFull, IPModeTruncate, IPModeHash:
+	default:
+		return nil, fmt.Errorf("mode d'enregistrement des IP inconnu '%s' (full, truncate ou hash)", ipMode)
+	}
+	return &PrivacyService{
+		clickRepo:    clickRepo,
+		auditService: auditService,
+		ipMode:       ipMode,
+		honorDNT:     honorDNT,
+	}, nil
+}
+
+// Scrub applique la politique de confidentialité à un clic avant son enregistrement.
+// Si le visiteur a demandé à ne pas être suivi (click.DoNotTrack) et que cette demande est respectée,
+// le clic ne garde que le lien, l'heure et la classification robot ; sinon son IP est anonymisée
+// selon le mode configuré. Sans PrivacyService (nil), le clic est enregistré tel quel.
+func (s *PrivacyService) Scrub(click *models.Click) {
+	if s == nil {
+		click.DoNotTrack = false
+		return
+	}
+	if click.DoNotTrack && s.honorDNT {
+		*click = models.Click{
+			LinkID:      click.LinkID,
+			Timestamp:   click.Timestamp.UTC().Truncate(time.Hour),
+			Bot:         click.Bot,
+			BotCategory: click.BotCategory,
+			DoNotTrack:  true,
+		}
+		return
+	}
+	click.DoNotTrack = false
+	click.IPAddress = s.anonymizeIP(click.IPAddress, click.Timestamp)
+}
+
+// LogIP retourne l'adresse IP d'un visiteur telle qu'elle peut apparaître dans les logs : anonymisée selon
+// le mode configuré, comme celle des clics enregistrés, et vide si le visiteur a demandé à ne pas être
+// suivi (dnt) et que cette demande est respectée. Sans PrivacyService (nil), l'IP n'est pas journalisée.
+func (s *PrivacyService) LogIP(ip string, dnt bool) string {
+	if s == nil || (dnt && s.honorDNT) {
+		return ""
+	}
+	return s.anonymizeIP(ip, time.Now())
+}
+
+// anonymizeIP retourne l'IP telle qu'elle doit être enregistrée selon le mode configuré.
+func (s *PrivacyService) anonymizeIP(ip string, at time.Time) string {
+	switch s.ipMode {
+	case IPModeTruncate:
+		return truncateIP(ip)
+	case IPModeHash:
+		if ip == "" {
+			return ""
+		}
+		return s.hashIP(ip, at)
+	}
+	return ip
+}
+
+// truncateIP remplace la partie hôte d'une adresse par des zéros : /24 en IPv4, /48 en IPv6.
+// Une valeur qui n'est pas une adresse IP est effacée.
+func truncateIP(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ""
+	}
+	if v4 := parsed.To4(); v4 != nil {
+		return v4.Mask(net.CIDRMask(24, 32)).String()
+	}
+	return parsed.Mask(net.CIDRMask(48, 128)).String()
+}
+
+// hashIP hache une IP avec la clé du jour UTC de 'at'. La clé est tirée au hasard au premier clic du jour
+// et oubliée le lendemain : une même IP a le même haché pendant une journée seulement.
+// Les clics arrivant en retard sur la veille sont hachés avec la clé du jour courant.
+func (s *PrivacyService) hashIP(ip string, at time.Time) string {
+	day := at.UTC().Format(dayLayout)
+	s.mu.Lock()
+	if s.hashKey == nil || day > s.hashDay {
+		s.hashKey = make([]byte, 32)
+		if _, err := rand.Read(s.hashKey); err != nil {
+			panic(fmt.Sprintf("privacy: impossible de générer une clé de hachage : %v", err))
+		}
+		s.hashDay = day
+	}
+	key := s.hashKey
+	s.mu.Unlock()
+	return hashIPWithKey(ip, key)
+}
+
+// currentIPHash retourne le haché d'une IP avec la clé du jour, si ce processus en a déjà tiré une.
+func (s *PrivacyService) currentIPHash(ip string) (string, bool) {
+	s.mu.Lock()
+	key := s.hashKey
+	s.mu.Unlock()
+	if key == nil {
+		return "", false
+	}
+	return hashIPWithKey(ip, key), true
+}
+
+func hashIPWithKey(ip string, key []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(ip))
+	return ipHashPrefix + hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// ClickPurge décrit les clics dont les données personnelles doivent être effacées.
+// Les critères renseignés se cumulent ; au moins un est requis.
+type ClickPurge struct {
+	IP     string
+	Before time.Time
+}
+
+// ParseClickPurge construit une demande d'effacement à partir d'une IP et d'un jour AAAA-MM-JJ (UTC) :
+// avec un jour, sont visés les clics antérieurs à ce jour. Chaque critère est optionnel.
+func ParseClickPurge(ip, before string) (ClickPurge, error) {
+	purge := ClickPurge{IP: strings.TrimSpace(ip)}
+	if before != "" {
+		day, err := time.Parse(dayLayout, before)
+		if err != nil {
+			return ClickPurge{}, fmt.Errorf("%w: date '%s' (format AAAA-MM-JJ attendu)", ErrInvalidPurge, before)
+		}
+		purge.Before = day
+	}
+	return purge, nil
+}
+
+// PurgeClicks efface l'adresse IP et le User-Agent des clics correspondant à la demande, et trace
+// l'effacement dans le journal d'audit (sans l'adresse concernée). Les clics restent comptés dans
+// les statistiques. Avec une IP, sont aussi visées sa forme tronquée et, dans le processus du serveur,
+// sa forme hachée du jour ; les autres hachés ne pouvant plus être rapprochés d'une IP, ils ne sont
+// effacés que par date.
+func (s *PrivacyService) PurgeClicks(ctx context.Context, actor string, purge ClickPurge) (int64, error) {
+	if purge.IP == "" && purge.Before.IsZero() {
+		return 0, fmt.Errorf("%w: une adresse IP ou une date est requise", ErrInvalidPurge)
+	}
+
+	filter := repository.ClickErasureFilter{Before: purge.Before}
+	var criteria []string
+	if purge.IP != "" {
+		if net.ParseIP(purge.IP) == nil {
+			return 0, fmt.Errorf("%w: adresse IP '%s' invalide", ErrInvalidPurge, purge.IP)
+		}
+		filter.IPAddresses = []string{purge.IP, truncateIP(purge.IP)}
+		if hashed, ok := s.currentIPHash(purge.IP); ok {
+			filter.IPAddresses = append(filter.IPAddresses, hashed)
+		}
+		criteria = append(criteria, "adresse IP")
+	}
+	if !purge.Before.IsZero() {
+		criteria = append(criteria, "avant le "+purge.Before.UTC().Format(dayLayout))
+	}
+
+	erased, err := s.clickRepo.EraseClickPersonalData(filter)
+	if err != nil {
+		return 0, fmt.Errorf("Echec de l'effacement des données des clics: %w", err)
+	}
+	s.auditService.Record(ctx, actor, "clicks.personal_data_erased", 0,
+		fmt.Sprintf("%d clic(s) anonymisé(s) (critères : %s)", erased, strings.Join(criteria, ", ")))
+	return erased, nil
+}
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/antoine-granier/urlshortener/internal/models"
+	"github.com/antoine-granier/urlshortener/internal/ratelimit"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	// ErrInvalidLinkPassword est retournée quand un mot de passe de lien ne respecte pas les contraintes de longueur.
+	ErrInvalidLinkPassword = errors.New("mot de passe de lien invalide (4 à 72 octets)")
+	// ErrWrongPassword est retournée quand le mot de passe saisi pour un lien protégé est incorrect.
+	ErrWrongPassword = errors.New("mot de passe incorrect")
+	// ErrTooManyAttempts est retournée quand trop d'échecs de mot de passe ont eu lieu récemment.
+	ErrTooManyAttempts = errors.New("trop de tentatives, réessayez plus tard")
+	// ErrLinkConsumed est retournée quand un lien à usage unique a déjà servi.
+	ErrLinkConsumed = errors.New("lien à usage unique déjà utilisé")
+)
+
+// Limites par défaut des échecs de mot de passe.
+const (
+	defaultPasswordMaxAttempts = 5
+	defaultPasswordLockout     = 15 * time.Minute
+	// linkAttemptsFactor : un lien accepte au total ce multiple de la limite par IP,
+	// ce qui freine aussi les attaques réparties sur de nombreuses adresses.
+	linkAttemptsFactor = 10
+)
+
+// passwordLimiter limite les échecs de mot de passe par couple (lien, IP) et par lien.
+// La limite par lien a un revers assumé : quelqu'un qui enchaîne les échecs depuis de nombreuses adresses
+// peut bloquer le lien pour ses visiteurs légitimes pendant la durée de blocage. C'est le prix de la
+// protection contre les attaques réparties ; security.password_max_attempts et
+// security.password_lockout_minutes règlent ce compromis.
+type passwordLimiter struct {
+	perIP   *ratelimit.Counter
+	perLink *ratelimit.Counter
+}
+
+func newPasswordLimiter(maxAttempts int, lockout time.Duration) *passwordLimiter {
+	return &passwordLimiter{
+		perIP:   ratelimit.NewCounter(maxAttempts, lockout),
+		perLink: ratelimit.NewCounter(maxAttempts*linkAttemptsFactor, lockout),
+	}
+}
+
+// SetPasswordAttemptLimit configure le nombre d'échecs de mot de passe tolérés par IP et par lien
+// sur la durée lockout, au-delà duquel les tentatives sont refusées.
+func (s *LinkService) SetPasswordAttemptLimit(maxAttempts int, lockout time.Duration) {
+	if maxAttempts <= 0 || lockout <= 0 {
+		return
+	}
+	s.passwordAttempts = newPasswordLimiter(maxAttempts, lockout)
+}
+
+// hashLinkPassword valide et hache (bcrypt) le mot de passe d'un lien. Un mot de passe vide donne un hash vide.
+func hashLinkPassword(password string) (string, error) {
+	if password == "" {
+		return "", nil
+	}
+	if len(password) < 4 || len(password) > 72 {
+		return "", ErrInvalidLinkPassword
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("Echec du hachage du mot de passe: %w", err)
+	}
+	return string(hash), nil
+}
+
+// CheckLinkPassword vérifie le mot de passe saisi pour un lien protégé.
+// Les échecs sont comptabilisés par IP et par lien ; au-delà de la limite, ErrTooManyAttempts est
+// retournée sans même vérifier le mot de passe. Chaque tentative est réservée avant la comparaison
+// (lente, bcrypt) pour que des requêtes simultanées ne puissent pas dépasser la limite ; un succès
+// annule la réservation du lien et remet à zéro le compteur de l'IP.
+func (s *LinkService) CheckLinkPassword(link *models.Link, password, clientIP string) error {
+	linkKey := strconv.FormatUint(uint64(link.ID), 10)
+	ipKey := linkKey + "|" + clientIP
+	ipAttempt, ok := s.passwordAttempts.perIP.Reserve(ipKey)
+	if !ok {
+		return ErrTooManyAttempts
+	}
+	linkAttempt, ok := s.passwordAttempts.perLink.Reserve(linkKey)
+	if !ok {
+		s.passwordAttempts.perIP.Release(ipKey, ipAttempt)
+		return ErrTooManyAttempts
+	}
+	if bcrypt.CompareHashAndPassword([]byte(link.PasswordHash), []byte(password)) != nil {
+		return ErrWrongPassword
+	}
+	s.passwordAttempts.perIP.Reset(ipKey)
+	s.passwordAttempts.perLink.Release(linkKey, linkAttempt)
+	return nil
+}
+
+// ConsumeOneTimeLink marque un lien à usage unique comme utilisé.
+// La mise à jour conditionnelle en base garantit qu'une seule redirection réussit,
+// même en cas de requêtes simultanées : les autres reçoivent ErrLinkConsumed.
+func (s *LinkService) ConsumeOneTimeLink(ctx context.Context, link *models.Link) error {
+	consumed, err := s.linkRepo.ConsumeLink(link.ID, time.Now())
+	if err != nil {
+		return fmt.Errorf("Echec de l'invalidation du lien '%s': %w", link.ShortCode, err)
+	}
+	if !consumed {
+		return ErrLinkConsumed
+	}
+	s.emitLinkEvent(ctx, EventLinkExpired, link)
+	return nil
+}
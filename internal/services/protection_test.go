@@ -0,0 +1,66 @@
+package services
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/antoine-granier/urlshortener/internal/models"
+)
+
+func TestCheckLinkPasswordConcurrentBurst(t *testing.T) {
+	hash, err := hashLinkPassword("correct-horse")
+	if err != nil {
+		t.Fatalf("hashLinkPassword: %v", err)
+	}
+	svc := &LinkService{passwordAttempts: newPasswordLimiter(5, time.Minute)}
+	link := &models.Link{ID: 1, PasswordHash: hash}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	wrong := 0
+	for i := 0; i < 40; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := svc.CheckLinkPassword(link, "guess", "203.0.113.7")
+			if errors.Is(err, ErrWrongPassword) {
+				mu.Lock()
+				wrong++
+				mu.Unlock()
+			} else if !errors.Is(err, ErrTooManyAttempts) {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if wrong != 5 {
+		t.Fatalf("%d passwords compared in a parallel burst, want 5", wrong)
+	}
+	if err := svc.CheckLinkPassword(link, "correct-horse", "203.0.113.7"); !errors.Is(err, ErrTooManyAttempts) {
+		t.Fatalf("correct password during lockout: error = %v, want ErrTooManyAttempts", err)
+	}
+}
+
+func TestCheckLinkPasswordSuccessReleasesAttempt(t *testing.T) {
+	hash, err := hashLinkPassword("correct-horse")
+	if err != nil {
+		t.Fatalf("hashLinkPassword: %v", err)
+	}
+	svc := &LinkService{passwordAttempts: newPasswordLimiter(2, time.Minute)}
+	link := &models.Link{ID: 1, PasswordHash: hash}
+
+	// Les succès ne consomment pas le quota du lien.
+	for i := 0; i < 30; i++ {
+		if err := svc.CheckLinkPassword(link, "correct-horse", "198.51.100.1"); err != nil {
+			t.Fatalf("attempt %d: %v", i, err)
+		}
+	}
+	if err := svc.CheckLinkPassword(link, "wrong", "198.51.100.1"); !errors.Is(err, ErrWrongPassword) {
+		t.Fatalf("wrong password: error = %v, want ErrWrongPassword", err)
+	}
+	if err := svc.CheckLinkPassword(link, "correct-horse", "198.51.100.1"); err != nil {
+		t.Fatalf("correct password after one failure: %v", err)
+	}
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"fmt"
+	"image"
+	_ "image/jpeg" // Enregistre le décodeur JPEG pour les logos
+	_ "image/png"  // Enregistre le décodeur PNG pour les logos
+	"io"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/antoine-granier/urlshortener/internal/qrcode"
+)
+
+// QRSource est la valeur de l'attribut "source" des clics issus d'un scan de QR code.
+const QRSource = "qr"
+
+// QROptions regroupe les paramètres de génération d'un QR code.
+type QROptions struct {
+	Format string       // "png" (par défaut) ou "svg"
+	Level  qrcode.Level // Niveau de correction d'erreur
+	Render qrcode.RenderOptions
+}
+
+// Bornes de la taille (en pixels) d'un QR code généré.
+const (
+	MinQRSize = 64
+	MaxQRSize = 2048
+)
+
+// NewQROptions construit et valide des options de génération à partir de leur forme textuelle
+// (paramètres de requête ou flags CLI). Les chaînes vides prennent les valeurs par défaut.
+// Sans niveau explicite, le niveau H est choisi lorsqu'un logo masque le centre du code, M sinon.
+func NewQROptions(size int, format, ecc, fg, bg string, logo image.Image) (QROptions, error) {
+	opts := QROptions{
+		Format: strings.ToLower(format),
+		Level:  qrcode.LevelM,
+		Render: qrcode.RenderOptions{Size: size, Logo: logo},
+	}
+	if opts.Format == "" {
+		opts.Format = "png"
+	}
+	if opts.Format != "png" && opts.Format != "svg" {
+		return opts, fmt.Errorf("Format de QR code non supporté: %q (png ou svg)", format)
+	}
+	if size < MinQRSize || size > MaxQRSize {
+		return opts, fmt.Errorf("Taille de QR code invalide: %d (entre %d et %d pixels)", size, MinQRSize, MaxQRSize)
+	}
+
+	var err error
+	switch {
+	case ecc != "":
+		if opts.Level, err = qrcode.ParseLevel(ecc); err != nil {
+			return opts, err
+		}
+	case logo != nil:
+		opts.Level = qrcode.LevelH
+	}
+	if fg != "" {
+		if opts.Render.Foreground, err = qrcode.ParseColor(fg); err != nil {
+			return opts, err
+		}
+	}
+	if bg != "" {
+		if opts.Render.Background, err = qrcode.ParseColor(bg); err != nil {
+			return opts, err
+		}
+	}
+	return opts, nil
+}
+
+// QRScanURL construit l'URL encodée dans le QR code d'un lien : l'URL courte complète,
+// marquée "source=qr" pour que les scans soient distingués dans les statistiques.
+func QRScanURL(baseURL, shortCode string) string {
+	return fmt.Sprintf("%s/%s?source=%s", strings.TrimRight(baseURL, "/"), url.PathEscape(shortCode), QRSource)
+}
+
+// WriteQRCode génère le QR code du contenu donné et l'écrit dans w au format demandé.
+func WriteQRCode(w io.Writer, content string, opts QROptions) error {
+	code, err := qrcode.Encode(content, opts.Level)
+	if err != nil {
+		return fmt.Errorf("Echec de l'encodage du QR code: %w", err)
+	}
+
+	switch opts.Format {
+	case "", "png":
+		err = code.WritePNG(w, opts.Render)
+	case "svg":
+		err = code.WriteSVG(w, opts.Render)
+	default:
+		return fmt.Errorf("Format de QR code non supporté: %q", opts.Format)
+	}
+	if err != nil {
+		return fmt.Errorf("Echec du rendu du QR code: %w", err)
+	}
+	return nil
+}
+
+// LoadQRLogo charge une image PNG ou JPEG à superposer au centre d'un QR code.
+func LoadQRLogo(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("Echec de l'ouverture du logo '%s': %w", path, err)
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("Echec du décodage du logo '%s': %w", path, err)
+	}
+	return img, nil
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/antoine-granier/urlshortener/internal/models"
+)
+
+// ErrInvalidRedirectType est retournée quand un code de redirection n'est pas supporté.
+var ErrInvalidRedirectType = errors.New("type de redirection invalide (301, 302, 307 ou 308)")
+
+// ErrPathNotAllowed est retournée quand un suffixe de chemin est demandé sur un lien qui n'est pas un lien "joker".
+var ErrPathNotAllowed = errors.New("suffixe de chemin non autorisé pour ce lien")
+
+// reservedQueryParams sont les paramètres de requête propres au service (ex: attribution des scans de QR code),
+// jamais transmis à la destination.
+var reservedQueryParams = []string{"source"}
+
+// ValidateRedirectType vérifie qu'un code de redirection est supporté. 0 signifie "par défaut" (302).
+func ValidateRedirectType(code int) error {
+	switch code {
+	case 0, http.StatusMovedPermanently, http.StatusFound, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return nil
+	}
+	return fmt.Errorf("%w: %d", ErrInvalidRedirectType, code)
+}
+
+// RedirectStatus retourne le code HTTP de redirection d'un lien (302 par défaut).
+func RedirectStatus(link *models.Link) int {
+	return normalizeRedirectType(link.RedirectType)
+}
+
+func normalizeRedirectType(code int) int {
+	if code == 0 {
+		return http.StatusFound
+	}
+	return code
+}
+
+// LinkActive indique si un lien est en service à l'instant now (sans date d'activation, il l'est toujours).
+func LinkActive(link *models.Link, now time.Time) bool {
+	return link.ActivatesAt == nil || !now.Before(*link.ActivatesAt)
+}
+
+// ResolveDestination calcule l'URL de destination d'une redirection à partir de la destination de base
+// choisie pour le visiteur (l'URL longue du lien, ou celle d'une variante) :
+//   - pour un lien "joker" (PathPassthrough), le suffixe de chemin demandé est ajouté au chemin de la destination ;
+//   - avec ForwardQuery, les paramètres de la requête entrante sont ajoutés à ceux de la destination,
+//     qui restent prioritaires en cas de conflit.
+func ResolveDestination(link *models.Link, base, suffix string, query url.Values) (string, error) {
+	suffix = strings.Trim(suffix, "/")
+	if suffix != "" && !link.PathPassthrough {
+		return "", ErrPathNotAllowed
+	}
+	if suffix == "" && (!link.ForwardQuery || len(query) == 0) {
+		return base, nil
+	}
+
+	dest, err := url.Parse(base)
+	if err != nil {
+		return "", fmt.Errorf("URL de destination invalide pour le lien '%s': %w", link.ShortCode, err)
+	}
+
+	if suffix != "" {
+		// Une destination sans chemin (https://docs.example.com) vaut sa racine : sans cela, JoinPath
+		// produirait un chemin relatif et le suffixe serait refusé.
+		if dest.Path == "" {
+			dest.Path = "/"
+		}
+		// JoinPath nettoie le chemin résultant, ce qui empêche de remonter au-dessus de la destination avec "..".
+		basePath := dest.Path
+		dest = dest.JoinPath(suffix)
+		if !strings.HasPrefix(dest.Path, strings.TrimRight(basePath, "/")+"/") {
+			return "", ErrPathNotAllowed
+		}
+	}
+
+	if link.ForwardQuery && len(query) > 0 {
+		merged := dest.Query()
+		for key, values := range query {
+			if _, exists := merged[key]; exists || isReservedQueryParam(key) {
+				continue
+			}
+			merged[key] = values
+		}
+		dest.RawQuery = merged.Encode()
+	}
+
+	return dest.String(), nil
+}
+
+func isReservedQueryParam(key string) bool {
+	for _, reserved := range reservedQueryParams {
+		if key == reserved {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+package services
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/antoine-granier/urlshortener/internal/models"
+)
+
+func TestResolveDestinationPathPassthrough(t *testing.T) {
+	link := &models.Link{ShortCode: "docs", PathPassthrough: true}
+	cases := []struct {
+		base, suffix, want string
+		err                error
+	}{
+		{"https://docs.example.com", "getting-started", "https://docs.example.com/getting-started", nil},
+		{"https://docs.example.com/", "getting-started", "https://docs.example.com/getting-started", nil},
+		{"https://docs.example.com/v2", "api/links", "https://docs.example.com/v2/api/links", nil},
+		{"https://docs.example.com/v2/", "/api/links/", "https://docs.example.com/v2/api/links", nil},
+		{"https://docs.example.com/v2?lang=fr", "intro", "https://docs.example.com/v2/intro?lang=fr", nil},
+		{"https://docs.example.com", "", "https://docs.example.com", nil},
+		{"https://docs.example.com/v2", "../admin", "", ErrPathNotAllowed},
+		{"https://docs.example.com/v2/", "a/../../etc", "", ErrPathNotAllowed},
+	}
+	for _, tc := range cases {
+		got, err := ResolveDestination(link, tc.base, tc.suffix, nil)
+		if !errors.Is(err, tc.err) {
+			t.Errorf("ResolveDestination(%q, %q) error = %v, want %v", tc.base, tc.suffix, err, tc.err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("ResolveDestination(%q, %q) = %q, want %q", tc.base, tc.suffix, got, tc.want)
+		}
+	}
+}
+
+func TestResolveDestinationRejectsSuffixWithoutPassthrough(t *testing.T) {
+	link := &models.Link{ShortCode: "docs"}
+	if _, err := ResolveDestination(link, "https://docs.example.com", "page", nil); !errors.Is(err, ErrPathNotAllowed) {
+		t.Fatalf("error = %v, want ErrPathNotAllowed", err)
+	}
+}
+
+func TestResolveDestinationForwardQuery(t *testing.T) {
+	link := &models.Link{ShortCode: "docs", ForwardQuery: true, PathPassthrough: true}
+	query := url.Values{"ref": {"newsletter"}, "lang": {"en"}}
+	got, err := ResolveDestination(link, "https://docs.example.com?lang=fr", "intro", query)
+	if err != nil {
+		t.Fatalf("ResolveDestination: %v", err)
+	}
+	if want := "https://docs.example.com/intro?lang=fr&ref=newsletter"; got != want {
+		t.Errorf("ResolveDestination = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/antoine-granier/urlshortener/internal/geoip"
+	"github.com/antoine-granier/urlshortener/internal/models"
+	"github.com/antoine-granier/urlshortener/internal/useragent"
+)
+
+// RedirectRequest regroupe les informations d'une requête de redirection utiles au choix de la destination.
+type RedirectRequest struct {
+	IP                string     // Adresse IP du visiteur (gin.Context.ClientIP)
+	UserAgent         string     // En-tête User-Agent
+	AcceptLanguage    string     // En-tête Accept-Language
+	Referrer          string     // En-tête Referer
+	Time              time.Time  // Instant de la requête (maintenant si zéro), utilisé par les conditions horaires
+	RememberedVariant string     // Variante A/B mémorisée par cookie, vide sinon
+	Suffix            string     // Suffixe de chemin des liens "joker"
+	Query             url.Values // Paramètres de la requête entrante
+}
+
+// RedirectDecision est le résultat de la résolution d'une redirection.
+type RedirectDecision struct {
+	Active      bool   `json:"active"`              // Faux si le lien n'est pas encore en service (ActivatesAt)
+	Destination string `json:"destination"`         // URL finale
+	Variant     string `json:"variant,omitempty"`   // Variante A/B servie, vide sinon
+	Rule        string `json:"rule,omitempty"`      // Règle de ciblage appliquée, vide sinon
+	DeepLink    string `json:"deep_link,omitempty"` // Lien profond d'application à tenter avant Destination, vide sinon
+	Country     string `json:"country,omitempty"`   // Pays du visiteur, vide si inconnu
+	Region      string `json:"region,omitempty"`    // Région du visiteur, vide si inconnue
+}
+
+// SetGeoLocator active la localisation des visiteurs (ciblage géographique et statistiques par pays).
+// Sans localisateur, les conditions de pays et de continent ne sont jamais remplies.
+func (s *LinkService) SetGeoLocator(l *geoip.Locator) {
+	s.geoLocator = l
+}
+
+// ResolveRedirect choisit la destination d'un visiteur. La destination de base est, par ordre de priorité :
+// la première règle de routage satisfaite (règles directes, puis de plateforme, puis géographiques,
+// voir models.RoutingRules.ReplaceOrigin), la variante A/B attachée au visiteur, puis l'URL longue du lien.
+// Le suffixe de chemin et la query string sont ensuite appliqués (voir ResolveDestination).
+// La résolution n'a aucun effet de bord : elle sert aussi aux simulations (dry-run).
+func (s *LinkService) ResolveRedirect(link *models.Link, req RedirectRequest) (*RedirectDecision, error) {
+	loc := s.geoLocator.Lookup(req.IP)
+	decision := &RedirectDecision{Country: loc.Country, Region: loc.Region}
+
+	now := req.Time
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	var routed *models.RoutingRule
+	if len(link.RoutingRules) > 0 {
+		routed = matchRoutingRule(link, ruleContext{
+			languages: parseAcceptLanguage(req.AcceptLanguage),
+			device:    useragent.Parse(req.UserAgent),
+			location:  loc,
+			referrer:  referrerHost(req.Referrer),
+			query:     req.Query,
+			now:       now,
+		})
+	}
+
+	decision.Active = LinkActive(link, now)
+
+	base := link.LongURL
+	if routed != nil {
+		base, decision.Rule, decision.DeepLink = routed.URL, routed.Name, routed.DeepLink
+	} else if v := ChooseVariant(link, req.RememberedVariant, req.IP+"|"+req.UserAgent); v != nil {
+		base, decision.Variant = v.URL, v.Name
+	}
+
+	destination, err := ResolveDestination(link, base, req.Suffix, req.Query)
+	if err != nil {
+		return nil, err
+	}
+	decision.Destination = destination
+	return decision, nil
+}
+
+// SimulateRedirect résout la destination d'une requête synthétique sans enregistrer de clic.
+// Si rules est non nil, ces règles de routage (validées au préalable) remplacent les règles directes
+// du lien, ce qui permet de tester des règles avant de les enregistrer.
+func (s *LinkService) SimulateRedirect(shortCode string, req RedirectRequest, rules []models.RoutingRule) (*RedirectDecision, error) {
+	link, err := s.linkRepo.GetLinkByShortCode(shortCode)
+	if err != nil {
+		return nil, fmt.Errorf("Echec de la récupération du lien '%s': %w", shortCode, err)
+	}
+	if rules != nil {
+		built, err := buildRoutingRules(rules)
+		if err != nil {
+			return nil, err
+		}
+		link.RoutingRules = link.RoutingRules.ReplaceOrigin("", built)
+	}
+	return s.ResolveRedirect(link, req)
+}
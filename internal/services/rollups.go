@@ -0,0 +1,89 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/antoine-granier/urlshortener/internal/logging"
+	"github.com/antoine-granier/urlshortener/internal/repository"
+)
+
+// compactionBatchSize est le nombre de clics agrégés par transaction.
+const compactionBatchSize = 5000
+
+// ClickCompactor agrège périodiquement les clics bruts dans les tables de statistiques horaires et
+// journalières, puis supprime les clics bruts plus anciens que la durée de rétention.
+type ClickCompactor struct {
+	clickRepo repository.ClickRepository
+	retention time.Duration // 0 : les clics bruts sont conservés
+}
+
+// NewClickCompactor crée et retourne une nouvelle instance de ClickCompactor.
+// Avec retentionDays <= 0, les clics bruts ne sont jamais supprimés.
+func NewClickCompactor(clickRepo repository.ClickRepository, retentionDays int) *ClickCompactor {
+	c := &ClickCompactor{clickRepo: clickRepo}
+	if retentionDays > 0 {
+		c.retention = time.Duration(retentionDays) * 24 * time.Hour
+	}
+	return c
+}
+
+// Start lance la compaction périodique (et la purge des clics bruts expirés) dans une goroutine.
+func (c *ClickCompactor) Start(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for ; ; <-ticker.C {
+			c.run(time.Now())
+		}
+	}()
+}
+
+// run exécute une passe de compaction puis de purge, en journalisant les erreurs.
+func (c *ClickCompactor) run(now time.Time) {
+	compacted, err := c.Compact()
+	if err != nil {
+		rollupsLogger.Error("Failed to compact clicks", logging.Err(err))
+		return // Les clics non agrégés ne doivent pas être purgés
+	}
+	if compacted > 0 {
+		rollupsLogger.Info("Clicks compacted", "count", compacted)
+	}
+
+	purged, err := c.Purge(now)
+	if err != nil {
+		rollupsLogger.Error("Failed to purge raw clicks", logging.Err(err))
+		return
+	}
+	if purged > 0 {
+		rollupsLogger.Info("Raw clicks purged", "count", purged, "retention_days", int(c.retention.Hours()/24))
+	}
+}
+
+// Compact agrège tous les clics en attente, par lots, et retourne leur nombre.
+func (c *ClickCompactor) Compact() (int, error) {
+	total := 0
+	for {
+		n, err := c.clickRepo.CompactClicks(compactionBatchSize)
+		total += n
+		if err != nil {
+			return total, fmt.Errorf("Echec de l'agrégation des clics : %w", err)
+		}
+		if n < compactionBatchSize {
+			return total, nil
+		}
+	}
+}
+
+// Purge supprime les clics bruts déjà agrégés plus anciens que la durée de rétention.
+// Sans rétention configurée, elle ne fait rien.
+func (c *ClickCompactor) Purge(now time.Time) (int64, error) {
+	if c.retention == 0 {
+		return 0, nil
+	}
+	purged, err := c.clickRepo.PurgeRawClicks(now.Add(-c.retention))
+	if err != nil {
+		return 0, fmt.Errorf("Echec de la purge des clics bruts : %w", err)
+	}
+	return purged, nil
+}
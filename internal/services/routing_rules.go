@@ -0,0 +1,384 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	_ "time/tzdata" // Fuseaux horaires embarqués : les règles restent valides sans base zoneinfo sur l'hôte
+
+	"github.com/antoine-granier/urlshortener/internal/geoip"
+	"github.com/antoine-granier/urlshortener/internal/metrics"
+	"github.com/antoine-granier/urlshortener/internal/models"
+	"github.com/antoine-granier/urlshortener/internal/useragent"
+)
+
+// ErrInvalidRoutingRules est retournée quand une liste de règles de routage est incohérente.
+var ErrInvalidRoutingRules = errors.New("règles de routage invalides")
+
+// maxRoutingRules limite le nombre de règles par lien, évaluées à chaque redirection.
+const maxRoutingRules = 50
+
+// weekDays associe les abréviations acceptées dans les règles aux jours de la semaine.
+var weekDays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// ruleDateLayouts sont les formats acceptés pour les bornes de fenêtre de dates sans fuseau explicite,
+// interprétées dans le fuseau de la règle.
+var ruleDateLayouts = []string{"2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02"}
+
+// locations met en cache les fuseaux horaires chargés (time.LoadLocation relit la base à chaque appel).
+var locations sync.Map
+
+// ruleContext regroupe les attributs d'une requête évalués par les conditions des règles.
+type ruleContext struct {
+	languages []string // Langues de l'Accept-Language, par ordre de préférence
+	device    useragent.Info
+	location  geoip.Location
+	referrer  string // Hôte du Referer, en minuscules
+	query     url.Values
+	now       time.Time
+}
+
+// buildRoutingRules valide et normalise des règles de routage directes (codes en minuscules/majuscules,
+// fuseau par défaut, noms par défaut). Toute condition invalide rejette l'ensemble.
+// L'origine fournie est ignorée : les règles de plateforme et géographiques ont leurs propres raccourcis.
+func buildRoutingRules(inputs []models.RoutingRule) (models.RoutingRules, error) {
+	if len(inputs) == 0 {
+		return nil, nil
+	}
+	if len(inputs) > maxRoutingRules {
+		return nil, fmt.Errorf("%w: %d règles au maximum", ErrInvalidRoutingRules, maxRoutingRules)
+	}
+	rules := make(models.RoutingRules, 0, len(inputs))
+	for i, in := range inputs {
+		name := strings.TrimSpace(in.Name)
+		if name == "" {
+			name = "route-" + strconv.Itoa(i+1)
+		}
+		if len(name) > 50 {
+			return nil, fmt.Errorf("%w: nom de règle trop long", ErrInvalidRoutingRules)
+		}
+		if err := validateTargetURL(in.URL, false); err != nil {
+			return nil, fmt.Errorf("%w: règle %q: %v", ErrInvalidRoutingRules, name, err)
+		}
+		if in.DeepLink != "" {
+			if err := validateTargetURL(in.DeepLink, true); err != nil {
+				return nil, fmt.Errorf("%w: lien profond de la règle %q: %v", ErrInvalidRoutingRules, name, err)
+			}
+		}
+		when, err := normalizeConditions(in.When)
+		if err != nil {
+			return nil, fmt.Errorf("%w: règle %q: %v", ErrInvalidRoutingRules, name, err)
+		}
+		rules = append(rules, models.RoutingRule{Name: name, When: when, URL: in.URL, DeepLink: in.DeepLink})
+	}
+	return rules, nil
+}
+
+// normalizeConditions valide les conditions d'une règle et retourne leur forme normalisée.
+func normalizeConditions(c models.RuleConditions) (models.RuleConditions, error) {
+	var out models.RuleConditions
+	for _, lang := range c.Languages {
+		lang = strings.ToLower(strings.TrimSpace(lang))
+		if lang == "" || len(lang) > 35 || strings.ContainsAny(lang, ",; ") {
+			return out, fmt.Errorf("langue invalide %q", lang)
+		}
+		out.Languages = append(out.Languages, lang)
+	}
+	for _, platform := range c.Platforms {
+		platform = strings.ToLower(strings.TrimSpace(platform))
+		if _, ok := devicePlatforms[platform]; !ok {
+			return out, fmt.Errorf("plateforme inconnue %q", platform)
+		}
+		out.Platforms = append(out.Platforms, platform)
+	}
+	for _, country := range c.Countries {
+		country = strings.ToUpper(strings.TrimSpace(country))
+		if !isCountryCode(country) {
+			return out, fmt.Errorf("code pays invalide %q", country)
+		}
+		out.Countries = append(out.Countries, country)
+	}
+	for _, continent := range c.Continents {
+		continent = strings.ToUpper(strings.TrimSpace(continent))
+		if !geoContinents[continent] {
+			return out, fmt.Errorf("code continent inconnu %q", continent)
+		}
+		out.Continents = append(out.Continents, continent)
+	}
+	for _, domain := range c.ReferrerDomains {
+		domain = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(domain)), ".")
+		if domain == "" || strings.ContainsAny(domain, "/: ") {
+			return out, fmt.Errorf("domaine de provenance invalide %q (nom de domaine seul attendu)", domain)
+		}
+		out.ReferrerDomains = append(out.ReferrerDomains, domain)
+	}
+	for key, value := range c.Query {
+		if key == "" {
+			return out, errors.New("nom de paramètre de requête vide")
+		}
+		if out.Query == nil {
+			out.Query = make(map[string]string, len(c.Query))
+		}
+		out.Query[key] = value
+	}
+
+	out.Timezone = strings.TrimSpace(c.Timezone)
+	loc, err := loadLocation(out.Timezone)
+	if err != nil {
+		return out, fmt.Errorf("fuseau horaire inconnu %q", c.Timezone)
+	}
+	for _, day := range c.Days {
+		day = strings.ToLower(strings.TrimSpace(day))
+		if _, ok := weekDays[day]; !ok {
+			return out, fmt.Errorf("jour invalide %q (mon, tue, wed, thu, fri, sat ou sun)", day)
+		}
+		out.Days = append(out.Days, day)
+	}
+	if (c.TimeFrom == "") != (c.TimeTo == "") {
+		return out, errors.New("time_from et time_to vont ensemble")
+	}
+	if c.TimeFrom != "" {
+		from, errFrom := parseClock(c.TimeFrom)
+		to, errTo := parseClock(c.TimeTo)
+		if errFrom != nil || errTo != nil {
+			return out, errors.New("heures invalides (format HH:MM attendu)")
+		}
+		if from == to {
+			return out, errors.New("plage horaire vide")
+		}
+		out.TimeFrom, out.TimeTo = c.TimeFrom, c.TimeTo
+	}
+	var after, before time.Time
+	if c.After != "" {
+		if after, err = parseRuleDate(c.After, loc); err != nil {
+			return out, fmt.Errorf("date 'after' invalide %q", c.After)
+		}
+		out.After = c.After
+	}
+	if c.Before != "" {
+		if before, err = parseRuleDate(c.Before, loc); err != nil {
+			return out, fmt.Errorf("date 'before' invalide %q", c.Before)
+		}
+		out.Before = c.Before
+	}
+	if !after.IsZero() && !before.IsZero() && !after.Before(before) {
+		return out, errors.New("la date 'after' doit précéder la date 'before'")
+	}
+	return out, nil
+}
+
+// matchRoutingRule retourne la première règle de routage du lien dont toutes les conditions sont remplies, ou nil.
+func matchRoutingRule(link *models.Link, ctx ruleContext) *models.RoutingRule {
+	for i := range link.RoutingRules {
+		rule := &link.RoutingRules[i]
+		if conditionsMatch(rule.When, ctx) {
+			return rule
+		}
+	}
+	return nil
+}
+
+// conditionsMatch indique si toutes les conditions renseignées sont remplies.
+// Les règles ayant été validées à l'enregistrement, une valeur illisible fait simplement échouer la condition.
+func conditionsMatch(c models.RuleConditions, ctx ruleContext) bool {
+	if len(c.Languages) > 0 && !languageMatches(c.Languages, ctx.languages) {
+		return false
+	}
+	if len(c.Platforms) > 0 && !anyOf(c.Platforms, func(p string) bool {
+		match, ok := devicePlatforms[p]
+		return ok && match(ctx.device)
+	}) {
+		return false
+	}
+	if len(c.Countries) > 0 && !anyOf(c.Countries, func(v string) bool { return v == ctx.location.Country }) {
+		return false
+	}
+	if len(c.Continents) > 0 && !anyOf(c.Continents, func(v string) bool { return v == ctx.location.Continent }) {
+		return false
+	}
+	if len(c.ReferrerDomains) > 0 && !anyOf(c.ReferrerDomains, func(d string) bool {
+		return ctx.referrer == d || strings.HasSuffix(ctx.referrer, "."+d)
+	}) {
+		return false
+	}
+	for key, want := range c.Query {
+		values, ok := ctx.query[key]
+		if !ok {
+			return false
+		}
+		if want != "" && want != "*" && !anyOf(values, func(v string) bool { return v == want }) {
+			return false
+		}
+	}
+
+	if len(c.Days) == 0 && c.TimeFrom == "" && c.After == "" && c.Before == "" {
+		return true
+	}
+	loc, err := loadLocation(c.Timezone)
+	if err != nil {
+		return false
+	}
+	now := ctx.now.In(loc)
+	if len(c.Days) > 0 && !anyOf(c.Days, func(d string) bool { return weekDays[d] == now.Weekday() }) {
+		return false
+	}
+	if c.TimeFrom != "" {
+		from, errFrom := parseClock(c.TimeFrom)
+		to, errTo := parseClock(c.TimeTo)
+		if errFrom != nil || errTo != nil {
+			return false
+		}
+		minute := now.Hour()*60 + now.Minute()
+		if from < to && (minute < from || minute >= to) {
+			return false
+		}
+		if from > to && minute < from && minute >= to { // Plage passant minuit (ex: 22:00-06:00)
+			return false
+		}
+	}
+	if c.After != "" {
+		after, err := parseRuleDate(c.After, loc)
+		if err != nil || now.Before(after) {
+			return false
+		}
+	}
+	if c.Before != "" {
+		before, err := parseRuleDate(c.Before, loc)
+		if err != nil || !now.Before(before) {
+			return false
+		}
+	}
+	return true
+}
+
+// languageMatches indique si une langue acceptée par le visiteur correspond à une langue de la règle :
+// "fr" couvre "fr-CA", alors que "en-gb" ne couvre que "en-gb".
+func languageMatches(wanted, accepted []string) bool {
+	for _, a := range accepted {
+		for _, w := range wanted {
+			if a == w || strings.HasPrefix(a, w+"-") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parseAcceptLanguage extrait les langues d'un en-tête Accept-Language par ordre de préférence,
+// en ignorant celles de poids nul et le joker "*".
+func parseAcceptLanguage(header string) []string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+	var tags []weighted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || tag == "*" {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+		if q > 0 {
+			tags = append(tags, weighted{tag, q})
+		}
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+	languages := make([]string, len(tags))
+	for i, t := range tags {
+		languages[i] = t.tag
+	}
+	return languages
+}
+
+// referrerHost retourne l'hôte (sans port, en minuscules) d'un en-tête Referer, ou "".
+func referrerHost(referrer string) string {
+	u, err := url.Parse(referrer)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+// parseClock convertit une heure "HH:MM" en minutes depuis minuit.
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// parseRuleDate lit une borne de fenêtre de dates : RFC 3339, ou date/heure locale au fuseau de la règle.
+func parseRuleDate(s string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	for _, layout := range ruleDateLayouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("date invalide %q", s)
+}
+
+// loadLocation charge un fuseau horaire IANA (UTC si vide), avec mise en cache.
+func loadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	if loc, ok := locations.Load(name); ok {
+		metrics.Caches.Hit("timezones")
+		return loc.(*time.Location), nil
+	}
+	metrics.Caches.Miss("timezones")
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+	locations.Store(name, loc)
+	return loc, nil
+}
+
+func anyOf(values []string, pred func(string) bool) bool {
+	for _, v := range values {
+		if pred(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetLinkRoutingRules remplace les règles de routage directes d'un lien après validation.
+// Ses règles de plateforme et géographiques sont conservées et restent évaluées après elles.
+// Une liste vide supprime toutes les règles directes.
+func (s *LinkService) SetLinkRoutingRules(ctx context.Context, shortCode string, inputs []models.RoutingRule) (*models.Link, error) {
+	link, err := s.linkRepo.GetLinkByShortCode(shortCode)
+	if err != nil {
+		return nil, fmt.Errorf("Echec de la récupération du lien '%s': %w", shortCode, err)
+	}
+	rules, err := buildRoutingRules(inputs)
+	if err != nil {
+		return nil, err
+	}
+	link.RoutingRules = link.RoutingRules.ReplaceOrigin("", rules)
+	if err := s.linkRepo.UpdateLink(link); err != nil {
+		return nil, fmt.Errorf("Echec de la mise à jour des règles de routage du lien '%s': %w", shortCode, err)
+	}
+	s.emitLinkEvent(ctx, EventLinkUpdated, link)
+	return link, nil
+}
@@ -0,0 +1,155 @@
+package services
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/antoine-granier/urlshortener/internal/models"
+)
+
+const iPhoneUA = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1"
+
+func TestDirectRulesPrecedePlatformRules(t *testing.T) {
+	svc, _ := newTestLinkService(t)
+	link, _, err := svc.CreateLinkWithOptions(t.Context(), CreateLinkOptions{
+		LongURL:      "https://example.com/",
+		DeviceRules:  []DeviceRuleInput{{Platform: "ios", URL: "https://apps.example.com/app", DeepLink: "myapp://home"}},
+		GeoRules:     []GeoRuleInput{{Continent: "EU", URL: "https://example.eu/"}, {Country: "FR", URL: "https://example.fr/"}},
+		RoutingRules: []models.RoutingRule{{Name: "fr", When: models.RuleConditions{Languages: []string{"fr"}}, URL: "https://example.com/fr"}},
+	})
+	if err != nil {
+		t.Fatalf("create link: %v", err)
+	}
+	var names []string
+	for _, rule := range link.RoutingRules {
+		names = append(names, rule.Name)
+	}
+	if got, want := fmt.Sprint(names), "[fr ios geo:FR geo:EU]"; got != want {
+		t.Fatalf("rules = %s, want %s", got, want)
+	}
+
+	cases := []struct {
+		name           string
+		userAgent      string
+		acceptLanguage string
+		wantURL        string
+		wantRule       string
+		wantDeepLink   string
+	}{
+		{"direct rule first", iPhoneUA, "fr-FR", "https://example.com/fr", "fr", ""},
+		{"platform rule", iPhoneUA, "en", "https://apps.example.com/app", "ios", "myapp://home"},
+		{"no rule", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) Firefox/120.0", "en", "https://example.com/", "", ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			decision, err := svc.ResolveRedirect(link, RedirectRequest{UserAgent: tc.userAgent, AcceptLanguage: tc.acceptLanguage})
+			if err != nil {
+				t.Fatalf("ResolveRedirect: %v", err)
+			}
+			if decision.Destination != tc.wantURL || decision.Rule != tc.wantRule || decision.DeepLink != tc.wantDeepLink {
+				t.Errorf("got (%s, %q, %q), want (%s, %q, %q)", decision.Destination, decision.Rule, decision.DeepLink,
+					tc.wantURL, tc.wantRule, tc.wantDeepLink)
+			}
+		})
+	}
+
+	// Chaque raccourci ne remplace que ses propres règles.
+	updated, err := svc.SetLinkRoutingRules(t.Context(), link.ShortCode, nil)
+	if err != nil {
+		t.Fatalf("SetLinkRoutingRules: %v", err)
+	}
+	if len(updated.RoutingRules) != 3 || updated.RoutingRules[0].Name != "ios" {
+		t.Errorf("after clearing direct rules: %+v", updated.RoutingRules)
+	}
+	updated, err = svc.SetLinkDeviceRules(t.Context(), link.ShortCode, nil)
+	if err != nil {
+		t.Fatalf("SetLinkDeviceRules: %v", err)
+	}
+	if len(updated.RoutingRules) != 2 || updated.RoutingRules[0].Name != "geo:FR" {
+		t.Errorf("after clearing platform rules: %+v", updated.RoutingRules)
+	}
+}
+
+func TestTimeConditionsAcrossMidnightAndTimezones(t *testing.T) {
+	utc := func(value string) time.Time {
+		at, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return at
+	}
+	night := models.RuleConditions{TimeFrom: "22:00", TimeTo: "06:00"}
+	parisNight := models.RuleConditions{Timezone: "Europe/Paris", TimeFrom: "22:00", TimeTo: "06:00"}
+	parisOffice := models.RuleConditions{Timezone: "Europe/Paris", TimeFrom: "09:00", TimeTo: "18:00"}
+	fridayNight := models.RuleConditions{Days: []string{"fri"}, TimeFrom: "22:00", TimeTo: "06:00"}
+	newYorkSunday := models.RuleConditions{Timezone: "America/New_York", Days: []string{"sun"}}
+	tokyoAprilFirst := models.RuleConditions{Timezone: "Asia/Tokyo", After: "2026-04-01", Before: "2026-04-02"}
+	absoluteAfter := models.RuleConditions{Timezone: "Asia/Tokyo", After: "2026-04-01T00:00:00Z"}
+
+	cases := []struct {
+		name string
+		when models.RuleConditions
+		now  string
+		want bool
+	}{
+		{"before the night range", night, "2026-01-15T21:59:00Z", false},
+		{"start is inclusive", night, "2026-01-15T22:00:00Z", true},
+		{"before midnight", night, "2026-01-15T23:59:00Z", true},
+		{"at midnight", night, "2026-01-16T00:00:00Z", true},
+		{"last minute", night, "2026-01-16T05:59:00Z", true},
+		{"end is exclusive", night, "2026-01-16T06:00:00Z", false},
+		{"midday", night, "2026-01-16T12:00:00Z", false},
+
+		{"paris winter offset", parisOffice, "2026-01-15T08:30:00Z", true}, // 09:30 CET
+		{"paris summer offset", parisOffice, "2026-07-15T07:30:00Z", true}, // 09:30 CEST
+		{"paris after hours", parisOffice, "2026-07-15T16:30:00Z", false},  // 18:30 CEST
+		{"paris before hours", parisOffice, "2026-01-15T07:59:00Z", false}, // 08:59 CET
+		{"paris night in utc evening", parisNight, "2026-01-15T21:00:00Z", true},
+		{"utc night is paris morning", parisNight, "2026-01-16T05:30:00Z", false}, // 06:30 CET
+		{"dst night end before", parisNight, "2026-03-29T03:30:00Z", true},        // 05:30 CEST
+		{"dst night end after", parisNight, "2026-03-29T04:30:00Z", false},        // 06:30 CEST
+
+		// Le jour est celui du clic : la fin d'une nuit de vendredi tombe un samedi.
+		{"friday night", fridayNight, "2026-01-16T23:00:00Z", true},
+		{"saturday early morning", fridayNight, "2026-01-17T01:00:00Z", false},
+		{"thursday night", fridayNight, "2026-01-15T23:00:00Z", false},
+
+		{"sunday evening in new york", newYorkSunday, "2026-03-02T03:00:00Z", true}, // lundi en UTC
+		{"monday in new york", newYorkSunday, "2026-03-02T05:00:00Z", false},        // lundi 00:00 EST
+
+		{"before local day", tokyoAprilFirst, "2026-03-31T14:59:00Z", false},
+		{"local day start", tokyoAprilFirst, "2026-03-31T15:00:00Z", true},
+		{"local day end", tokyoAprilFirst, "2026-04-01T14:59:00Z", true},
+		{"after local day", tokyoAprilFirst, "2026-04-01T15:00:00Z", false},
+		{"rfc 3339 bound ignores the zone", absoluteAfter, "2026-03-31T23:00:00Z", false},
+		{"rfc 3339 bound reached", absoluteAfter, "2026-04-01T00:00:00Z", true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			when, err := normalizeConditions(tc.when)
+			if err != nil {
+				t.Fatalf("normalizeConditions: %v", err)
+			}
+			if got := conditionsMatch(when, ruleContext{now: utc(tc.now)}); got != tc.want {
+				t.Errorf("match at %s = %v, want %v", tc.now, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestTimeConditionsValidation(t *testing.T) {
+	for name, when := range map[string]models.RuleConditions{
+		"empty range":      {TimeFrom: "08:00", TimeTo: "08:00"},
+		"missing end":      {TimeFrom: "08:00"},
+		"invalid clock":    {TimeFrom: "24:00", TimeTo: "06:00"},
+		"unknown timezone": {Timezone: "Mars/Olympus"},
+		"unknown day":      {Days: []string{"monday"}},
+		"inverted window":  {After: "2026-04-02", Before: "2026-04-01"},
+		"unreadable bound": {Before: "01/04/2026"},
+	} {
+		if _, err := normalizeConditions(when); err == nil {
+			t.Errorf("%s: accepted %+v", name, when)
+		}
+	}
+}
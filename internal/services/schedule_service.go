@@ -0,0 +1,166 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/antoine-granier/urlshortener/internal/logging"
+	"github.com/antoine-granier/urlshortener/internal/models"
+	"github.com/antoine-granier/urlshortener/internal/repository"
+	"gorm.io/gorm"
+)
+
+// ErrInvalidSchedule est retournée quand un changement programmé est incohérent (date passée, URL invalide...).
+var ErrInvalidSchedule = errors.New("programmation invalide")
+
+// dueChangesBatch est le nombre maximal de changements appliqués par passe du planificateur.
+const dueChangesBatch = 100
+
+// ScheduleService gère les changements de destination programmés et leur application.
+type ScheduleService struct {
+	linkService *LinkService
+	changeRepo  repository.ScheduledChangeRepository
+	audit       *AuditService
+	wake        chan struct{} // Signale au planificateur qu'un changement a été programmé
+}
+
+// NewScheduleService crée et retourne une nouvelle instance de ScheduleService.
+func NewScheduleService(linkService *LinkService, changeRepo repository.ScheduledChangeRepository, audit *AuditService) *ScheduleService {
+	return &ScheduleService{
+		linkService: linkService,
+		changeRepo:  changeRepo,
+		audit:       audit,
+		wake:        make(chan struct{}, 1),
+	}
+}
+
+// Wake retourne le channel signalant qu'un nouveau changement a été programmé dans ce processus.
+func (s *ScheduleService) Wake() <-chan struct{} {
+	return s.wake
+}
+
+// ScheduleChange programme le remplacement de la destination d'un lien à la date applyAt (future).
+func (s *ScheduleService) ScheduleChange(ctx context.Context, shortCode, longURL string, applyAt time.Time, actor string) (*models.ScheduledChange, error) {
+	link, err := s.linkService.linkRepo.GetLinkByShortCode(shortCode)
+	if err != nil {
+		return nil, fmt.Errorf("Echec de la récupération du lien '%s': %w", shortCode, err)
+	}
+	if _, err := s.linkService.canonicalizer.Canonicalize(longURL); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSchedule, err)
+	}
+	if !applyAt.After(time.Now()) {
+		return nil, fmt.Errorf("%w: la date d'application doit être dans le futur", ErrInvalidSchedule)
+	}
+
+	change := &models.ScheduledChange{
+		LinkID:  link.ID,
+		LongURL: longURL,
+		ApplyAt: applyAt.UTC(),
+		Status:  models.ScheduledChangePending,
+	}
+	if err := s.changeRepo.CreateChange(change); err != nil {
+		return nil, fmt.Errorf("Echec de la programmation du changement: %w", err)
+	}
+	s.audit.Record(ctx, actor, "link.change_scheduled", link.ID,
+		fmt.Sprintf("changement #%d : destination %s le %s", change.ID, longURL, change.ApplyAt.Format(time.RFC3339)))
+
+	select {
+	case s.wake <- struct{}{}:
+	default: // Un réveil est déjà en attente
+	}
+	return change, nil
+}
+
+// ListScheduledChanges retourne les changements programmés d'un lien (tous statuts confondus).
+func (s *ScheduleService) ListScheduledChanges(shortCode string) ([]models.ScheduledChange, error) {
+	link, err := s.linkService.linkRepo.GetLinkByShortCode(shortCode)
+	if err != nil {
+		return nil, fmt.Errorf("Echec de la récupération du lien '%s': %w", shortCode, err)
+	}
+	changes, err := s.changeRepo.ListChangesByLinkID(link.ID)
+	if err != nil {
+		return nil, fmt.Errorf("Echec de la récupération des changements programmés: %w", err)
+	}
+	return changes, nil
+}
+
+// CancelScheduledChange annule un changement encore en attente.
+func (s *ScheduleService) CancelScheduledChange(ctx context.Context, shortCode string, id uint, actor string) error {
+	link, err := s.linkService.linkRepo.GetLinkByShortCode(shortCode)
+	if err != nil {
+		return fmt.Errorf("Echec de la récupération du lien '%s': %w", shortCode, err)
+	}
+	change, err := s.changeRepo.GetChange(id)
+	if err != nil {
+		return fmt.Errorf("Echec de la récupération du changement #%d: %w", id, err)
+	}
+	if change.LinkID != link.ID {
+		return fmt.Errorf("Changement #%d introuvable pour le lien '%s': %w", id, shortCode, gorm.ErrRecordNotFound)
+	}
+	if err := s.changeRepo.SetChangeStatus(id, models.ScheduledChangeCancelled, "", time.Now()); err != nil {
+		return fmt.Errorf("Echec de l'annulation du changement #%d: %w", id, err)
+	}
+	s.audit.Record(ctx, actor, "link.change_cancelled", link.ID, fmt.Sprintf("changement #%d annulé", id))
+	return nil
+}
+
+// NextChangeAt retourne la date du prochain changement en attente, ou nil.
+func (s *ScheduleService) NextChangeAt() (*time.Time, error) {
+	return s.changeRepo.NextPendingChangeAt()
+}
+
+// ApplyDueChanges applique les changements dont la date est atteinte et retourne leur nombre.
+// Un changement dont la destination est devenue invalide est marqué en échec.
+func (s *ScheduleService) ApplyDueChanges(now time.Time) (int, error) {
+	applied := 0
+	for {
+		changes, err := s.changeRepo.ListDueChanges(now, dueChangesBatch)
+		if err != nil {
+			return applied, fmt.Errorf("Echec de la récupération des changements programmés: %w", err)
+		}
+		batchApplied := 0
+		for i := range changes {
+			if s.applyChange(&changes[i], now) {
+				batchApplied++
+			}
+		}
+		applied += batchApplied
+		// Lot incomplet : tout est traité. Lot sans aucun succès : inutile d'insister avant la prochaine passe.
+		if len(changes) < dueChangesBatch || batchApplied == 0 {
+			return applied, nil
+		}
+	}
+}
+
+// applyChange applique un changement et l'inscrit au journal d'audit. Retourne true s'il a été appliqué.
+// Un changement dont la destination est invalide ou dont le lien n'existe plus est marqué en échec ;
+// une autre erreur (base indisponible...) le laisse en attente pour la passe suivante.
+func (s *ScheduleService) applyChange(change *models.ScheduledChange, now time.Time) bool {
+	canonicalURL, err := s.linkService.canonicalizer.Canonicalize(change.LongURL)
+	if err == nil {
+		err = s.changeRepo.ApplyChange(change, canonicalURL, ExtractUTM(change.LongURL), now)
+	}
+	switch {
+	case err == nil:
+		ctx := context.Background() // Passe du planificateur, hors de toute requête
+		s.audit.Record(ctx, ActorScheduler, "link.destination_changed", change.LinkID,
+			fmt.Sprintf("changement #%d appliqué : destination %s", change.ID, change.LongURL))
+		s.linkService.metadata.Enqueue(ctx, change.LinkID, change.LongURL)
+		s.linkService.emitLinkEventByID(ctx, EventLinkUpdated, change.LinkID)
+		return true
+	case errors.Is(err, repository.ErrChangeNotPending):
+		return false // Annulé ou appliqué entre-temps
+	case !errors.Is(err, ErrInvalidURL) && !errors.Is(err, gorm.ErrRecordNotFound):
+		scheduleLogger.Error("Failed to apply scheduled change, retrying on next pass", "change_id", change.ID, logging.Err(err))
+		return false
+	}
+
+	if statusErr := s.changeRepo.SetChangeStatus(change.ID, models.ScheduledChangeFailed, err.Error(), now); statusErr != nil {
+		scheduleLogger.Error("Failed to mark scheduled change as failed", "change_id", change.ID, logging.Err(statusErr))
+	}
+	s.audit.Record(context.Background(), ActorScheduler, "link.change_failed", change.LinkID,
+		fmt.Sprintf("changement #%d en échec : %v", change.ID, err))
+	return false
+}
@@ -0,0 +1,197 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/antoine-granier/urlshortener/internal/repository"
+)
+
+// Noms des stratégies de génération de codes courts (clé de configuration 'shortcode.strategy').
+const (
+	StrategyRandom   = "random"
+	StrategySequence = "sequence"
+	StrategyHash     = "hash"
+)
+
+// maxShortCodeLength correspond à la taille de la colonne 'short_code' de la table 'links'.
+const maxShortCodeLength = 10
+
+// linkSequenceName est le nom du compteur utilisé par la stratégie "sequence".
+const linkSequenceName = "links"
+
+// CodeGenerator est une stratégie de génération de codes courts.
+// Generate est appelée avec le numéro de tentative (0 pour la première) : après une violation
+// de l'index unique, CreateLink rappelle Generate avec attempt+1 pour obtenir un autre code.
+type CodeGenerator interface {
+	Generate(longURL string, attempt int) (string, error)
+}
+
+// CodeGeneratorConfig regroupe les paramètres des stratégies de génération.
+type CodeGeneratorConfig struct {
+	Strategy  string // random, sequence ou hash
+	Length    int    // Longueur initiale (minimale) des codes
+	Obfuscate bool   // Stratégie sequence : masque l'ordre des identifiants par une permutation réversible
+	Secret    string // Stratégie sequence : clé dont dérive la permutation
+}
+
+// NewCodeGenerator construit la stratégie de génération décrite par la configuration.
+// La stratégie "sequence" nécessite un SequenceRepository.
+func NewCodeGenerator(cfg CodeGeneratorConfig, seqRepo repository.SequenceRepository) (CodeGenerator, error) {
+	length := cfg.Length
+	if length <= 0 {
+		length = 6
+	}
+	if length > maxShortCodeLength {
+		return nil, fmt.Errorf("Longueur de code court invalide: %d (maximum %d)", length, maxShortCodeLength)
+	}
+
+	switch strings.ToLower(cfg.Strategy) {
+	case "", StrategyRandom:
+		return &RandomCodeGenerator{Length: length, MaxLength: maxShortCodeLength}, nil
+	case StrategySequence:
+		if seqRepo == nil {
+			return nil, fmt.Errorf("La stratégie %q nécessite un SequenceRepository", StrategySequence)
+		}
+		return &SequenceCodeGenerator{seqRepo: seqRepo, minLength: length, obfuscate: cfg.Obfuscate, secret: cfg.Secret}, nil
+	case StrategyHash:
+		return &HashCodeGenerator{Length: length}, nil
+	}
+	return nil, fmt.Errorf("Stratégie de génération de code court inconnue: %q", cfg.Strategy)
+}
+
+// RandomCodeGenerator tire des codes aléatoires. Sa longueur augmente d'un caractère
+// toutes les deux collisions, ce qui lui permet de continuer à fonctionner quand l'espace
+// des codes de longueur initiale se remplit.
+type RandomCodeGenerator struct {
+	Length    int
+	MaxLength int
+}
+
+// Generate implémente CodeGenerator.
+func (g *RandomCodeGenerator) Generate(_ string, attempt int) (string, error) {
+	return randomCode(min(g.Length+attempt/2, g.MaxLength))
+}
+
+// SequenceCodeGenerator encode en base62 un identifiant monotone issu d'un compteur en base.
+// Les codes sont aussi courts que possible et ne collisionnent jamais entre eux ; une nouvelle
+// valeur est prise à chaque tentative au cas où un code aurait été choisi par une autre stratégie.
+// Avec obfuscate, l'identifiant passe par une permutation affine réversible de [0, 62^n)
+// afin que les codes successifs ne soient pas devinables.
+type SequenceCodeGenerator struct {
+	seqRepo   repository.SequenceRepository
+	minLength int
+	obfuscate bool
+	secret    string
+}
+
+// Generate implémente CodeGenerator.
+func (g *SequenceCodeGenerator) Generate(_ string, _ int) (string, error) {
+	id, err := g.seqRepo.NextValue(linkSequenceName)
+	if err != nil {
+		return "", fmt.Errorf("Echec de l'obtention d'un identifiant: %w", err)
+	}
+	return g.Encode(id)
+}
+
+// Encode convertit un identifiant en code court de longueur n, la plus petite longueur
+// (au moins minLength) dont l'espace 62^n contient l'identifiant.
+func (g *SequenceCodeGenerator) Encode(id uint64) (string, error) {
+	x := new(big.Int).SetUint64(id)
+	length := g.minLength
+	for x.Cmp(keyspace(length)) >= 0 {
+		length++
+		if length > maxShortCodeLength {
+			return "", fmt.Errorf("Identifiant %d hors de l'espace des codes courts", id)
+		}
+	}
+	if g.obfuscate {
+		a, b := g.permutation(length)
+		m := keyspace(length)
+		x.Mul(x, a).Add(x, b).Mod(x, m)
+	}
+	return base62(x, length), nil
+}
+
+// Decode retrouve l'identifiant d'un code produit par Encode.
+func (g *SequenceCodeGenerator) Decode(code string) (uint64, error) {
+	x := new(big.Int)
+	base := big.NewInt(int64(len(charset)))
+	for _, r := range code {
+		i := strings.IndexRune(charset, r)
+		if i < 0 {
+			return 0, fmt.Errorf("Caractère invalide dans le code court %q", code)
+		}
+		x.Mul(x, base).Add(x, big.NewInt(int64(i)))
+	}
+	if g.obfuscate {
+		a, b := g.permutation(len(code))
+		m := keyspace(len(code))
+		inv := new(big.Int).ModInverse(a, m)
+		x.Sub(x, b).Mod(x, m).Mul(x, inv).Mod(x, m)
+	}
+	if !x.IsUint64() {
+		return 0, fmt.Errorf("Code court %q hors de l'espace des identifiants", code)
+	}
+	return x.Uint64(), nil
+}
+
+// permutation dérive du secret les coefficients (a, b) de la bijection x -> a*x + b mod 62^length.
+// a est choisi premier avec 62^length (ni pair ni multiple de 31) pour être inversible.
+func (g *SequenceCodeGenerator) permutation(length int) (*big.Int, *big.Int) {
+	sum := sha256.Sum256([]byte(g.secret + ":" + strconv.Itoa(length)))
+	m := keyspace(length)
+	a := new(big.Int).SetUint64(binary.BigEndian.Uint64(sum[0:8]))
+	a.Mod(a, m)
+	one, two, thirtyOne := big.NewInt(1), big.NewInt(2), big.NewInt(31)
+	if a.Bit(0) == 0 {
+		a.Add(a, one)
+	}
+	for new(big.Int).Mod(a, thirtyOne).Sign() == 0 || a.Cmp(one) == 0 {
+		a.Add(a, two).Mod(a, m)
+	}
+	b := new(big.Int).SetUint64(binary.BigEndian.Uint64(sum[8:16]))
+	b.Mod(b, m)
+	return a, b
+}
+
+// HashCodeGenerator dérive le code d'un hash SHA-256 de l'URL longue : une même URL
+// donne toujours le même code à la première tentative. En cas de collision, le numéro
+// de tentative est ajouté à l'entrée du hash.
+type HashCodeGenerator struct {
+	Length int
+}
+
+// Generate implémente CodeGenerator.
+func (g *HashCodeGenerator) Generate(longURL string, attempt int) (string, error) {
+	input := longURL
+	if attempt > 0 {
+		input = fmt.Sprintf("%s\x00%d", longURL, attempt)
+	}
+	sum := sha256.Sum256([]byte(input))
+	x := new(big.Int).SetBytes(sum[:])
+	x.Mod(x, keyspace(g.Length))
+	return base62(x, g.Length), nil
+}
+
+// keyspace retourne 62^length, le nombre de codes possibles de cette longueur.
+func keyspace(length int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(int64(len(charset))), big.NewInt(int64(length)), nil)
+}
+
+// base62 écrit x sur exactement 'length' caractères du charset (complété à gauche).
+func base62(x *big.Int, length int) string {
+	code := make([]byte, length)
+	n := new(big.Int).Set(x)
+	base := big.NewInt(int64(len(charset)))
+	rem := new(big.Int)
+	for i := length - 1; i >= 0; i-- {
+		n.DivMod(n, base, rem)
+		code[i] = charset[rem.Int64()]
+	}
+	return string(code)
+}
@@ -0,0 +1,210 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/antoine-granier/urlshortener/internal/models"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrSigningDisabled est retournée quand aucune clé de signature n'est configurée.
+	ErrSigningDisabled = errors.New("signature des liens non configurée")
+	// ErrInvalidSigningKeys est retournée quand la configuration des clés de signature est invalide.
+	ErrInvalidSigningKeys = errors.New("clés de signature invalides")
+	// ErrInvalidSignedLink est retournée quand les paramètres d'un lien signé sont invalides (date d'expiration).
+	ErrInvalidSignedLink = errors.New("lien signé invalide")
+	// ErrBadSignature est retournée quand un code signé est malformé, signé par une clé inconnue ou falsifié.
+	ErrBadSignature = errors.New("signature du lien invalide")
+	// ErrSignedLinkExpired est retournée quand un code signé authentique a dépassé sa date d'expiration.
+	ErrSignedLinkExpired = errors.New("lien signé expiré")
+)
+
+// signatureSize est la taille (en octets) de la signature HMAC-SHA256 tronquée embarquée dans les codes.
+const signatureSize = 16
+
+// maxKeyIDLength borne la longueur des identifiants de clé, qui apparaissent en clair dans les codes.
+const maxKeyIDLength = 8
+
+// signedCodeSeparator sépare l'identifiant de clé, la charge utile et la signature.
+// Il n'apparaît jamais dans un code court ordinaire (base62).
+const signedCodeSeparator = "."
+
+// SigningKey est une clé de signature des liens, identifiée par un court identifiant.
+type SigningKey struct {
+	ID     string
+	Secret string
+}
+
+// LinkSigner émet et vérifie les codes signés. Un code signé embarque l'identifiant du lien,
+// sa date d'expiration et une signature HMAC : il se vérifie sans aucune écriture en base.
+//
+// La rotation se fait en ajoutant une nouvelle clé, en la rendant active, puis en retirant
+// l'ancienne une fois expirés les codes qu'elle a signés.
+type LinkSigner struct {
+	keys   map[string][]byte
+	active string
+	maxTTL time.Duration
+}
+
+// NewLinkSigner crée un LinkSigner. Toutes les clés sont acceptées en vérification ;
+// seule la clé 'active' sert à signer. maxTTL (0 = illimité) borne la durée de validité des codes émis.
+func NewLinkSigner(keys []SigningKey, active string, maxTTL time.Duration) (*LinkSigner, error) {
+	if len(keys) == 0 {
+		return nil, ErrSigningDisabled
+	}
+	signer := &LinkSigner{keys: make(map[string][]byte, len(keys)), active: active, maxTTL: maxTTL}
+	for _, k := range keys {
+		if !isKeyID(k.ID) {
+			return nil, fmt.Errorf("%w: identifiant %q (1 à %d caractères alphanumériques)", ErrInvalidSigningKeys, k.ID, maxKeyIDLength)
+		}
+		if len(k.Secret) < 16 {
+			return nil, fmt.Errorf("%w: le secret de la clé %q doit faire au moins 16 caractères", ErrInvalidSigningKeys, k.ID)
+		}
+		if _, dup := signer.keys[k.ID]; dup {
+			return nil, fmt.Errorf("%w: identifiant %q en double", ErrInvalidSigningKeys, k.ID)
+		}
+		signer.keys[k.ID] = []byte(k.Secret)
+	}
+	if signer.active == "" && len(keys) == 1 {
+		signer.active = keys[0].ID
+	}
+	if _, ok := signer.keys[signer.active]; !ok {
+		return nil, fmt.Errorf("%w: clé active %q inconnue", ErrInvalidSigningKeys, active)
+	}
+	return signer, nil
+}
+
+// Sign retourne le code signé donnant accès au lien linkID jusqu'à expiresAt (à la seconde près).
+func (s *LinkSigner) Sign(linkID uint, expiresAt time.Time, now time.Time) (string, error) {
+	if !expiresAt.After(now) {
+		return "", fmt.Errorf("%w: la date d'expiration doit être dans le futur", ErrInvalidSignedLink)
+	}
+	if s.maxTTL > 0 && expiresAt.Sub(now) > s.maxTTL {
+		return "", fmt.Errorf("%w: durée de validité supérieure au maximum autorisé (%v)", ErrInvalidSignedLink, s.maxTTL)
+	}
+
+	payload := binary.AppendUvarint(nil, uint64(linkID))
+	payload = binary.AppendUvarint(payload, uint64(expiresAt.Unix()))
+
+	signed := s.active + signedCodeSeparator + base64.RawURLEncoding.EncodeToString(payload)
+	sig := s.mac(s.keys[s.active], signed)
+	return signed + signedCodeSeparator + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// Verify contrôle la signature puis l'expiration d'un code signé et retourne l'identifiant du lien.
+func (s *LinkSigner) Verify(code string, now time.Time) (uint, error) {
+	kid, rest, _ := strings.Cut(code, signedCodeSeparator)
+	encPayload, encSig, ok := strings.Cut(rest, signedCodeSeparator)
+	if !ok {
+		return 0, ErrBadSignature
+	}
+	key, ok := s.keys[kid]
+	if !ok {
+		return 0, ErrBadSignature
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(encSig)
+	if err != nil || !hmac.Equal(sig, s.mac(key, kid+signedCodeSeparator+encPayload)) {
+		return 0, ErrBadSignature
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encPayload)
+	if err != nil {
+		return 0, ErrBadSignature
+	}
+	linkID, n := binary.Uvarint(payload)
+	if n <= 0 {
+		return 0, ErrBadSignature
+	}
+	expiry, m := binary.Uvarint(payload[n:])
+	if m <= 0 || n+m != len(payload) {
+		return 0, ErrBadSignature
+	}
+	if now.Unix() >= int64(expiry) {
+		return 0, ErrSignedLinkExpired
+	}
+	return uint(linkID), nil
+}
+
+// mac calcule la signature HMAC-SHA256 tronquée de data.
+func (s *LinkSigner) mac(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)[:signatureSize]
+}
+
+// IsSignedCode indique si un code court est un code signé (et non le code d'un lien en base).
+func IsSignedCode(code string) bool {
+	return strings.Contains(code, signedCodeSeparator)
+}
+
+// isKeyID valide un identifiant de clé : 1 à maxKeyIDLength caractères alphanumériques.
+func isKeyID(id string) bool {
+	if id == "" || len(id) > maxKeyIDLength {
+		return false
+	}
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// SetLinkSigner active l'émission et la vérification des liens signés.
+func (s *LinkService) SetLinkSigner(signer *LinkSigner) {
+	s.signer = signer
+}
+
+// SignLink émet un code signé donnant accès au lien shortCode jusqu'à expiresAt.
+// Aucune donnée n'est écrite en base.
+func (s *LinkService) SignLink(shortCode string, expiresAt time.Time) (string, error) {
+	if s.signer == nil {
+		return "", ErrSigningDisabled
+	}
+	link, err := s.linkRepo.GetLinkByShortCode(shortCode)
+	if err != nil {
+		return "", fmt.Errorf("Echec de la récupération du lien '%s': %w", shortCode, err)
+	}
+	return s.signer.Sign(link.ID, expiresAt, time.Now())
+}
+
+// GetLinkBySignedCode vérifie un code signé (signature puis expiration) et retourne le lien désigné.
+func (s *LinkService) GetLinkBySignedCode(code string) (*models.Link, error) {
+	if s.signer == nil {
+		return nil, ErrBadSignature
+	}
+	linkID, err := s.signer.Verify(code, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	link, err := s.linkRepo.GetLinkByID(linkID)
+	if err != nil {
+		return nil, fmt.Errorf("Echec de la récupération du lien signé %d: %w", linkID, err)
+	}
+	return link, nil
+}
+
+// LookupLink retourne le lien désigné par le code d'une URL visiteur : code court ordinaire ou code signé
+// (vérifié au préalable). Un lien réservé aux liens signés n'est pas accessible par son code court :
+// gorm.ErrRecordNotFound est alors retournée, comme pour un code inconnu.
+func (s *LinkService) LookupLink(code string) (*models.Link, error) {
+	if IsSignedCode(code) {
+		return s.GetLinkBySignedCode(code)
+	}
+	link, err := s.GetLinkByShortCode(code)
+	if err != nil {
+		return nil, err
+	}
+	if link.SignedOnly {
+		return nil, fmt.Errorf("Lien '%s' accessible uniquement par lien signé: %w", code, gorm.ErrRecordNotFound)
+	}
+	return link, nil
+}
@@ -0,0 +1,165 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	signingNow = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	keyV1      = SigningKey{ID: "v1", Secret: "first-secret-0123456789"}
+	keyV2      = SigningKey{ID: "v2", Secret: "second-secret-0123456789"}
+)
+
+func mustSigner(t *testing.T, keys []SigningKey, active string, maxTTL time.Duration) *LinkSigner {
+	t.Helper()
+	signer, err := NewLinkSigner(keys, active, maxTTL)
+	if err != nil {
+		t.Fatalf("NewLinkSigner: %v", err)
+	}
+	return signer
+}
+
+func mustSign(t *testing.T, signer *LinkSigner, linkID uint, expiresAt time.Time) string {
+	t.Helper()
+	code, err := signer.Sign(linkID, expiresAt, signingNow)
+	if err != nil {
+		t.Fatalf("Sign(%d): %v", linkID, err)
+	}
+	return code
+}
+
+func TestSignedCodeKnownVector(t *testing.T) {
+	signer := mustSigner(t, []SigningKey{keyV1}, "", 0)
+	code := mustSign(t, signer, 300, time.Unix(1_800_000_000, 0))
+
+	// Charge utile : uvarint(300) = ac 02, uvarint(1800000000) = 80 a4 a7 da 06, suivie de
+	// HMAC-SHA256("v1.rAKApKfaBg") tronqué à 16 octets, calculés indépendamment.
+	if want := "v1.rAKApKfaBg.TiKVYV0RPZCdNkyUZzXRFw"; code != want {
+		t.Errorf("code = %q, want %q", code, want)
+	}
+	if !IsSignedCode(code) || IsSignedCode("aZ3kP9q") {
+		t.Error("IsSignedCode does not tell signed codes from short codes")
+	}
+}
+
+func TestSignedCodeExpiry(t *testing.T) {
+	signer := mustSigner(t, []SigningKey{keyV1}, "", 24*time.Hour)
+	expiresAt := signingNow.Add(time.Hour)
+	code := mustSign(t, signer, 42, expiresAt)
+
+	for _, tc := range []struct {
+		name string
+		now  time.Time
+		err  error
+	}{
+		{"just signed", signingNow, nil},
+		{"last second", expiresAt.Add(-time.Second), nil},
+		{"at expiry", expiresAt, ErrSignedLinkExpired},
+		{"after expiry", expiresAt.Add(time.Minute), ErrSignedLinkExpired},
+	} {
+		id, err := signer.Verify(code, tc.now)
+		if !errors.Is(err, tc.err) {
+			t.Errorf("%s: err = %v, want %v", tc.name, err, tc.err)
+		}
+		if tc.err == nil && id != 42 {
+			t.Errorf("%s: link id = %d, want 42", tc.name, id)
+		}
+	}
+
+	if _, err := signer.Sign(42, signingNow, signingNow); !errors.Is(err, ErrInvalidSignedLink) {
+		t.Errorf("Sign with a past expiry: err = %v, want ErrInvalidSignedLink", err)
+	}
+	if _, err := signer.Sign(42, signingNow.Add(25*time.Hour), signingNow); !errors.Is(err, ErrInvalidSignedLink) {
+		t.Errorf("Sign beyond the max TTL: err = %v, want ErrInvalidSignedLink", err)
+	}
+}
+
+func TestSignedCodeTampering(t *testing.T) {
+	signer := mustSigner(t, []SigningKey{keyV1}, "", 0)
+	expiresAt := signingNow.Add(time.Hour)
+	code := mustSign(t, signer, 42, expiresAt)
+	kid, rest, _ := strings.Cut(code, signedCodeSeparator)
+	payload, sig, _ := strings.Cut(rest, signedCodeSeparator)
+
+	// Charge utile d'un autre lien, et d'une expiration repoussée, sous la signature d'origine.
+	otherLink := strings.Split(mustSign(t, signer, 43, expiresAt), signedCodeSeparator)[1]
+	laterExpiry := strings.Split(mustSign(t, signer, 42, expiresAt.Add(time.Hour)), signedCodeSeparator)[1]
+
+	flipped := []byte(sig)
+	if flipped[0] = 'A'; sig[0] == 'A' {
+		flipped[0] = 'B'
+	}
+
+	for name, tampered := range map[string]string{
+		"other link id":     kid + "." + otherLink + "." + sig,
+		"extended expiry":   kid + "." + laterExpiry + "." + sig,
+		"altered signature": kid + "." + payload + "." + string(flipped),
+		"truncated":         code[:len(code)-2],
+		"unknown key":       "v9." + payload + "." + sig,
+		"missing part":      kid + "." + payload,
+		"empty":             "",
+		"short code":        "aZ3kP9q",
+	} {
+		if _, err := signer.Verify(tampered, signingNow); !errors.Is(err, ErrBadSignature) {
+			t.Errorf("%s: err = %v, want ErrBadSignature", name, err)
+		}
+	}
+
+	// Un code signé par un autre secret sous le même identifiant de clé est refusé.
+	forger := mustSigner(t, []SigningKey{{ID: "v1", Secret: "attacker-secret-0123456789"}}, "", 0)
+	if _, err := signer.Verify(mustSign(t, forger, 42, expiresAt), signingNow); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("forged key: err = %v, want ErrBadSignature", err)
+	}
+}
+
+func TestSignedCodeKeyRotation(t *testing.T) {
+	expiresAt := signingNow.Add(time.Hour)
+	before := mustSigner(t, []SigningKey{keyV1}, "", 0)
+	oldCode := mustSign(t, before, 7, expiresAt)
+
+	// Étape 1 : la nouvelle clé est ajoutée et devient active, l'ancienne reste vérifiable.
+	during := mustSigner(t, []SigningKey{keyV1, keyV2}, "v2", 0)
+	newCode := mustSign(t, during, 8, expiresAt)
+	if !strings.HasPrefix(newCode, "v2.") {
+		t.Errorf("code signed during rotation = %q, want key v2", newCode)
+	}
+	for code, want := range map[string]uint{oldCode: 7, newCode: 8} {
+		if id, err := during.Verify(code, signingNow); err != nil || id != want {
+			t.Errorf("Verify(%q) during rotation = %d, %v; want %d", code, id, err, want)
+		}
+	}
+
+	// Étape 2 : l'ancienne clé est retirée, ses codes ne sont plus acceptés.
+	after := mustSigner(t, []SigningKey{keyV2}, "", 0)
+	if _, err := after.Verify(oldCode, signingNow); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("old code after rotation: err = %v, want ErrBadSignature", err)
+	}
+	if id, err := after.Verify(newCode, signingNow); err != nil || id != 8 {
+		t.Errorf("new code after rotation = %d, %v; want 8", id, err)
+	}
+}
+
+func TestNewLinkSignerRejectsInvalidKeys(t *testing.T) {
+	if _, err := NewLinkSigner(nil, "", 0); !errors.Is(err, ErrSigningDisabled) {
+		t.Errorf("no keys: err = %v, want ErrSigningDisabled", err)
+	}
+	for name, tc := range map[string]struct {
+		keys   []SigningKey
+		active string
+	}{
+		"empty id":        {[]SigningKey{{ID: "", Secret: keyV1.Secret}}, ""},
+		"id too long":     {[]SigningKey{{ID: "abcdefghi", Secret: keyV1.Secret}}, ""},
+		"separator in id": {[]SigningKey{{ID: "v.1", Secret: keyV1.Secret}}, ""},
+		"short secret":    {[]SigningKey{{ID: "v1", Secret: "too-short"}}, ""},
+		"duplicate id":    {[]SigningKey{keyV1, keyV1}, "v1"},
+		"no active key":   {[]SigningKey{keyV1, keyV2}, ""},
+		"unknown active":  {[]SigningKey{keyV1, keyV2}, "v3"},
+	} {
+		if _, err := NewLinkSigner(tc.keys, tc.active, 0); !errors.Is(err, ErrInvalidSigningKeys) {
+			t.Errorf("%s: err = %v, want ErrInvalidSigningKeys", name, err)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/antoine-granier/urlshortener/internal/models"
+	"github.com/antoine-granier/urlshortener/internal/repository"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidUTM est retournée quand des paramètres UTM (ou le nom d'un jeu de paramètres) sont invalides.
+	ErrInvalidUTM = errors.New("paramètres UTM invalides")
+	// ErrUTMPresetNotFound est retournée quand le jeu de paramètres UTM demandé n'existe pas pour le propriétaire.
+	ErrUTMPresetNotFound = errors.New("jeu de paramètres UTM introuvable")
+)
+
+// Longueurs maximales des paramètres UTM et des noms de jeux de paramètres.
+const (
+	maxUTMValueLength      = 200
+	maxUTMPresetNameLength = 100
+)
+
+// UTMFields liste les paramètres UTM, dans l'ordre où ils sont ajoutés aux URLs.
+var UTMFields = []string{"source", "medium", "campaign", "term", "content"}
+
+// utmPairs retourne les paramètres UTM renseignés, dans l'ordre de UTMFields.
+func utmPairs(utm models.UTM) [][2]string {
+	values := []string{utm.Source, utm.Medium, utm.Campaign, utm.Term, utm.Content}
+	pairs := make([][2]string, 0, len(values))
+	for i, v := range values {
+		if v != "" {
+			pairs = append(pairs, [2]string{"utm_" + UTMFields[i], v})
+		}
+	}
+	return pairs
+}
+
+// normalizeUTM retire les espaces de début et de fin des paramètres UTM et les met en minuscules :
+// "Newsletter" et "newsletter " ne doivent pas apparaître comme deux sources dans les rapports.
+func normalizeUTM(utm models.UTM) (models.UTM, error) {
+	fields := []*string{&utm.Source, &utm.Medium, &utm.Campaign, &utm.Term, &utm.Content}
+	for i, f := range fields {
+		v := strings.ToLower(strings.TrimSpace(*f))
+		if utf8.RuneCountInString(v) > maxUTMValueLength {
+			return models.UTM{}, fmt.Errorf("%w: utm_%s trop long (%d caractères maximum)", ErrInvalidUTM, UTMFields[i], maxUTMValueLength)
+		}
+		if strings.IndexFunc(v, unicode.IsControl) >= 0 {
+			return models.UTM{}, fmt.Errorf("%w: caractères de contrôle interdits dans utm_%s", ErrInvalidUTM, UTMFields[i])
+		}
+		*f = v
+	}
+	return utm, nil
+}
+
+// mergeUTM complète les paramètres d'un jeu enregistré avec ceux fournis explicitement, prioritaires.
+func mergeUTM(preset, explicit models.UTM) models.UTM {
+	return models.UTM{
+		Source:   firstNonEmpty(explicit.Source, preset.Source),
+		Medium:   firstNonEmpty(explicit.Medium, preset.Medium),
+		Campaign: firstNonEmpty(explicit.Campaign, preset.Campaign),
+		Term:     firstNonEmpty(explicit.Term, preset.Term),
+		Content:  firstNonEmpty(explicit.Content, preset.Content),
+	}
+}
+
+// ApplyUTM ajoute les paramètres UTM renseignés à une URL absolue. Un paramètre utm_* déjà présent
+// est remplacé ; les autres paramètres, leur ordre et le fragment (#...) sont conservés tels quels.
+func ApplyUTM(longURL string, utm models.UTM) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(longURL))
+	if err != nil {
+		return "", fmt.Errorf("%w '%s': %v", ErrInvalidURL, longURL, err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("%w '%s': schéma et hôte requis", ErrInvalidURL, longURL)
+	}
+	pairs := utmPairs(utm)
+	if len(pairs) == 0 {
+		return longURL, nil
+	}
+
+	replaced := make(map[string]bool, len(pairs))
+	for _, p := range pairs {
+		replaced[p[0]] = true
+	}
+	var query []string
+	for _, param := range strings.Split(u.RawQuery, "&") {
+		if param == "" {
+			continue
+		}
+		key, _, _ := strings.Cut(param, "=")
+		if k, err := url.QueryUnescape(key); err == nil && replaced[strings.ToLower(k)] {
+			continue
+		}
+		query = append(query, param)
+	}
+	for _, p := range pairs {
+		query = append(query, url.QueryEscape(p[0])+"="+url.QueryEscape(p[1]))
+	}
+	u.RawQuery = strings.Join(query, "&")
+	u.ForceQuery = false
+	return u.String(), nil
+}
+
+// ExtractUTM retourne les paramètres UTM présents dans une URL (vides si l'URL est invalide).
+func ExtractUTM(longURL string) models.UTM {
+	u, err := url.Parse(longURL)
+	if err != nil {
+		return models.UTM{}
+	}
+	query := u.Query()
+	return models.UTM{
+		Source:   query.Get("utm_source"),
+		Medium:   query.Get("utm_medium"),
+		Campaign: query.Get("utm_campaign"),
+		Term:     query.Get("utm_term"),
+		Content:  query.Get("utm_content"),
+	}
+}
+
+// BuildUTMURL construit l'URL longue marquée d'un lien : les paramètres du jeu presetName du propriétaire
+// (optionnel), complétés par ceux fournis explicitement, sont normalisés puis ajoutés à longURL.
+func (s *LinkService) BuildUTMURL(owner, longURL, presetName string, utm models.UTM) (string, error) {
+	if presetName != "" {
+		preset, err := s.linkRepo.GetUTMPreset(owner, strings.TrimSpace(presetName))
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return "", fmt.Errorf("%w: '%s'", ErrUTMPresetNotFound, presetName)
+			}
+			return "", fmt.Errorf("Echec de la récupération du jeu de paramètres UTM '%s': %w", presetName, err)
+		}
+		utm = mergeUTM(preset.UTM, utm)
+	}
+	utm, err := normalizeUTM(utm)
+	if err != nil {
+		return "", err
+	}
+	return ApplyUTM(longURL, utm)
+}
+
+// SaveUTMPreset enregistre (ou remplace) un jeu de paramètres UTM réutilisable par un propriétaire.
+func (s *LinkService) SaveUTMPreset(owner, name string, utm models.UTM) (*models.UTMPreset, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > maxUTMPresetNameLength || strings.IndexFunc(name, unicode.IsControl) >= 0 {
+		return nil, fmt.Errorf("%w: nom de jeu de paramètres requis (%d caractères maximum)", ErrInvalidUTM, maxUTMPresetNameLength)
+	}
+	utm, err := normalizeUTM(utm)
+	if err != nil {
+		return nil, err
+	}
+	if utm.IsZero() {
+		return nil, fmt.Errorf("%w: au moins un paramètre est requis", ErrInvalidUTM)
+	}
+
+	preset := &models.UTMPreset{Owner: owner, Name: name, UTM: utm}
+	if err := s.linkRepo.SaveUTMPreset(preset); err != nil {
+		return nil, fmt.Errorf("Echec de l'enregistrement du jeu de paramètres UTM '%s': %w", name, err)
+	}
+	return preset, nil
+}
+
+// ListUTMPresets retourne les jeux de paramètres UTM d'un propriétaire.
+func (s *LinkService) ListUTMPresets(owner string) ([]models.UTMPreset, error) {
+	presets, err := s.linkRepo.ListUTMPresets(owner)
+	if err != nil {
+		return nil, fmt.Errorf("Echec de la récupération des jeux de paramètres UTM: %w", err)
+	}
+	return presets, nil
+}
+
+// DeleteUTMPreset supprime un jeu de paramètres UTM d'un propriétaire (les liens déjà créés ne changent pas).
+func (s *LinkService) DeleteUTMPreset(owner, name string) error {
+	if err := s.linkRepo.DeleteUTMPreset(owner, strings.TrimSpace(name)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("%w: '%s'", ErrUTMPresetNotFound, name)
+		}
+		return fmt.Errorf("Echec de la suppression du jeu de paramètres UTM '%s': %w", name, err)
+	}
+	return nil
+}
+
+// GetUTMStats retourne les clics des liens d'un propriétaire (tous si vide), éventuellement limités
+// à une campagne, regroupés par valeur de chaque paramètre UTM (clé : "source", "medium"...).
+func (s *LinkService) GetUTMStats(owner, campaign string) (map[string]map[string]int, error) {
+	filter := repository.UTMFilter{Owner: owner}
+	if campaign != "" {
+		c, err := s.linkRepo.GetCampaign(owner, strings.TrimSpace(campaign))
+		if err != nil {
+			return nil, fmt.Errorf("Echec de la récupération de la campagne '%s': %w", campaign, err)
+		}
+		filter.CampaignID = c.ID
+	}
+
+	stats := make(map[string]map[string]int, len(UTMFields))
+	for _, field := range UTMFields {
+		counts, err := s.clickRepo.CountClicksByUTM(filter, field)
+		if err != nil {
+			return nil, fmt.Errorf("Echec de la ventilation des clics par utm_%s: %w", field, err)
+		}
+		stats[field] = counts
+	}
+	return stats, nil
+}
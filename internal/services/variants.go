@@ -0,0 +1,124 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/antoine-granier/urlshortener/internal/models"
+)
+
+// ErrInvalidVariants est retournée quand une liste de variantes A/B est incohérente.
+var ErrInvalidVariants = errors.New("variantes invalides")
+
+// VariantInput décrit une variante A/B fournie par l'API ou la CLI.
+type VariantInput struct {
+	Name   string `json:"name"`
+	URL    string `json:"url"`
+	Weight int    `json:"weight"`
+}
+
+// ParseVariant lit une variante au format CLI "nom:poids:url".
+func ParseVariant(s string) (VariantInput, error) {
+	parts := strings.SplitN(s, ":", 3)
+	if len(parts) != 3 {
+		return VariantInput{}, fmt.Errorf("%w: %q (format attendu nom:poids:url)", ErrInvalidVariants, s)
+	}
+	weight, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return VariantInput{}, fmt.Errorf("%w: poids invalide dans %q", ErrInvalidVariants, s)
+	}
+	return VariantInput{Name: parts[0], URL: parts[2], Weight: weight}, nil
+}
+
+// buildVariants valide des variantes et les convertit en modèles :
+// noms uniques et non vides, URLs absolues, poids positifs dont au moins un non nul.
+func buildVariants(inputs []VariantInput) ([]models.LinkVariant, error) {
+	if len(inputs) == 0 {
+		return nil, nil
+	}
+	variants := make([]models.LinkVariant, 0, len(inputs))
+	names := make(map[string]bool, len(inputs))
+	total := 0
+	for _, in := range inputs {
+		name := strings.TrimSpace(in.Name)
+		if name == "" || len(name) > 50 {
+			return nil, fmt.Errorf("%w: nom de variante vide ou trop long", ErrInvalidVariants)
+		}
+		if names[name] {
+			return nil, fmt.Errorf("%w: variante %q en double", ErrInvalidVariants, name)
+		}
+		names[name] = true
+		if u, err := url.ParseRequestURI(in.URL); err != nil || u.Host == "" {
+			return nil, fmt.Errorf("%w: URL invalide pour la variante %q", ErrInvalidVariants, name)
+		}
+		if in.Weight < 0 {
+			return nil, fmt.Errorf("%w: poids négatif pour la variante %q", ErrInvalidVariants, name)
+		}
+		total += in.Weight
+		variants = append(variants, models.LinkVariant{Name: name, URL: in.URL, Weight: in.Weight})
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("%w: au moins une variante doit avoir un poids non nul", ErrInvalidVariants)
+	}
+	return variants, nil
+}
+
+// ChooseVariant sélectionne la variante servie à un visiteur.
+// La variante mémorisée (cookie) est conservée tant qu'elle existe et reste active ;
+// sinon la variante est tirée de façon déterministe à partir de stickyKey (ex: IP + User-Agent),
+// au prorata des poids. Retourne nil si le lien n'a aucune variante active.
+func ChooseVariant(link *models.Link, remembered, stickyKey string) *models.LinkVariant {
+	total := 0
+	for i := range link.Variants {
+		v := &link.Variants[i]
+		if v.Weight <= 0 {
+			continue
+		}
+		if v.Name == remembered {
+			return v
+		}
+		total += v.Weight
+	}
+	if total == 0 {
+		return nil
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte(link.ShortCode + "|" + stickyKey))
+	bucket := int(h.Sum64() % uint64(total))
+	for i := range link.Variants {
+		v := &link.Variants[i]
+		if v.Weight <= 0 {
+			continue
+		}
+		if bucket < v.Weight {
+			return v
+		}
+		bucket -= v.Weight
+	}
+	return nil
+}
+
+// SetLinkVariants remplace les variantes A/B d'un lien. Les poids peuvent ainsi être modifiés
+// à chaud ; les visiteurs déjà attachés à une variante toujours active la conservent.
+func (s *LinkService) SetLinkVariants(ctx context.Context, shortCode string, inputs []VariantInput) (*models.Link, error) {
+	link, err := s.linkRepo.GetLinkByShortCode(shortCode)
+	if err != nil {
+		return nil, fmt.Errorf("Echec de la récupération du lien '%s': %w", shortCode, err)
+	}
+	variants, err := buildVariants(inputs)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.linkRepo.ReplaceVariants(link.ID, variants); err != nil {
+		return nil, fmt.Errorf("Echec de la mise à jour des variantes du lien '%s': %w", shortCode, err)
+	}
+	link.Variants = variants
+	s.emitLinkEvent(ctx, EventLinkUpdated, link)
+	return link, nil
+}
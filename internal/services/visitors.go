@@ -0,0 +1,215 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/antoine-granier/urlshortener/internal/hll"
+	"github.com/antoine-granier/urlshortener/internal/logging"
+	"github.com/antoine-granier/urlshortener/internal/repository"
+)
+
+// ErrInvalidDayRange est retournée quand une période de statistiques est invalide.
+var ErrInvalidDayRange = errors.New("période invalide")
+
+// dayLayout est le format des jours des statistiques (UTC).
+const dayLayout = "2006-01-02"
+
+// visitorKey identifie un sketch en attente d'enregistrement : un lien, un jour.
+type visitorKey struct {
+	linkID uint
+	day    string
+}
+
+// VisitorCounter estime les visiteurs uniques des liens. Chaque clic est réduit à un hash salé
+// (IP + User-Agent), ajouté au sketch HyperLogLog du lien pour le jour du clic. Les sketches
+// sont accumulés en mémoire puis fusionnés périodiquement avec ceux enregistrés en base.
+type VisitorCounter struct {
+	clickRepo repository.ClickRepository
+	salt      []byte
+
+	mu      sync.Mutex
+	pending map[visitorKey]*hll.Sketch
+}
+
+// NewVisitorCounter crée et retourne une nouvelle instance de VisitorCounter.
+// Le sel doit rester le même d'un démarrage à l'autre : sans lui, un même visiteur obtiendrait
+// un autre hash et serait compté deux fois. S'il est vide, un sel aléatoire est utilisé
+// le temps du processus ; une erreur est retournée s'il ne peut pas être généré.
+func NewVisitorCounter(clickRepo repository.ClickRepository, salt string) (*VisitorCounter, error) {
+	v := &VisitorCounter{
+		clickRepo: clickRepo,
+		salt:      []byte(salt),
+		pending:   make(map[visitorKey]*hll.Sketch),
+	}
+	if salt == "" {
+		v.salt = make([]byte, 32)
+		if _, err := rand.Read(v.salt); err != nil {
+			return nil, fmt.Errorf("Echec de la génération du sel des visiteurs: %w", err)
+		}
+		visitorsLogger.Warn("analytics.visitor_salt not set: using a random salt, returning visitors will be counted again after a restart")
+	}
+	return v, nil
+}
+
+// Observe enregistre la visite d'un lien. Sans VisitorCounter (nil), l'appel est ignoré.
+func (v *VisitorCounter) Observe(linkID uint, at time.Time, ip, userAgent string) {
+	if v == nil {
+		return
+	}
+	mac := hmac.New(sha256.New, v.salt)
+	mac.Write([]byte(ip))
+	mac.Write([]byte{0})
+	mac.Write([]byte(userAgent))
+	hash := binary.BigEndian.Uint64(mac.Sum(nil))
+
+	key := visitorKey{linkID: linkID, day: at.UTC().Format(dayLayout)}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	sketch, ok := v.pending[key]
+	if !ok {
+		sketch = hll.New()
+		v.pending[key] = sketch
+	}
+	sketch.Add(hash)
+}
+
+// Start lance l'enregistrement périodique des sketches en attente.
+func (v *VisitorCounter) Start(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := v.Flush(); err != nil {
+				visitorsLogger.Error("Failed to save unique visitors", logging.Err(err))
+			}
+		}
+	}()
+}
+
+// Flush fusionne les sketches en attente avec ceux enregistrés en base.
+// Un sketch dont l'enregistrement échoue est conservé pour la prochaine passe.
+func (v *VisitorCounter) Flush() error {
+	v.mu.Lock()
+	pending := v.pending
+	v.pending = make(map[visitorKey]*hll.Sketch)
+	v.mu.Unlock()
+
+	var errs []error
+	for key, sketch := range pending {
+		err := v.clickRepo.MergeVisitorSketch(key.linkID, key.day, func(existing []byte) ([]byte, error) {
+			merged := hll.New()
+			if existing != nil {
+				if err := merged.UnmarshalBinary(existing); err != nil {
+					visitorsLogger.Warn("Unreadable visitor sketch replaced", "link_id", key.linkID, "day", key.day, logging.Err(err))
+					merged = hll.New()
+				}
+			}
+			merged.Merge(sketch)
+			return merged.MarshalBinary()
+		})
+		if err != nil {
+			errs = append(errs, err)
+			v.requeue(key, sketch)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// requeue remet un sketch non enregistré en attente, fusionné avec les visites arrivées entre-temps.
+func (v *VisitorCounter) requeue(key visitorKey, sketch *hll.Sketch) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if current, ok := v.pending[key]; ok {
+		sketch.Merge(current)
+	}
+	v.pending[key] = sketch
+}
+
+// DayRange est une période de statistiques en jours UTC, bornes incluses.
+// Une borne zéro n'est pas limitée.
+type DayRange struct {
+	From time.Time
+	To   time.Time
+}
+
+// ParseDayRange lit une période au format AAAA-MM-JJ ; chaque borne est optionnelle.
+func ParseDayRange(from, to string) (DayRange, error) {
+	var r DayRange
+	var err error
+	if from != "" {
+		if r.From, err = time.Parse(dayLayout, from); err != nil {
+			return DayRange{}, fmt.Errorf("%w: date de début '%s' (format AAAA-MM-JJ attendu)", ErrInvalidDayRange, from)
+		}
+	}
+	if to != "" {
+		if r.To, err = time.Parse(dayLayout, to); err != nil {
+			return DayRange{}, fmt.Errorf("%w: date de fin '%s' (format AAAA-MM-JJ attendu)", ErrInvalidDayRange, to)
+		}
+	}
+	if !r.From.IsZero() && !r.To.IsZero() && r.To.Before(r.From) {
+		return DayRange{}, fmt.Errorf("%w: la date de fin précède la date de début", ErrInvalidDayRange)
+	}
+	return r, nil
+}
+
+// IsZero indique que la période n'est pas limitée.
+func (r DayRange) IsZero() bool {
+	return r.From.IsZero() && r.To.IsZero()
+}
+
+// bounds retourne les instants [début, fin[ de la période (zéro si non limités).
+func (r DayRange) bounds() (from, to time.Time) {
+	if !r.To.IsZero() {
+		to = r.To.AddDate(0, 0, 1)
+	}
+	return r.From, to
+}
+
+// days retourne les jours extrêmes de la période, au format des sketches.
+func (r DayRange) days() (from, to string) {
+	from, to = "0000-01-01", "9999-12-31"
+	if !r.From.IsZero() {
+		from = r.From.Format(dayLayout)
+	}
+	if !r.To.IsZero() {
+		to = r.To.Format(dayLayout)
+	}
+	return from, to
+}
+
+// CountClicks compte les clics d'un lien sur une période.
+func (s *LinkService) CountClicks(linkID uint, r DayRange) (int, error) {
+	from, to := r.bounds()
+	count, err := s.clickRepo.CountClicksInRange(linkID, from, to)
+	if err != nil {
+		return 0, fmt.Errorf("Echec du comptage des clics pour LinkID %d: %w", linkID, err)
+	}
+	return count, nil
+}
+
+// CountUniqueVisitors estime le nombre de visiteurs uniques d'un lien sur une période,
+// en fusionnant les sketches journaliers (les visites pas encore enregistrées ne sont pas comptées).
+func (s *LinkService) CountUniqueVisitors(linkID uint, r DayRange) (int, error) {
+	fromDay, toDay := r.days()
+	sketches, err := s.clickRepo.GetVisitorSketches(linkID, fromDay, toDay)
+	if err != nil {
+		return 0, fmt.Errorf("Echec de la récupération des visiteurs pour LinkID %d: %w", linkID, err)
+	}
+	merged := hll.New()
+	for _, stored := range sketches {
+		day := hll.New()
+		if err := day.UnmarshalBinary(stored.Sketch); err != nil {
+			visitorsLogger.Warn("Unreadable visitor sketch ignored", "link_id", linkID, "day", stored.Day, logging.Err(err))
+			continue
+		}
+		merged.Merge(day)
+	}
+	return int(merged.Estimate()), nil
+}
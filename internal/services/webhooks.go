@@ -0,0 +1,554 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	mathrand "math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/antoine-granier/urlshortener/internal/logging"
+	"github.com/antoine-granier/urlshortener/internal/metrics"
+	"github.com/antoine-granier/urlshortener/internal/models"
+	"github.com/antoine-granier/urlshortener/internal/pageinfo"
+	"github.com/antoine-granier/urlshortener/internal/repository"
+)
+
+// ErrInvalidWebhook est retournée quand la configuration d'un webhook est incorrecte (URL, événements, secret).
+var ErrInvalidWebhook = errors.New("webhook invalide")
+
+// EventWebhookPing est l'événement de test envoyé à un seul webhook (voir PingWebhook).
+const EventWebhookPing = "webhook.ping"
+
+const (
+	defaultWebhookMaxAttempts = 8
+	defaultWebhookRetryBase   = 30 * time.Second
+	defaultWebhookTimeout     = 10 * time.Second
+	webhookRetryMax           = 6 * time.Hour // Délai maximal entre deux tentatives
+	webhookDispatchBatch      = 50            // Livraisons envoyées par passe du dispatcher
+	webhookDispatchWorkers    = 4             // Envois simultanés
+	webhookDeliveryListLimit  = 100
+	webhookSecretMinLength    = 16
+	webhookResponseExcerpt    = 200       // Octets de la réponse conservés dans LastError
+	webhookPruneInterval      = time.Hour // Intervalle entre deux purges des livraisons terminées
+	// webhookListCacheTTL : durée de conservation de la liste des webhooks consultée à chaque événement.
+	// Les modifications faites par ce processus l'invalident aussitôt ; celles faites par la CLI
+	// sont prises en compte au plus tard après ce délai.
+	webhookListCacheTTL = 30 * time.Second
+)
+
+// WebhookService enregistre les webhooks et leur livre les événements du bus.
+// Chaque événement est d'abord enregistré comme livraison en attente (journal persistant), puis envoyé
+// par le dispatcher avec de nouveaux essais à délai exponentiel ; après maxAttempts échecs, la livraison
+// rejoint la file des lettres mortes et peut être rejouée.
+type WebhookService struct {
+	webhookRepo repository.WebhookRepository
+	client      *http.Client
+	maxAttempts int
+	retryBase   time.Duration
+	retention   time.Duration // Conservation des livraisons terminées (0 : conservées)
+	wake        chan struct{} // Signale au dispatcher qu'une livraison a été enregistrée
+
+	cacheMu        sync.Mutex
+	cachedWebhooks []models.Webhook // Tous les webhooks, pour router les événements sans requête par événement
+	cachedAt       time.Time
+}
+
+// NewWebhookService crée et retourne une nouvelle instance de WebhookService, abonnée aux événements de bus.
+// Les valeurs <= 0 sont remplacées par les valeurs par défaut (8 tentatives, 30s, 10s).
+func NewWebhookService(webhookRepo repository.WebhookRepository, bus *EventBus, maxAttempts int, retryBase, timeout time.Duration) *WebhookService {
+	if maxAttempts <= 0 {
+		maxAttempts = defaultWebhookMaxAttempts
+	}
+	if retryBase <= 0 {
+		retryBase = defaultWebhookRetryBase
+	}
+	if timeout <= 0 {
+		timeout = defaultWebhookTimeout
+	}
+	s := &WebhookService{
+		webhookRepo: webhookRepo,
+		client:      newWebhookClient(timeout),
+		maxAttempts: maxAttempts,
+		retryBase:   retryBase,
+		wake:        make(chan struct{}, 1),
+	}
+	bus.Subscribe(s.enqueue)
+	return s
+}
+
+// SetDeliveryRetention fixe la durée de conservation des livraisons terminées (acquittées ou lettres mortes),
+// purgées périodiquement par le dispatcher. Avec retentionDays <= 0, elles sont conservées.
+func (s *WebhookService) SetDeliveryRetention(retentionDays int) {
+	s.retention = 0
+	if retentionDays > 0 {
+		s.retention = time.Duration(retentionDays) * 24 * time.Hour
+	}
+}
+
+// CreateWebhook enregistre un webhook. events liste les types d'événements souscrits (tous si vide ou "*").
+// click.recorded, émis à chaque clic, n'est couvert par "*" que s'il est aussi listé explicitement.
+// Sans secret fourni, un secret aléatoire est généré ; il n'est retourné qu'à la création.
+func (s *WebhookService) CreateWebhook(owner, rawURL string, events []string, secret string) (*models.Webhook, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("%w: l'URL doit être une URL http(s) absolue", ErrInvalidWebhook)
+	}
+	if !isPublicWebhookHost(u.Hostname()) {
+		return nil, fmt.Errorf("%w: l'hôte '%s' n'est pas une adresse publique", ErrInvalidWebhook, u.Hostname())
+	}
+	subscribed, err := normalizeWebhookEvents(events)
+	if err != nil {
+		return nil, err
+	}
+	if secret == "" {
+		if secret, err = newWebhookSecret(); err != nil {
+			return nil, fmt.Errorf("Echec de la génération du secret: %w", err)
+		}
+	} else if len(secret) < webhookSecretMinLength || len(secret) > 64 {
+		return nil, fmt.Errorf("%w: le secret doit contenir entre %d et 64 caractères", ErrInvalidWebhook, webhookSecretMinLength)
+	}
+
+	webhook := &models.Webhook{
+		Owner:  strings.TrimSpace(owner),
+		URL:    u.String(),
+		Secret: secret,
+		Events: subscribed,
+	}
+	if err := s.webhookRepo.CreateWebhook(webhook); err != nil {
+		return nil, fmt.Errorf("Echec de l'enregistrement du webhook: %w", err)
+	}
+	s.invalidateWebhooks()
+	return webhook, nil
+}
+
+// ListWebhooks retourne les webhooks d'une équipe, ou tous les webhooks si owner est nil.
+func (s *WebhookService) ListWebhooks(owner *string) ([]models.Webhook, error) {
+	webhooks, err := s.webhookRepo.ListWebhooks(owner)
+	if err != nil {
+		return nil, fmt.Errorf("Echec de la récupération des webhooks: %w", err)
+	}
+	return webhooks, nil
+}
+
+// GetWebhook récupère un webhook (gorm.ErrRecordNotFound s'il n'existe pas).
+func (s *WebhookService) GetWebhook(id uint) (*models.Webhook, error) {
+	webhook, err := s.webhookRepo.GetWebhook(id)
+	if err != nil {
+		return nil, fmt.Errorf("Echec de la récupération du webhook %d: %w", id, err)
+	}
+	return webhook, nil
+}
+
+// DeleteWebhook supprime un webhook et son journal de livraisons.
+func (s *WebhookService) DeleteWebhook(id uint) error {
+	if err := s.webhookRepo.DeleteWebhook(id); err != nil {
+		return fmt.Errorf("Echec de la suppression du webhook %d: %w", id, err)
+	}
+	s.invalidateWebhooks()
+	return nil
+}
+
+// ListDeliveries retourne les dernières livraisons d'un webhook, éventuellement filtrées par statut.
+func (s *WebhookService) ListDeliveries(webhookID uint, status string) ([]models.WebhookDelivery, error) {
+	switch status {
+	case "", models.DeliveryPending, models.DeliverySucceeded, models.DeliveryDead:
+	default:
+		return nil, fmt.Errorf("%w: statut de livraison inconnu '%s'", ErrInvalidWebhook, status)
+	}
+	if _, err := s.GetWebhook(webhookID); err != nil {
+		return nil, err
+	}
+	deliveries, err := s.webhookRepo.ListDeliveries(webhookID, status, webhookDeliveryListLimit)
+	if err != nil {
+		return nil, fmt.Errorf("Echec de la récupération des livraisons du webhook %d: %w", webhookID, err)
+	}
+	return deliveries, nil
+}
+
+// ReplayDelivery renvoie une livraison d'un webhook, quel que soit son statut.
+// Elle retourne false si la livraison n'existe pas pour ce webhook.
+func (s *WebhookService) ReplayDelivery(webhookID, deliveryID uint) (bool, error) {
+	n, err := s.webhookRepo.RequeueDeliveries(webhookID, []uint{deliveryID}, time.Now().UTC())
+	if err != nil {
+		return false, fmt.Errorf("Echec de la remise en file de la livraison %d: %w", deliveryID, err)
+	}
+	s.signal()
+	return n > 0, nil
+}
+
+// ReplayDeadLetters remet en file toutes les lettres mortes d'un webhook et retourne leur nombre.
+func (s *WebhookService) ReplayDeadLetters(webhookID uint) (int64, error) {
+	if _, err := s.GetWebhook(webhookID); err != nil {
+		return 0, err
+	}
+	n, err := s.webhookRepo.RequeueDeliveries(webhookID, nil, time.Now().UTC())
+	if err != nil {
+		return 0, fmt.Errorf("Echec de la remise en file des lettres mortes du webhook %d: %w", webhookID, err)
+	}
+	s.signal()
+	return n, nil
+}
+
+// PingWebhook envoie un événement de test (webhook.ping) au seul webhook indiqué.
+func (s *WebhookService) PingWebhook(webhookID uint) (*models.WebhookDelivery, error) {
+	webhook, err := s.GetWebhook(webhookID)
+	if err != nil {
+		return nil, err
+	}
+	event := Event{ID: newEventID(), Type: EventWebhookPing, OccurredAt: time.Now().UTC(), Owner: webhook.Owner,
+		Data: map[string]uint{"webhook_id": webhook.ID}}
+	deliveries, err := s.record(event, []models.Webhook{*webhook})
+	if err != nil {
+		return nil, err
+	}
+	return &deliveries[0], nil
+}
+
+// enqueue enregistre la livraison d'un événement du bus à chaque webhook concerné.
+// L'enregistrement est fait immédiatement : un événement n'est pas perdu si le serveur s'arrête avant l'envoi.
+func (s *WebhookService) enqueue(event Event) {
+	ctx := logging.WithRequestID(context.Background(), event.RequestID)
+	webhooks, err := s.allWebhooks()
+	if err != nil {
+		webhooksLogger.ErrorContext(ctx, "Failed to list webhooks for event", "event", event.Type, logging.Err(err))
+		return
+	}
+	var targets []models.Webhook
+	for _, webhook := range webhooks {
+		if webhookMatches(&webhook, event) {
+			targets = append(targets, webhook)
+		}
+	}
+	if len(targets) == 0 {
+		return
+	}
+	if _, err := s.record(event, targets); err != nil {
+		webhooksLogger.ErrorContext(ctx, "Event lost", "event", event.Type, "event_id", event.ID, logging.Err(err))
+	}
+}
+
+// allWebhooks retourne la liste de tous les webhooks, depuis le cache ou la base.
+func (s *WebhookService) allWebhooks() ([]models.Webhook, error) {
+	s.cacheMu.Lock()
+	defer s.cacheMu.Unlock()
+	if s.cachedWebhooks != nil && time.Since(s.cachedAt) < webhookListCacheTTL {
+		metrics.Caches.Hit("webhooks")
+		return s.cachedWebhooks, nil
+	}
+	metrics.Caches.Miss("webhooks")
+	webhooks, err := s.webhookRepo.ListWebhooks(nil)
+	if err != nil {
+		return nil, err
+	}
+	if webhooks == nil {
+		webhooks = []models.Webhook{}
+	}
+	s.cachedWebhooks, s.cachedAt = webhooks, time.Now()
+	return webhooks, nil
+}
+
+// invalidateWebhooks oublie la liste des webhooks en cache après une modification.
+func (s *WebhookService) invalidateWebhooks() {
+	s.cacheMu.Lock()
+	defer s.cacheMu.Unlock()
+	s.cachedWebhooks = nil
+}
+
+// record enregistre les livraisons d'un événement et réveille le dispatcher.
+func (s *WebhookService) record(event Event, targets []models.Webhook) ([]models.WebhookDelivery, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("Echec de la sérialisation de l'événement: %w", err)
+	}
+	deliveries := make([]models.WebhookDelivery, len(targets))
+	for i, webhook := range targets {
+		deliveries[i] = models.WebhookDelivery{
+			WebhookID:     webhook.ID,
+			EventID:       event.ID,
+			EventType:     event.Type,
+			Payload:       string(payload),
+			Status:        models.DeliveryPending,
+			NextAttemptAt: event.OccurredAt,
+		}
+	}
+	if err := s.webhookRepo.CreateDeliveries(deliveries); err != nil {
+		return nil, fmt.Errorf("Echec de l'enregistrement des livraisons: %w", err)
+	}
+	s.signal()
+	return deliveries, nil
+}
+
+// signal réveille le dispatcher sans bloquer.
+func (s *WebhookService) signal() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Start lance le dispatcher des livraisons. Il est réveillé à chaque nouvel événement, à l'échéance
+// du prochain nouvel essai, et au plus tard toutes les pollInterval (livraisons enregistrées par la CLI).
+// Cette fonction est conçue pour être lancée dans une goroutine séparée.
+func (s *WebhookService) Start(pollInterval time.Duration) {
+	webhooksLogger.Info("Starting webhook dispatcher", "max_attempts", s.maxAttempts)
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	var lastPrune time.Time
+	for {
+		select {
+		case <-timer.C:
+		case <-s.wake:
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+		}
+
+		for s.dispatchDue() == webhookDispatchBatch {
+		}
+		if now := time.Now(); now.Sub(lastPrune) >= webhookPruneInterval {
+			s.pruneDeliveries(now)
+			lastPrune = now
+		}
+
+		wait := pollInterval
+		next, err := s.webhookRepo.NextDeliveryAt()
+		if err != nil {
+			webhooksLogger.Error("Failed to find next delivery", logging.Err(err))
+		} else if next != nil && time.Until(*next) < wait {
+			wait = max(time.Until(*next), 0)
+		}
+		timer.Reset(wait)
+	}
+}
+
+// pruneDeliveries supprime les livraisons terminées plus anciennes que la durée de conservation.
+func (s *WebhookService) pruneDeliveries(now time.Time) {
+	if s.retention == 0 {
+		return
+	}
+	pruned, err := s.webhookRepo.PruneDeliveries(now.Add(-s.retention).UTC())
+	if err != nil {
+		webhooksLogger.Error("Failed to prune webhook deliveries", logging.Err(err))
+		return
+	}
+	if pruned > 0 {
+		webhooksLogger.Info("Webhook deliveries pruned", "count", pruned, "retention_days", int(s.retention.Hours()/24))
+	}
+}
+
+// dispatchDue envoie les livraisons arrivées à échéance et retourne le nombre de tentatives effectuées.
+func (s *WebhookService) dispatchDue() int {
+	due, err := s.webhookRepo.DueDeliveries(time.Now().UTC(), webhookDispatchBatch)
+	if err != nil {
+		webhooksLogger.Error("Failed to list due deliveries", logging.Err(err))
+		return 0
+	}
+
+	webhooks := make(map[uint]*models.Webhook)
+	for _, delivery := range due {
+		if _, ok := webhooks[delivery.WebhookID]; !ok {
+			webhook, err := s.webhookRepo.GetWebhook(delivery.WebhookID)
+			if err != nil {
+				webhooksLogger.Error("Webhook not found for delivery", "webhook_id", delivery.WebhookID, "delivery_id", delivery.ID, logging.Err(err))
+			}
+			webhooks[delivery.WebhookID] = webhook
+		}
+	}
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, webhookDispatchWorkers)
+	attempted := 0
+	for i := range due {
+		webhook := webhooks[due[i].WebhookID]
+		if webhook == nil {
+			continue // Webhook supprimé pendant la passe : ses livraisons l'ont été aussi
+		}
+		attempted++
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(delivery *models.WebhookDelivery) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			s.attempt(webhook, delivery)
+		}(&due[i])
+	}
+	wg.Wait()
+	return attempted
+}
+
+// attempt effectue une tentative de livraison et enregistre son résultat.
+func (s *WebhookService) attempt(webhook *models.Webhook, delivery *models.WebhookDelivery) {
+	status, err := s.send(webhook, delivery)
+	now := time.Now().UTC()
+	delivery.Attempts++
+	delivery.ResponseStatus = status
+
+	switch {
+	case err == nil:
+		delivery.Status = models.DeliverySucceeded
+		delivery.DeliveredAt = &now
+		delivery.LastError = ""
+	case delivery.Attempts >= s.maxAttempts:
+		delivery.Status = models.DeliveryDead
+		delivery.LastError = err.Error()
+		webhooksLogger.Warn("Delivery moved to dead letters", "delivery_id", delivery.ID, "event", delivery.EventType,
+			"attempts", delivery.Attempts, "url", webhook.URL, logging.Err(err))
+	default:
+		delivery.LastError = err.Error()
+		delivery.NextAttemptAt = now.Add(s.retryDelay(delivery.Attempts))
+	}
+
+	if err := s.webhookRepo.UpdateDelivery(delivery); err != nil {
+		webhooksLogger.Error("Failed to save delivery result", "delivery_id", delivery.ID, logging.Err(err))
+	}
+}
+
+// send envoie la charge utile signée d'une livraison. Seule une réponse 2xx vaut acquittement ;
+// le code HTTP reçu est retourné (0 sans réponse).
+func (s *WebhookService) send(webhook *models.Webhook, delivery *models.WebhookDelivery) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, webhook.URL, strings.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "urlshortener-webhooks/1.0")
+	req.Header.Set("X-Webhook-Id", strconv.FormatUint(uint64(webhook.ID), 10))
+	req.Header.Set("X-Webhook-Event", delivery.EventType)
+	req.Header.Set("X-Webhook-Delivery", strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", SignWebhookPayload(webhook.Secret, timestamp, []byte(delivery.Payload)))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	excerpt, _ := io.ReadAll(io.LimitReader(resp.Body, webhookResponseExcerpt))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("réponse HTTP %d: %s", resp.StatusCode, bytes.TrimSpace(excerpt))
+	}
+	return resp.StatusCode, nil
+}
+
+// newWebhookClient crée le client HTTP des livraisons. N'importe quel utilisateur pouvant enregistrer
+// un webhook, et le code et l'erreur de chaque réponse étant consultables, le client ne doit pas servir
+// à sonder le réseau interne : l'adresse de chaque connexion est contrôlée après la résolution DNS,
+// aucun proxy n'est utilisé et les redirections ne sont pas suivies (la réponse 3xx est un échec).
+func newWebhookClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:         pageinfo.PublicDialer(timeout).DialContext,
+			ForceAttemptHTTP2:   true,
+			MaxIdleConns:        20,
+			IdleConnTimeout:     90 * time.Second,
+			TLSHandshakeTimeout: timeout,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// isPublicWebhookHost refuse les hôtes désignant à coup sûr une adresse non publique (IP littérale
+// privée ou locale, localhost) dès l'enregistrement ; les noms d'hôte sont contrôlés à chaque connexion.
+func isPublicWebhookHost(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return false
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return pageinfo.IsPublicIP(ip)
+	}
+	return true
+}
+
+// retryDelay retourne le délai avant la tentative suivant la n-ième : retryBase·2^(n-1),
+// plafonné à webhookRetryMax, plus jusqu'à 10 % d'aléa pour étaler les nouveaux essais.
+func (s *WebhookService) retryDelay(attempts int) time.Duration {
+	delay := webhookRetryMax
+	if shift := attempts - 1; shift < 32 && s.retryBase<<shift < webhookRetryMax {
+		delay = s.retryBase << shift
+	}
+	return delay + mathrand.N(delay/10+1)
+}
+
+// SignWebhookPayload retourne la signature d'une livraison (en-tête X-Webhook-Signature) :
+// "sha256=" suivi du HMAC-SHA256 hexadécimal, avec le secret du webhook, de "<timestamp>.<corps>".
+// Les destinataires la recalculent avec l'en-tête X-Webhook-Timestamp pour authentifier la requête.
+func SignWebhookPayload(secret, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookMatches indique si un webhook doit recevoir un événement : même équipe (ou webhook sans équipe)
+// et type d'événement souscrit. "*" couvre tous les types sauf click.recorded, émis à chaque clic :
+// une livraison par clic doit être demandée explicitement.
+func webhookMatches(webhook *models.Webhook, event Event) bool {
+	if webhook.Owner != "" && webhook.Owner != event.Owner {
+		return false
+	}
+	subscribed := strings.Split(webhook.Events, ",")
+	if slices.Contains(subscribed, event.Type) {
+		return true
+	}
+	return event.Type != EventClickRecorded && slices.Contains(subscribed, "*")
+}
+
+// normalizeWebhookEvents valide les types d'événements souscrits et les retourne sous forme stockée.
+// Avec "*", seul click.recorded est conservé à côté du joker (les autres types sont déjà couverts).
+func normalizeWebhookEvents(events []string) (string, error) {
+	var subscribed []string
+	all := false
+	for _, event := range events {
+		event = strings.TrimSpace(event)
+		switch {
+		case event == "":
+			continue
+		case event == "*":
+			all = true
+		case !slices.Contains(EventTypes, event):
+			return "", fmt.Errorf("%w: événement inconnu '%s' (attendus : %s)", ErrInvalidWebhook, event, strings.Join(EventTypes, ", "))
+		case !slices.Contains(subscribed, event):
+			subscribed = append(subscribed, event)
+		}
+	}
+	if all || len(subscribed) == 0 {
+		if slices.Contains(subscribed, EventClickRecorded) {
+			return "*," + EventClickRecorded, nil
+		}
+		return "*", nil
+	}
+	return strings.Join(subscribed, ","), nil
+}
+
+// newWebhookSecret génère un secret de signature aléatoire (64 caractères hexadécimaux).
+func newWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,375 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/antoine-granier/urlshortener/internal/models"
+	"github.com/antoine-granier/urlshortener/internal/pageinfo"
+	"github.com/antoine-granier/urlshortener/internal/repository"
+	"gorm.io/gorm"
+)
+
+const testWebhookSecret = "0123456789abcdef-secret"
+
+// webhookStandIn est un destinataire de webhooks local qui enregistre les requêtes reçues
+// et répond avec le code configuré.
+type webhookStandIn struct {
+	*httptest.Server
+	status atomic.Int32
+
+	mu       sync.Mutex
+	requests []receivedWebhook
+}
+
+type receivedWebhook struct {
+	header http.Header
+	body   []byte
+}
+
+func newWebhookStandIn(t *testing.T) *webhookStandIn {
+	t.Helper()
+	s := &webhookStandIn{}
+	s.status.Store(http.StatusOK)
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		s.mu.Lock()
+		s.requests = append(s.requests, receivedWebhook{header: r.Header.Clone(), body: body})
+		s.mu.Unlock()
+		w.WriteHeader(int(s.status.Load()))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *webhookStandIn) received() []receivedWebhook {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]receivedWebhook(nil), s.requests...)
+}
+
+// newTestWebhookService crée un WebhookService dont le client accepte la boucle locale, où écoutent
+// les destinataires de test ; la politique de redirection reste celle du service.
+func newTestWebhookService(t *testing.T, db *gorm.DB, bus *EventBus) *WebhookService {
+	t.Helper()
+	svc := NewWebhookService(repository.NewWebhookRepository(db), bus, 3, time.Second, 2*time.Second)
+	svc.client.Transport = http.DefaultTransport
+	return svc
+}
+
+// addTestWebhook enregistre un webhook directement en base : CreateWebhook refuse les adresses
+// de boucle locale des destinataires de test.
+func addTestWebhook(t *testing.T, svc *WebhookService, owner, rawURL string, events ...string) *models.Webhook {
+	t.Helper()
+	subscribed, err := normalizeWebhookEvents(events)
+	if err != nil {
+		t.Fatalf("normalize events: %v", err)
+	}
+	webhook := &models.Webhook{Owner: owner, URL: rawURL, Secret: testWebhookSecret, Events: subscribed}
+	if err := svc.webhookRepo.CreateWebhook(webhook); err != nil {
+		t.Fatalf("create webhook: %v", err)
+	}
+	svc.invalidateWebhooks()
+	return webhook
+}
+
+func getDelivery(t *testing.T, db *gorm.DB, id uint) models.WebhookDelivery {
+	t.Helper()
+	var delivery models.WebhookDelivery
+	if err := db.First(&delivery, id).Error; err != nil {
+		t.Fatalf("load delivery %d: %v", id, err)
+	}
+	return delivery
+}
+
+// makeDue avance l'échéance d'une livraison pour simuler l'écoulement du délai de nouvel essai.
+func makeDue(t *testing.T, db *gorm.DB, id uint) {
+	t.Helper()
+	if err := db.Model(&models.WebhookDelivery{}).Where("id = ?", id).
+		Update("next_attempt_at", time.Now().UTC().Add(-time.Second)).Error; err != nil {
+		t.Fatalf("make delivery %d due: %v", id, err)
+	}
+}
+
+func TestWebhookDeliverySignedLinkDeleted(t *testing.T) {
+	linkSvc, db := newTestLinkService(t)
+	bus := NewEventBus()
+	linkSvc.SetEventBus(bus)
+	webhookSvc := newTestWebhookService(t, db, bus)
+	standIn := newWebhookStandIn(t)
+
+	link, _, err := linkSvc.CreateLinkWithOptions(t.Context(), CreateLinkOptions{LongURL: "https://example.com/page", Owner: "crm"})
+	if err != nil {
+		t.Fatalf("create link: %v", err)
+	}
+	addTestWebhook(t, webhookSvc, "crm", standIn.URL, EventLinkDeleted)
+	if err := db.Create(&models.Click{LinkID: link.ID, Timestamp: time.Now()}).Error; err != nil {
+		t.Fatalf("create click: %v", err)
+	}
+	if _, err := linkSvc.DeleteLink(t.Context(), link.ShortCode); err != nil {
+		t.Fatalf("delete link: %v", err)
+	}
+	if n := webhookSvc.dispatchDue(); n != 1 {
+		t.Fatalf("dispatchDue attempted %d deliveries, want 1", n)
+	}
+
+	received := standIn.received()
+	if len(received) != 1 {
+		t.Fatalf("stand-in received %d requests, want 1", len(received))
+	}
+	req := received[0]
+	mac := hmac.New(sha256.New, []byte(testWebhookSecret))
+	mac.Write([]byte(req.header.Get("X-Webhook-Timestamp") + "."))
+	mac.Write(req.body)
+	if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); req.header.Get("X-Webhook-Signature") != want {
+		t.Errorf("X-Webhook-Signature = %q, want %q", req.header.Get("X-Webhook-Signature"), want)
+	}
+	if got := req.header.Get("X-Webhook-Event"); got != EventLinkDeleted {
+		t.Errorf("X-Webhook-Event = %q, want %q", got, EventLinkDeleted)
+	}
+
+	var event struct {
+		Type string        `json:"type"`
+		Data LinkEventData `json:"data"`
+	}
+	if err := json.Unmarshal(req.body, &event); err != nil {
+		t.Fatalf("decode payload: %v", err)
+	}
+	if event.Type != EventLinkDeleted || event.Data.ShortCode != link.ShortCode {
+		t.Errorf("payload = %+v, want link.deleted for %s", event, link.ShortCode)
+	}
+
+	if _, err := linkSvc.GetLinkByShortCode(link.ShortCode); err == nil {
+		t.Error("link still exists after DeleteLink")
+	}
+	var clicks int64
+	db.Model(&models.Click{}).Where("link_id = ?", link.ID).Count(&clicks)
+	if clicks != 0 {
+		t.Errorf("%d clicks left after DeleteLink", clicks)
+	}
+}
+
+func TestWebhookRetriesDeadLetterAndReplay(t *testing.T) {
+	db := newTestDB(t)
+	webhookSvc := newTestWebhookService(t, db, NewEventBus())
+	standIn := newWebhookStandIn(t)
+	standIn.status.Store(http.StatusInternalServerError)
+
+	webhook := addTestWebhook(t, webhookSvc, "", standIn.URL)
+	ping, err := webhookSvc.PingWebhook(webhook.ID)
+	if err != nil {
+		t.Fatalf("ping webhook: %v", err)
+	}
+
+	// Nouveaux essais à délai exponentiel : 1s puis 2s (plus 10 % d'aléa au plus).
+	for attempt, base := range []time.Duration{time.Second, 2 * time.Second} {
+		before := time.Now().UTC()
+		webhookSvc.dispatchDue()
+		delivery := getDelivery(t, db, ping.ID)
+		if delivery.Status != models.DeliveryPending || delivery.Attempts != attempt+1 {
+			t.Fatalf("after attempt %d: status %s, attempts %d", attempt+1, delivery.Status, delivery.Attempts)
+		}
+		if delivery.ResponseStatus != http.StatusInternalServerError || delivery.LastError == "" {
+			t.Errorf("after attempt %d: response %d, last error %q", attempt+1, delivery.ResponseStatus, delivery.LastError)
+		}
+		delay := delivery.NextAttemptAt.Sub(before)
+		if delay < base || delay > base+base/10+time.Second {
+			t.Errorf("after attempt %d: next attempt in %v, want about %v", attempt+1, delay, base)
+		}
+		if n := webhookSvc.dispatchDue(); n != 0 {
+			t.Fatalf("retry sent %d deliveries before its delay", n)
+		}
+		makeDue(t, db, ping.ID)
+	}
+
+	// Troisième échec : maxAttempts atteint, la livraison rejoint les lettres mortes.
+	webhookSvc.dispatchDue()
+	if delivery := getDelivery(t, db, ping.ID); delivery.Status != models.DeliveryDead || delivery.Attempts != 3 {
+		t.Fatalf("after max attempts: status %s, attempts %d, want dead after 3", delivery.Status, delivery.Attempts)
+	}
+	makeDue(t, db, ping.ID)
+	if n := webhookSvc.dispatchDue(); n != 0 {
+		t.Fatalf("dead letter was sent again (%d attempts)", n)
+	}
+
+	// Rejeu des lettres mortes une fois le destinataire rétabli.
+	standIn.status.Store(http.StatusNoContent)
+	replayed, err := webhookSvc.ReplayDeadLetters(webhook.ID)
+	if err != nil || replayed != 1 {
+		t.Fatalf("ReplayDeadLetters = %d, %v, want 1", replayed, err)
+	}
+	webhookSvc.dispatchDue()
+	delivery := getDelivery(t, db, ping.ID)
+	if delivery.Status != models.DeliverySucceeded || delivery.Attempts != 1 || delivery.DeliveredAt == nil {
+		t.Fatalf("after replay: status %s, attempts %d", delivery.Status, delivery.Attempts)
+	}
+
+	received := standIn.received()
+	if len(received) != 4 {
+		t.Fatalf("stand-in received %d requests, want 4", len(received))
+	}
+	for _, req := range received[1:] {
+		if string(req.body) != string(received[0].body) {
+			t.Error("retried payload differs from the first attempt")
+		}
+	}
+}
+
+func TestWebhookRetryDelayIsCapped(t *testing.T) {
+	svc := &WebhookService{retryBase: 30 * time.Second}
+	for attempts, want := range map[int]time.Duration{1: 30 * time.Second, 2: time.Minute, 4: 4 * time.Minute, 40: webhookRetryMax} {
+		got := svc.retryDelay(attempts)
+		if got < want || got > want+want/10 {
+			t.Errorf("retryDelay(%d) = %v, want %v (+10%%)", attempts, got, want)
+		}
+	}
+}
+
+func TestCreateWebhookRejectsNonPublicHosts(t *testing.T) {
+	webhookSvc := newTestWebhookService(t, newTestDB(t), NewEventBus())
+	for _, rawURL := range []string{
+		"http://127.0.0.1:8080/admin",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://10.0.0.5/hook",
+		"http://192.168.1.1/hook",
+		"http://[::1]:9090/hook",
+		"http://[fd00::1]/hook",
+		"http://0.0.0.0/hook",
+		"http://localhost:8080/hook",
+		"http://api.localhost./hook",
+	} {
+		if _, err := webhookSvc.CreateWebhook("", rawURL, nil, ""); !errors.Is(err, ErrInvalidWebhook) {
+			t.Errorf("CreateWebhook(%s) error = %v, want ErrInvalidWebhook", rawURL, err)
+		}
+	}
+	if _, err := webhookSvc.CreateWebhook("", "https://hooks.example.com/in", nil, ""); err != nil {
+		t.Errorf("CreateWebhook(public host) error = %v", err)
+	}
+}
+
+func TestWebhookDeliveryRefusesNonPublicAddresses(t *testing.T) {
+	db := newTestDB(t)
+	webhookSvc := NewWebhookService(repository.NewWebhookRepository(db), NewEventBus(), 3, time.Second, 2*time.Second)
+	standIn := newWebhookStandIn(t)
+
+	// Un nom d'hôte se résolvant vers la boucle locale est refusé à la connexion.
+	_, port, _ := net.SplitHostPort(standIn.Listener.Addr().String())
+	for _, rawURL := range []string{standIn.URL, "http://localhost:" + port} {
+		webhook := addTestWebhook(t, webhookSvc, "", rawURL)
+		ping, err := webhookSvc.PingWebhook(webhook.ID)
+		if err != nil {
+			t.Fatalf("ping webhook: %v", err)
+		}
+		webhookSvc.dispatchDue()
+		delivery := getDelivery(t, db, ping.ID)
+		if delivery.Status != models.DeliveryPending || delivery.ResponseStatus != 0 ||
+			!strings.Contains(delivery.LastError, pageinfo.ErrBlockedAddress.Error()) {
+			t.Errorf("%s: status %s, response %d, last error %q; want a blocked address",
+				rawURL, delivery.Status, delivery.ResponseStatus, delivery.LastError)
+		}
+	}
+	if n := len(standIn.received()); n != 0 {
+		t.Errorf("stand-in received %d requests, want 0", n)
+	}
+}
+
+func TestWebhookDoesNotFollowRedirects(t *testing.T) {
+	db := newTestDB(t)
+	webhookSvc := newTestWebhookService(t, db, NewEventBus())
+	target := newWebhookStandIn(t)
+	redirector := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusTemporaryRedirect))
+	t.Cleanup(redirector.Close)
+
+	webhook := addTestWebhook(t, webhookSvc, "", redirector.URL)
+	ping, err := webhookSvc.PingWebhook(webhook.ID)
+	if err != nil {
+		t.Fatalf("ping webhook: %v", err)
+	}
+	webhookSvc.dispatchDue()
+	if delivery := getDelivery(t, db, ping.ID); delivery.Status != models.DeliveryPending ||
+		delivery.ResponseStatus != http.StatusTemporaryRedirect {
+		t.Errorf("status %s, response %d; want a failed attempt with %d", delivery.Status, delivery.ResponseStatus,
+			http.StatusTemporaryRedirect)
+	}
+	if n := len(target.received()); n != 0 {
+		t.Errorf("redirect target received %d requests, want 0", n)
+	}
+}
+
+func TestWebhookWildcardExcludesClickRecorded(t *testing.T) {
+	for _, tc := range []struct {
+		events []string
+		stored string
+		clicks bool
+	}{
+		{nil, "*", false},
+		{[]string{"*"}, "*", false},
+		{[]string{"*", EventClickRecorded}, "*," + EventClickRecorded, true},
+		{[]string{EventClickRecorded, "*", EventLinkCreated}, "*," + EventClickRecorded, true},
+		{[]string{EventClickRecorded}, EventClickRecorded, true},
+		{[]string{EventLinkCreated}, EventLinkCreated, false},
+	} {
+		stored, err := normalizeWebhookEvents(tc.events)
+		if err != nil || stored != tc.stored {
+			t.Errorf("normalizeWebhookEvents(%v) = %q, %v; want %q", tc.events, stored, err, tc.stored)
+			continue
+		}
+		webhook := &models.Webhook{Events: stored}
+		if got := webhookMatches(webhook, Event{Type: EventClickRecorded}); got != tc.clicks {
+			t.Errorf("%q receives click.recorded = %v, want %v", stored, got, tc.clicks)
+		}
+		wantCreated := stored != EventClickRecorded
+		if got := webhookMatches(webhook, Event{Type: EventLinkCreated}); got != wantCreated {
+			t.Errorf("%q receives link.created = %v, want %v", stored, got, wantCreated)
+		}
+	}
+}
+
+func TestWebhookPrunesFinishedDeliveries(t *testing.T) {
+	db := newTestDB(t)
+	webhookSvc := newTestWebhookService(t, db, NewEventBus())
+	webhook := addTestWebhook(t, webhookSvc, "", "https://hooks.example.com/in")
+	now := time.Now().UTC()
+
+	deliveries := []models.WebhookDelivery{
+		{Status: models.DeliverySucceeded}, // Ancienne, supprimée
+		{Status: models.DeliveryDead},      // Ancienne, supprimée
+		{Status: models.DeliveryPending},   // Ancienne mais en attente, conservée
+		{Status: models.DeliverySucceeded}, // Récente, conservée
+	}
+	for i := range deliveries {
+		deliveries[i].WebhookID, deliveries[i].EventID, deliveries[i].EventType = webhook.ID, newEventID(), EventLinkCreated
+		deliveries[i].Payload, deliveries[i].NextAttemptAt = "{}", now
+	}
+	if err := webhookSvc.webhookRepo.CreateDeliveries(deliveries); err != nil {
+		t.Fatalf("create deliveries: %v", err)
+	}
+	old := []uint{deliveries[0].ID, deliveries[1].ID, deliveries[2].ID}
+	if err := db.Model(&models.WebhookDelivery{}).Where("id IN ?", old).
+		UpdateColumn("updated_at", now.Add(-8*24*time.Hour)).Error; err != nil {
+		t.Fatalf("age deliveries: %v", err)
+	}
+
+	webhookSvc.pruneDeliveries(now) // Sans durée de conservation : rien n'est supprimé
+	webhookSvc.SetDeliveryRetention(7)
+	webhookSvc.pruneDeliveries(now)
+
+	var kept []uint
+	db.Model(&models.WebhookDelivery{}).Order("id").Pluck("id", &kept)
+	if want := []uint{deliveries[2].ID, deliveries[3].ID}; !slices.Equal(kept, want) {
+		t.Errorf("deliveries kept = %v, want %v", kept, want)
+	}
+}
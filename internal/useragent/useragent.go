@@ -0,0 +1,107 @@
+package useragent
+
+import "strings"
+
+// Systèmes d'exploitation reconnus.
+const (
+	OSiOS      = "ios"
+	OSAndroid  = "android"
+	OSWindows  = "windows"
+	OSMacOS    = "macos"
+	OSLinux    = "linux"
+	OSChromeOS = "chromeos"
+	OSOther    = "other"
+)
+
+// Types d'appareils reconnus.
+const (
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceDesktop = "desktop"
+	DeviceBot     = "bot"
+	DeviceOther   = "other"
+)
+
+// Info est le résultat de l'analyse d'un en-tête User-Agent.
+type Info struct {
+	OS      string
+	Device  string
+	Browser string
+}
+
+// botMarkers sont des fragments caractéristiques des User-Agents de robots.
+var botMarkers = []string{"bot", "crawler", "spider", "slurp", "curl/", "wget/", "python-requests", "headless"}
+
+// socialCrawlerMarkers sont des fragments des User-Agents des robots qui construisent les aperçus
+// de liens des réseaux sociaux et messageries (cartes Open Graph / Twitter).
+var socialCrawlerMarkers = []string{
+	"facebookexternalhit", "facebot", "twitterbot", "linkedinbot", "slackbot", "slack-imgproxy",
+	"discordbot", "telegrambot", "whatsapp", "pinterest", "redditbot", "embedly", "skypeuripreview",
+	"vkshare", "iframely", "mastodon", "bluesky", "google-pagerenderer", "snapchat", "viber", "line-poker",
+}
+
+// IsSocialCrawler indique si le User-Agent est celui d'un robot d'aperçu de liens d'un réseau social.
+func IsSocialCrawler(ua string) bool {
+	lower := strings.ToLower(ua)
+	for _, marker := range socialCrawlerMarkers {
+		if strings.Contains(lower, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// Parse analyse un User-Agent de façon heuristique (sans base de signatures exhaustive).
+// Une chaîne vide ou inconnue donne OS "other" et appareil "other".
+func Parse(ua string) Info {
+	lower := strings.ToLower(ua)
+	info := Info{OS: OSOther, Device: DeviceOther, Browser: parseBrowser(lower)}
+
+	switch {
+	case strings.Contains(lower, "iphone") || strings.Contains(lower, "ipod"):
+		info.OS, info.Device = OSiOS, DeviceMobile
+	case strings.Contains(lower, "ipad"):
+		info.OS, info.Device = OSiOS, DeviceTablet
+	case strings.Contains(lower, "android"):
+		info.OS, info.Device = OSAndroid, DeviceTablet
+		if strings.Contains(lower, "mobile") {
+			info.Device = DeviceMobile
+		}
+	case strings.Contains(lower, "windows phone"):
+		info.OS, info.Device = OSWindows, DeviceMobile
+	case strings.Contains(lower, "windows"):
+		info.OS, info.Device = OSWindows, DeviceDesktop
+	case strings.Contains(lower, "macintosh") || strings.Contains(lower, "mac os x"):
+		info.OS, info.Device = OSMacOS, DeviceDesktop
+	case strings.Contains(lower, "cros"):
+		info.OS, info.Device = OSChromeOS, DeviceDesktop
+	case strings.Contains(lower, "linux") || strings.Contains(lower, "x11"):
+		info.OS, info.Device = OSLinux, DeviceDesktop
+	}
+
+	for _, marker := range botMarkers {
+		if strings.Contains(lower, marker) {
+			info.Device = DeviceBot
+			break
+		}
+	}
+	return info
+}
+
+// parseBrowser identifie la famille de navigateur. L'ordre compte : Edge et Opera
+// s'annoncent aussi comme Chrome, et Chrome comme Safari.
+func parseBrowser(lower string) string {
+	switch {
+	case strings.Contains(lower, "edg/") || strings.Contains(lower, "edga/") || strings.Contains(lower, "edgios/"):
+		return "edge"
+	case strings.Contains(lower, "opr/") || strings.Contains(lower, "opera"):
+		return "opera"
+	case strings.Contains(lower, "firefox/") || strings.Contains(lower, "fxios/"):
+		return "firefox"
+	case strings.Contains(lower, "chrome/") || strings.Contains(lower, "crios/"):
+		return "chrome"
+	case strings.Contains(lower, "safari/"):
+		return "safari"
+	}
+	return "other"
+}
@@ -1,41 +1,128 @@
 package workers
 
 import (
-	"log"
+	"context"
+	"time"
 
+	"github.com/antoine-granier/urlshortener/internal/logging"
+	"github.com/antoine-granier/urlshortener/internal/metrics"
 	"github.com/antoine-granier/urlshortener/internal/models"
 	"github.com/antoine-granier/urlshortener/internal/repository" // Nécessaire pour interagir avec le ClickRepository
+	"github.com/antoine-granier/urlshortener/internal/services"
 )
 
+// logger journalise l'enregistrement des clics.
+var logger = logging.For("clicks")
+
 // StartClickWorkers lance un pool de goroutines "workers" pour traiter les événements de clic.
-// Chaque worker lira depuis le même 'clickEventsChan' et utilisera le 'clickRepo' pour la persistance.
-func StartClickWorkers(workerCount int, clickEventsChan <-chan models.ClickEvent, clickRepo repository.ClickRepository) {
-	log.Printf("Starting %d click worker(s)...", workerCount)
+// Chaque worker lira depuis le même 'clickEventsChan' et utilisera le 'clickRepo' pour la persistance,
+// par lots d'au plus 'batchSize' clics (les événements déjà en attente dans le channel).
+// Les clics enregistrés sont aussi comptés par 'visitors' pour l'estimation des visiteurs uniques (optionnel),
+// après application de la politique de confidentialité de 'privacy' (optionnelle), puis diffusés
+// aux abonnés du suivi en temps réel de 'live' (optionnel) et émis sur 'events' (optionnel, webhooks).
+func StartClickWorkers(workerCount, batchSize int, clickEventsChan <-chan models.ClickEvent, clickRepo repository.ClickRepository,
+	visitors *services.VisitorCounter, privacy *services.PrivacyService, live *services.ClickStream, events *services.EventBus) {
+	batchSize = max(batchSize, 1)
+	logger.Info("Starting click workers", "count", workerCount, "batch_size", batchSize)
 	for i := 0; i < workerCount; i++ {
-		go clickWorker(clickEventsChan, clickRepo)
+		go clickWorker(clickEventsChan, batchSize, clickRepo, visitors, privacy, live, events)
 	}
 }
 
 // clickWorker est la fonction exécutée par chaque goroutine worker.
 // Elle tourne indéfiniment, lisant les événements de clic dès qu'ils sont disponibles dans le channel.
-func clickWorker(clickEventsChan <-chan models.ClickEvent, clickRepo repository.ClickRepository) {
+// Les événements déjà en attente rejoignent le lot du premier, sans attendre les suivants :
+// un clic isolé est enregistré immédiatement, et les lots grossissent d'eux-mêmes sous la charge.
+func clickWorker(clickEventsChan <-chan models.ClickEvent, batchSize int, clickRepo repository.ClickRepository,
+	visitors *services.VisitorCounter, privacy *services.PrivacyService, live *services.ClickStream, events *services.EventBus) {
+	batch := make([]models.ClickEvent, 0, batchSize)
 	for event := range clickEventsChan { // Boucle qui lit les événements du channel
+		batch = append(batch[:0], event)
+	pending:
+		for len(batch) < batchSize {
+			select {
+			case next, ok := <-clickEventsChan:
+				if !ok {
+					break pending
+				}
+				batch = append(batch, next)
+			default:
+				break pending
+			}
+		}
+		recordClicks(batch, clickRepo, visitors, privacy, live, events)
+	}
+}
+
+// recordClicks enregistre un lot de clics en une requête. Si le lot échoue, chaque clic est
+// enregistré individuellement, pour qu'un clic invalide n'entraîne pas la perte des autres.
+func recordClicks(batch []models.ClickEvent, clickRepo repository.ClickRepository,
+	visitors *services.VisitorCounter, privacy *services.PrivacyService, live *services.ClickStream, events *services.EventBus) {
+	clicks := make([]*models.Click, len(batch))
+	for i, event := range batch {
 		// TODO 1: Convertir le 'ClickEvent' (reçu du channel) en un modèle 'models.Click'.
-		click := &models.Click{
-			LinkID: event.LinkID,
-			// CreatedAt sera géré automatiquement par GORM si laissé à zéro
+		clicks[i] = &models.Click{
+			LinkID:    event.LinkID,
+			Timestamp: event.Timestamp,
+			UserAgent: event.UserAgent,
+			IPAddress: event.IPAddress,
+			Source:    event.Source,
+			Variant:   event.Variant,
+			Rule:      event.Rule,
+			Country:   event.Country,
+			Region:    event.Region,
+
+			Bot:         event.Bot,
+			BotCategory: event.BotCategory,
+			BotReason:   event.BotReason,
+			DoNotTrack:  event.DoNotTrack,
+			ClickID:     event.ClickID,
 		}
+		// Anonymisation de l'IP, ou réduction à un décompte anonyme pour les visiteurs Do-Not-Track
+		privacy.Scrub(clicks[i])
+	}
 
-		// TODO 2: Persister le clic en base de données via le 'clickRepo'.
-		if err := clickRepo.CreateClick(click); err != nil {
-			// En cas d'erreur, on logge l'échec
-			log.Printf(
-				"ERROR: Failed to save click for LinkID %d (UserAgent: %s, IP: %s): %v",
-				event.LinkID, event.UserAgent, event.IPAddress, err,
-			)
-		} else {
-			// Log optionnel pour confirmer l'enregistrement
-			log.Printf("Click recorded successfully for LinkID %d", event.LinkID)
+	// TODO 2: Persister les clics en base de données via le 'clickRepo'.
+	start := time.Now()
+	err := clickRepo.CreateClicks(clicks)
+	metrics.ClickBatchDuration.Observe(time.Since(start).Seconds(), insertResult(err))
+	metrics.ClickBatchSize.Observe(float64(len(clicks)))
+	if err != nil {
+		logger.Warn("Failed to save click batch, saving clicks one by one", "clicks", len(clicks), logging.Err(err))
+	}
+
+	for i, click := range clicks {
+		event := batch[i]
+		// Les logs reprennent l'identifiant de la requête de redirection
+		ctx := logging.WithRequestID(context.Background(), event.RequestID)
+		if err != nil {
+			start := time.Now()
+			insertErr := clickRepo.CreateClick(click)
+			metrics.ClickInsertDuration.Observe(time.Since(start).Seconds(), insertResult(insertErr))
+			if insertErr != nil {
+				// En cas d'erreur, on logge l'échec
+				logger.ErrorContext(ctx, "Failed to save click", "link_id", click.LinkID, logging.Err(insertErr))
+				continue
+			}
+		}
+		// Un message par clic : en debug uniquement
+		logger.DebugContext(ctx, "Click recorded", "link_id", event.LinkID)
+		// Les robots ne sont pas des visiteurs : ils n'entrent pas dans les visiteurs uniques,
+		// pas plus que les visiteurs qui ont demandé à ne pas être suivis.
+		if !click.Bot && !click.DoNotTrack {
+			visitors.Observe(event.LinkID, event.Timestamp, event.IPAddress, event.UserAgent)
 		}
+		recorded := services.NewLiveClick(click, event.ShortCode, event.Owner)
+		live.Publish(recorded)
+		// Les abonnés (paliers de clics, webhooks) sont appelés hors du worker
+		events.EmitAsync(services.EventClickRecorded, event.Owner, recorded)
+	}
+}
+
+// insertResult retourne le label de résultat d'un enregistrement de clic pour les métriques.
+func insertResult(err error) string {
+	if err != nil {
+		return "error"
 	}
+	return "ok"
 }
@@ -0,0 +1,72 @@
+package workers
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/antoine-granier/urlshortener/internal/models"
+	"github.com/antoine-granier/urlshortener/internal/repository"
+)
+
+// recordingClickRepo enregistre en mémoire les lots et les clics reçus ; les autres méthodes
+// de repository.ClickRepository ne sont pas utilisées par les workers.
+type recordingClickRepo struct {
+	repository.ClickRepository
+	failBatches bool
+	batches     []int
+	saved       []uint
+}
+
+func (r *recordingClickRepo) CreateClicks(clicks []*models.Click) error {
+	r.batches = append(r.batches, len(clicks))
+	if r.failBatches {
+		return errors.New("batch rejected")
+	}
+	for _, click := range clicks {
+		r.saved = append(r.saved, click.LinkID)
+	}
+	return nil
+}
+
+func (r *recordingClickRepo) CreateClick(click *models.Click) error {
+	if click.LinkID == 0 {
+		return errors.New("missing link")
+	}
+	r.saved = append(r.saved, click.LinkID)
+	return nil
+}
+
+func queuedClicks(linkIDs ...uint) chan models.ClickEvent {
+	ch := make(chan models.ClickEvent, len(linkIDs))
+	for _, id := range linkIDs {
+		ch <- models.ClickEvent{LinkID: id}
+	}
+	close(ch)
+	return ch
+}
+
+func TestClickWorkerBatchesPendingEvents(t *testing.T) {
+	repo := &recordingClickRepo{}
+	clickWorker(queuedClicks(1, 2, 3, 4, 5, 6, 7, 8, 9, 10), 4, repo, nil, nil, nil, nil)
+
+	if want := []int{4, 4, 2}; !slices.Equal(repo.batches, want) {
+		t.Errorf("batch sizes = %v, want %v", repo.batches, want)
+	}
+	if want := []uint{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}; !slices.Equal(repo.saved, want) {
+		t.Errorf("saved clicks = %v, want %v", repo.saved, want)
+	}
+}
+
+func TestClickWorkerFallsBackToSingleInserts(t *testing.T) {
+	repo := &recordingClickRepo{failBatches: true}
+	clickWorker(queuedClicks(1, 0, 3), 10, repo, nil, nil, nil, nil)
+
+	if want := []int{3}; !slices.Equal(repo.batches, want) {
+		t.Errorf("batch sizes = %v, want %v", repo.batches, want)
+	}
+	// Le clic invalide est perdu seul, les autres sont enregistrés.
+	if want := []uint{1, 3}; !slices.Equal(repo.saved, want) {
+		t.Errorf("saved clicks = %v, want %v", repo.saved, want)
+	}
+}